@@ -14,10 +14,16 @@ type (
 		Database `yaml:"database"`
 		Log      `yaml:"log"`
 
-		Email  `yaml:"email"`
-		S3Data `yaml:"s3"`
+		Email   `yaml:"email"`
+		S3Data  `yaml:"s3"`
+		Todo    `yaml:"todo"`
+		Rewards `yaml:"rewards"`
 	}
 
+	// HTTP - настройки API. Присутствие пользователей в чатах и подписки на изменения покупок хранятся
+	// в памяти процесса, поэтому API запускается в одном экземпляре: при нескольких репликах пользователь,
+	// подключенный к другой, считается офлайн и получает пуш-уведомления о сообщениях, которые уже видит,
+	// а изменения покупок, сделанные через другую реплику, до него не доходят
 	HTTP struct {
 		Port        string        `env-required:"true" yaml:"port" env:"SERVER_PORT"`
 		Address     string        `env-required:"true" yaml:"address" env:"SERVER_ADDRESS"`
@@ -41,6 +47,28 @@ type (
 		Addr      string `env-required:"true"  env:"SMTP_ADDR"`
 	}
 
+	Todo struct {
+		// RecurrenceInterval - период запуска генератора повторяющихся заданий
+		RecurrenceInterval time.Duration `yaml:"recurrence_interval" env:"TODO_RECURRENCE_INTERVAL" env-default:"5m"`
+		// ReminderInterval - период запуска планировщика напоминаний о сроках и просроченных заданий
+		ReminderInterval time.Duration `yaml:"reminder_interval" env:"TODO_REMINDER_INTERVAL" env-default:"1m"`
+		// ReminderOffsets - за сколько до срока напоминать исполнителю, например "24h,1h"
+		ReminderOffsets []time.Duration `yaml:"reminder_offsets" env:"TODO_REMINDER_OFFSETS" env-default:"24h,1h"`
+		// ArchiveInterval - период запуска автоархивации выполненных заданий и очистки удаленных
+		ArchiveInterval time.Duration `yaml:"archive_interval" env:"TODO_ARCHIVE_INTERVAL" env-default:"1h"`
+		// AutoArchiveAfter - через сколько после одобрения задание попадает в архив, 0 - не архивировать
+		AutoArchiveAfter time.Duration `yaml:"auto_archive_after" env:"TODO_AUTO_ARCHIVE_AFTER" env-default:"168h"`
+		// DeleteUndoWindow - сколько удаленное задание можно восстановить
+		DeleteUndoWindow time.Duration `yaml:"delete_undo_window" env:"TODO_DELETE_UNDO_WINDOW" env-default:"30s"`
+	}
+
+	Rewards struct {
+		// LeaderboardTTL - сколько хранится таблица лидеров семьи, 0 - не кэшировать
+		LeaderboardTTL time.Duration `yaml:"leaderboard_ttl" env:"REWARDS_LEADERBOARD_TTL" env-default:"1m"`
+		// DigestInterval - период проверки, всем ли семьям отправлены итоги прошедшей недели
+		DigestInterval time.Duration `yaml:"digest_interval" env:"REWARDS_DIGEST_INTERVAL" env-default:"1h"`
+	}
+
 	S3Data struct {
 		BucketName       string `env-required:"true"  env:"BUCKET_NAME"`
 		Region           string `env-required:"true"  env:"REGION"`
@@ -55,6 +55,12 @@ func Run(configPath string) {
 	//services
 	services := service.NewServices(ctx, dependencies)
 
+	//background jobs
+	go services.TodoItem.RunRecurrenceGenerator(ctx, log, cfg.Todo.RecurrenceInterval)
+	go services.TodoItem.RunDeadlineScheduler(ctx, log, cfg.Todo.ReminderInterval, cfg.Todo.ReminderOffsets)
+	go services.TodoItem.RunArchiveScheduler(ctx, log, cfg.Todo.ArchiveInterval, cfg.Todo.AutoArchiveAfter)
+	go services.Rewards.RunWeeklyDigest(ctx, log, cfg.Rewards.DigestInterval)
+
 	//handlers
 	log.Info("Initializing handlers and routes...")
 
@@ -0,0 +1,86 @@
+package entity
+
+import "time"
+
+// Показатели, по которым правила выдают достижения
+const (
+	// AchievementEventTodoApproved - число одобренных заданий исполнителя
+	AchievementEventTodoApproved = "TodoApproved"
+	// AchievementEventTodoStreak - число дней подряд, в которые у исполнителя одобрялись задания
+	AchievementEventTodoStreak = "TodoStreak"
+	// AchievementEventWishFulfilled - число исполненных пользователем желаний из вишлистов
+	AchievementEventWishFulfilled = "WishFulfilled"
+	// AchievementEventRewardRedeemed - число обменов баллов на награды
+	AchievementEventRewardRedeemed = "RewardRedeemed"
+)
+
+// AchievementRule - правило выдачи достижения. Пустой FamilyID у правила по умолчанию
+type AchievementRule struct {
+	ID          string `json:"id"`
+	FamilyID    string `json:"family_id,omitempty"`
+	Code        string `json:"code"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Event       string `json:"event"`
+	// Threshold - значение показателя Event, с которого выдается достижение
+	Threshold   int       `json:"threshold"`
+	BonusPoints int       `json:"bonus_points"`
+	Enabled     bool      `json:"enabled"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserAchievement - полученное пользователем достижение
+type UserAchievement struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Code        string    `json:"code"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	BonusPoints int       `json:"bonus_points"`
+	AwardedAt   time.Time `json:"awarded_at"`
+}
+
+// AchievementStats - текущие значения показателей пользователя
+type AchievementStats struct {
+	TodosApproved   int `json:"todos_approved"`
+	StreakDays      int `json:"streak_days"`
+	WishesFulfilled int `json:"wishes_fulfilled"`
+	RewardsRedeemed int `json:"rewards_redeemed"`
+}
+
+// Value возвращает значение показателя event
+func (s AchievementStats) Value(event string) int {
+	switch event {
+	case AchievementEventTodoApproved:
+		return s.TodosApproved
+	case AchievementEventTodoStreak:
+		return s.StreakDays
+	case AchievementEventWishFulfilled:
+		return s.WishesFulfilled
+	case AchievementEventRewardRedeemed:
+		return s.RewardsRedeemed
+	default:
+		return 0
+	}
+}
+
+// AchievementProgress - достижение в списке пользователя: полученное или прогресс к нему
+type AchievementProgress struct {
+	Code        string `json:"code"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Event       string `json:"event,omitempty"`
+	Threshold   int    `json:"threshold,omitempty"`
+	BonusPoints int    `json:"bonus_points"`
+	// Current - значение показателя, не больше Threshold
+	Current   int        `json:"current"`
+	Earned    bool       `json:"earned"`
+	AwardedAt *time.Time `json:"awarded_at,omitempty"`
+}
+
+// UserAchievements - достижения пользователя: сначала полученные, затем доступные по правилам семьи
+type UserAchievements struct {
+	UserID string                `json:"user_id"`
+	Stats  AchievementStats      `json:"stats"`
+	Items  []AchievementProgress `json:"items"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// AllowanceSettings - перевод баллов в карманные деньги в семье
+type AllowanceSettings struct {
+	FamilyID string `json:"family_id"`
+	Enabled  bool   `json:"enabled"`
+	// MinorPerPoint - стоимость балла в минимальных единицах валюты (копейках, центах)
+	MinorPerPoint int       `json:"minor_per_point"`
+	Currency      string    `json:"currency"`
+	UpdatedBy     string    `json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// AllowancePayout - начисление карманных денег одному члену семьи за неделю
+type AllowancePayout struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Points - баллы, заработанные за неделю за задания
+	Points int `json:"points"`
+	// AmountMinor - сумма к выплате в минимальных единицах валюты
+	AmountMinor int `json:"amount_minor"`
+}
+
+// AllowanceReport - отчет о карманных деньгах семьи за неделю [WeekStart, WeekEnd)
+type AllowanceReport struct {
+	WeekStart     time.Time         `json:"week_start"`
+	WeekEnd       time.Time         `json:"week_end"`
+	Currency      string            `json:"currency"`
+	MinorPerPoint int               `json:"minor_per_point"`
+	Payouts       []AllowancePayout `json:"payouts"`
+	TotalMinor    int               `json:"total_minor"`
+}
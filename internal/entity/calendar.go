@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// Типы событий календаря
+const (
+	CalendarEventTodo     = "todo"
+	CalendarEventBirthday = "birthday"
+	CalendarEventAbsence  = "absence"
+)
+
+// CalendarEvent - датированное событие семьи: срок задания, день рождения или отсутствие участника
+type CalendarEvent struct {
+	// UID - постоянный идентификатор события, не меняется между выгрузками календаря
+	UID         string `json:"uid"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// StartsAt - начало события. Для событий на весь день (AllDay) значима только дата
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	AllDay   bool       `json:"all_day"`
+	// SourceID - задание, пользователь или отсутствие, из которого получено событие
+	SourceID string `json:"source_id"`
+	// UserID - участник, к которому относится событие: исполнитель задания, именинник, отсутствующий
+	UserID string `json:"user_id,omitempty"`
+	// Status - статус задания для событий типа todo
+	Status string `json:"status,omitempty"`
+}
+
+// CalendarSubscription - ссылка на подписку на календарь в формате iCalendar
+type CalendarSubscription struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
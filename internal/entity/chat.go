@@ -1,12 +1,22 @@
 package entity
 
 import (
+	"database/sql"
 	"time"
 )
 
+const (
+	ChatTypeGroup  = "Group"
+	ChatTypeFamily = "Family"
+	ChatTypeDirect = "Direct"
+)
+
 type Chat struct {
 	ID           string            `json:"id" db:"id"`
 	Name         string            `json:"name" db:"name"`
+	Type         string            `json:"type" db:"type"`
+	FamilyID     sql.NullString    `json:"family_id" db:"family_id" swaggerignore:"true"`
+	IsLocked     bool              `json:"is_locked" db:"is_locked"`
 	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
 	Participants []ChatParticipant `json:"participants" db:"participants"`
 	LastMessage  Message           `json:"last_message,omitempty"`
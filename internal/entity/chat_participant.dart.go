@@ -1,10 +1,20 @@
 package entity
 
-import `time`
+import "time"
+
+const (
+	ChatRoleOwner  = "Owner"
+	ChatRoleAdmin  = "Admin"
+	ChatRoleMember = "Member"
+)
 
 type ChatParticipant struct {
-	ID       string    `json:"id" db:"id"`
-	ChatId   string    `json:"chat_id" db:"chat_id"`
-	UserId   string    `json:"user_id" db:"user_id"`
-	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+	ID      string `json:"id" db:"id"`
+	ChatId  string `json:"chat_id" db:"chat_id"`
+	UserId  string `json:"user_id" db:"user_id"`
+	Role    string `json:"role" db:"role"`
+	IsMuted bool   `json:"is_muted" db:"is_muted"`
+	// NotificationsMuted - участник отключил пуш-уведомления для этого чата
+	NotificationsMuted bool      `json:"notifications_muted" db:"notifications_muted"`
+	JoinedAt           time.Time `json:"joined_at" db:"joined_at"`
 }
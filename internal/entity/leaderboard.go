@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// Периоды таблицы лидеров: текущая неделя с понедельника, текущий месяц (UTC) или все время
+const (
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+)
+
+// LeaderboardEntry - итоги члена семьи за период
+type LeaderboardEntry struct {
+	// Rank - место по заработанным баллам, при равенстве баллов - по числу заданий. Равные итоги делят место
+	Rank   int    `json:"rank"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// PointsEarned - начисленные баллы за задания и достижения
+	PointsEarned    int `json:"points_earned"`
+	ChoresCompleted int `json:"chores_completed"`
+	// PointsSpent - стоимость неотклоненных обменов на награды
+	PointsSpent int `json:"points_spent"`
+	Redemptions int `json:"redemptions"`
+}
+
+// Leaderboard - таблица лидеров семьи за [From, To). Пустой From - с начала истории
+type Leaderboard struct {
+	Period      string             `json:"period"`
+	From        *time.Time         `json:"from,omitempty"`
+	To          time.Time          `json:"to"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Entries     []LeaderboardEntry `json:"entries"`
+}
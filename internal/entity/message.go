@@ -11,3 +11,12 @@ type Message struct {
 	Content   string    `json:"content" db:"content"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
+
+type MessageSearchResult struct {
+	Message Message `json:"message"`
+	// Snippet - фрагмент сообщения, найденные слова обернуты в <mark></mark>
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+	// Cursor - ID сообщения для загрузки окружающих его сообщений (GET /chats/{chatID}/messages?around=)
+	Cursor string `json:"cursor"`
+}
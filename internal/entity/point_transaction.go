@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// Виды операций с баллами
+const (
+	PointTransactionEarn   = "Earn"
+	PointTransactionSpend  = "Spend"
+	PointTransactionAdjust = "Adjust"
+	PointTransactionRefund = "Refund"
+	// PointTransactionReserve и PointTransactionRelease - перевод баллов на цель накопления и обратно
+	PointTransactionReserve = "Reserve"
+	PointTransactionRelease = "Release"
+)
+
+// Источники операций с баллами
+const (
+	PointSourceTodo        = "todo"
+	PointSourceRedemption  = "reward_redemption"
+	PointSourceSavingsGoal = "savings_goal"
+	PointSourceAchievement = "achievement"
+	// PointSourceAdjustment - ручная корректировка родителем, PointSourceAdjustmentUndo - ее отмена
+	PointSourceAdjustment     = "adjustment"
+	PointSourceAdjustmentUndo = "adjustment_undo"
+)
+
+// PointTransaction - запись журнала баллов пользователя
+type PointTransaction struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Kind   string `json:"kind"`
+	// Amount - изменение баланса: положительное при начислении, отрицательное при списании
+	Amount       int    `json:"amount"`
+	BalanceAfter int    `json:"balance_after"`
+	Reason       string `json:"reason"`
+	// SourceType и SourceID - задание, обмен или другая сущность, вызвавшая операцию
+	SourceType string `json:"source_type,omitempty"`
+	SourceID   string `json:"source_id,omitempty"`
+	// CreatedBy - пользователь, выполнивший операцию, пусто для системных операций
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PointHistoryPage - страница журнала баллов от новых операций к старым. NextCursor пуст на последней странице
+type PointHistoryPage struct {
+	Items      []PointTransaction `json:"items"`
+	NextCursor string             `json:"next_cursor"`
+}
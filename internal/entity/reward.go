@@ -4,6 +4,25 @@ import (
 	"time"
 )
 
+// Периоды ограничения числа обменов награды на одного пользователя
+const (
+	RewardLimitDay   = "Day"
+	RewardLimitWeek  = "Week"
+	RewardLimitMonth = "Month"
+	RewardLimitEver  = "Ever"
+)
+
+// Причины, по которым награда недоступна пользователю
+const (
+	RewardUnavailableOutOfStock   = "out_of_stock"
+	RewardUnavailableLimitReached = "limit_reached"
+	RewardUnavailableNotStarted   = "not_started"
+	RewardUnavailableEnded        = "ended"
+	RewardUnavailableRole         = "role_restricted"
+	RewardUnavailableAge          = "age_restricted"
+	RewardUnavailablePoints       = "not_enough_points"
+)
+
 type Reward struct {
 	ID          string    `json:"id" pgdb:"id"`
 	FamilyID    string    `json:"family_id" pgdb:"family_id"`
@@ -12,4 +31,20 @@ type Reward struct {
 	Cost        int       `json:"cost" pgdb:"cost"`
 	CreatedAt   time.Time `json:"created_at" pgdb:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" pgdb:"updated_at"`
+	// Stock - остаток награды, nil - без ограничения
+	Stock *int `json:"stock" pgdb:"stock"`
+	// PerUserLimit - сколько раз пользователь может обменять награду за LimitPeriod, 0 - без ограничения
+	PerUserLimit int    `json:"per_user_limit" pgdb:"per_user_limit"`
+	LimitPeriod  string `json:"limit_period,omitempty" pgdb:"limit_period"`
+	// AvailableFrom и AvailableUntil - период доступности награды, nil - без ограничения
+	AvailableFrom  *time.Time `json:"available_from,omitempty" pgdb:"available_from"`
+	AvailableUntil *time.Time `json:"available_until,omitempty" pgdb:"available_until"`
+	// AllowedRoles - роли, которым доступна награда, пусто - всем
+	AllowedRoles []string `json:"allowed_roles" pgdb:"allowed_roles"`
+	// MinAge и MaxAge - возраст в полных годах, 0 - без ограничения
+	MinAge int `json:"min_age" pgdb:"min_age"`
+	MaxAge int `json:"max_age" pgdb:"max_age"`
+	// Available и UnavailableReason заполняются для пользователя, запросившего список наград
+	Available         bool   `json:"available"`
+	UnavailableReason string `json:"unavailable_reason,omitempty"`
 }
@@ -4,10 +4,35 @@ import (
 	"time"
 )
 
+// Статусы обмена баллов на вознаграждение
+const (
+	RedemptionStatusPending   = "Pending"
+	RedemptionStatusApproved  = "Approved"
+	RedemptionStatusDeclined  = "Declined"
+	RedemptionStatusFulfilled = "Fulfilled"
+)
+
 type RewardRedemption struct {
 	ID         string    `json:"id" pgdb:"id"`
 	UserID     string    `json:"user_id" pgdb:"user_id"`
 	RewardID   string    `json:"reward_id" pgdb:"reward_id"`
 	RedeemedAt time.Time `json:"redeemed_at" pgdb:"redeemed_at"`
 	Reward     Reward    `json:"reward" pgdb:"reward"`
+	Status     string    `json:"status" pgdb:"status"`
+	// Cost - списанные баллы, возвращаются при отклонении
+	Cost          int        `json:"cost" pgdb:"cost"`
+	ReviewedBy    string     `json:"reviewed_by,omitempty" pgdb:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty" pgdb:"reviewed_at"`
+	ReviewComment string     `json:"review_comment,omitempty" pgdb:"review_comment"`
+	FulfilledAt   *time.Time `json:"fulfilled_at,omitempty" pgdb:"fulfilled_at"`
+}
+
+// RedemptionTransition - смена статуса обмена родителем
+type RedemptionTransition struct {
+	ID         string
+	ReviewerID string
+	// From - статусы, из которых допустим переход
+	From    []string
+	To      string
+	Comment string
 }
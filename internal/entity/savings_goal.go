@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// SavingsGoal - цель накопления баллов на награду. Отложенные баллы не входят в баланс пользователя
+type SavingsGoal struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	RewardID string `json:"reward_id"`
+	Reward   Reward `json:"reward"`
+	// Saved - отложенные баллы
+	Saved int `json:"saved"`
+	// Progress - доля стоимости награды, которая уже отложена, в процентах от 0 до 100
+	Progress int `json:"progress"`
+	// Remaining - сколько баллов осталось отложить
+	Remaining   int        `json:"remaining"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// Типы событий синхронизации покупок
+const (
+	ShoppingEventItemCreated = "shopping.item.created"
+	ShoppingEventItemUpdated = "shopping.item.updated"
+	ShoppingEventItemDeleted = "shopping.item.deleted"
+)
+
+// Что именно изменилось в покупке
+const (
+	ShoppingChangeCreated    = "created"
+	ShoppingChangeEdited     = "edited"
+	ShoppingChangeReserved   = "reserved"
+	ShoppingChangeUnreserved = "unreserved"
+	ShoppingChangeBought     = "bought"
+	ShoppingChangeDeleted    = "deleted"
+)
+
+// ShoppingEvent - изменение покупки, которое получают все, кому виден ее список. Version - версия покупки
+// после изменения: событие с версией не больше известной клиенту уже применено, пропуск версии означает
+// потерянное событие, и покупку нужно перечитать
+type ShoppingEvent struct {
+	Type    string `json:"type"`
+	Change  string `json:"change"`
+	ItemID  string `json:"item_id"`
+	ListID  string `json:"list_id,omitempty"`
+	Version int64  `json:"version"`
+	ActorID string `json:"actor_id,omitempty"`
+	// Item - покупка после изменения, для удаленной - ее последнее состояние
+	Item      ShoppingItem `json:"item"`
+	CreatedAt time.Time    `json:"created_at"`
+}
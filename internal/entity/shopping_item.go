@@ -8,6 +8,7 @@ import (
 type ShoppingItem struct {
 	ID          string         `json:"id" pgdb:"id"`
 	FamilyID    string         `json:"family_id" pgdb:"family_id"`
+	ListID      string         `json:"list_id" pgdb:"list_id"`
 	Title       string         `json:"title" pgdb:"title"`
 	Description string         `json:"description" pgdb:"description"`
 	Status      string         `json:"status" pgdb:"status"`
@@ -18,4 +19,46 @@ type ShoppingItem struct {
 	IsArchived  bool           `json:"is_archived" pgdb:"is_archived"`
 	CreatedAt   time.Time      `json:"created_at" pgdb:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at" pgdb:"updated_at"`
+
+	// Quantity и Unit - сколько купить и в чем (шт, кг, л), Category - отдел магазина
+	Quantity float64 `json:"quantity" pgdb:"quantity"`
+	Unit     string  `json:"unit" pgdb:"unit"`
+	Category string  `json:"category" pgdb:"category"`
+	// EstimatedPriceMinor и ActualPriceMinor - ожидаемая и фактическая цена всей позиции
+	// в минимальных единицах валюты
+	EstimatedPriceMinor *int   `json:"estimated_price_minor" pgdb:"estimated_price_minor"`
+	ActualPriceMinor    *int   `json:"actual_price_minor" pgdb:"actual_price_minor"`
+	Store               string `json:"store" pgdb:"store"`
+	// BoughtAt - когда покупку отметили купленной
+	BoughtAt *time.Time `json:"bought_at,omitempty" pgdb:"bought_at"`
+	// Version растет при каждом изменении покупки
+	Version int64 `json:"version" pgdb:"version"`
+}
+
+// Отделы магазина для группировки покупок
+const (
+	ShoppingCategoryProduce   = "produce"
+	ShoppingCategoryBakery    = "bakery"
+	ShoppingCategoryDairy     = "dairy"
+	ShoppingCategoryMeat      = "meat"
+	ShoppingCategoryGrocery   = "grocery"
+	ShoppingCategoryFrozen    = "frozen"
+	ShoppingCategoryDrinks    = "drinks"
+	ShoppingCategoryHousehold = "household"
+	ShoppingCategoryPharmacy  = "pharmacy"
+	ShoppingCategoryOther     = "other"
+)
+
+// ShoppingCategories - отделы в порядке обхода магазина
+var ShoppingCategories = []string{
+	ShoppingCategoryProduce,
+	ShoppingCategoryBakery,
+	ShoppingCategoryDairy,
+	ShoppingCategoryMeat,
+	ShoppingCategoryGrocery,
+	ShoppingCategoryFrozen,
+	ShoppingCategoryDrinks,
+	ShoppingCategoryHousehold,
+	ShoppingCategoryPharmacy,
+	ShoppingCategoryOther,
 }
@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+// ShoppingList - именованный список покупок. Список принадлежит семье FamilyID и виден всем ее членам
+// или пользователю UserID и виден ему и членам семьи из SharedWith
+type ShoppingList struct {
+	ID        string `json:"id"`
+	FamilyID  string `json:"family_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	Title     string `json:"title"`
+	CreatedBy string `json:"created_by,omitempty"`
+	// IsDefault - список, в который попадают покупки без явного списка. Его нельзя удалить или архивировать
+	IsDefault  bool       `json:"is_default"`
+	IsArchived bool       `json:"is_archived"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	SharedWith []string   `json:"shared_with"`
+	// ItemsTotal и ItemsCompleted - неархивные покупки списка и купленные из них
+	ItemsTotal     int       `json:"items_total"`
+	ItemsCompleted int       `json:"items_completed"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ShoppingListWithItems - список покупок вместе с его неархивными покупками, сгруппированными по отделам
+// магазина в порядке entity.ShoppingCategories
+type ShoppingListWithItems struct {
+	ShoppingList
+	Groups []ShoppingCategoryGroup `json:"groups"`
+}
+
+// ShoppingCategoryGroup - покупки одного отдела: сначала некупленные, затем купленные
+type ShoppingCategoryGroup struct {
+	Category string         `json:"category"`
+	Items    []ShoppingItem `json:"items"`
+}
+
+// ShoppingSpend - купленные покупки и траты на них: всего, по списку или по отделу
+type ShoppingSpend struct {
+	ListID   string `json:"list_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category,omitempty"`
+	// ItemsWithoutPrice - купленные покупки без фактической цены, в ActualMinor они не входят
+	ItemsBought       int `json:"items_bought"`
+	ItemsWithoutPrice int `json:"items_without_price"`
+	// ActualMinor и EstimatedMinor - фактические и ожидаемые траты в минимальных единицах валюты
+	ActualMinor    int `json:"actual_minor"`
+	EstimatedMinor int `json:"estimated_minor"`
+}
+
+// ShoppingSpendSummary - траты на покупки, отмеченные купленными за месяц [From, To) (UTC)
+type ShoppingSpendSummary struct {
+	Month string    `json:"month"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	ShoppingSpend
+	Lists      []ShoppingSpend `json:"lists"`
+	Categories []ShoppingSpend `json:"categories"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// События ленты задания
+const (
+	TodoActivityCreated         = "Created"
+	TodoActivityUpdated         = "Updated"
+	TodoActivityReassigned      = "Reassigned"
+	TodoActivityDeadlineChanged = "DeadlineChanged"
+	TodoActivitySubmitted       = "Submitted"
+	TodoActivityApproved        = "Approved"
+	TodoActivityRejected        = "Rejected"
+	TodoActivityCommented       = "Commented"
+	TodoActivityArchived        = "Archived"
+	TodoActivityUnarchived      = "Unarchived"
+	TodoActivityDeleted         = "Deleted"
+	TodoActivityRestored        = "Restored"
+)
+
+// TodoActivity - событие в ленте задания
+type TodoActivity struct {
+	ID     string `json:"id" pgdb:"id"`
+	TodoID string `json:"todo_id" pgdb:"todo_id"`
+	// ActorID - кто выполнил действие, пустая строка - система
+	ActorID string `json:"actor_id" pgdb:"actor_id"`
+	Kind    string `json:"kind" pgdb:"kind"`
+	// Details - подробности события, например from и to при переназначении или переносе срока
+	Details   map[string]string `json:"details" pgdb:"details"`
+	CreatedAt time.Time         `json:"created_at" pgdb:"created_at"`
+}
+
+// TodoComment - комментарий к заданию. Mentions - упомянутые члены семьи
+type TodoComment struct {
+	ID        string    `json:"id" pgdb:"id"`
+	TodoID    string    `json:"todo_id" pgdb:"todo_id"`
+	AuthorID  string    `json:"author_id" pgdb:"author_id"`
+	Body      string    `json:"body" pgdb:"body"`
+	Mentions  []string  `json:"mentions" pgdb:"mentions"`
+	CreatedAt time.Time `json:"created_at" pgdb:"created_at"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// Действия над несколькими заданиями
+const (
+	TodoBulkComplete  = "complete"
+	TodoBulkDelete    = "delete"
+	TodoBulkRestore   = "restore"
+	TodoBulkReassign  = "reassign"
+	TodoBulkArchive   = "archive"
+	TodoBulkUnarchive = "unarchive"
+)
+
+// TodoBulkOperation - действие над несколькими заданиями. Выполняется в одной транзакции:
+// если действие нельзя применить хотя бы к одному заданию, не меняется ни одно
+type TodoBulkOperation struct {
+	Action string
+	IDs    []string
+	// UserID - пользователь, выполняющий действие. При complete он становится проверяющим
+	UserID string
+	// AssignedTo - новый исполнитель для reassign
+	AssignedTo string
+	// Points - баллы, начисляемые за каждое задание при complete
+	Points map[string]int
+	// DeletedAfter - при restore восстанавливаются только задания, удаленные позже этого времени
+	DeletedAfter time.Time
+}
+
+// TodoBulkResult - задания после выполнения действия. UndoUntil - до какого времени можно
+// восстановить удаленные задания
+type TodoBulkResult struct {
+	Items     []TodoItem `json:"items"`
+	UndoUntil *time.Time `json:"undo_until,omitempty"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Правила начисления баллов за задание с чек-листом
+const (
+	// TodoPointsRuleAll - баллы начисляются, только если отмечены все пункты
+	TodoPointsRuleAll = "All"
+	// TodoPointsRuleProportional - баллы начисляются пропорционально отмеченным пунктам
+	TodoPointsRuleProportional = "Proportional"
+)
+
+// TodoChecklistItem - пункт чек-листа задания
+type TodoChecklistItem struct {
+	ID        string         `json:"id" pgdb:"id"`
+	TodoID    string         `json:"todo_id" pgdb:"todo_id"`
+	Title     string         `json:"title" pgdb:"title"`
+	IsDone    bool           `json:"is_done" pgdb:"is_done"`
+	Position  int            `json:"position" pgdb:"position"`
+	DoneBy    sql.NullString `json:"done_by" pgdb:"done_by" swaggerignore:"true"`
+	DoneAt    *time.Time     `json:"done_at,omitempty" pgdb:"done_at"`
+	CreatedAt time.Time      `json:"created_at" pgdb:"created_at"`
+}
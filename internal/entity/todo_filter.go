@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Поля сортировки списка заданий
+const (
+	TodoSortDeadline  = "deadline"
+	TodoSortCreatedAt = "created_at"
+)
+
+// TodoFilter - условия выборки списка заданий
+type TodoFilter struct {
+	// FamilyID - задания семьи, пустое значение - без ограничения
+	FamilyID string
+	// VisibleTo - только задания, где пользователь исполнитель или создатель, пустое значение - без ограничения
+	VisibleTo  string
+	AssignedTo string
+	CreatedBy  string
+	Statuses   []string
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Archived   bool
+	// Sort - поле сортировки (TodoSortDeadline, TodoSortCreatedAt), Desc - по убыванию
+	Sort string
+	Desc bool
+	// After - позиция, после которой начинается страница
+	After *TodoCursor
+	Limit uint64
+}
+
+// TodoCursor - позиция в списке заданий: значение поля сортировки и ID последнего задания страницы
+type TodoCursor struct {
+	Value time.Time
+	ID    string
+}
+
+// TodoPage - страница списка заданий. NextCursor пуст на последней странице
+type TodoPage struct {
+	Items      []TodoItem `json:"items"`
+	NextCursor string     `json:"next_cursor"`
+}
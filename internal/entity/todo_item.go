@@ -1,6 +1,17 @@
 package entity
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
+
+// Статусы задания: Active -> Submitted -> Approved / Rejected, Rejected -> Submitted
+const (
+	TodoStatusActive    = "Active"
+	TodoStatusSubmitted = "Submitted"
+	TodoStatusApproved  = "Approved"
+	TodoStatusRejected  = "Rejected"
+)
 
 type TodoItem struct {
 	ID          string    `json:"id" pgdb:"id"`
@@ -15,4 +26,30 @@ type TodoItem struct {
 	CreatedAt   time.Time `json:"created_at" pgdb:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" pgdb:"updated_at"`
 	Point       int       `json:"point" pgdb:"point"`
+	// SeriesID - серия, к которой относится повторяющееся задание
+	SeriesID     sql.NullString `json:"series_id" pgdb:"series_id" swaggerignore:"true"`
+	OccurrenceAt *time.Time     `json:"occurrence_at,omitempty" pgdb:"occurrence_at"`
+	// IsDetached - повторение изменено отдельно от серии
+	IsDetached bool `json:"is_detached" pgdb:"is_detached"`
+
+	SubmittedAt *time.Time     `json:"submitted_at,omitempty" pgdb:"submitted_at"`
+	ReviewedBy  sql.NullString `json:"reviewed_by" pgdb:"reviewed_by" swaggerignore:"true"`
+	ReviewedAt  *time.Time     `json:"reviewed_at,omitempty" pgdb:"reviewed_at"`
+	// ReviewComment - комментарий проверяющего при отклонении задания
+	ReviewComment string `json:"review_comment" pgdb:"review_comment"`
+	PointsAwarded bool   `json:"points_awarded" pgdb:"points_awarded"`
+	// RequiresProof - при отправке на проверку нужно приложить фото
+	RequiresProof bool        `json:"requires_proof" pgdb:"requires_proof"`
+	Proofs        []TodoProof `json:"proofs,omitempty"`
+	// IsOverdue - срок прошел, а задание не отправлено на проверку
+	IsOverdue bool `json:"is_overdue" pgdb:"is_overdue"`
+	// PointsRule - правило начисления баллов при наличии чек-листа (All, Proportional)
+	PointsRule    string `json:"points_rule" pgdb:"points_rule"`
+	AwardedPoints int    `json:"awarded_points" pgdb:"awarded_points"`
+	// Checklist - пункты чек-листа по порядку, Progress - процент отмеченных пунктов
+	Checklist []TodoChecklistItem `json:"checklist,omitempty"`
+	Progress  int                 `json:"progress"`
+	// ArchivedAt - время архивации, DeletedAt - время удаления, пока задание можно восстановить
+	ArchivedAt *time.Time `json:"archived_at,omitempty" pgdb:"archived_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" pgdb:"deleted_at"`
 }
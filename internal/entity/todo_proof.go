@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// TodoProof - фото, подтверждающее выполнение задания
+type TodoProof struct {
+	ID         string    `json:"id" pgdb:"id"`
+	TodoID     string    `json:"todo_id" pgdb:"todo_id"`
+	UploadedBy string    `json:"uploaded_by" pgdb:"uploaded_by"`
+	Path       string    `json:"-" pgdb:"path"`
+	URL        string    `json:"url" pgdb:"url"`
+	CreatedAt  time.Time `json:"created_at" pgdb:"created_at"`
+}
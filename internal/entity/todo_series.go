@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// TodoSeries - шаблон повторяющегося задания. Повторения создаются как обычные TodoItem со ссылкой на серию
+type TodoSeries struct {
+	ID          string `json:"id" pgdb:"id"`
+	FamilyID    string `json:"family_id" pgdb:"family_id"`
+	Title       string `json:"title" pgdb:"title"`
+	Description string `json:"description" pgdb:"description"`
+	AssignedTo  string `json:"assigned_to" pgdb:"assigned_to"`
+	CreatedBy   string `json:"created_by" pgdb:"created_by"`
+	Point       int    `json:"point" pgdb:"point"`
+	// RRule - правило повторения в формате RFC 5545 (FREQ=DAILY|WEEKLY|MONTHLY)
+	RRule            string    `json:"rrule" pgdb:"rrule"`
+	StartsAt         time.Time `json:"starts_at" pgdb:"starts_at"`
+	LastOccurrenceAt time.Time `json:"last_occurrence_at" pgdb:"last_occurrence_at"`
+	IsActive         bool      `json:"is_active" pgdb:"is_active"`
+	RequiresProof    bool      `json:"requires_proof" pgdb:"requires_proof"`
+	PointsRule       string    `json:"points_rule" pgdb:"points_rule"`
+	// Checklist - названия пунктов чек-листа, который получает каждое повторение
+	Checklist []string `json:"checklist" pgdb:"checklist"`
+	// RotationMembers - участники, которым повторения назначаются по очереди. Пустой список - без ротации.
+	// RotationIndex - индекс участника, которому назначено последнее созданное повторение
+	RotationMembers []string  `json:"rotation_members" pgdb:"rotation_members"`
+	RotationIndex   int       `json:"rotation_index" pgdb:"rotation_index"`
+	CreatedAt       time.Time `json:"created_at" pgdb:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" pgdb:"updated_at"`
+}
+
+// TodoRotationSlot - повторение серии в расписании ротации
+type TodoRotationSlot struct {
+	OccurrenceAt time.Time `json:"occurrence_at"`
+	AssignedTo   string    `json:"assigned_to"`
+	// Skipped - участники, пропущенные из-за отсутствия
+	Skipped []string `json:"skipped"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserAbsence - период, когда участник семьи отсутствует и не получает задания по ротации
+type UserAbsence struct {
+	ID        string         `json:"id" pgdb:"id"`
+	UserID    string         `json:"user_id" pgdb:"user_id"`
+	StartsAt  time.Time      `json:"starts_at" pgdb:"starts_at"`
+	EndsAt    time.Time      `json:"ends_at" pgdb:"ends_at"`
+	CreatedBy sql.NullString `json:"created_by" pgdb:"created_by" swaggerignore:"true"`
+	CreatedAt time.Time      `json:"created_at" pgdb:"created_at"`
+}
@@ -0,0 +1,217 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"family-flow-app/internal/service"
+	"family-flow-app/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+const achievementsPath = "/achievements"
+
+type AchievementsRoutes struct {
+	achievementsService service.Achievements
+}
+
+// NewAchievementsRoutes регистрирует достижения пользователей и правила их выдачи
+func NewAchievementsRoutes(
+	ctx context.Context, log *slog.Logger, route chi.Router, achievementsService service.Achievements,
+) {
+	a := AchievementsRoutes{achievementsService: achievementsService}
+	route.Get(achievementsPath, a.getAchievements(ctx, log))
+	route.Get(achievementsPath+"/rules", a.getRules(ctx, log))
+	route.Put(achievementsPath+"/rules/{code}", a.saveRule(ctx, log))
+	route.Delete(achievementsPath+"/rules/{code}", a.deleteRule(ctx, log))
+}
+
+func achievementsErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrAchievementRuleNotFound):
+		return http.StatusNotFound, "Achievement rule not found"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Forbidden"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// @Summary Get achievements
+// @Description Earned badges of a user, newest first, followed by the enabled family rules not earned yet
+// @Description with progress towards them. stats holds the current counters including the streak of days
+// @Description with approved todos. Members can view achievements of their own family
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param user_id query string false "Family member ID, the current user by default"
+// @Success 200 {object} entity.UserAchievements
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /achievements [get]
+func (a *AchievementsRoutes) getAchievements(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			userID = user.Id
+		}
+		if err = validator.New().Var(userID, "uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		achievements, err := a.achievementsService.GetUserAchievements(ctx, log, user.Id, userID)
+		if err != nil {
+			status, message := achievementsErrorResponse(err, "Failed to get achievements")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, achievements)
+	}
+}
+
+// @Summary Get achievement rules
+// @Description Rules of the current user's family: default rules replaced by family rules with the same code,
+// @Description including disabled ones. Rules without family_id are defaults
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.AchievementRule
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /achievements/rules [get]
+func (a *AchievementsRoutes) getRules(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		rules, err := a.achievementsService.GetRules(ctx, log, user.Id)
+		if err != nil {
+			status, message := achievementsErrorResponse(err, "Failed to get achievement rules")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, rules)
+	}
+}
+
+type inputAchievementRule struct {
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=1000"`
+	Event       string `json:"event" validate:"required,oneof=TodoApproved TodoStreak WishFulfilled RewardRedeemed"`
+	Threshold   int    `json:"threshold" validate:"min=1,max=100000"`
+	BonusPoints int    `json:"bonus_points" validate:"min=0,max=100000"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// @Summary Save achievement rule
+// @Description Create a family rule or override the default rule with the same code, e.g. to change its bonus
+// @Description or disable it. Badges already awarded are kept. Available to family parents
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param code path string true "Rule code"
+// @Param input body inputAchievementRule true "Rule"
+// @Success 200 {object} entity.AchievementRule
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /achievements/rules/{code} [put]
+func (a *AchievementsRoutes) saveRule(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		code := chi.URLParam(r, "code")
+		if err = validator.New().Var(code, "required,max=50"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+		var input inputAchievementRule
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		rule, err := a.achievementsService.SaveRule(
+			ctx, log, service.AchievementRuleInput{
+				UserID:      user.Id,
+				Code:        code,
+				Title:       input.Title,
+				Description: input.Description,
+				Event:       input.Event,
+				Threshold:   input.Threshold,
+				BonusPoints: input.BonusPoints,
+				Enabled:     input.Enabled,
+			},
+		)
+		if err != nil {
+			status, message := achievementsErrorResponse(err, "Failed to save achievement rule")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, rule)
+	}
+}
+
+// @Summary Delete achievement rule
+// @Description Delete a family rule. The default rule with the same code, if any, applies again.
+// @Description Available to family parents
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param code path string true "Rule code"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /achievements/rules/{code} [delete]
+func (a *AchievementsRoutes) deleteRule(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		if err = a.achievementsService.DeleteRule(ctx, log, user.Id, chi.URLParam(r, "code")); err != nil {
+			status, message := achievementsErrorResponse(err, "Failed to delete achievement rule")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, map[string]string{"message": "Achievement rule deleted successfully"})
+	}
+}
@@ -0,0 +1,203 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/service"
+	"family-flow-app/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	calendarPath = "/calendar"
+	// calendarFeedFormat - расширение ссылки подписки, middleware.URLFormat отделяет его от токена
+	calendarFeedFormat = "ics"
+	// calendarFeedMaxAge - сколько секунд календарное приложение может не перезапрашивать ленту (15 минут)
+	calendarFeedMaxAge = "900"
+)
+
+type CalendarRoutes struct {
+	calendarService service.Calendar
+}
+
+// NewCalendarRoutes регистрирует календарь и управление подпиской. Маршруты требуют авторизации
+func NewCalendarRoutes(ctx context.Context, log *slog.Logger, route chi.Router, calendarService service.Calendar) {
+	c := CalendarRoutes{calendarService: calendarService}
+	route.Get(calendarPath, c.getEvents(ctx, log))
+	route.Get(calendarPath+"/subscription", c.getSubscription(ctx, log))
+	route.Post(calendarPath+"/subscription", c.resetSubscription(ctx, log))
+}
+
+// NewCalendarFeedRoutes регистрирует ленту подписки. Календарные приложения не передают токен авторизации,
+// поэтому доступ к ленте дает только секретный токен в ссылке
+func NewCalendarFeedRoutes(ctx context.Context, log *slog.Logger, route chi.Router, calendarService service.Calendar) {
+	c := CalendarRoutes{calendarService: calendarService}
+	route.Get(calendarPath+"/{token}", c.getFeed(ctx, log))
+}
+
+func calendarErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCalendarRange):
+		return http.StatusBadRequest, "Invalid range: to must be after from and at most 366 days later"
+	case errors.Is(err, service.ErrCalendarTokenNotFound):
+		return http.StatusNotFound, "Calendar not found"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// @Summary Get calendar
+// @Description Dated family events in [from, to): deadlines of todos visible to the user, birthdays
+// @Description and absences of family members. The range is at most 366 days
+// @Tags calendar
+// @Accept json
+// @Produce json
+// @Param from query string true "Range start (RFC 3339)"
+// @Param to query string true "Range end (RFC 3339)"
+// @Success 200 {object} []entity.CalendarEvent
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /calendar [get]
+func (c *CalendarRoutes) getEvents(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		from, err := timeQueryParam(r.URL.Query(), "from")
+		if err != nil || from == nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid from, expected RFC 3339")
+			return
+		}
+		to, err := timeQueryParam(r.URL.Query(), "to")
+		if err != nil || to == nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid to, expected RFC 3339")
+			return
+		}
+
+		events, err := c.calendarService.GetEvents(
+			ctx, log, service.CalendarInput{UserID: user.Id, From: *from, To: *to},
+		)
+		if err != nil {
+			status, message := calendarErrorResponse(err, "Failed to get calendar")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, events)
+	}
+}
+
+// @Summary Get calendar subscription
+// @Description Secret iCalendar subscription URL of the current user for Google/Apple Calendar
+// @Tags calendar
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.CalendarSubscription
+// @Failure 500 {object} response.Response
+// @Router /calendar/subscription [get]
+func (c *CalendarRoutes) getSubscription(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		token, err := c.calendarService.GetSubscription(ctx, log, user.Id)
+		if err != nil {
+			status, message := calendarErrorResponse(err, "Failed to get calendar subscription")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, calendarSubscription(r, token))
+	}
+}
+
+// @Summary Reset calendar subscription
+// @Description Issue a new subscription URL. The previous URL stops working
+// @Tags calendar
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.CalendarSubscription
+// @Failure 500 {object} response.Response
+// @Router /calendar/subscription [post]
+func (c *CalendarRoutes) resetSubscription(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		token, err := c.calendarService.ResetSubscription(ctx, log, user.Id)
+		if err != nil {
+			status, message := calendarErrorResponse(err, "Failed to reset calendar subscription")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, calendarSubscription(r, token))
+	}
+}
+
+// @Summary Calendar feed
+// @Description iCalendar (RFC 5545) feed for calendar apps. Does not require authorization: access is granted
+// @Description by the secret token from GET /calendar/subscription
+// @Tags calendar
+// @Produce plain
+// @Param token path string true "Subscription token"
+// @Success 200 {string} string "text/calendar"
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /calendar/{token}.ics [get]
+func (c *CalendarRoutes) getFeed(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+		if format != calendarFeedFormat {
+			response.NewError(w, r, log, nil, http.StatusNotFound, "Calendar not found")
+			return
+		}
+
+		feed, err := c.calendarService.GetFeed(ctx, log, chi.URLParam(r, "token"))
+		if err != nil {
+			status, message := calendarErrorResponse(err, "Failed to get calendar")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, max-age="+calendarFeedMaxAge)
+		w.WriteHeader(http.StatusOK)
+		if _, err = w.Write([]byte(feed)); err != nil {
+			log.Error("Handler - getFeed - Failed to write calendar", "error", err)
+		}
+	}
+}
+
+// calendarSubscription строит ссылку подписки с хостом, на который пришел запрос
+func calendarSubscription(r *http.Request, token string) entity.CalendarSubscription {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return entity.CalendarSubscription{
+		Token: token,
+		URL:   scheme + "://" + r.Host + api + calendarPath + "/" + token + "." + calendarFeedFormat,
+	}
+}
@@ -2,8 +2,10 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"family-flow-app/internal/service"
 	"family-flow-app/pkg/response"
@@ -25,11 +27,20 @@ func NewChatsRoutes(ctx context.Context, log *slog.Logger, route chi.Router, cha
 	u := ChatsRoutes{chatService: chatService}
 	route.Route(
 		chatsString, func(r chi.Router) {
-			r.Post("/", u.createChat(ctx, log))                                  // Создание чата
-			r.Post("/{chatID}/participants", u.addParticipant(ctx, log))         // Добавление участника
-			r.Post("/with-participants", u.createChatWithParticipants(ctx, log)) // Создание чата с участниками
-			r.Get("/user", u.getChatsByUserID(ctx, log))                         // Получение чатов по ID пользователя
-			r.Get("/{chatID}/messages", u.handleGetMessages(ctx, log))           // Получение сообщений по ID чата
+			r.Post("/", u.createChat(ctx, log))                                           // Создание чата
+			r.Post("/{chatID}/participants", u.addParticipant(ctx, log))                  // Добавление участника
+			r.Post("/with-participants", u.createChatWithParticipants(ctx, log))          // Создание чата с участниками
+			r.Get("/user", u.getChatsByUserID(ctx, log))                                  // Получение чатов по ID пользователя
+			r.Get("/{chatID}/messages", u.handleGetMessages(ctx, log))                    // Получение сообщений по ID чата
+			r.Get("/family", u.getFamilyChat(ctx, log))                                   // Получение семейного чата
+			r.Get("/search", u.searchMessages(ctx, log))                                  // Полнотекстовый поиск по сообщениям
+			r.Post("/direct/{userId}", u.getOrCreateDirectChat(ctx, log))                 // Получение или создание личного чата
+			r.Delete("/{chatID}/participants/{userID}", u.removeParticipant(ctx, log))    // Удаление участника
+			r.Put("/{chatID}/participants/{userID}/mute", u.muteParticipant(ctx, log))    // Заглушение участника
+			r.Put("/{chatID}/participants/{userID}/role", u.setParticipantRole(ctx, log)) // Назначение роли
+			r.Put("/{chatID}/lock", u.lockChat(ctx, log))                                 // Режим только для чтения
+			r.Delete("/{chatID}/messages/{messageID}", u.deleteMessage(ctx, log))         // Удаление сообщения
+			r.Put("/{chatID}/notifications", u.setNotificationsMuted(ctx, log))           // Настройка пуш-уведомлений
 		},
 	)
 }
@@ -96,6 +107,13 @@ func (u *ChatsRoutes) addParticipant(ctx context.Context, log *slog.Logger) http
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Info("Handler - addParticipant - Start")
 
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			log.Error("Handler - addParticipant - Failed to get current user", "error", err)
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
 		chatID := chi.URLParam(r, "chatID")
 		if chatID == "" {
 			log.Error("Handler - addParticipant - Chat ID is required")
@@ -115,15 +133,17 @@ func (u *ChatsRoutes) addParticipant(ctx context.Context, log *slog.Logger) http
 			return
 		}
 
-		err := u.chatService.AddParticipant(
+		err = u.chatService.AddParticipant(
 			ctx, log, service.AddParticipantInput{
-				ChatID: chatID,
-				UserID: input.UserID,
+				ChatID:  chatID,
+				UserID:  input.UserID,
+				ActorID: user.Id,
 			},
 		)
 		if err != nil {
 			log.Error("Handler - addParticipant - Failed to add participant", "error", err)
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to add participant")
+			status, message := chatErrorResponse(err, "Failed to add participant")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -185,12 +205,12 @@ func (u *ChatsRoutes) createChatWithParticipants(ctx context.Context, log *slog.
 		// log participant IDs
 		log.Info("Handler - createChatWithParticipants - Participant IDs", "participant_ids", input.ParticipantIDs)
 
-		input.ParticipantIDs = append(input.ParticipantIDs, user.Id)
-
 		chatID, err := u.chatService.CreateChatWithParticipants(
 			ctx, log, service.CreateChatWithParticipantsInput{
 				Name:         input.Name,
 				Participants: input.ParticipantIDs,
+				OwnerID:      user.Id,
+				FamilyID:     user.FamilyId.String,
 			},
 		)
 		if err != nil {
@@ -270,6 +290,8 @@ func (u *ChatsRoutes) getChatsByUserID(ctx context.Context, log *slog.Logger) ht
 // @Accept json
 // @Produce json
 // @Param chatID path string true "Chat ID"
+// @Param around query string false "Message ID to load surrounding messages for"
+// @Param limit query int false "Number of messages before and after the 'around' message"
 // @Success 200 {array} entity.Message "List of messages"
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -286,6 +308,12 @@ func (u *ChatsRoutes) handleGetMessages(ctx context.Context, log *slog.Logger) h
 			return
 		}
 
+		// Переход к сообщению из результатов поиска
+		if around := r.URL.Query().Get("around"); around != "" {
+			u.handleGetMessagesAround(ctx, log, w, r, chatID, around)
+			return
+		}
+
 		log.Info("Handler - handleGetMessages - Fetching messages", "chat_id", chatID)
 
 		// Получение сообщений из сервиса
@@ -307,3 +335,496 @@ func (u *ChatsRoutes) handleGetMessages(ctx context.Context, log *slog.Logger) h
 		render.JSON(w, r, messages)
 	}
 }
+
+// @Summary Get family chat
+// @Description Get the family group chat of the current user, creating it if needed
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.Chat
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/family [get]
+func (u *ChatsRoutes) getFamilyChat(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - getFamilyChat - Start")
+
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			log.Error("Handler - getFamilyChat - Failed to get current user", "error", err)
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		if !user.FamilyId.Valid {
+			response.NewError(w, r, log, service.ErrNotFamilyMember, http.StatusBadRequest, "User has no family")
+			return
+		}
+
+		chat, err := u.chatService.GetFamilyChat(ctx, log, user.FamilyId.String)
+		if err != nil {
+			log.Error("Handler - getFamilyChat - Failed to get family chat", "error", err)
+			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to get family chat")
+			return
+		}
+
+		log.Info("Handler - getFamilyChat - Family chat retrieved successfully", "chat_id", chat.ID)
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, chat)
+	}
+}
+
+// @Summary Get or create direct chat
+// @Description Get the direct chat with a family member, creating it if it does not exist
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {string} string "Chat ID"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/direct/{userId} [post]
+func (u *ChatsRoutes) getOrCreateDirectChat(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - getOrCreateDirectChat - Start")
+
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			log.Error("Handler - getOrCreateDirectChat - Failed to get current user", "error", err)
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		otherUserID := chi.URLParam(r, "userId")
+		if err = validator.New().Var(otherUserID, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		chatID, err := u.chatService.GetOrCreateDirectChat(
+			ctx, log, service.DirectChatInput{
+				UserID:      user.Id,
+				OtherUserID: otherUserID,
+			},
+		)
+		switch {
+		case errors.Is(err, service.ErrDirectChatWithSelf):
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Cannot create direct chat with yourself")
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			response.NewError(w, r, log, err, http.StatusNotFound, MsgUserNotFound)
+			return
+		case errors.Is(err, service.ErrNotFamilyMember):
+			response.NewError(w, r, log, err, http.StatusForbidden, "Users are not in the same family")
+			return
+		case err != nil:
+			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to get direct chat")
+			return
+		}
+
+		log.Info("Handler - getOrCreateDirectChat - Direct chat retrieved successfully", "chat_id", chatID)
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, map[string]string{"chat_id": chatID})
+	}
+}
+
+// chatErrorResponse подбирает HTTP-статус и сообщение для ошибок сервиса чатов
+func chatErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrChatNotFound):
+		return http.StatusNotFound, "Chat not found"
+	case errors.Is(err, service.ErrMessageNotFound):
+		return http.StatusNotFound, "Message not found"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, MsgUserNotFound
+	case errors.Is(err, service.ErrNotChatParticipant):
+		return http.StatusForbidden, "User is not a chat participant"
+	case errors.Is(err, service.ErrParticipantMuted):
+		return http.StatusForbidden, "You are muted in this chat"
+	case errors.Is(err, service.ErrChatLocked):
+		return http.StatusForbidden, "Chat is read-only"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Insufficient permissions"
+	case errors.Is(err, service.ErrFamilyChatMembership):
+		return http.StatusConflict, "Family chat participants follow the family"
+	case errors.Is(err, service.ErrInvalidChatRole):
+		return http.StatusBadRequest, "Invalid role"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// moderationParams достает текущего пользователя и параметры пути для действий модератора
+func moderationParams(w http.ResponseWriter, r *http.Request, log *slog.Logger) (
+	service.ModerateParticipantInput, bool,
+) {
+	user, err := GetCurrentUserFromContext(r.Context())
+	if err != nil {
+		response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+		return service.ModerateParticipantInput{}, false
+	}
+
+	input := service.ModerateParticipantInput{
+		ChatID:  chi.URLParam(r, "chatID"),
+		UserID:  chi.URLParam(r, "userID"),
+		ActorID: user.Id,
+	}
+	if err = validator.New().Var(input.ChatID, "required,uuid"); err != nil {
+		response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.ModerateParticipantInput{}, false
+	}
+	if err = validator.New().Var(input.UserID, "omitempty,uuid"); err != nil {
+		response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.ModerateParticipantInput{}, false
+	}
+	return input, true
+}
+
+// @Summary Remove participant
+// @Description Remove a participant from a chat (owner, admins and family parents only)
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param userID path string true "User ID"
+// @Success 200 {string} string "Participant removed"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/participants/{userID} [delete]
+func (u *ChatsRoutes) removeParticipant(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - removeParticipant - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		if err := u.chatService.RemoveParticipant(ctx, log, input); err != nil {
+			status, message := chatErrorResponse(err, "Failed to remove participant")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		broadcastToChat(
+			ctx, log, u.chatService, input.ChatID, wsEventParticipantRemoved,
+			moderateParticipantInput{ChatID: input.ChatID, UserID: input.UserID}, nil, input.UserID,
+		)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Participant removed successfully")
+	}
+}
+
+type inputMuteParticipant struct {
+	Muted bool `json:"muted"`
+}
+
+// @Summary Mute participant
+// @Description Mute or unmute a chat participant (owner, admins and family parents only)
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param userID path string true "User ID"
+// @Param input body inputMuteParticipant true "Mute flag"
+// @Success 200 {string} string "Participant updated"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/participants/{userID}/mute [put]
+func (u *ChatsRoutes) muteParticipant(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - muteParticipant - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputMuteParticipant
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+
+		if err := u.chatService.SetParticipantMuted(ctx, log, input, body.Muted); err != nil {
+			status, message := chatErrorResponse(err, "Failed to update participant")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		broadcastToChat(
+			ctx, log, u.chatService, input.ChatID, wsEventParticipantMuted,
+			moderateParticipantInput{ChatID: input.ChatID, UserID: input.UserID, Muted: body.Muted}, nil,
+		)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Participant updated successfully")
+	}
+}
+
+type inputSetParticipantRole struct {
+	Role string `json:"role" validate:"required,oneof=Admin Member"`
+}
+
+// @Summary Set participant role
+// @Description Promote a participant to admin or demote to member (owner and family parents only)
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param userID path string true "User ID"
+// @Param input body inputSetParticipantRole true "Role"
+// @Success 200 {string} string "Participant updated"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/participants/{userID}/role [put]
+func (u *ChatsRoutes) setParticipantRole(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - setParticipantRole - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputSetParticipantRole
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		if err := u.chatService.SetParticipantRole(ctx, log, input, body.Role); err != nil {
+			status, message := chatErrorResponse(err, "Failed to update participant")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Participant updated successfully")
+	}
+}
+
+type inputLockChat struct {
+	Locked bool `json:"locked"`
+}
+
+// @Summary Lock chat
+// @Description Switch a chat to read-only mode or back (owner, admins and family parents only)
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param input body inputLockChat true "Lock flag"
+// @Success 200 {string} string "Chat updated"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/lock [put]
+func (u *ChatsRoutes) lockChat(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - lockChat - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputLockChat
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+
+		if err := u.chatService.SetChatLocked(ctx, log, input.ChatID, input.ActorID, body.Locked); err != nil {
+			status, message := chatErrorResponse(err, "Failed to update chat")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		broadcastToChat(
+			ctx, log, u.chatService, input.ChatID, wsEventChatLocked,
+			lockChatInput{ChatID: input.ChatID, Locked: body.Locked}, nil,
+		)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Chat updated successfully")
+	}
+}
+
+type inputChatNotifications struct {
+	Muted bool `json:"muted"`
+}
+
+// @Summary Mute chat notifications
+// @Description Enable or disable push notifications about new messages in a chat for the current user
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param input body inputChatNotifications true "Mute flag"
+// @Success 200 {string} string "Notification settings updated"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/notifications [put]
+func (u *ChatsRoutes) setNotificationsMuted(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - setNotificationsMuted - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputChatNotifications
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+
+		err := u.chatService.SetNotificationsMuted(ctx, log, input.ChatID, input.ActorID, body.Muted)
+		if err != nil {
+			status, message := chatErrorResponse(err, "Failed to update notification settings")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Notification settings updated successfully")
+	}
+}
+
+// @Summary Delete message
+// @Description Delete a message. Authors can delete their own messages, moderators any message
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param chatID path string true "Chat ID"
+// @Param messageID path string true "Message ID"
+// @Success 200 {string} string "Message deleted"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/{chatID}/messages/{messageID} [delete]
+func (u *ChatsRoutes) deleteMessage(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - deleteMessage - Start")
+
+		input, ok := moderationParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		messageID := chi.URLParam(r, "messageID")
+		if err := validator.New().Var(messageID, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		message, err := u.chatService.DeleteMessage(ctx, log, input.ChatID, messageID, input.ActorID)
+		if err != nil {
+			status, msg := chatErrorResponse(err, "Failed to delete message")
+			response.NewError(w, r, log, err, status, msg)
+			return
+		}
+
+		broadcastToChat(ctx, log, u.chatService, message.ChatID, wsEventMessageDeleted, message, nil)
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Message deleted successfully")
+	}
+}
+
+func (u *ChatsRoutes) handleGetMessagesAround(
+	ctx context.Context, log *slog.Logger, w http.ResponseWriter, r *http.Request, chatID, messageID string,
+) {
+	user, err := GetCurrentUserFromContext(r.Context())
+	if err != nil {
+		response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+		return
+	}
+
+	if err = validator.New().Var(messageID, "uuid"); err != nil {
+		response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	messages, err := u.chatService.GetMessagesAround(ctx, log, user.Id, chatID, messageID, limit)
+	if err != nil {
+		status, msg := chatErrorResponse(err, "Failed to get messages")
+		response.NewError(w, r, log, err, status, msg)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, messages)
+}
+
+// @Summary Search messages
+// @Description Full-text search across messages in chats the user participates in
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param q query string true "Search query"
+// @Param chat_id query string false "Restrict search to a chat"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} entity.MessageSearchResult
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /chats/search [get]
+func (u *ChatsRoutes) searchMessages(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("Handler - searchMessages - Start")
+
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		query := r.URL.Query()
+		q := query.Get("q")
+		if q == "" {
+			response.NewError(w, r, log, nil, http.StatusBadRequest, "Query parameter q is required")
+			return
+		}
+		chatID := query.Get("chat_id")
+		if err = validator.New().Var(chatID, "omitempty,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+
+		results, err := u.chatService.SearchMessages(
+			ctx, log, service.SearchMessagesInput{
+				UserID: user.Id,
+				ChatID: chatID,
+				Query:  q,
+				Limit:  limit,
+				Offset: offset,
+			},
+		)
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to search messages")
+			return
+		}
+
+		log.Info("Handler - searchMessages - Messages found", "count", len(results))
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, results)
+	}
+}
@@ -2,8 +2,11 @@ package v1
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"family-flow-app/internal/entity"
 	"family-flow-app/internal/service"
@@ -13,6 +16,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
 )
 
 type RewardsRoutes struct {
@@ -37,8 +41,11 @@ func NewRewardsRoutes(
 			r.Get(
 				"/",
 				routes.getRewardsByFamilyID(ctx, log),
-			)                                            // Получить список вознаграждений семьи
-			r.Get("/points", routes.getPoints(ctx, log)) // Получить очки пользователя
+			)                                                          // Получить список вознаграждений семьи
+			r.Get("/points", routes.getPoints(ctx, log))               // Получить очки пользователя
+			r.Get("/points/history", routes.getPointHistory(ctx, log)) // Получить журнал операций с очками
+			r.Post("/points/adjust", routes.adjustPoints(ctx, log))
+			r.Post("/points/adjustments/{transactionID}/undo", routes.undoAdjustment(ctx, log))
 			r.Post(
 				"/{rewardID}/redeem",
 				routes.redeemReward(ctx, log),
@@ -53,15 +60,112 @@ func NewRewardsRoutes(
 				routes.getRedemptionsByUserIDParam(ctx, log),
 			)                                                      // Получить список обменов для указанного пользователя
 			r.Delete("/{rewardID}", routes.deleteReward(ctx, log)) // Удалить награду
+			r.Get("/redemptions/family", routes.getFamilyRedemptions(ctx, log))
+			r.Post("/redemptions/{redemptionID}/approve", routes.approveRedemption(ctx, log))
+			r.Post("/redemptions/{redemptionID}/decline", routes.declineRedemption(ctx, log))
+			r.Post("/redemptions/{redemptionID}/fulfill", routes.fulfillRedemption(ctx, log))
+			r.Get("/goals", routes.getSavingsGoals(ctx, log))
+			r.Post("/goals", routes.createSavingsGoal(ctx, log))
+			r.Post("/goals/{goalID}/deposit", routes.depositSavingsGoal(ctx, log))
+			r.Post("/goals/{goalID}/withdraw", routes.withdrawSavingsGoal(ctx, log))
+			r.Post("/goals/{goalID}/redeem", routes.redeemSavingsGoal(ctx, log))
+			r.Delete("/goals/{goalID}", routes.deleteSavingsGoal(ctx, log))
+			r.Get("/allowance", routes.getAllowanceSettings(ctx, log))
+			r.Put("/allowance", routes.updateAllowanceSettings(ctx, log))
+			r.Get("/allowance/report", routes.getAllowanceReport(ctx, log))
+			r.Get("/leaderboard", routes.getLeaderboard(ctx, log))
 		},
 	)
 }
 
+// rewardsErrorResponse возвращает HTTP-статус и сообщение для ошибки сервиса вознаграждений
+func rewardsErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrRewardNotFound):
+		return http.StatusNotFound, "Reward not found"
+	case errors.Is(err, service.ErrNotEnoughPoints):
+		return http.StatusConflict, "Not enough points"
+	case errors.Is(err, service.ErrRewardUnavailable):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrRedemptionNotFound):
+		return http.StatusNotFound, "Redemption not found"
+	case errors.Is(err, service.ErrInvalidRedemptionTransition):
+		return http.StatusConflict, "Redemption status does not allow this action"
+	case errors.Is(err, service.ErrSavingsGoalNotFound):
+		return http.StatusNotFound, "Savings goal not found"
+	case errors.Is(err, service.ErrSavingsGoalExists):
+		return http.StatusConflict, "Savings goal for this reward already exists"
+	case errors.Is(err, service.ErrSavingsGoalCompleted):
+		return http.StatusConflict, "Savings goal is already completed"
+	case errors.Is(err, service.ErrAdjustmentNotFound):
+		return http.StatusNotFound, "Point adjustment not found"
+	case errors.Is(err, service.ErrAdjustmentUndone):
+		return http.StatusConflict, "Point adjustment is already undone"
+	case errors.Is(err, service.ErrAllowanceDisabled):
+		return http.StatusConflict, "Allowance is disabled for the family"
+	case errors.Is(err, service.ErrFamilyNotFound):
+		return http.StatusNotFound, "Family not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Only family parents can perform this action"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// RewardLimitsInput - необязательные ограничения награды, общие для создания и обновления
+type RewardLimitsInput struct {
+	// Stock - остаток, null - без ограничения
+	Stock *int `json:"stock" validate:"omitempty,min=0"`
+	// PerUserLimit - сколько раз один пользователь может обменять награду за LimitPeriod
+	PerUserLimit int `json:"per_user_limit" validate:"min=0"`
+	// LimitPeriod - Day, Week, Month (скользящее окно) или Ever, обязателен вместе с PerUserLimit
+	LimitPeriod    string     `json:"limit_period" validate:"required_with=PerUserLimit,omitempty,oneof=Day Week Month Ever"`
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+	AllowedRoles   []string   `json:"allowed_roles" validate:"max=3,dive,oneof=Parent Child Unknown"`
+	MinAge         int        `json:"min_age" validate:"min=0,max=150"`
+	MaxAge         int        `json:"max_age" validate:"min=0,max=150"`
+}
+
 type RewardCreateInput struct {
 	FamilyID    string `json:"family_id" validate:"required"`
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description" `
 	Cost        int    `json:"cost" validate:"required"`
+	RewardLimitsInput
+}
+
+// validateRewardLimits проверяет ограничения награды, включая согласованность пар полей
+func validateRewardLimits(w http.ResponseWriter, req *http.Request, log *slog.Logger, limits RewardLimitsInput) bool {
+	if err := validator.New().Struct(limits); err != nil {
+		response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return false
+	}
+	from, until := limits.AvailableFrom, limits.AvailableUntil
+	if from != nil && until != nil && !until.After(*from) {
+		response.NewError(w, req, log, nil, http.StatusBadRequest, "available_until must be after available_from")
+		return false
+	}
+	if limits.MaxAge > 0 && limits.MaxAge < limits.MinAge {
+		response.NewError(w, req, log, nil, http.StatusBadRequest, "max_age must not be less than min_age")
+		return false
+	}
+	return true
+}
+
+// rewardWithLimits переносит ограничения из запроса в награду
+func rewardWithLimits(reward entity.Reward, limits RewardLimitsInput) entity.Reward {
+	reward.Stock = limits.Stock
+	reward.PerUserLimit = limits.PerUserLimit
+	reward.LimitPeriod = limits.LimitPeriod
+	reward.AvailableFrom = limits.AvailableFrom
+	reward.AvailableUntil = limits.AvailableUntil
+	reward.AllowedRoles = limits.AllowedRoles
+	reward.MinAge = limits.MinAge
+	reward.MaxAge = limits.MaxAge
+	return reward
 }
 
 // createReward создает новое вознаграждение
@@ -80,16 +184,22 @@ func (r *RewardsRoutes) createReward(ctx context.Context, log *slog.Logger) http
 			return
 		}
 
+		if !validateRewardLimits(w, req, log, input.RewardLimitsInput) {
+			return
+		}
+
 		// Устанавливаем familyID из контекста пользователя
 		input.FamilyID = user.FamilyId.String
 
 		rewardID, err := r.rewardsService.Create(
-			ctx, log, entity.Reward{
-				FamilyID:    input.FamilyID,
-				Title:       input.Title,
-				Description: input.Description,
-				Cost:        input.Cost,
-			},
+			ctx, log, rewardWithLimits(
+				entity.Reward{
+					FamilyID:    input.FamilyID,
+					Title:       input.Title,
+					Description: input.Description,
+					Cost:        input.Cost,
+				}, input.RewardLimitsInput,
+			),
 		)
 		if err != nil {
 			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to create reward")
@@ -109,7 +219,7 @@ func (r *RewardsRoutes) getRewardsByFamilyID(ctx context.Context, log *slog.Logg
 			return
 		}
 
-		rewards, err := r.rewardsService.GetRewardsByFamilyID(ctx, log, user.FamilyId.String)
+		rewards, err := r.rewardsService.GetRewardsByFamilyID(ctx, log, user.FamilyId.String, user.Id)
 		if err != nil {
 			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to get rewards")
 			return
@@ -138,8 +248,19 @@ func (r *RewardsRoutes) getPoints(ctx context.Context, log *slog.Logger) http.Ha
 	}
 }
 
-// redeemReward обменивает очки на вознаграждение
-func (r *RewardsRoutes) redeemReward(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+// @Summary Get point history
+// @Description Ledger of the current user's points from newest to oldest: earned for todos, spent on rewards,
+// @Description adjustments and refunds. balance_after is the balance right after each entry
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default, at most 100"
+// @Success 200 {object} entity.PointHistoryPage
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/points/history [get]
+func (r *RewardsRoutes) getPointHistory(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		user, err := GetCurrentUserFromContext(req.Context())
 		if err != nil {
@@ -147,48 +268,169 @@ func (r *RewardsRoutes) redeemReward(ctx context.Context, log *slog.Logger) http
 			return
 		}
 
-		rewardID := chi.URLParam(req, "rewardID")
-		if rewardID == "" {
-			response.NewError(w, req, log, nil, http.StatusBadRequest, "Reward ID is required")
+		input := service.PointHistoryInput{UserID: user.Id, Cursor: req.URL.Query().Get("cursor")}
+		if err = validator.New().Var(input.Cursor, "omitempty,uuid"); err != nil {
+			response.NewError(w, req, log, err, http.StatusBadRequest, "Invalid cursor")
 			return
 		}
+		if limit := req.URL.Query().Get("limit"); limit != "" {
+			if input.Limit, err = strconv.Atoi(limit); err != nil {
+				response.NewError(w, req, log, err, http.StatusBadRequest, "Invalid limit")
+				return
+			}
+		}
 
-		err = r.rewardsService.Redeem(ctx, log, user.Id, rewardID)
+		page, err := r.rewardsService.GetPointHistory(ctx, log, input)
 		if err != nil {
-			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to redeem reward")
+			status, message := rewardsErrorResponse(err, "Failed to get point history")
+			response.NewError(w, req, log, err, status, message)
 			return
 		}
 
-		// Получаем информацию о награде
-		reward, err := r.rewardsService.GetByID(ctx, log, rewardID)
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, page)
+	}
+}
+
+type inputAdjustPoints struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+	// Points - положительное значение начисляет баллы, отрицательное списывает
+	Points int    `json:"points" validate:"required,min=-100000,max=100000"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+// @Summary Adjust points
+// @Description Add (positive points) or deduct (negative points) points of a family member with a mandatory
+// @Description reason. The ledger entry has kind Adjust, source_type adjustment and the parent in created_by.
+// @Description The member is notified. Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param input body inputAdjustPoints true "Adjustment"
+// @Success 200 {object} entity.PointTransaction
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/points/adjust [post]
+func (r *RewardsRoutes) adjustPoints(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
 		if err != nil {
-			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to get reward details")
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		var input inputAdjustPoints
+		if err = render.DecodeJSON(req.Body, &input); err != nil {
+			response.NewError(w, req, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		input.Reason = strings.TrimSpace(input.Reason)
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
 			return
 		}
 
-		// Получаем информацию о семье
-		family, err := r.familyService.GetByFamilyID(ctx, log, user.FamilyId.String)
+		transaction, err := r.rewardsService.AdjustPoints(
+			ctx, log, service.PointAdjustmentInput{
+				ParentID: user.Id,
+				UserID:   input.UserID,
+				Points:   input.Points,
+				Reason:   input.Reason,
+			},
+		)
 		if err != nil {
-			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to get family details")
+			status, message := rewardsErrorResponse(err, "Failed to adjust points")
+			response.NewError(w, req, log, err, status, message)
 			return
 		}
-		for _, member := range family {
-			if member.Role == "Parent" {
-				// Отправляем уведомление всем членам семьи
-				err = r.notificationService.SendNotification(
-					ctx, log, service.NotificationCreateInput{
-						UserID: member.Id,
-						Title:  "Награда получена",
-						Body:   fmt.Sprintf("Пользователь '%s' обменял очки на награду '%s'", user.Name, reward.Title),
-					},
-				)
-				if err != nil {
-					log.Error("Failed to send notification: %v", err)
-				}
-			}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, transaction)
+	}
+}
+
+// @Summary Undo point adjustment
+// @Description Reverse a manual adjustment with an opposite ledger entry (source_type adjustment_undo, source_id
+// @Description is the adjustment). An adjustment can be undone once; 409 if it is already undone or the member
+// @Description has already spent the added points. The member is notified. Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param transactionID path string true "Adjustment ledger entry ID"
+// @Success 200 {object} entity.PointTransaction
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/points/adjustments/{transactionID}/undo [post]
+func (r *RewardsRoutes) undoAdjustment(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		transactionID := chi.URLParam(req, "transactionID")
+		if err = validator.New().Var(transactionID, "required,uuid"); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		transaction, err := r.rewardsService.UndoAdjustment(ctx, log, user.Id, transactionID)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to undo point adjustment")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, transaction)
+	}
+}
+
+// @Summary Redeem reward
+// @Description Spend points on a reward. The redemption waits for a parent in the Pending state, declining it
+// @Description refunds the points. Stock, per-user limits, availability window and role/age restrictions are checked
+// @Description in the same transaction, 409 names the reason (out_of_stock, limit_reached, not_started, ended,
+// @Description role_restricted, age_restricted)
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param rewardID path string true "Reward ID"
+// @Success 200 {object} entity.RewardRedemption
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/{rewardID}/redeem [post]
+// redeemReward обменивает очки на вознаграждение
+func (r *RewardsRoutes) redeemReward(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
 		}
 
-		render.JSON(w, req, map[string]string{"message": "Reward redeemed successfully"})
+		rewardID := chi.URLParam(req, "rewardID")
+		if rewardID == "" {
+			response.NewError(w, req, log, nil, http.StatusBadRequest, "Reward ID is required")
+			return
+		}
+
+		// Родители получают уведомление о запросе от сервиса
+		redemption, err := r.rewardsService.Redeem(ctx, log, user.Id, rewardID)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to redeem reward")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		render.JSON(w, req, redemption)
 	}
 }
 
@@ -212,16 +454,16 @@ func (r *RewardsRoutes) getRedemptionsByUserID(ctx context.Context, log *slog.Lo
 }
 
 type RewardUpdateInput struct {
-	FamilyID    string `json:"family_id" validate:"required"`
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description" `
 	Cost        int    `json:"cost" validate:"required"`
+	RewardLimitsInput
 }
 
-// updateReward обновляет существующую награду
+// updateReward обновляет существующую награду. Доступно родителю семьи награды
 func (r *RewardsRoutes) updateReward(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		_, err := GetCurrentUserFromContext(req.Context())
+		user, err := GetCurrentUserFromContext(req.Context())
 		if err != nil {
 			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -238,31 +480,25 @@ func (r *RewardsRoutes) updateReward(ctx context.Context, log *slog.Logger) http
 			response.NewError(w, req, log, err, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
-		log.Info("RewardUpdateInput - input: %v", input)
-
-		// // Проверяем, принадлежит ли награда семье пользователя
-		// reward, err := r.rewardsService.GetByID(ctx, log, rewardID)
-		// if err != nil {
-		//     response.NewError(w, req, log, err, http.StatusNotFound, "Reward not found")
-		//     return
-		// }
-		// if reward.FamilyID != user.FamilyId.String {
-		//     response.NewError(w, req, log, nil, http.StatusForbidden, "You do not have permission to update this reward")
-		//     return
-		// }
+		log.Info("RewardUpdateInput", "input", input)
+		if !validateRewardLimits(w, req, log, input.RewardLimitsInput) {
+			return
+		}
 
 		// Обновляем награду
 		err = r.rewardsService.Update(
-			ctx, log, entity.Reward{
-				ID:          rewardID,
-				FamilyID:    input.FamilyID,
-				Title:       input.Title,
-				Description: input.Description,
-				Cost:        input.Cost,
-			},
+			ctx, log, user.Id, rewardWithLimits(
+				entity.Reward{
+					ID:          rewardID,
+					Title:       input.Title,
+					Description: input.Description,
+					Cost:        input.Cost,
+				}, input.RewardLimitsInput,
+			),
 		)
 		if err != nil {
-			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to update reward")
+			status, message := rewardsErrorResponse(err, "Failed to update reward")
+			response.NewError(w, req, log, err, status, message)
 			return
 		}
 
@@ -304,18 +540,21 @@ func (r *RewardsRoutes) getRedemptionsByUserIDParam(ctx context.Context, log *sl
 
 // delete reward
 // @Summary Delete reward
-// @Description Delete reward
+// @Description Delete reward. Available to parents of the reward's family. Points held by savings goals
+// @Description and spent on pending or approved redemptions of the reward are refunded
 // @Tags rewards
 // @Accept json
 // @Produce json
 // @Param rewardID path string true "Reward ID"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /rewards/{rewardID} [delete]
 func (r *RewardsRoutes) deleteReward(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		_, err := GetCurrentUserFromContext(req.Context())
+		user, err := GetCurrentUserFromContext(req.Context())
 		if err != nil {
 			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -327,12 +566,593 @@ func (r *RewardsRoutes) deleteReward(ctx context.Context, log *slog.Logger) http
 			return
 		}
 
-		err = r.rewardsService.Delete(ctx, log, rewardID)
+		err = r.rewardsService.Delete(ctx, log, user.Id, rewardID)
 		if err != nil {
-			response.NewError(w, req, log, err, http.StatusInternalServerError, "Failed to delete reward")
+			status, message := rewardsErrorResponse(err, "Failed to delete reward")
+			response.NewError(w, req, log, err, status, message)
 			return
 		}
 
 		render.JSON(w, req, map[string]string{"message": "Reward deleted successfully"})
 	}
 }
+
+// @Summary Get family redemptions
+// @Description Redemptions of all family members from newest to oldest. Available to parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param status query string false "Pending, Approved, Declined or Fulfilled"
+// @Success 200 {array} entity.RewardRedemption
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/redemptions/family [get]
+func (r *RewardsRoutes) getFamilyRedemptions(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		status := req.URL.Query().Get("status")
+		if err = validator.New().Var(status, "omitempty,oneof=Pending Approved Declined Fulfilled"); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		redemptions, err := r.rewardsService.GetFamilyRedemptions(ctx, log, user.Id, status)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to get family redemptions")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, redemptions)
+	}
+}
+
+type inputRedemptionReview struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// @Summary Approve redemption
+// @Description Approve a Pending redemption. Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param redemptionID path string true "Redemption ID"
+// @Param comment body string false "Comment for the requester"
+// @Success 200 {object} entity.RewardRedemption
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/redemptions/{redemptionID}/approve [post]
+func (r *RewardsRoutes) approveRedemption(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := redemptionReviewParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		redemption, err := r.rewardsService.ApproveRedemption(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to approve redemption")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, redemption)
+	}
+}
+
+// @Summary Decline redemption
+// @Description Decline a Pending or Approved redemption and refund the spent points. Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param redemptionID path string true "Redemption ID"
+// @Param comment body string false "Reason shown to the requester"
+// @Success 200 {object} entity.RewardRedemption
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/redemptions/{redemptionID}/decline [post]
+func (r *RewardsRoutes) declineRedemption(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := redemptionReviewParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		redemption, err := r.rewardsService.DeclineRedemption(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to decline redemption")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, redemption)
+	}
+}
+
+// @Summary Fulfill redemption
+// @Description Mark an Approved redemption as handed over. Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param redemptionID path string true "Redemption ID"
+// @Success 200 {object} entity.RewardRedemption
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/redemptions/{redemptionID}/fulfill [post]
+func (r *RewardsRoutes) fulfillRedemption(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := redemptionReviewParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		redemption, err := r.rewardsService.FulfillRedemption(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to fulfill redemption")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, redemption)
+	}
+}
+
+// redemptionReviewParams возвращает текущего пользователя, ID обмена и необязательный комментарий из запроса
+func redemptionReviewParams(w http.ResponseWriter, req *http.Request, log *slog.Logger) (
+	service.RedemptionReviewInput, bool,
+) {
+	user, err := GetCurrentUserFromContext(req.Context())
+	if err != nil {
+		response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+		return service.RedemptionReviewInput{}, false
+	}
+
+	input := service.RedemptionReviewInput{ID: chi.URLParam(req, "redemptionID"), UserID: user.Id}
+	if err = validator.New().Var(input.ID, "required,uuid"); err != nil {
+		response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.RedemptionReviewInput{}, false
+	}
+
+	if req.ContentLength != 0 {
+		var body inputRedemptionReview
+		if err = render.DecodeJSON(req.Body, &body); err != nil {
+			response.NewError(w, req, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return service.RedemptionReviewInput{}, false
+		}
+		if err = validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return service.RedemptionReviewInput{}, false
+		}
+		input.Comment = body.Comment
+	}
+	return input, true
+}
+
+type inputSavingsGoal struct {
+	RewardID string `json:"reward_id" validate:"required,uuid"`
+}
+
+type inputSavingsGoalPoints struct {
+	Points int `json:"points" validate:"required,min=1"`
+}
+
+// @Summary Get savings goals
+// @Description Savings goals of the current user with progress: active goals first, then completed ones.
+// @Description progress is the saved share of the reward cost in percent, remaining is the number of points left
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.SavingsGoal
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals [get]
+func (r *RewardsRoutes) getSavingsGoals(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		goals, err := r.rewardsService.GetSavingsGoals(ctx, log, user.Id)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to get savings goals")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, goals)
+	}
+}
+
+// @Summary Create savings goal
+// @Description Start saving points towards a family reward. A user has at most one active goal per reward
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param input body inputSavingsGoal true "Reward to save for"
+// @Success 201 {object} entity.SavingsGoal
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals [post]
+func (r *RewardsRoutes) createSavingsGoal(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		var input inputSavingsGoal
+		if err = render.DecodeJSON(req.Body, &input); err != nil {
+			response.NewError(w, req, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		goal, err := r.rewardsService.CreateSavingsGoal(ctx, log, user.Id, input.RewardID)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to create savings goal")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, req, goal)
+	}
+}
+
+// @Summary Deposit to savings goal
+// @Description Move points from the balance to an active savings goal. Saved points can't be spent elsewhere
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param goalID path string true "Savings goal ID"
+// @Param input body inputSavingsGoalPoints true "Points to save"
+// @Success 200 {object} entity.SavingsGoal
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals/{goalID}/deposit [post]
+func (r *RewardsRoutes) depositSavingsGoal(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := savingsGoalPointsParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		goal, err := r.rewardsService.DepositSavingsGoal(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to deposit to savings goal")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, goal)
+	}
+}
+
+// @Summary Withdraw from savings goal
+// @Description Move saved points from an active savings goal back to the balance
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param goalID path string true "Savings goal ID"
+// @Param input body inputSavingsGoalPoints true "Points to withdraw"
+// @Success 200 {object} entity.SavingsGoal
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals/{goalID}/withdraw [post]
+func (r *RewardsRoutes) withdrawSavingsGoal(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := savingsGoalPointsParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		goal, err := r.rewardsService.WithdrawSavingsGoal(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to withdraw from savings goal")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, goal)
+	}
+}
+
+// @Summary Redeem savings goal
+// @Description Spend the saved points on the goal's reward and complete the goal. Missing points are taken
+// @Description from the balance, extra points stay on it. The redemption is checked and reviewed
+// @Description like POST /rewards/{rewardID}/redeem
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param goalID path string true "Savings goal ID"
+// @Success 200 {object} entity.RewardRedemption
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals/{goalID}/redeem [post]
+func (r *RewardsRoutes) redeemSavingsGoal(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := savingsGoalParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		redemption, err := r.rewardsService.RedeemSavingsGoal(ctx, log, input)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to redeem savings goal")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, redemption)
+	}
+}
+
+// @Summary Delete savings goal
+// @Description Delete a savings goal. Points saved on an active goal return to the balance
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param goalID path string true "Savings goal ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/goals/{goalID} [delete]
+func (r *RewardsRoutes) deleteSavingsGoal(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		input, ok := savingsGoalParams(w, req, log)
+		if !ok {
+			return
+		}
+
+		if err := r.rewardsService.DeleteSavingsGoal(ctx, log, input); err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to delete savings goal")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, map[string]string{"message": "Savings goal deleted successfully"})
+	}
+}
+
+// savingsGoalParams возвращает текущего пользователя и ID цели из запроса
+func savingsGoalParams(w http.ResponseWriter, req *http.Request, log *slog.Logger) (service.SavingsGoalInput, bool) {
+	user, err := GetCurrentUserFromContext(req.Context())
+	if err != nil {
+		response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+		return service.SavingsGoalInput{}, false
+	}
+
+	input := service.SavingsGoalInput{GoalID: chi.URLParam(req, "goalID"), UserID: user.Id}
+	if err = validator.New().Var(input.GoalID, "required,uuid"); err != nil {
+		response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.SavingsGoalInput{}, false
+	}
+	return input, true
+}
+
+// savingsGoalPointsParams возвращает ID цели и число баллов из запроса
+func savingsGoalPointsParams(w http.ResponseWriter, req *http.Request, log *slog.Logger) (
+	service.SavingsGoalInput, bool,
+) {
+	input, ok := savingsGoalParams(w, req, log)
+	if !ok {
+		return service.SavingsGoalInput{}, false
+	}
+
+	var body inputSavingsGoalPoints
+	if err := render.DecodeJSON(req.Body, &body); err != nil {
+		response.NewError(w, req, log, err, http.StatusBadRequest, MsgFailedParsing)
+		return service.SavingsGoalInput{}, false
+	}
+	if err := validator.New().Struct(body); err != nil {
+		response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.SavingsGoalInput{}, false
+	}
+	input.Points = body.Points
+	return input, true
+}
+
+type inputAllowanceSettings struct {
+	Enabled bool `json:"enabled"`
+	// MinorPerPoint - стоимость балла в минимальных единицах валюты
+	MinorPerPoint int    `json:"minor_per_point" validate:"min=0,max=100000"`
+	Currency      string `json:"currency" validate:"omitempty,iso4217"`
+}
+
+// @Summary Get allowance settings
+// @Description Points-to-money conversion of the current user's family. Disabled until a parent configures it
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.AllowanceSettings
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/allowance [get]
+func (r *RewardsRoutes) getAllowanceSettings(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		settings, err := r.rewardsService.GetAllowanceSettings(ctx, log, user.Id)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to get allowance settings")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, settings)
+	}
+}
+
+// @Summary Update allowance settings
+// @Description Enable or disable the points-to-money conversion and set the price of a point in minor currency
+// @Description units (kopecks, cents). Available to family parents
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param input body inputAllowanceSettings true "Allowance settings"
+// @Success 200 {object} entity.AllowanceSettings
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/allowance [put]
+func (r *RewardsRoutes) updateAllowanceSettings(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		var input inputAllowanceSettings
+		if err = render.DecodeJSON(req.Body, &input); err != nil {
+			response.NewError(w, req, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		settings, err := r.rewardsService.UpdateAllowanceSettings(
+			ctx, log, service.AllowanceSettingsInput{
+				UserID:        user.Id,
+				Enabled:       input.Enabled,
+				MinorPerPoint: input.MinorPerPoint,
+				Currency:      input.Currency,
+			},
+		)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to update allowance settings")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, settings)
+	}
+}
+
+// @Summary Get weekly allowance report
+// @Description Money to pay out for points earned for todos during a Monday-Sunday week (UTC). Parents see every
+// @Description family member, other members see only themselves. Amounts are in minor currency units
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param week query string false "Any date of the week (YYYY-MM-DD), the current week by default"
+// @Success 200 {object} entity.AllowanceReport
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/allowance/report [get]
+func (r *RewardsRoutes) getAllowanceReport(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		week := time.Now()
+		if value := req.URL.Query().Get("week"); value != "" {
+			if week, err = time.Parse(time.DateOnly, value); err != nil {
+				response.NewError(w, req, log, err, http.StatusBadRequest, "Invalid week, expected YYYY-MM-DD")
+				return
+			}
+		}
+
+		report, err := r.rewardsService.GetAllowanceReport(ctx, log, user.Id, week)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to get allowance report")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, report)
+	}
+}
+
+// @Summary Get family leaderboard
+// @Description Points earned, approved todos and points spent on redemptions (declined ones excluded) of every
+// @Description family member for the current week (from Monday, UTC), the current month or all time. Members
+// @Description with equal points and todos share a rank. The result is cached per family and may lag slightly
+// @Tags rewards
+// @Accept json
+// @Produce json
+// @Param period query string false "week, month or all, week by default"
+// @Success 200 {object} entity.Leaderboard
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /rewards/leaderboard [get]
+func (r *RewardsRoutes) getLeaderboard(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, err := GetCurrentUserFromContext(req.Context())
+		if err != nil {
+			response.NewError(w, req, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		period := req.URL.Query().Get("period")
+		if period == "" {
+			period = entity.LeaderboardPeriodWeek
+		}
+		if err = validator.New().Var(period, "oneof=week month all"); err != nil {
+			response.NewValidateError(w, req, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		board, err := r.rewardsService.GetLeaderboard(ctx, log, user.Id, period)
+		if err != nil {
+			status, message := rewardsErrorResponse(err, "Failed to get leaderboard")
+			response.NewError(w, req, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, req, board)
+	}
+}
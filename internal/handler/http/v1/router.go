@@ -30,8 +30,9 @@ func NewRouter(ctx context.Context, log *slog.Logger, route *chi.Mux, services *
 	log.Info("Initializing websocket..")
 	route.Group(
 		func(r chi.Router) {
+			r.Use(AuthMiddleware(ctx, log, services.User))
 			r.HandleFunc(
-				"/ws", WebSocketHandler(ctx, log, services.Chats),
+				"/ws", WebSocketHandler(ctx, log, services.Chats, services.ShoppingItem),
 			)
 		},
 	)
@@ -48,18 +49,21 @@ func NewRouter(ctx context.Context, log *slog.Logger, route *chi.Mux, services *
 			r.Get("/ping", Ping())
 			NewAuthRoutes(ctx, log, r, services.User)
 			NewEmailRoutes(ctx, log, r, services.Email)
+			NewCalendarFeedRoutes(ctx, log, r, services.Calendar)
 			r.Group(
 				func(g chi.Router) {
 					g.Use(AuthMiddleware(ctx, log, services.User))
 					NewUserRoutes(ctx, log, g, services.User, services.File)
 					NewFamilyRoutes(ctx, log, g, services.Email, services.Family, services.File, services.Notification)
-					NewTodoRoutes(ctx, log, g, services.TodoItem, services.Notification)
+					NewTodoRoutes(ctx, log, g, services.TodoItem, services.Notification, services.File)
 					NewShoppingRoutes(ctx, log, g, services.ShoppingItem, services.Notification, services.Family)
 					NewWishlistRoutes(ctx, log, g, services.WishlistItem, services.Notification, services.Family)
 					NewNotificationRoutes(ctx, log, g, services.Notification)
 					NewChatsRoutes(ctx, log, g, services.Chats)
 					NewRewardsRoutes(ctx, log, g, services.Rewards, services.Notification, services.Family)
 					NewDiaryRoutes(ctx, log, g, services.Diary)
+					NewCalendarRoutes(ctx, log, g, services.Calendar)
+					NewAchievementsRoutes(ctx, log, g, services.Achievements)
 				},
 			)
 		},
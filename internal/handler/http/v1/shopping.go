@@ -2,10 +2,14 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"family-flow-app/internal/entity"
 	"family-flow-app/internal/service"
 	"family-flow-app/pkg/response"
 
@@ -18,6 +22,9 @@ const (
 	shoppingString = "/shopping"
 )
 
+// shoppingCategoryRule - правило проверки отдела магазина покупки, пустой отдел допустим
+var shoppingCategoryRule = "omitempty,oneof=" + strings.Join(entity.ShoppingCategories, " ")
+
 type ShoppingRoutes struct {
 	shoppingService     service.ShoppingItem
 	notificationService service.Notification
@@ -42,6 +49,15 @@ func NewShoppingRoutes(
 			r.Put("/buyer/{id}", u.updateBuyerId(ctx, log))
 			r.Get("/archived", u.getArchivedByUserID(ctx, log))
 			r.Put("/cancel_reserved/{id}", u.cancelUpdateReservedBy(ctx, log))
+			r.Get("/lists", u.getLists(ctx, log))
+			r.Post("/lists", u.createList(ctx, log))
+			r.Get("/lists/{listID}", u.getList(ctx, log))
+			r.Put("/lists/{listID}", u.updateList(ctx, log))
+			r.Delete("/lists/{listID}", u.deleteList(ctx, log))
+			r.Post("/lists/{listID}/archive", u.archiveList(ctx, log))
+			r.Post("/lists/{listID}/unarchive", u.unarchiveList(ctx, log))
+			r.Put("/lists/{listID}/shares", u.shareList(ctx, log))
+			r.Get("/spend", u.getSpend(ctx, log))
 		},
 	)
 }
@@ -50,7 +66,15 @@ type inputShoppingCreate struct {
 	FamilyId    string `json:"family_id" validate:"required"`
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description"`
-	Visibility  string `json:"visibility" validate:"required"`
+	Visibility  string `json:"visibility" validate:"required_without=ListId"`
+	ListId      string `json:"list_id" validate:"omitempty,uuid"`
+
+	Quantity float64 `json:"quantity" validate:"omitempty,gt=0,max=100000"`
+	Unit     string  `json:"unit" validate:"max=16"`
+	// Category - отдел магазина из entity.ShoppingCategories, other по умолчанию
+	Category            string `json:"category"`
+	EstimatedPriceMinor *int   `json:"estimated_price_minor" validate:"omitempty,min=0"`
+	Store               string `json:"store" validate:"max=255"`
 }
 
 // @Summary Create shopping item
@@ -64,6 +88,12 @@ type inputShoppingCreate struct {
 // @Param status body string true "Status"
 // @Param visibility body string true "Visibility"
 // @Param created_by body string true "Created by"
+// @Param list_id body string false "Shopping list ID, the default family (Public) or personal (Private) list if empty"
+// @Param quantity body number false "Quantity, 1 by default"
+// @Param unit body string false "Unit (pcs, kg, l)"
+// @Param category body string false "Store category (produce, dairy, household...), other by default"
+// @Param estimated_price_minor body int false "Estimated price of the whole item in minor currency units"
+// @Param store body string false "Store"
 // @Success 201 {string} string "Shopping item created"
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -87,24 +117,43 @@ func (u *ShoppingRoutes) create(ctx context.Context, log *slog.Logger) http.Hand
 			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
 			return
 		}
+		if err = validator.New().Var(input.Category, shoppingCategoryRule); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
 
-		_, err = u.shoppingService.Create(
+		id, err := u.shoppingService.Create(
 			ctx, log, service.ShoppingCreateInput{
 				FamilyID:    input.FamilyId,
 				Title:       input.Title,
 				Description: input.Description,
 				Visibility:  input.Visibility,
 				CreatedBy:   user.Id,
+				ListID:      input.ListId,
+
+				Quantity:            input.Quantity,
+				Unit:                input.Unit,
+				Category:            input.Category,
+				EstimatedPriceMinor: input.EstimatedPriceMinor,
+				Store:               input.Store,
 			},
 		)
 
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to create shopping item")
+			status, message := shoppingErrorResponse(err, "Failed to create shopping item")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		// Видимость и семья покупки в списке определяются самим списком
+		item, err := u.shoppingService.GetByID(ctx, log, id)
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to get shopping item")
 			return
 		}
 
-		if input.Visibility == "Public" {
-			family, err := u.familyService.GetByFamilyID(ctx, log, input.FamilyId)
+		if item.Visibility == "Public" {
+			family, err := u.familyService.GetByFamilyID(ctx, log, item.FamilyID)
 			if err != nil {
 				response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to get family members")
 				return
@@ -121,7 +170,7 @@ func (u *ShoppingRoutes) create(ctx context.Context, log *slog.Logger) http.Hand
 					},
 				)
 				if err != nil {
-					log.Error("Failed to send notification: %v", err)
+					log.Error("Failed to send notification", "error", err)
 				}
 			}
 		}
@@ -137,13 +186,16 @@ func (u *ShoppingRoutes) create(ctx context.Context, log *slog.Logger) http.Hand
 // @Accept json
 // @Produce json
 // @Param id path string true "ID"
+// @Param version query int false "Item version known to the client, 409 if the item was changed since"
 // @Success 200 {string} string "Shopping item deleted"
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /shopping/{id} [delete]
 func (u *ShoppingRoutes) delete(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := GetCurrentUserFromContext(r.Context())
+		user, err := GetCurrentUserFromContext(r.Context())
 		if err != nil {
 			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -154,10 +206,15 @@ func (u *ShoppingRoutes) delete(ctx context.Context, log *slog.Logger) http.Hand
 			response.NewError(w, r, log, nil, http.StatusBadRequest, "Invalid request")
 			return
 		}
+		version, ok := shoppingVersionParam(w, r, log)
+		if !ok {
+			return
+		}
 
-		err = u.shoppingService.Delete(ctx, log, id)
+		err = u.shoppingService.Delete(ctx, log, service.ShoppingDeleteInput{Id: id, UserID: user.Id, Version: version})
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to delete shopping item")
+			status, message := shoppingErrorResponse(err, "Failed to delete shopping item")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -172,6 +229,13 @@ type inputShoppingUpdate struct {
 	Status      string `json:"status"`
 	Visibility  string `json:"visibility"`
 	IsArchived  bool   `json:"is_archived"`
+
+	Quantity            float64 `json:"quantity" validate:"omitempty,gt=0,max=100000"`
+	Unit                string  `json:"unit" validate:"max=16"`
+	Category            string  `json:"category"`
+	EstimatedPriceMinor *int    `json:"estimated_price_minor" validate:"omitempty,min=0"`
+	ActualPriceMinor    *int    `json:"actual_price_minor" validate:"omitempty,min=0"`
+	Store               string  `json:"store" validate:"max=255"`
 }
 
 // @Summary Update shopping item
@@ -184,13 +248,22 @@ type inputShoppingUpdate struct {
 // @Param description body string true "Description"
 // @Param status body string true "Status"
 // @Param visibility body string true "Visibility"
+// @Param quantity body number false "Quantity, 1 by default"
+// @Param unit body string false "Unit (pcs, kg, l)"
+// @Param category body string false "Store category, other by default"
+// @Param estimated_price_minor body int false "Estimated price of the whole item in minor currency units"
+// @Param actual_price_minor body int false "Actual price of the whole item in minor currency units"
+// @Param store body string false "Store"
+// @Param version query int false "Item version known to the client, 409 if the item was changed since"
 // @Success 200 {string} string "Shopping item updated"
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /shopping/{id} [put]
 func (u *ShoppingRoutes) update(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := GetCurrentUserFromContext(r.Context())
+		user, err := GetCurrentUserFromContext(r.Context())
 		if err != nil {
 			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -201,6 +274,10 @@ func (u *ShoppingRoutes) update(ctx context.Context, log *slog.Logger) http.Hand
 			response.NewError(w, r, log, nil, http.StatusBadRequest, "Invalid request")
 			return
 		}
+		version, ok := shoppingVersionParam(w, r, log)
+		if !ok {
+			return
+		}
 
 		var input inputShoppingUpdate
 
@@ -213,6 +290,10 @@ func (u *ShoppingRoutes) update(ctx context.Context, log *slog.Logger) http.Hand
 			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
 			return
 		}
+		if err = validator.New().Var(input.Category, shoppingCategoryRule); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
 
 		log.Info(
 			"ShoppingRoutes - Update - Input fields",
@@ -226,15 +307,25 @@ func (u *ShoppingRoutes) update(ctx context.Context, log *slog.Logger) http.Hand
 		err = u.shoppingService.Update(
 			ctx, log, service.ShoppingUpdateInput{
 				ID:          id,
+				UserID:      user.Id,
+				Version:     version,
 				Title:       input.Title,
 				Description: input.Description,
 				Status:      input.Status,
 				Visibility:  input.Visibility,
 				IsArchived:  input.IsArchived,
+
+				Quantity:            input.Quantity,
+				Unit:                input.Unit,
+				Category:            input.Category,
+				EstimatedPriceMinor: input.EstimatedPriceMinor,
+				ActualPriceMinor:    input.ActualPriceMinor,
+				Store:               input.Store,
 			},
 		)
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to update shopping item")
+			status, message := shoppingErrorResponse(err, "Failed to update shopping item")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -312,8 +403,11 @@ func (u *ShoppingRoutes) getPrivateByCreatedBy(ctx context.Context, log *slog.Lo
 // @Produce json
 // @Param id path string true "ID"
 // @Param reserved_by body string true "Reserved by"
+// @Param version query int false "Item version known to the client, 409 if the item was changed since"
 // @Success 200 {string} string "Shopping item updated"
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /shopping/reserved/{id} [put]
 func (u *ShoppingRoutes) updateReservedBy(ctx context.Context, log *slog.Logger) http.HandlerFunc {
@@ -330,14 +424,21 @@ func (u *ShoppingRoutes) updateReservedBy(ctx context.Context, log *slog.Logger)
 			return
 		}
 
+		version, ok := shoppingVersionParam(w, r, log)
+		if !ok {
+			return
+		}
+
 		err = u.shoppingService.UpdateReservedBy(
 			ctx, log, service.ShoppingUpdateReservedByInput{
 				Id:         id,
 				ReservedBy: user.Id,
+				Version:    version,
 			},
 		)
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to update reserved by")
+			status, message := shoppingErrorResponse(err, "Failed to update reserved by")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -355,7 +456,7 @@ func (u *ShoppingRoutes) updateReservedBy(ctx context.Context, log *slog.Logger)
 			},
 		)
 		if err != nil {
-			log.Error("Failed to send notification: %v", err)
+			log.Error("Failed to send notification", "error", err)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -363,15 +464,25 @@ func (u *ShoppingRoutes) updateReservedBy(ctx context.Context, log *slog.Logger)
 	}
 }
 
+type inputShoppingBuy struct {
+	// ActualPriceMinor - сколько заплатили за всю позицию, в минимальных единицах валюты
+	ActualPriceMinor *int   `json:"actual_price_minor" validate:"omitempty,min=0"`
+	Store            string `json:"store" validate:"max=255"`
+}
+
 // @Summary Update buyer ID
-// @Description Update buyer ID
+// @Description Mark the item bought by the current user. The body is optional: the actual price and the store
+// @Description are kept as before when omitted. Bought items count towards the monthly spend
 // @Tags shopping
 // @Accept json
 // @Produce json
 // @Param id path string true "ID"
-// @Param buyer_id body string true "Buyer ID"
+// @Param input body inputShoppingBuy false "Actual price and store"
+// @Param version query int false "Item version known to the client, 409 if the item was changed since"
 // @Success 200 {string} string "Shopping item updated"
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /shopping/buyer/{id} [put]
 func (u *ShoppingRoutes) updateBuyerId(ctx context.Context, log *slog.Logger) http.HandlerFunc {
@@ -388,14 +499,34 @@ func (u *ShoppingRoutes) updateBuyerId(ctx context.Context, log *slog.Logger) ht
 			return
 		}
 
+		version, ok := shoppingVersionParam(w, r, log)
+		if !ok {
+			return
+		}
+
+		// Тело необязательно: старые клиенты отмечают покупку без цены
+		var input inputShoppingBuy
+		if err = render.DecodeJSON(r.Body, &input); err != nil && !errors.Is(err, io.EOF) {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Failed to parse request")
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
+
 		err = u.shoppingService.UpdateBuyerId(
 			ctx, log, service.ShoppingUpdateBuyerIdInput{
-				Id:      id,
-				BuyerId: user.Id,
+				Id:               id,
+				BuyerId:          user.Id,
+				ActualPriceMinor: input.ActualPriceMinor,
+				Store:            input.Store,
+				Version:          version,
 			},
 		)
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to update buyer ID")
+			status, message := shoppingErrorResponse(err, "Failed to update buyer ID")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -414,7 +545,7 @@ func (u *ShoppingRoutes) updateBuyerId(ctx context.Context, log *slog.Logger) ht
 			},
 		)
 		if err != nil {
-			log.Error("Failed to send notification: %v", err)
+			log.Error("Failed to send notification", "error", err)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -456,13 +587,16 @@ func (u *ShoppingRoutes) getArchivedByUserID(ctx context.Context, log *slog.Logg
 // @Accept json
 // @Produce json
 // @Param id path string true "ID"
+// @Param version query int false "Item version known to the client, 409 if the item was changed since"
 // @Success 200 {string} string "Shopping item updated"
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /shopping/cancel_reserved/{id} [put]
 func (u *ShoppingRoutes) cancelUpdateReservedBy(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := GetCurrentUserFromContext(r.Context())
+		user, err := GetCurrentUserFromContext(r.Context())
 		if err != nil {
 			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -474,13 +608,21 @@ func (u *ShoppingRoutes) cancelUpdateReservedBy(ctx context.Context, log *slog.L
 			return
 		}
 
+		version, ok := shoppingVersionParam(w, r, log)
+		if !ok {
+			return
+		}
+
 		err = u.shoppingService.CancelUpdateReservedBy(
 			ctx, log, service.ShoppingCancelUpdateReservedByInput{
-				Id: id,
+				Id:      id,
+				UserID:  user.Id,
+				Version: version,
 			},
 		)
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to cancel update reserved by")
+			status, message := shoppingErrorResponse(err, "Failed to cancel update reserved by")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -0,0 +1,453 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"family-flow-app/internal/service"
+	"family-flow-app/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+func shoppingErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrShoppingListNotFound):
+		return http.StatusNotFound, "Shopping list not found"
+	case errors.Is(err, service.ErrShoppingListArchived):
+		return http.StatusConflict, "Shopping list is archived"
+	case errors.Is(err, service.ErrShoppingListDefault):
+		return http.StatusConflict, "Default shopping list cannot be archived or deleted"
+	case errors.Is(err, service.ErrShoppingListNotPersonal):
+		return http.StatusConflict, "Only personal shopping lists can be shared"
+	case errors.Is(err, service.ErrShoppingItemNotFound):
+		return http.StatusNotFound, "Shopping item not found"
+	case errors.Is(err, service.ErrShoppingItemConflict):
+		return http.StatusConflict, "Shopping item was changed by someone else, reload it"
+	case errors.Is(err, service.ErrFamilyNotFound):
+		return http.StatusNotFound, "Family not found"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Only the list owner or family parents can perform this action"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// @Summary Get shopping lists
+// @Description Lists available to the current user: family lists, personal lists and lists shared with the user.
+// @Description Default lists go first. items_total and items_completed count non-archived items
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param archived query bool false "Archived lists instead of active ones"
+// @Success 200 {array} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists [get]
+func (u *ShoppingRoutes) getLists(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		archived := false
+		if value := r.URL.Query().Get("archived"); value != "" {
+			if archived, err = strconv.ParseBool(value); err != nil {
+				response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid archived")
+				return
+			}
+		}
+
+		lists, err := u.shoppingService.GetLists(ctx, log, user.Id, archived)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to get shopping lists")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, lists)
+	}
+}
+
+type inputShoppingListCreate struct {
+	Title string `json:"title" validate:"required,max=255"`
+	// Owner - family для списка семьи, user для личного списка
+	Owner      string   `json:"owner" validate:"required,oneof=family user"`
+	SharedWith []string `json:"shared_with" validate:"omitempty,max=50,dive,uuid"`
+}
+
+// @Summary Create shopping list
+// @Description Create a family list visible to every family member or a personal list visible to the owner
+// @Description and the family members from shared_with
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param input body inputShoppingListCreate true "Shopping list"
+// @Success 201 {object} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists [post]
+func (u *ShoppingRoutes) createList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		var input inputShoppingListCreate
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		list, err := u.shoppingService.CreateList(
+			ctx, log, service.ShoppingListCreateInput{
+				UserID:     user.Id,
+				Title:      input.Title,
+				Owner:      input.Owner,
+				SharedWith: input.SharedWith,
+			},
+		)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to create shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, list)
+	}
+}
+
+// @Summary Get shopping list
+// @Description Shopping list with its non-archived items grouped by store category in aisle order: produce,
+// @Description bakery, dairy, meat, grocery, frozen, drinks, household, pharmacy, other. Empty categories are
+// @Description skipped. Within a category items to buy go first, then bought ones, oldest first
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Success 200 {object} entity.ShoppingListWithItems
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID} [get]
+func (u *ShoppingRoutes) getList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		listID, ok := shoppingListParam(w, r, log)
+		if !ok {
+			return
+		}
+
+		list, err := u.shoppingService.GetList(ctx, log, user.Id, listID)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to get shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, list)
+	}
+}
+
+type inputShoppingListUpdate struct {
+	Title string `json:"title" validate:"required,max=255"`
+}
+
+// @Summary Rename shopping list
+// @Description Available to the owner of a personal list, the creator of a family list and family parents
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Param input body inputShoppingListUpdate true "New title"
+// @Success 200 {object} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID} [put]
+func (u *ShoppingRoutes) updateList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		listID, ok := shoppingListParam(w, r, log)
+		if !ok {
+			return
+		}
+		var input inputShoppingListUpdate
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		list, err := u.shoppingService.UpdateList(
+			ctx, log, service.ShoppingListUpdateInput{UserID: user.Id, ListID: listID, Title: input.Title},
+		)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to update shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, list)
+	}
+}
+
+// @Summary Delete shopping list
+// @Description Delete a shopping list together with its items. The default list cannot be deleted
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID} [delete]
+func (u *ShoppingRoutes) deleteList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		listID, ok := shoppingListParam(w, r, log)
+		if !ok {
+			return
+		}
+
+		if err = u.shoppingService.DeleteList(ctx, log, user.Id, listID); err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to delete shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, map[string]string{"message": "Shopping list deleted successfully"})
+	}
+}
+
+// @Summary Archive shopping list
+// @Description Move a shopping list to the archive: its items disappear from /shopping/public and
+// @Description /shopping/private. The default list cannot be archived
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Success 200 {object} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID}/archive [post]
+func (u *ShoppingRoutes) archiveList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return u.setListArchived(ctx, log, true)
+}
+
+// @Summary Unarchive shopping list
+// @Description Return a shopping list from the archive
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Success 200 {object} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID}/unarchive [post]
+func (u *ShoppingRoutes) unarchiveList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return u.setListArchived(ctx, log, false)
+}
+
+// setListArchived архивирует список покупок или возвращает его из архива
+func (u *ShoppingRoutes) setListArchived(ctx context.Context, log *slog.Logger, archived bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		listID, ok := shoppingListParam(w, r, log)
+		if !ok {
+			return
+		}
+
+		list, err := u.shoppingService.ArchiveList(ctx, log, user.Id, listID, archived)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to archive shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, list)
+	}
+}
+
+type inputShoppingListShare struct {
+	UserIDs []string `json:"user_ids" validate:"max=50,dive,uuid"`
+}
+
+// @Summary Share shopping list
+// @Description Replace the family members who can see and fill a personal list. An empty user_ids stops sharing.
+// @Description Family lists are visible to the whole family and cannot be shared
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param listID path string true "Shopping list ID"
+// @Param input body inputShoppingListShare true "Family members"
+// @Success 200 {object} entity.ShoppingList
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/lists/{listID}/shares [put]
+func (u *ShoppingRoutes) shareList(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		listID, ok := shoppingListParam(w, r, log)
+		if !ok {
+			return
+		}
+		var input inputShoppingListShare
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		list, err := u.shoppingService.ShareList(
+			ctx, log, service.ShoppingListShareInput{UserID: user.Id, ListID: listID, UserIDs: input.UserIDs},
+		)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to share shopping list")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, list)
+	}
+}
+
+// shoppingListParam читает и проверяет listID из пути. false - ответ с ошибкой уже отправлен
+func shoppingListParam(w http.ResponseWriter, r *http.Request, log *slog.Logger) (string, bool) {
+	listID := chi.URLParam(r, "listID")
+	if err := validator.New().Var(listID, "required,uuid"); err != nil {
+		response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return "", false
+	}
+	return listID, true
+}
+
+// shoppingVersionParam возвращает версию покупки из параметра version, 0 - параметра нет
+func shoppingVersionParam(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int64, bool) {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 1 {
+		response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid version")
+		return 0, false
+	}
+	return version, true
+}
+
+// @Summary Get shopping spend
+// @Description Spend on items marked bought during a month (UTC): totals, per list and per store category.
+// @Description Amounts are in minor currency units. actual_minor sums actual prices, items bought without one
+// @Description are counted in items_without_price. Without list_id every list available to the user is included,
+// @Description archived ones too
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param month query string false "Month (YYYY-MM), the current month by default"
+// @Param list_id query string false "Shopping list ID"
+// @Success 200 {object} entity.ShoppingSpendSummary
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /shopping/spend [get]
+func (u *ShoppingRoutes) getSpend(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		month := time.Now().UTC()
+		if value := r.URL.Query().Get("month"); value != "" {
+			if month, err = time.Parse("2006-01", value); err != nil {
+				response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid month, expected YYYY-MM")
+				return
+			}
+		}
+		listID := r.URL.Query().Get("list_id")
+		if err = validator.New().Var(listID, "omitempty,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		summary, err := u.shoppingService.GetSpend(ctx, log, user.Id, listID, month)
+		if err != nil {
+			status, message := shoppingErrorResponse(err, "Failed to get shopping spend")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, summary)
+	}
+}
@@ -2,11 +2,18 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"family-flow-app/internal/entity"
 	"family-flow-app/internal/service"
 	"family-flow-app/pkg/response"
 
@@ -19,23 +26,53 @@ const (
 	todoString = "/todo"
 )
 
+const (
+	// maxProofPhotos - максимальное число фото, прикладываемых к заданию за одну отправку
+	maxProofPhotos = 5
+	// maxProofUploadSize - ограничение на размер всего multipart-запроса с фото: 20 MB, больше - 413
+	maxProofUploadSize = 20 << 20
+)
+
 type TodoRoutes struct {
 	todoService         service.TodoItem
 	notificationService service.Notification
+	fileService         service.File
 }
 
 func NewTodoRoutes(
 	ctx context.Context, log *slog.Logger, route chi.Router, todoService service.TodoItem,
-	notificationService service.Notification,
+	notificationService service.Notification, fileService service.File,
 ) {
-	u := TodoRoutes{todoService: todoService, notificationService: notificationService}
+	u := TodoRoutes{todoService: todoService, notificationService: notificationService, fileService: fileService}
 	route.Route(
 		todoString, func(r chi.Router) {
 			r.Post("/", u.create(ctx, log))
+			r.Get("/", u.list(ctx, log))
 			r.Put("/{id}", u.update(ctx, log))
 			r.Delete("/{id}", u.delete(ctx, log))
 			r.Get("/assigned_to", u.getByAssignedTo(ctx, log))
 			r.Get("/created_by", u.getByCreatedBy(ctx, log))
+			r.Get("/review", u.getForReview(ctx, log))
+			r.Get("/archived", u.getArchived(ctx, log))
+			r.Post("/bulk", u.bulk(ctx, log))
+			r.Get("/{id}", u.getByID(ctx, log))
+			r.Get("/{id}/series", u.getSeries(ctx, log))
+			r.Put("/{id}/rotation", u.setRotation(ctx, log))
+			r.Get("/{id}/rotation", u.getRotationSchedule(ctx, log))
+			r.Post("/{id}/submit", u.submit(ctx, log))
+			r.Post("/{id}/approve", u.approve(ctx, log))
+			r.Post("/{id}/reject", u.reject(ctx, log))
+			r.Post("/{id}/archive", u.archive(ctx, log))
+			r.Post("/{id}/unarchive", u.unarchive(ctx, log))
+			r.Post("/{id}/restore", u.restore(ctx, log))
+			r.Post("/{id}/checklist", u.addChecklistItem(ctx, log))
+			r.Put("/{id}/checklist/order", u.reorderChecklist(ctx, log))
+			r.Put("/{id}/checklist/{itemID}", u.updateChecklistItem(ctx, log))
+			r.Delete("/{id}/checklist/{itemID}", u.deleteChecklistItem(ctx, log))
+			r.Get("/{id}/comments", u.getComments(ctx, log))
+			r.Post("/{id}/comments", u.addComment(ctx, log))
+			r.Delete("/{id}/comments/{commentID}", u.deleteComment(ctx, log))
+			r.Get("/{id}/activity", u.getActivity(ctx, log))
 		},
 	)
 }
@@ -45,8 +82,56 @@ type inputTodoCreate struct {
 	Title       string    `json:"title" validate:"required"`
 	Description string    `json:"description" validate:"required"`
 	Deadline    time.Time `json:"deadline" validate:"required"`
-	AssignedTo  string    `json:"assigned_to" validate:"required"`
+	AssignedTo  string    `json:"assigned_to" validate:"required_without=Rotation"`
 	Point       int       `json:"point"`
+	// RecurrenceRule - правило повторения (RRULE), например FREQ=WEEKLY;BYDAY=MO,TH
+	RecurrenceRule string `json:"recurrence_rule"`
+	// RequiresProof - исполнитель должен приложить фото при отправке на проверку
+	RequiresProof bool `json:"requires_proof"`
+	// Checklist - пункты чек-листа по порядку
+	Checklist []string `json:"checklist" validate:"max=50,dive,required,max=255"`
+	// PointsRule - правило начисления баллов за чек-лист: All (по умолчанию) или Proportional
+	PointsRule string `json:"points_rule" validate:"omitempty,oneof=All Proportional"`
+	// Rotation - участники, которым повторения назначаются по очереди, вместо assigned_to
+	Rotation []string `json:"rotation" validate:"max=20,dive,uuid"`
+}
+
+// todoErrorResponse возвращает HTTP-статус и сообщение для ошибки сервиса заданий
+func todoErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrItemNotFound):
+		return http.StatusNotFound, "Todo not found"
+	case errors.Is(err, service.ErrInvalidRecurrenceRule):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrTodoNotRecurring):
+		return http.StatusBadRequest, "Todo is not recurring"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Not allowed to change this todo"
+	case errors.Is(err, service.ErrInvalidTodoTransition):
+		return http.StatusConflict, "Todo status does not allow this action"
+	case errors.Is(err, service.ErrProofRequired):
+		return http.StatusBadRequest, "Photo proof is required"
+	case errors.Is(err, service.ErrChecklistItemNotFound):
+		return http.StatusNotFound, "Checklist item not found"
+	case errors.Is(err, service.ErrInvalidChecklistOrder):
+		return http.StatusBadRequest, "Checklist order must list every item once"
+	case errors.Is(err, service.ErrInvalidCursor):
+		return http.StatusBadRequest, "Invalid cursor"
+	case errors.Is(err, service.ErrRotationRequiresRecurrence):
+		return http.StatusBadRequest, "Rotation requires a recurrence rule"
+	case errors.Is(err, service.ErrDuplicateRotationMember):
+		return http.StatusBadRequest, "Rotation member is listed twice"
+	case errors.Is(err, service.ErrNotFamilyMember):
+		return http.StatusBadRequest, "Assignees must belong to the family"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	case errors.Is(err, service.ErrCommentNotFound):
+		return http.StatusNotFound, "Comment not found"
+	case errors.Is(err, service.ErrUndoWindowExpired):
+		return http.StatusGone, "Todo can no longer be restored"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
 }
 
 // @Summary Create todo
@@ -60,6 +145,11 @@ type inputTodoCreate struct {
 // @Param status body string true "Status"
 // @Param deadline body string true "Deadline"
 // @Param assigned_to body string true "Assigned to"
+// @Param recurrence_rule body string false "Recurrence rule (RRULE subset: FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, UNTIL)"
+// @Param requires_proof body bool false "Require a photo proof on submit"
+// @Param checklist body []string false "Checklist entries in order"
+// @Param points_rule body string false "Points rule for the checklist: All (default) or Proportional"
+// @Param rotation body []string false "Members assigned to occurrences in turn, requires recurrence_rule"
 // @Success 201 {string} string "Todo created"
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -84,7 +174,7 @@ func (u *TodoRoutes) create(ctx context.Context, log *slog.Logger) http.HandlerF
 			return
 		}
 
-		_, err = u.todoService.Create(
+		id, err := u.todoService.Create(
 			ctx, log, service.TodoCreateInput{
 				FamilyId:    input.FamilyId,
 				Title:       input.Title,
@@ -93,24 +183,37 @@ func (u *TodoRoutes) create(ctx context.Context, log *slog.Logger) http.HandlerF
 				AssignedTo:  input.AssignedTo,
 				CreatedBy:   user.Id,
 				Point:       input.Point,
+
+				RecurrenceRule: input.RecurrenceRule,
+				RequiresProof:  input.RequiresProof,
+				Checklist:      input.Checklist,
+				PointsRule:     input.PointsRule,
+				Rotation:       input.Rotation,
 			},
 		)
 
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to create family")
+			status, message := todoErrorResponse(err, "Failed to create family")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
-		// Отправляем уведомление пользователю, которому назначено задание
+		// Отправляем уведомление пользователю, которому назначено задание. При ротации его выбирает сервис
+		assignedTo := input.AssignedTo
+		if assignedTo == "" {
+			if todo, err := u.todoService.GetByID(ctx, log, id); err == nil {
+				assignedTo = todo.AssignedTo
+			}
+		}
 		err = u.notificationService.SendNotification(
 			ctx, log, service.NotificationCreateInput{
-				UserID: input.AssignedTo,
+				UserID: assignedTo,
 				Title:  "Новое задание",
 				Body:   fmt.Sprintf("Вам назначено новое задание: '%s'", input.Title),
 			},
 		)
 		if err != nil {
-			log.Error("Failed to send notification: %v", err)
+			log.Error("Failed to send notification", "error", err)
 		}
 
 		w.WriteHeader(http.StatusCreated)
@@ -119,19 +222,24 @@ func (u *TodoRoutes) create(ctx context.Context, log *slog.Logger) http.HandlerF
 }
 
 // @Summary Delete todo
-// @Description Delete todo
+// @Description Delete todo. A deleted todo can be restored with POST /todo/{id}/restore within a short undo window.
+// @Description Deleting a whole series stops it and returns its deleted open occurrences, which can be restored
+// @Description with POST /todo/bulk within the same window. Available to the creator and family parents
 // @Tags todo
 // @Accept json
 // @Produce json
 // @Param id path string true "ID"
+// @Param scope query string false "Scope for recurring todos: this (default) or series"
 // @Success 200 {string} string "Todo deleted"
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /todo/{id} [delete]
 func (u *TodoRoutes) delete(ctx context.Context, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		_, err := GetCurrentUserFromContext(r.Context())
+		user, err := GetCurrentUserFromContext(r.Context())
 		if err != nil {
 			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
 			return
@@ -143,9 +251,28 @@ func (u *TodoRoutes) delete(ctx context.Context, log *slog.Logger) http.HandlerF
 			return
 		}
 
-		err = u.todoService.Delete(ctx, log, id)
-		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to delete todo")
+		scope := r.URL.Query().Get("scope")
+		if err = validator.New().Var(scope, "omitempty,oneof=this series"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		if scope == service.TodoScopeSeries {
+			result, err := u.todoService.DeleteSeries(ctx, log, user.Id, id)
+			if err != nil {
+				status, message := todoErrorResponse(err, "Failed to delete todo series")
+				response.NewError(w, r, log, err, status, message)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			render.JSON(w, r, result)
+			return
+		}
+
+		if err = u.todoService.Delete(ctx, log, id, user.Id); err != nil {
+			status, message := todoErrorResponse(err, "Failed to delete todo")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
@@ -157,25 +284,31 @@ func (u *TodoRoutes) delete(ctx context.Context, log *slog.Logger) http.HandlerF
 type inputTodoUpdate struct {
 	Title       string    `json:"title" validate:"required"`
 	Description string    `json:"description" validate:"required"`
-	Status      string    `json:"status" validate:"required"`
 	Deadline    time.Time `json:"deadline" validate:"required"`
 	AssignedTo  string    `json:"assigned_to" validate:"required"`
 	Point       int       `json:"point"`
+	// RecurrenceRule - новое правило повторения, применяется только при scope=series
+	RecurrenceRule string `json:"recurrence_rule"`
 }
 
 // @Summary Update todo
-// @Description Update todo
+// @Description Update todo. Available to the creator and parents of the todo's family while the todo
+// @Description is Active or Rejected
 // @Tags todo
 // @Accept json
 // @Produce json
 // @Param id path string true "ID"
 // @Param title body string true "Title"
 // @Param description body string true "Description"
-// @Param status body string true "Status"
 // @Param deadline body string true "Deadline"
 // @Param assigned_to body string true "Assigned to"
+// @Param recurrence_rule body string false "New recurrence rule, used with scope=series"
+// @Param scope query string false "Scope for recurring todos: this (default) or series"
 // @Success 200 {string} string "Todo updated"
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /todo/{id} [put]
 func (u *TodoRoutes) update(ctx context.Context, log *slog.Logger) http.HandlerFunc {
@@ -187,7 +320,7 @@ func (u *TodoRoutes) update(ctx context.Context, log *slog.Logger) http.HandlerF
 		}
 
 		id := chi.URLParam(r, "id")
-		log.Info("id: %v", id)
+		log.Info("id", "id", id)
 		if id == "" {
 			response.NewError(w, r, log, nil, http.StatusBadRequest, MsgInvalidReq)
 			return
@@ -204,51 +337,39 @@ func (u *TodoRoutes) update(ctx context.Context, log *slog.Logger) http.HandlerF
 			return
 		}
 
+		scope := r.URL.Query().Get("scope")
+		if err = validator.New().Var(scope, "omitempty,oneof=this series"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
 		err = u.todoService.Update(
 			ctx, log, service.TodoUpdateInput{
 				ID:          id,
+				UserID:      user.Id,
 				Title:       input.Title,
 				Description: input.Description,
-				Status:      input.Status,
 				Deadline:    input.Deadline,
 				AssignedTo:  input.AssignedTo,
 				Point:       input.Point,
+
+				Scope:          scope,
+				RecurrenceRule: input.RecurrenceRule,
 			},
 		)
 		if err != nil {
-			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to update todo")
+			status, message := todoErrorResponse(err, "Failed to update todo")
+			response.NewError(w, r, log, err, status, message)
 			return
 		}
 
-		// Если задание завершено, отправляем уведомление создателю
-		if input.Status == "Completed" {
-			// Получаем задание, чтобы узнать, кто его создал
-			todo, err := u.todoService.GetByID(ctx, log, id)
-			if err != nil {
-				response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to fetch todo")
-				return
-			}
-
-			// Отправляем уведомление создателю задания
-			err = u.notificationService.SendNotification(
-				ctx, log, service.NotificationCreateInput{
-					UserID: todo.AssignedTo,
-					Title:  "Задание выполнено",
-					Body:   fmt.Sprintf("Задание '%s' было выполнено пользователем %s", todo.Title, user.Id),
-				},
-			)
-			if err != nil {
-				log.Error("Failed to send notification: %v", err)
-			}
-		}
-
 		w.WriteHeader(http.StatusOK)
 		render.JSON(w, r, "Todo updated")
 	}
 }
 
 // @Summary Get todo by assigned to
-// @Description Get todo by assigned to
+// @Description Get todo by assigned to. Deprecated: use GET /todo?assigned_to=
 // @Tags todo
 // @Accept json
 // @Produce json
@@ -276,7 +397,7 @@ func (u *TodoRoutes) getByAssignedTo(ctx context.Context, log *slog.Logger) http
 }
 
 // @Summary Get todo by created by
-// @Description Get todo by created by
+// @Description Get todo by created by. Deprecated: use GET /todo?created_by=
 // @Tags todo
 // @Accept json
 // @Produce json
@@ -302,3 +423,1105 @@ func (u *TodoRoutes) getByCreatedBy(ctx context.Context, log *slog.Logger) http.
 		render.JSON(w, r, items)
 	}
 }
+
+// @Summary Get todo series
+// @Description Get the recurrence series of a recurring todo. Available to members of the todo's family
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoSeries
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/series [get]
+func (u *TodoRoutes) getSeries(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if err = validator.New().Var(id, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		series, err := u.todoService.GetSeries(ctx, log, user.Id, id)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get todo series")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, series)
+	}
+}
+
+type inputTodoRotation struct {
+	Members []string `json:"members" validate:"max=20,dive,uuid"`
+}
+
+// @Summary Set todo rotation
+// @Description Set the members that recurring todo occurrences are assigned to in turn. An empty list disables
+// @Description the rotation. Available to the creator and family parents
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param members body []string true "Rotation members in order"
+// @Success 200 {object} entity.TodoSeries
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/rotation [put]
+func (u *TodoRoutes) setRotation(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var input inputTodoRotation
+		if err := render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		series, err := u.todoService.SetRotation(
+			ctx, log, service.TodoRotationInput{TodoID: params.ID, UserID: params.UserID, Members: input.Members},
+		)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to set rotation")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, series)
+	}
+}
+
+// @Summary Get rotation schedule
+// @Description Get upcoming occurrences of a recurring todo with their assignees, starting from the latest
+// @Description generated occurrence. Members marked as away are skipped
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param count query int false "Number of occurrences (default 10, max 50)"
+// @Success 200 {object} []entity.TodoRotationSlot
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/rotation [get]
+func (u *TodoRoutes) getRotationSchedule(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		schedule, err := u.todoService.GetRotationSchedule(ctx, log, params.UserID, params.ID, count)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get rotation schedule")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, schedule)
+	}
+}
+
+// reviewParams возвращает текущего пользователя и ID задания из запроса
+func reviewParams(w http.ResponseWriter, r *http.Request, log *slog.Logger) (service.TodoReviewInput, bool) {
+	user, err := GetCurrentUserFromContext(r.Context())
+	if err != nil {
+		response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+		return service.TodoReviewInput{}, false
+	}
+
+	id := chi.URLParam(r, "id")
+	if err = validator.New().Var(id, "required,uuid"); err != nil {
+		response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+		return service.TodoReviewInput{}, false
+	}
+
+	return service.TodoReviewInput{ID: id, UserID: user.Id}, true
+}
+
+// uploadProofs загружает фото выполнения из multipart-запроса (поле photos) в хранилище.
+// Запрос без multipart-тела допустим и возвращает пустой список
+func (u *TodoRoutes) uploadProofs(
+	ctx context.Context, w http.ResponseWriter, r *http.Request, log *slog.Logger,
+) ([]entity.TodoProof, bool) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return nil, true
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxProofUploadSize)
+	if err := r.ParseMultipartForm(maxProofUploadSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.NewError(
+				w, r, log, err, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Photos must not exceed %d MB in total", maxProofUploadSize>>20),
+			)
+			return nil, false
+		}
+		response.NewError(w, r, log, err, http.StatusBadRequest, "Failed to parse form data")
+		return nil, false
+	}
+
+	files := r.MultipartForm.File["photos"]
+	if len(files) > maxProofPhotos {
+		response.NewError(
+			w, r, log, nil, http.StatusBadRequest, fmt.Sprintf("No more than %d photos allowed", maxProofPhotos),
+		)
+		return nil, false
+	}
+
+	proofs := make([]entity.TodoProof, 0, len(files))
+	for _, header := range files {
+		body, err := readFormFile(header)
+		if err != nil {
+			u.deleteProofs(ctx, log, proofs)
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Failed to read photo")
+			return nil, false
+		}
+		if !strings.HasPrefix(http.DetectContentType(body), "image/") {
+			u.deleteProofs(ctx, log, proofs)
+			response.NewError(w, r, log, nil, http.StatusBadRequest, "Only images can be attached as proof")
+			return nil, false
+		}
+
+		path, err := u.fileService.Upload(ctx, log, service.FileUploadInput{FileName: header.Filename, FileBody: body})
+		if err != nil {
+			u.deleteProofs(ctx, log, proofs)
+			response.NewError(w, r, log, err, http.StatusInternalServerError, "Failed to upload photo")
+			return nil, false
+		}
+		proofs = append(proofs, entity.TodoProof{Path: path, URL: u.fileService.BuildImageURL(path)})
+	}
+
+	return proofs, true
+}
+
+// deleteProofs удаляет из хранилища фото, которые не удалось привязать к заданию
+func (u *TodoRoutes) deleteProofs(ctx context.Context, log *slog.Logger, proofs []entity.TodoProof) {
+	for _, proof := range proofs {
+		if _, err := u.fileService.Delete(ctx, proof.Path); err != nil {
+			log.Error("Handler - deleteProofs - Failed to delete photo", "path", proof.Path, "error", err)
+		}
+	}
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// @Summary Submit todo
+// @Description Submit a todo for review. Only the assignee can submit; rejected todos can be resubmitted.
+// @Description Photos proving completion can be attached as multipart/form-data files in the "photos" field
+// @Tags todo
+// @Accept json,mpfd
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param photos formData file false "Photo proof (up to 5 files)"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/submit [post]
+func (u *TodoRoutes) submit(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		proofs, ok := u.uploadProofs(ctx, w, r, log)
+		if !ok {
+			return
+		}
+		input.Proofs = proofs
+
+		todo, err := u.todoService.Submit(ctx, log, input)
+		if err != nil {
+			u.deleteProofs(ctx, log, proofs)
+			status, message := todoErrorResponse(err, "Failed to submit todo")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		// Сообщаем создателю задания, что его пора проверить
+		err = u.notificationService.SendNotification(
+			ctx, log, service.NotificationCreateInput{
+				UserID: todo.CreatedBy,
+				Title:  "Задание на проверке",
+				Body:   fmt.Sprintf("Задание '%s' выполнено и ждет проверки", todo.Title),
+			},
+		)
+		if err != nil {
+			log.Error("Handler - submit - Failed to send notification", "error", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+// @Summary Approve todo
+// @Description Approve a submitted todo and credit its points to the assignee (creator or family parent only)
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/approve [post]
+func (u *TodoRoutes) approve(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		todo, err := u.todoService.Approve(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to approve todo")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		err = u.notificationService.SendNotification(
+			ctx, log, service.NotificationCreateInput{
+				UserID: todo.AssignedTo,
+				Title:  "Задание принято",
+				Body:   fmt.Sprintf("Задание '%s' принято, начислено баллов: %d", todo.Title, todo.AwardedPoints),
+			},
+		)
+		if err != nil {
+			log.Error("Handler - approve - Failed to send notification", "error", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+type inputTodoReject struct {
+	Comment string `json:"comment" validate:"required,max=1000"`
+}
+
+// @Summary Reject todo
+// @Description Return a submitted todo to the assignee with a comment (creator or family parent only)
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param input body inputTodoReject true "Rejection comment"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/reject [post]
+func (u *TodoRoutes) reject(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputTodoReject
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+		input.Comment = body.Comment
+
+		todo, err := u.todoService.Reject(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to reject todo")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		err = u.notificationService.SendNotification(
+			ctx, log, service.NotificationCreateInput{
+				UserID: todo.AssignedTo,
+				Title:  "Задание возвращено",
+				Body:   fmt.Sprintf("Задание '%s' нужно доделать: %s", todo.Title, todo.ReviewComment),
+			},
+		)
+		if err != nil {
+			log.Error("Handler - reject - Failed to send notification", "error", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+// @Summary Get todo
+// @Description Get a todo with its photo proofs
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id} [get]
+func (u *TodoRoutes) getByID(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if err = validator.New().Var(id, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		todo, err := u.todoService.GetByID(ctx, log, id)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get todo")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+		// Чужое задание не отдаем вместе с фото подтверждения и не раскрываем, что оно существует
+		if !canViewTodo(user, todo) {
+			response.NewError(w, r, log, nil, http.StatusNotFound, "Todo not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+// canViewTodo проверяет, что задание видно пользователю: он состоит в семье задания, исполнитель или автор
+func canViewTodo(user *entity.User, todo entity.TodoItem) bool {
+	if todo.AssignedTo == user.Id || todo.CreatedBy == user.Id {
+		return true
+	}
+	return todo.FamilyID != "" && user.FamilyId.Valid && user.FamilyId.String == todo.FamilyID
+}
+
+// @Summary Get todos for review
+// @Description Get submitted todos waiting for the current user's review, with photo proofs.
+// @Description Parents see all submitted todos of their family
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Success 200 {object} []entity.TodoItem
+// @Failure 500 {object} response.Response
+// @Router /todo/review [get]
+func (u *TodoRoutes) getForReview(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		items, err := u.todoService.GetForReview(ctx, log, user.Id)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get todos for review")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, items)
+	}
+}
+
+// checklistParams возвращает текущего пользователя, ID задания и, если withItem, ID пункта чек-листа из запроса
+func checklistParams(w http.ResponseWriter, r *http.Request, log *slog.Logger, withItem bool) (
+	service.TodoChecklistInput, bool,
+) {
+	review, ok := reviewParams(w, r, log)
+	if !ok {
+		return service.TodoChecklistInput{}, false
+	}
+	input := service.TodoChecklistInput{TodoID: review.ID, UserID: review.UserID}
+
+	if withItem {
+		input.ItemID = chi.URLParam(r, "itemID")
+		if err := validator.New().Var(input.ItemID, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return service.TodoChecklistInput{}, false
+		}
+	}
+	return input, true
+}
+
+type inputChecklistItemCreate struct {
+	Title string `json:"title" validate:"required,max=255"`
+}
+
+// @Summary Add checklist item
+// @Description Add an entry to the end of the todo checklist. Available to the assignee, the creator and family parents
+// @Description while the todo is not submitted for review
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param title body string true "Entry title"
+// @Success 201 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/checklist [post]
+func (u *TodoRoutes) addChecklistItem(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := checklistParams(w, r, log, false)
+		if !ok {
+			return
+		}
+
+		var body inputChecklistItemCreate
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+		input.Title = &body.Title
+
+		todo, err := u.todoService.AddChecklistItem(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to add checklist item")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, todo)
+	}
+}
+
+type inputChecklistItemUpdate struct {
+	Title  *string `json:"title" validate:"omitempty,min=1,max=255"`
+	IsDone *bool   `json:"is_done"`
+}
+
+// @Summary Update checklist item
+// @Description Rename a checklist entry or check/uncheck it. Omitted fields are left unchanged
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param itemID path string true "Checklist item ID"
+// @Param title body string false "Entry title"
+// @Param is_done body bool false "Entry is checked"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/checklist/{itemID} [put]
+func (u *TodoRoutes) updateChecklistItem(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := checklistParams(w, r, log, true)
+		if !ok {
+			return
+		}
+
+		var body inputChecklistItemUpdate
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+		input.Title, input.IsDone = body.Title, body.IsDone
+
+		todo, err := u.todoService.UpdateChecklistItem(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to update checklist item")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+// @Summary Delete checklist item
+// @Description Delete a checklist entry
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param itemID path string true "Checklist item ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/checklist/{itemID} [delete]
+func (u *TodoRoutes) deleteChecklistItem(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := checklistParams(w, r, log, true)
+		if !ok {
+			return
+		}
+
+		todo, err := u.todoService.DeleteChecklistItem(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to delete checklist item")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+type inputChecklistReorder struct {
+	IDs []string `json:"ids" validate:"required,dive,uuid"`
+}
+
+// @Summary Reorder checklist
+// @Description Set the order of checklist entries. The list must contain every entry exactly once
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param ids body []string true "Checklist item IDs in the new order"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/checklist/order [put]
+func (u *TodoRoutes) reorderChecklist(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, ok := checklistParams(w, r, log, false)
+		if !ok {
+			return
+		}
+
+		var body inputChecklistReorder
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		todo, err := u.todoService.ReorderChecklist(ctx, log, input, body.IDs)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to reorder checklist")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, todo)
+	}
+}
+
+// timeQueryParam разбирает необязательный параметр запроса в формате RFC 3339
+func timeQueryParam(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+type inputTodoList struct {
+	AssignedTo string   `validate:"omitempty,uuid"`
+	CreatedBy  string   `validate:"omitempty,uuid"`
+	Statuses   []string `validate:"dive,oneof=Active Submitted Approved Rejected"`
+	Sort       string   `validate:"omitempty,oneof=deadline -deadline created_at -created_at"`
+	Limit      int      `validate:"gte=0,lte=100"`
+}
+
+// @Summary List todos
+// @Description List todos visible to the current user with filters and cursor pagination.
+// @Description Parents see the tasks of all family members, other users see tasks they are assigned to or created
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param assigned_to query string false "Assignee ID"
+// @Param created_by query string false "Creator ID"
+// @Param status query string false "Comma-separated statuses: Active, Submitted, Approved, Rejected"
+// @Param due_before query string false "Deadline before (RFC 3339)"
+// @Param due_after query string false "Deadline at or after (RFC 3339)"
+// @Param archived query bool false "Archived todos instead of active ones"
+// @Param sort query string false "deadline (default), -deadline, created_at or -created_at"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} entity.TodoPage
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo [get]
+func (u *TodoRoutes) list(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		query := r.URL.Query()
+		input := service.TodoListInput{
+			UserID:     user.Id,
+			AssignedTo: query.Get("assigned_to"),
+			CreatedBy:  query.Get("created_by"),
+			Sort:       query.Get("sort"),
+			Cursor:     query.Get("cursor"),
+		}
+		if status := query.Get("status"); status != "" {
+			input.Statuses = strings.Split(status, ",")
+		}
+		if limit := query.Get("limit"); limit != "" {
+			if input.Limit, err = strconv.Atoi(limit); err != nil {
+				response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid limit")
+				return
+			}
+		}
+		if archived := query.Get("archived"); archived != "" {
+			if input.Archived, err = strconv.ParseBool(archived); err != nil {
+				response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid archived flag")
+				return
+			}
+		}
+		if input.DueBefore, err = timeQueryParam(query, "due_before"); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid due_before, expected RFC 3339")
+			return
+		}
+		if input.DueAfter, err = timeQueryParam(query, "due_after"); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid due_after, expected RFC 3339")
+			return
+		}
+
+		err = validator.New().Struct(
+			inputTodoList{
+				AssignedTo: input.AssignedTo,
+				CreatedBy:  input.CreatedBy,
+				Statuses:   input.Statuses,
+				Sort:       input.Sort,
+				Limit:      input.Limit,
+			},
+		)
+		if err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		page, err := u.todoService.List(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to list todos")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, page)
+	}
+}
+
+// @Summary Get archived todos
+// @Description Archived todos visible to the current user, newest first. Same as GET /todo?archived=true&sort=-created_at
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} entity.TodoPage
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/archived [get]
+func (u *TodoRoutes) getArchived(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		input := service.TodoListInput{
+			UserID:   user.Id,
+			Archived: true,
+			Sort:     "-" + entity.TodoSortCreatedAt,
+			Cursor:   r.URL.Query().Get("cursor"),
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			if input.Limit, err = strconv.Atoi(limit); err != nil {
+				response.NewError(w, r, log, err, http.StatusBadRequest, "Invalid limit")
+				return
+			}
+		}
+
+		page, err := u.todoService.List(ctx, log, input)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get archived todos")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, page)
+	}
+}
+
+type inputTodoBulk struct {
+	Action     string   `json:"action" validate:"required,oneof=complete delete restore reassign archive unarchive"`
+	IDs        []string `json:"ids" validate:"required,min=1,max=100,dive,uuid"`
+	AssignedTo string   `json:"assigned_to" validate:"required_if=Action reassign,omitempty,uuid"`
+}
+
+// @Summary Bulk todo action
+// @Description Apply an action to up to 100 todos in a single transaction: either every todo is changed or none.
+// @Description complete approves the todos and awards points (reviewer only); delete, restore, reassign, archive
+// @Description and unarchive are allowed to the todo creator and family parents. Deleted todos can be restored
+// @Description until undo_until
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param input body inputTodoBulk true "Action and todo IDs"
+// @Success 200 {object} entity.TodoBulkResult
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 410 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/bulk [post]
+func (u *TodoRoutes) bulk(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, ErrNoUserInContextMsg)
+			return
+		}
+
+		var input inputTodoBulk
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		result, err := u.todoService.Bulk(
+			ctx, log, service.TodoBulkInput{
+				UserID:     user.Id,
+				Action:     input.Action,
+				IDs:        input.IDs,
+				AssignedTo: input.AssignedTo,
+			},
+		)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to apply bulk action")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, result)
+	}
+}
+
+// @Summary Archive todo
+// @Description Move a todo to the archive (creator or family parent only)
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/archive [post]
+func (u *TodoRoutes) archive(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u.singleBulk(ctx, w, r, log, entity.TodoBulkArchive, "Failed to archive todo")
+	}
+}
+
+// @Summary Unarchive todo
+// @Description Return an archived todo to the active list (creator or family parent only)
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/unarchive [post]
+func (u *TodoRoutes) unarchive(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u.singleBulk(ctx, w, r, log, entity.TodoBulkUnarchive, "Failed to unarchive todo")
+	}
+}
+
+// @Summary Restore deleted todo
+// @Description Undo deleting a todo. Possible only within a short window after deletion (creator or family parent only)
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} entity.TodoItem
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 410 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/restore [post]
+func (u *TodoRoutes) restore(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u.singleBulk(ctx, w, r, log, entity.TodoBulkRestore, "Failed to restore todo")
+	}
+}
+
+// singleBulk выполняет действие Bulk над заданием из URL и возвращает задание
+func (u *TodoRoutes) singleBulk(
+	ctx context.Context, w http.ResponseWriter, r *http.Request, log *slog.Logger, action, fallback string,
+) {
+	input, ok := reviewParams(w, r, log)
+	if !ok {
+		return
+	}
+
+	result, err := u.todoService.Bulk(
+		ctx, log, service.TodoBulkInput{UserID: input.UserID, Action: action, IDs: []string{input.ID}},
+	)
+	if err != nil {
+		status, message := todoErrorResponse(err, fallback)
+		response.NewError(w, r, log, err, status, message)
+		return
+	}
+	if len(result.Items) == 0 {
+		response.NewError(w, r, log, nil, http.StatusNotFound, "Todo not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, result.Items[0])
+}
+
+type inputTodoComment struct {
+	Body string `json:"body" validate:"required,max=2000"`
+	// Mentions - упомянутые члены семьи, получают отдельное уведомление
+	Mentions []string `json:"mentions" validate:"max=20,dive,uuid"`
+}
+
+// @Summary Get todo comments
+// @Description Comments of the todo from oldest to newest. Available to family members
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} []entity.TodoComment
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/comments [get]
+func (u *TodoRoutes) getComments(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		comments, err := u.todoService.GetComments(ctx, log, params.ID, params.UserID)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get comments")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, comments)
+	}
+}
+
+// @Summary Add todo comment
+// @Description Comment on the todo. Mentioned family members get a mention notification, the creator, the assignee
+// @Description and previous commenters get a comment notification
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param body body string true "Comment text"
+// @Param mentions body []string false "IDs of mentioned family members"
+// @Success 201 {object} entity.TodoComment
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/comments [post]
+func (u *TodoRoutes) addComment(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		var body inputTodoComment
+		if err := render.DecodeJSON(r.Body, &body); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, MsgFailedParsing)
+			return
+		}
+		if err := validator.New().Struct(body); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		comment, err := u.todoService.AddComment(
+			ctx, log, service.TodoCommentInput{
+				TodoID:   params.ID,
+				UserID:   params.UserID,
+				Body:     body.Body,
+				Mentions: body.Mentions,
+			},
+		)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to add comment")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, comment)
+	}
+}
+
+// @Summary Delete todo comment
+// @Description Delete a comment. Available to its author and family parents
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param commentID path string true "Comment ID"
+// @Success 200 {string} string "Comment deleted"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/comments/{commentID} [delete]
+func (u *TodoRoutes) deleteComment(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		commentID := chi.URLParam(r, "commentID")
+		if err := validator.New().Var(commentID, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, MsgInvalidReq, err)
+			return
+		}
+
+		err := u.todoService.DeleteComment(
+			ctx, log, service.TodoCommentInput{TodoID: params.ID, UserID: params.UserID, CommentID: commentID},
+		)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to delete comment")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Comment deleted")
+	}
+}
+
+// @Summary Get todo activity
+// @Description Timeline of the todo from oldest to newest: creation, edits, reassignments, deadline changes,
+// @Description review, comments, archiving and deletion. Available to family members
+// @Tags todo
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} []entity.TodoActivity
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /todo/{id}/activity [get]
+func (u *TodoRoutes) getActivity(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := reviewParams(w, r, log)
+		if !ok {
+			return
+		}
+
+		activities, err := u.todoService.GetActivity(ctx, log, params.ID, params.UserID)
+		if err != nil {
+			status, message := todoErrorResponse(err, "Failed to get todo activity")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, activities)
+	}
+}
@@ -37,6 +37,9 @@ func NewUserRoutes(
 			r.Put("/", u.update(ctx, log))
 			r.Put("/family_id", u.resetFamilyId(ctx, log))
 			r.Put("/location", u.updateLocation(ctx, log))
+			r.Get("/absences", u.getAbsences(ctx, log))
+			r.Post("/absences", u.addAbsence(ctx, log))
+			r.Delete("/absences/{id}", u.deleteAbsence(ctx, log))
 		},
 	)
 }
@@ -315,3 +318,143 @@ func (u *UserRoutes) updateLocation(ctx context.Context, log *slog.Logger) http.
 		render.JSON(w, r, "Location updated successfully")
 	}
 }
+
+// absenceErrorResponse возвращает HTTP-статус и сообщение для ошибки работы с отсутствиями
+func absenceErrorResponse(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrAbsenceNotFound):
+		return http.StatusNotFound, "Absence not found"
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, "User not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "Not allowed to manage absences of this user"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+type AddAbsenceInput struct {
+	// UserID - отсутствующий член семьи, по умолчанию текущий пользователь
+	UserID   string    `json:"user_id" validate:"omitempty,uuid"`
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+}
+
+// @Summary Add absence
+// @Description Mark a family member as away for a period. Chore rotations skip absent members.
+// @Description Users mark themselves, parents can mark any member of their family
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param input body AddAbsenceInput true "Absence period"
+// @Success 201 {object} entity.UserAbsence
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /user/absences [post]
+func (u *UserRoutes) addAbsence(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		var input AddAbsenceInput
+		if err = render.DecodeJSON(r.Body, &input); err != nil {
+			response.NewError(w, r, log, err, http.StatusBadRequest, "Failed to parse request")
+			return
+		}
+		if err = validator.New().Struct(input); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
+		if input.UserID == "" {
+			input.UserID = user.Id
+		}
+
+		absence, err := u.userService.AddAbsence(
+			ctx, log, service.AbsenceCreateInput{
+				UserID:   user.Id,
+				MemberID: input.UserID,
+				StartsAt: input.StartsAt,
+				EndsAt:   input.EndsAt,
+			},
+		)
+		if err != nil {
+			status, message := absenceErrorResponse(err, "Failed to add absence")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		render.JSON(w, r, absence)
+	}
+}
+
+// @Summary Get absences
+// @Description Get current and upcoming absences of the user's family members
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} []entity.UserAbsence
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /user/absences [get]
+func (u *UserRoutes) getAbsences(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		absences, err := u.userService.GetAbsences(ctx, log, user.Id)
+		if err != nil {
+			status, message := absenceErrorResponse(err, "Failed to get absences")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, absences)
+	}
+}
+
+// @Summary Delete absence
+// @Description Delete an absence period
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path string true "Absence ID"
+// @Success 200 {object} string
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /user/absences/{id} [delete]
+func (u *UserRoutes) deleteAbsence(ctx context.Context, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			response.NewError(w, r, log, err, http.StatusUnauthorized, "Failed to get current user")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if err = validator.New().Var(id, "required,uuid"); err != nil {
+			response.NewValidateError(w, r, log, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
+
+		if err = u.userService.DeleteAbsence(ctx, log, user.Id, id); err != nil {
+			status, message := absenceErrorResponse(err, "Failed to delete absence")
+			response.NewError(w, r, log, err, status, message)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		render.JSON(w, r, "Absence deleted")
+	}
+}
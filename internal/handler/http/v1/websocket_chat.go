@@ -7,69 +7,296 @@ import (
 	"net/http"
 	"sync"
 
+	"family-flow-app/internal/entity"
 	"family-flow-app/internal/service"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// Протокол чата описан в docs/websocket.asyncapi.yaml.
+// Клиент выбирает версию через подпротокол WebSocket (заголовок Sec-WebSocket-Protocol).
+// Клиенты без подпротокола работают по устаревшему формату action/data (версия 0)
+const (
+	wsProtocolV1 = "family-flow.v1"
+
+	wsVersionLegacy = 0
+	wsVersion1      = 1
+)
+
+// Типы сообщений клиента
+const (
+	wsTypePing              = "ping"
+	wsTypeMessageSend       = "message.send"
+	wsTypeMessageDelete     = "message.delete"
+	wsTypeParticipantMute   = "participant.mute"
+	wsTypeParticipantRemove = "participant.remove"
+	wsTypeChatLock          = "chat.lock"
+	wsTypePresenceSet       = "presence.set"
+)
+
+// Типы сообщений сервера: ответы на запросы клиента и события
+const (
+	wsTypePong  = "pong"
+	wsTypeAck   = "ack"
+	wsTypeError = "error"
+
+	wsEventMessageCreated     = "message.created"
+	wsEventMessageDeleted     = "message.deleted"
+	wsEventParticipantMuted   = "participant.muted"
+	wsEventParticipantRemoved = "participant.removed"
+	wsEventChatLocked         = "chat.locked"
+)
+
+// Соответствие действий и событий устаревшего протокола типам версии 1
+var (
+	wsLegacyActions = map[string]string{
+		"send_message":       wsTypeMessageSend,
+		"delete_message":     wsTypeMessageDelete,
+		"mute_participant":   wsTypeParticipantMute,
+		"remove_participant": wsTypeParticipantRemove,
+		"lock_chat":          wsTypeChatLock,
+		"set_presence":       wsTypePresenceSet,
+	}
+	wsLegacyEvents = map[string]string{
+		wsEventMessageCreated:     "",
+		wsEventMessageDeleted:     "message_deleted",
+		wsEventParticipantMuted:   "participant_muted",
+		wsEventParticipantRemoved: "participant_removed",
+		wsEventChatLocked:         "chat_locked",
+	}
+)
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Разрешить все соединения (для тестов)
 	},
+	Subprotocols: []string{wsProtocolV1},
+}
+
+// wsClient - активное соединение пользователя. Запись в соединение gorilla/websocket
+// должна выполняться из одной горутины, поэтому она защищена мьютексом
+type wsClient struct {
+	conn    *websocket.Conn
+	id      string
+	userID  string
+	version int
+	mu      sync.Mutex
+}
+
+func (c *wsClient) writeJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(v)
 }
 
 // Хранилище для активных WebSocket-соединений
 var connections = struct {
 	sync.Mutex
-	clients map[*websocket.Conn]bool
+	clients map[*wsClient]bool
 }{
-	clients: make(map[*websocket.Conn]bool),
+	clients: make(map[*wsClient]bool),
+}
+
+// WebSocketEnvelope - конверт сообщения протокола версии 1.
+// ID задает клиент, сервер возвращает его в ReplyTo ответа (ack или error)
+type WebSocketEnvelope struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	ReplyTo string          `json:"reply_to,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+}
+
+// WebSocketError - payload сообщения типа error. Code совпадает с HTTP-статусом аналогичного REST-запроса
+type WebSocketError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
+func (e *WebSocketError) Error() string {
+	return e.Message
+}
+
+// WebSocketRequest - сообщение клиента устаревшего протокола
 type WebSocketRequest struct {
 	Action string          `json:"action"`
 	Data   json.RawMessage `json:"data"`
 }
 
+// WebSocketResponse - сообщение сервера устаревшего протокола
 type WebSocketResponse struct {
 	Status  string      `json:"status"`
+	Action  string      `json:"action,omitempty"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
-func sendError(conn *websocket.Conn, message string) {
+func newWSError(code int, message string) *WebSocketError {
+	return &WebSocketError{Code: code, Message: message}
+}
+
+// wsServiceError переводит ошибку сервиса чатов в ошибку протокола так же, как это делает REST API
+func wsServiceError(err error, fallback string) *WebSocketError {
+	status, message := chatErrorResponse(err, fallback)
+	return newWSError(status, message)
+}
+
+func wsInvalidPayload(msgType string) *WebSocketError {
+	return newWSError(http.StatusBadRequest, "Invalid payload for "+msgType)
+}
+
+// send отправляет клиенту сообщение версии 1
+func (c *wsClient) send(msgType, replyTo string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.writeJSON(
+		WebSocketEnvelope{
+			Type:    msgType,
+			ID:      uuid.NewString(),
+			ReplyTo: replyTo,
+			Payload: raw,
+		},
+	)
+}
+
+// ack подтверждает успешную обработку запроса клиента
+func (c *wsClient) ack(requestID string, payload interface{}) {
+	if c.version == wsVersionLegacy {
+		return
+	}
+	if err := c.send(wsTypeAck, requestID, payload); err != nil {
+		slog.Default().Error("wsClient - ack - Failed to write", "error", err)
+	}
+}
+
+// sendError сообщает клиенту об ошибке обработки запроса
+func (c *wsClient) sendError(requestID string, wsErr *WebSocketError) {
 	log := slog.Default()
-	log.Error("sendError - Sending error response", "message", message)
+	log.Error("wsClient - sendError - Sending error response", "code", wsErr.Code, "message", wsErr.Message)
 
-	resp := WebSocketResponse{
-		Status:  "error",
-		Message: message,
+	var err error
+	if c.version == wsVersionLegacy {
+		err = c.writeJSON(WebSocketResponse{Status: "error", Message: wsErr.Message})
+	} else {
+		err = c.send(wsTypeError, requestID, wsErr)
 	}
-	conn.WriteJSON(resp)
+	if err != nil {
+		log.Error("wsClient - sendError - Failed to write", "error", err)
+	}
+}
+
+// sendEvent отправляет клиенту событие, инициированное сервером
+func (c *wsClient) sendEvent(eventType string, payload interface{}) error {
+	if c.version == wsVersionLegacy {
+		return c.writeJSON(
+			WebSocketResponse{
+				Status: "success",
+				Action: wsLegacyEvents[eventType],
+				Data:   payload,
+			},
+		)
+	}
+	return c.send(eventType, "", payload)
 }
 
-func WebSocketHandler(ctx context.Context, log *slog.Logger, chatService service.Chats) http.HandlerFunc {
+// broadcastToChat отправляет событие всем подключенным участникам чата, кроме exclude
+func broadcastToChat(
+	ctx context.Context, log *slog.Logger, chatService service.Chats, chatID, eventType string, payload interface{},
+	exclude *wsClient, extraUserIDs ...string,
+) {
+	participants, err := chatService.GetParticipants(ctx, log, chatID)
+	if err != nil {
+		log.Error("broadcastToChat - Failed to get participants", "chat_id", chatID, "error", err)
+		return
+	}
+
+	recipients := make(map[string]bool, len(participants)+len(extraUserIDs))
+	for _, userID := range participants {
+		recipients[userID] = true
+	}
+	for _, userID := range extraUserIDs {
+		recipients[userID] = true
+	}
+
+	connections.Lock()
+	defer connections.Unlock()
+	log.Info(
+		"broadcastToChat - Broadcasting", "chat_id", chatID, "event", eventType,
+		"connected_clients", len(connections.clients),
+	)
+	for client := range connections.clients {
+		if client == exclude || !recipients[client.userID] {
+			continue
+		}
+		if err := client.sendEvent(eventType, payload); err != nil {
+			log.Error("broadcastToChat - Failed to send message to client", "error", err)
+			client.conn.Close()
+			delete(connections.clients, client)
+		}
+	}
+}
+
+// wsHandler обрабатывает запрос клиента. Возвращенный payload отправляется клиенту в ack
+type wsHandler func(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError)
+
+var wsHandlers = map[string]wsHandler{
+	wsTypeMessageSend:       handleSendMessage,
+	wsTypeMessageDelete:     handleDeleteMessage,
+	wsTypeParticipantMute:   handleMuteParticipant,
+	wsTypeParticipantRemove: handleRemoveParticipant,
+	wsTypeChatLock:          handleLockChat,
+	wsTypePresenceSet:       handleSetPresence,
+}
+
+func WebSocketHandler(
+	ctx context.Context, log *slog.Logger, chatService service.Chats, shoppingService service.ShoppingItem,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Info("WebSocketHandler - Start connection upgrade")
 
+		user, err := GetCurrentUserFromContext(r.Context())
+		if err != nil {
+			log.Error("WebSocketHandler - Failed to get current user", "error", err)
+			http.Error(w, ErrNoUserInContextMsg, http.StatusUnauthorized)
+			return
+		}
+
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Error("WebSocketHandler - Failed to upgrade connection", "error", err)
 			return
 		}
+		client := &wsClient{conn: conn, id: uuid.NewString(), userID: user.Id, version: wsVersionLegacy}
+		if conn.Subprotocol() == wsProtocolV1 {
+			client.version = wsVersion1
+		}
 		defer func() {
 			connections.Lock()
-			delete(connections.clients, conn)
+			delete(connections.clients, client)
 			connections.Unlock()
+			chatService.SetPresence(log, client.userID, client.id, service.ChatPresenceOffline)
 			conn.Close()
 			log.Info("WebSocketHandler - Connection closed")
 		}()
 
 		// Добавляем соединение в хранилище
 		connections.Lock()
-		connections.clients[conn] = true
+		connections.clients[client] = true
 		connections.Unlock()
-		log.Info("WebSocketHandler - Connection upgraded successfully")
+		// Пока соединение активно, пуш-уведомления о новых сообщениях не отправляются
+		chatService.SetPresence(log, client.userID, client.id, service.ChatPresenceActive)
+		log.Info("WebSocketHandler - Connection upgraded successfully", "user_id", user.Id, "version", client.version)
+
+		// Изменения покупок получают только клиенты версии 1: в устаревшем протоколе для них нет событий
+		if client.version != wsVersionLegacy {
+			events, unsubscribe := shoppingService.SubscribeEvents(client.userID)
+			defer unsubscribe()
+			go forwardShoppingEvents(log, client, events)
+		}
 
 		// Устанавливаем pong handler для продления соединения
 		conn.SetPongHandler(
@@ -88,36 +315,77 @@ func WebSocketHandler(ctx context.Context, log *slog.Logger, chatService service
 
 			log.Info("WebSocketHandler - Message received", "message", string(message))
 
-			// Обработка ping от клиента
-			var raw map[string]interface{}
-			if err := json.Unmarshal(message, &raw); err == nil {
-				if t, ok := raw["type"]; ok && t == "ping" {
-					// Отправляем pong в ответ
-					conn.WriteJSON(map[string]string{"type": "pong"})
-					log.Info("WebSocketHandler - Pong sent")
-					continue
-				}
+			req, ok := decodeWSRequest(client, message)
+			if !ok {
+				continue
 			}
 
-			// Обработка остальных сообщений
-			var req WebSocketRequest
-			if err := json.Unmarshal(message, &req); err != nil {
-				log.Error("WebSocketHandler - Invalid message format", "error", err)
-				sendError(conn, "Invalid message format")
+			log.Info("WebSocketHandler - Processing request", "type", req.Type, "id", req.ID)
+
+			if req.Type == wsTypePing {
+				if client.version == wsVersionLegacy {
+					client.writeJSON(map[string]string{"type": wsTypePong})
+				} else {
+					client.send(wsTypePong, req.ID, nil)
+				}
+				log.Info("WebSocketHandler - Pong sent")
 				continue
 			}
 
-			log.Info("WebSocketHandler - Processing action", "action", req.Action)
+			handler, ok := wsHandlers[req.Type]
+			if !ok {
+				log.Warn("WebSocketHandler - Unknown message type", "type", req.Type)
+				client.sendError(req.ID, newWSError(http.StatusBadRequest, "Unknown message type"))
+				continue
+			}
 
-			switch req.Action {
-			case "send_message":
-				handleSendMessage(ctx, log, conn, chatService, req.Data)
-			default:
-				log.Warn("WebSocketHandler - Unknown action", "action", req.Action)
-				sendError(conn, "Unknown action")
+			payload, wsErr := handler(ctx, log, client, chatService, req.Payload)
+			if wsErr != nil {
+				client.sendError(req.ID, wsErr)
+				continue
 			}
+			client.ack(req.ID, payload)
+		}
+	}
+}
+
+// forwardShoppingEvents отправляет клиенту изменения покупок, пока подписка не закрыта
+func forwardShoppingEvents(log *slog.Logger, client *wsClient, events <-chan entity.ShoppingEvent) {
+	for event := range events {
+		if err := client.sendEvent(event.Type, event); err != nil {
+			log.Error("forwardShoppingEvents - Failed to send event", "user_id", client.userID, "error", err)
+		}
+	}
+}
+
+// decodeWSRequest разбирает сообщение клиента. Сообщения устаревшего протокола приводятся к конверту версии 1
+func decodeWSRequest(client *wsClient, message []byte) (WebSocketEnvelope, bool) {
+	if client.version != wsVersionLegacy {
+		var req WebSocketEnvelope
+		if err := json.Unmarshal(message, &req); err != nil || req.Type == "" {
+			client.sendError("", newWSError(http.StatusBadRequest, "Invalid message format"))
+			return WebSocketEnvelope{}, false
 		}
+		return req, true
+	}
+
+	var req struct {
+		WebSocketRequest
+		Type string `json:"type"`
 	}
+	if err := json.Unmarshal(message, &req); err != nil {
+		client.sendError("", newWSError(http.StatusBadRequest, "Invalid message format"))
+		return WebSocketEnvelope{}, false
+	}
+	if req.Type == wsTypePing {
+		return WebSocketEnvelope{Type: wsTypePing}, true
+	}
+
+	msgType, ok := wsLegacyActions[req.Action]
+	if !ok {
+		msgType = req.Action
+	}
+	return WebSocketEnvelope{Type: msgType, Payload: req.Data}, true
 }
 
 type CreateMessageInput struct {
@@ -127,17 +395,19 @@ type CreateMessageInput struct {
 }
 
 func handleSendMessage(
-	ctx context.Context, log *slog.Logger, conn *websocket.Conn, chatService service.Chats, data json.RawMessage,
-) {
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
 	log.Info("handleSendMessage - Start")
 
 	var input CreateMessageInput
-	if err := json.Unmarshal(data, &input); err != nil {
+	if err := json.Unmarshal(payload, &input); err != nil {
 		log.Error("handleSendMessage - Invalid input", "error", err)
-		sendError(conn, "Invalid input for send_message")
-		return
+		return nil, wsInvalidPayload(wsTypeMessageSend)
 	}
 
+	// Отправителем всегда считается владелец соединения
+	input.SenderID = client.userID
+
 	log.Info("handleSendMessage - Sending message", "chat_id", input.ChatID, "sender_id", input.SenderID)
 
 	output, err := chatService.CreateMessage(
@@ -149,27 +419,143 @@ func handleSendMessage(
 	)
 	if err != nil {
 		log.Error("handleSendMessage - Failed to send message", "error", err)
-		sendError(conn, "Failed to send message: "+err.Error())
-		return
+		return nil, wsServiceError(err, "Failed to send message")
 	}
 
 	log.Info("handleSendMessage - Message sent successfully", "message:", output)
-	connections.Lock()
-	log.Info("handleSendMessage - Broadcasting message", "connected_clients", len(connections.clients))
-	defer connections.Unlock()
-	for client := range connections.clients {
-		if client == conn {
-			continue // Не отправляем сообщение обратно тому, кто его отправил
-		}
-		if err := client.WriteJSON(
-			WebSocketResponse{
-				Status: "success",
-				Data:   output,
-			},
-		); err != nil {
-			log.Error("handleSendMessage - Failed to send message to client", "error", err)
-			client.Close()
-			delete(connections.clients, client)
-		}
+	// Отправитель получает сообщение в ack, поэтому событие ему не отправляется
+	broadcastToChat(ctx, log, chatService, output.ChatID, wsEventMessageCreated, output, client)
+	return output, nil
+}
+
+type deleteMessageInput struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+}
+
+func handleDeleteMessage(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
+	log.Info("handleDeleteMessage - Start")
+
+	var input deleteMessageInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		log.Error("handleDeleteMessage - Invalid input", "error", err)
+		return nil, wsInvalidPayload(wsTypeMessageDelete)
+	}
+
+	message, err := chatService.DeleteMessage(ctx, log, input.ChatID, input.MessageID, client.userID)
+	if err != nil {
+		return nil, wsServiceError(err, "Failed to delete message")
 	}
+
+	broadcastToChat(ctx, log, chatService, message.ChatID, wsEventMessageDeleted, message, nil)
+	return message, nil
+}
+
+type moderateParticipantInput struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+	Muted  bool   `json:"muted"`
+}
+
+func handleMuteParticipant(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
+	log.Info("handleMuteParticipant - Start")
+
+	var input moderateParticipantInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		log.Error("handleMuteParticipant - Invalid input", "error", err)
+		return nil, wsInvalidPayload(wsTypeParticipantMute)
+	}
+
+	err := chatService.SetParticipantMuted(
+		ctx, log, service.ModerateParticipantInput{
+			ChatID:  input.ChatID,
+			UserID:  input.UserID,
+			ActorID: client.userID,
+		}, input.Muted,
+	)
+	if err != nil {
+		return nil, wsServiceError(err, "Failed to update participant")
+	}
+
+	broadcastToChat(ctx, log, chatService, input.ChatID, wsEventParticipantMuted, input, nil)
+	return input, nil
+}
+
+func handleRemoveParticipant(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
+	log.Info("handleRemoveParticipant - Start")
+
+	var input moderateParticipantInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		log.Error("handleRemoveParticipant - Invalid input", "error", err)
+		return nil, wsInvalidPayload(wsTypeParticipantRemove)
+	}
+
+	err := chatService.RemoveParticipant(
+		ctx, log, service.ModerateParticipantInput{
+			ChatID:  input.ChatID,
+			UserID:  input.UserID,
+			ActorID: client.userID,
+		},
+	)
+	if err != nil {
+		return nil, wsServiceError(err, "Failed to remove participant")
+	}
+
+	// Удаленный участник тоже должен узнать об исключении
+	broadcastToChat(ctx, log, chatService, input.ChatID, wsEventParticipantRemoved, input, nil, input.UserID)
+	return input, nil
+}
+
+type lockChatInput struct {
+	ChatID string `json:"chat_id"`
+	Locked bool   `json:"locked"`
+}
+
+func handleLockChat(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
+	log.Info("handleLockChat - Start")
+
+	var input lockChatInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		log.Error("handleLockChat - Invalid input", "error", err)
+		return nil, wsInvalidPayload(wsTypeChatLock)
+	}
+
+	if err := chatService.SetChatLocked(ctx, log, input.ChatID, client.userID, input.Locked); err != nil {
+		return nil, wsServiceError(err, "Failed to update chat")
+	}
+
+	broadcastToChat(ctx, log, chatService, input.ChatID, wsEventChatLocked, input, nil)
+	return input, nil
+}
+
+type setPresenceInput struct {
+	State string `json:"state"`
+}
+
+// handleSetPresence позволяет клиенту сообщить, что приложение ушло в фон или вернулось на передний план.
+// В фоне сообщения по-прежнему приходят в сокет, но дополнительно отправляются пуш-уведомления
+func handleSetPresence(
+	ctx context.Context, log *slog.Logger, client *wsClient, chatService service.Chats, payload json.RawMessage,
+) (interface{}, *WebSocketError) {
+	log.Info("handleSetPresence - Start")
+
+	var input setPresenceInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		log.Error("handleSetPresence - Invalid input", "error", err)
+		return nil, wsInvalidPayload(wsTypePresenceSet)
+	}
+	if input.State != service.ChatPresenceActive && input.State != service.ChatPresenceBackground {
+		return nil, newWSError(http.StatusBadRequest, "Invalid presence state")
+	}
+
+	chatService.SetPresence(log, client.userID, client.id, input.State)
+	return input, nil
 }
@@ -0,0 +1,396 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/service"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsTestChatID = "3d9b6a70-5c8e-4a1f-9f2b-0e1d2c3b4a59"
+
+// wsStubChats - сервис чатов с одним чатом wsTestChatID и участниками participants
+type wsStubChats struct {
+	service.Chats
+	participants []string
+}
+
+func (s *wsStubChats) SetPresence(log *slog.Logger, userID, connID, state string) {}
+
+func (s *wsStubChats) GetParticipants(ctx context.Context, log *slog.Logger, chatID string) ([]string, error) {
+	if chatID != wsTestChatID {
+		return nil, service.ErrChatNotFound
+	}
+	return s.participants, nil
+}
+
+func (s *wsStubChats) CreateMessage(ctx context.Context, log *slog.Logger, input service.CreateMessageInput) (
+	entity.Message, error,
+) {
+	if input.ChatID != wsTestChatID {
+		return entity.Message{}, service.ErrChatNotFound
+	}
+	if !slices.Contains(s.participants, input.SenderID) {
+		return entity.Message{}, service.ErrNotChatParticipant
+	}
+	return entity.Message{ID: "message-1", ChatID: input.ChatID, SenderID: input.SenderID, Content: input.Content}, nil
+}
+
+func (s *wsStubChats) DeleteMessage(ctx context.Context, log *slog.Logger, chatID, messageID, actorID string) (
+	entity.Message, error,
+) {
+	return entity.Message{}, service.ErrMessageNotFound
+}
+
+// wsStubShopping - сервис покупок без событий
+type wsStubShopping struct {
+	service.ShoppingItem
+}
+
+func (wsStubShopping) SubscribeEvents(userID string) (<-chan entity.ShoppingEvent, func()) {
+	events := make(chan entity.ShoppingEvent)
+	var once sync.Once
+	return events, func() { once.Do(func() { close(events) }) }
+}
+
+// newWSTestServer поднимает WebSocketHandler. Пользователь соединения передается параметром user
+func newWSTestServer(t *testing.T, participants ...string) *httptest.Server {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := WebSocketHandler(
+		context.Background(), log, &wsStubChats{participants: participants}, wsStubShopping{},
+	)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				user := entity.User{Id: r.URL.Query().Get("user")}
+				handler(w, r.WithContext(context.WithValue(r.Context(), CurrentUserKey, user)))
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialWS подключается к серверу от имени userID с подпротоколами protocols
+func dialWS(t *testing.T, server *httptest.Server, userID string, protocols ...string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: protocols, HandshakeTimeout: 2 * time.Second}
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?user=" + userID
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWS читает одно сообщение сервера в v
+func readWS(t *testing.T, conn *websocket.Conn, v interface{}) {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("set read deadline: %v", err)
+	}
+	if err := conn.ReadJSON(v); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+}
+
+func writeWS(t *testing.T, conn *websocket.Conn, v interface{}) {
+	t.Helper()
+	if err := conn.WriteJSON(v); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+// waitRegistered дожидается pong: к этому моменту соединение уже зарегистрировано и получает события
+func waitRegistered(t *testing.T, conn *websocket.Conn, legacy bool) {
+	t.Helper()
+	if legacy {
+		writeWS(t, conn, map[string]string{"type": wsTypePing})
+		var pong map[string]string
+		readWS(t, conn, &pong)
+		return
+	}
+	writeWS(t, conn, WebSocketEnvelope{Type: wsTypePing, ID: "ping"})
+	var pong WebSocketEnvelope
+	readWS(t, conn, &pong)
+}
+
+func TestWebSocketSubprotocolNegotiation(t *testing.T) {
+	server := newWSTestServer(t, "user-1")
+
+	tests := []struct {
+		name      string
+		protocols []string
+		want      string
+	}{
+		{name: "v1", protocols: []string{wsProtocolV1}, want: wsProtocolV1},
+		{name: "v1 among unknown", protocols: []string{"family-flow.v9", wsProtocolV1}, want: wsProtocolV1},
+		{name: "no subprotocol", protocols: nil, want: ""},
+		{name: "unknown subprotocol", protocols: []string{"family-flow.v9"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				conn := dialWS(t, server, "user-1", tt.protocols...)
+				if got := conn.Subprotocol(); got != tt.want {
+					t.Errorf("Subprotocol() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestWebSocketV1Requests(t *testing.T) {
+	server := newWSTestServer(t, "user-1")
+
+	tests := []struct {
+		name     string
+		user     string
+		request  string
+		wantType string
+		// wantReplyTo пуст, если запрос не удалось разобрать
+		wantReplyTo string
+		wantCode    int
+	}{
+		{
+			name:        "ping",
+			user:        "user-1",
+			request:     `{"type":"ping","id":"req-ping"}`,
+			wantType:    wsTypePong,
+			wantReplyTo: "req-ping",
+		},
+		{
+			name:        "send message",
+			user:        "user-1",
+			request:     `{"type":"message.send","id":"req-send","payload":{"chat_id":"` + wsTestChatID + `","content":"hi"}}`,
+			wantType:    wsTypeAck,
+			wantReplyTo: "req-send",
+		},
+		{
+			name:        "unknown type",
+			user:        "user-1",
+			request:     `{"type":"message.edit","id":"req-unknown","payload":{}}`,
+			wantType:    wsTypeError,
+			wantReplyTo: "req-unknown",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "bad payload",
+			user:        "user-1",
+			request:     `{"type":"message.send","id":"req-bad","payload":"hi"}`,
+			wantType:    wsTypeError,
+			wantReplyTo: "req-bad",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "not a participant",
+			user:        "user-2",
+			request:     `{"type":"message.send","id":"req-forbidden","payload":{"chat_id":"` + wsTestChatID + `","content":"hi"}}`,
+			wantType:    wsTypeError,
+			wantReplyTo: "req-forbidden",
+			wantCode:    http.StatusForbidden,
+		},
+		{
+			name:        "message not found",
+			user:        "user-1",
+			request:     `{"type":"message.delete","id":"req-delete","payload":{"chat_id":"` + wsTestChatID + `","message_id":"x"}}`,
+			wantType:    wsTypeError,
+			wantReplyTo: "req-delete",
+			wantCode:    http.StatusNotFound,
+		},
+		{
+			name:     "invalid json",
+			user:     "user-1",
+			request:  `{"type":`,
+			wantType: wsTypeError,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing type",
+			user:     "user-1",
+			request:  `{"id":"req-no-type"}`,
+			wantType: wsTypeError,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				conn := dialWS(t, server, tt.user, wsProtocolV1)
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+
+				var resp WebSocketEnvelope
+				readWS(t, conn, &resp)
+				if resp.Type != tt.wantType {
+					t.Fatalf("type = %q, want %q (payload %s)", resp.Type, tt.wantType, resp.Payload)
+				}
+				if resp.ReplyTo != tt.wantReplyTo {
+					t.Errorf("reply_to = %q, want %q", resp.ReplyTo, tt.wantReplyTo)
+				}
+				if resp.ID == "" {
+					t.Error("server message has no id")
+				}
+				if tt.wantType != wsTypeError {
+					return
+				}
+				var wsErr WebSocketError
+				if err := json.Unmarshal(resp.Payload, &wsErr); err != nil {
+					t.Fatalf("error payload: %v", err)
+				}
+				if wsErr.Code != tt.wantCode {
+					t.Errorf("code = %d, want %d (%s)", wsErr.Code, tt.wantCode, wsErr.Message)
+				}
+			},
+		)
+	}
+}
+
+func TestWebSocketAckPayload(t *testing.T) {
+	server := newWSTestServer(t, "user-1")
+	conn := dialWS(t, server, "user-1", wsProtocolV1)
+
+	payload, _ := json.Marshal(CreateMessageInput{ChatID: wsTestChatID, SenderID: "someone-else", Content: "hi"})
+	writeWS(t, conn, WebSocketEnvelope{Type: wsTypeMessageSend, ID: "req-1", Payload: payload})
+
+	var resp WebSocketEnvelope
+	readWS(t, conn, &resp)
+	var message entity.Message
+	if err := json.Unmarshal(resp.Payload, &message); err != nil {
+		t.Fatalf("ack payload: %v", err)
+	}
+	if resp.Type != wsTypeAck || resp.ReplyTo != "req-1" {
+		t.Fatalf("got %s reply_to %q, want ack reply_to req-1", resp.Type, resp.ReplyTo)
+	}
+	// Отправителем всегда считается владелец соединения
+	if message.SenderID != "user-1" || message.Content != "hi" {
+		t.Errorf("ack message = %+v", message)
+	}
+}
+
+func TestWebSocketLegacyFallback(t *testing.T) {
+	server := newWSTestServer(t, "user-1")
+
+	tests := []struct {
+		name    string
+		request string
+		want    map[string]interface{}
+	}{
+		{
+			name:    "ping",
+			request: `{"type":"ping"}`,
+			want:    map[string]interface{}{"type": wsTypePong},
+		},
+		{
+			name:    "unknown action",
+			request: `{"action":"edit_message","data":{}}`,
+			want:    map[string]interface{}{"status": "error", "message": "Unknown message type"},
+		},
+		{
+			name:    "bad data",
+			request: `{"action":"send_message","data":"hi"}`,
+			want:    map[string]interface{}{"status": "error", "message": "Invalid payload for " + wsTypeMessageSend},
+		},
+		{
+			name:    "v1 type without subprotocol",
+			request: `{"action":"message.delete","data":{"chat_id":"` + wsTestChatID + `","message_id":"x"}}`,
+			want:    map[string]interface{}{"status": "error", "message": "Message not found"},
+		},
+		{
+			name:    "invalid json",
+			request: `{"action":`,
+			want:    map[string]interface{}{"status": "error", "message": "Invalid message format"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				conn := dialWS(t, server, "user-1")
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+
+				var resp map[string]interface{}
+				readWS(t, conn, &resp)
+				for key, want := range tt.want {
+					if resp[key] != want {
+						t.Errorf("%s = %v, want %v (response %v)", key, resp[key], want, resp)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestWebSocketServerEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy bool
+	}{
+		{name: "v1 recipient"},
+		{name: "legacy recipient", legacy: true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				server := newWSTestServer(t, "sender", "recipient")
+				sender := dialWS(t, server, "sender", wsProtocolV1)
+				var recipient *websocket.Conn
+				if tt.legacy {
+					recipient = dialWS(t, server, "recipient")
+				} else {
+					recipient = dialWS(t, server, "recipient", wsProtocolV1)
+				}
+				waitRegistered(t, recipient, tt.legacy)
+
+				payload, _ := json.Marshal(CreateMessageInput{ChatID: wsTestChatID, Content: "hello"})
+				writeWS(t, sender, WebSocketEnvelope{Type: wsTypeMessageSend, ID: "req-1", Payload: payload})
+
+				// Отправитель получает только ack, событие о своем сообщении ему не приходит
+				var ack WebSocketEnvelope
+				readWS(t, sender, &ack)
+				if ack.Type != wsTypeAck {
+					t.Fatalf("sender got %q, want ack", ack.Type)
+				}
+
+				var message entity.Message
+				if tt.legacy {
+					var event WebSocketResponse
+					event.Data = &message
+					readWS(t, recipient, &event)
+					if event.Status != "success" || event.Action != wsLegacyEvents[wsEventMessageCreated] {
+						t.Fatalf("legacy event = %+v", event)
+					}
+				} else {
+					var event WebSocketEnvelope
+					readWS(t, recipient, &event)
+					if event.Type != wsEventMessageCreated || event.ReplyTo != "" {
+						t.Fatalf("event type %q reply_to %q, want %s without reply_to", event.Type, event.ReplyTo,
+							wsEventMessageCreated)
+					}
+					if err := json.Unmarshal(event.Payload, &message); err != nil {
+						t.Fatalf("event payload: %v", err)
+					}
+				}
+				if message.Content != "hello" || message.SenderID != "sender" {
+					t.Errorf("event message = %+v", message)
+				}
+			},
+		)
+	}
+}
@@ -339,7 +339,7 @@ func (u *WishlistRoutes) updateReservedBy(ctx context.Context, log *slog.Logger)
 			},
 		)
 		if err != nil {
-			log.Error("Failed to send notification: %v", err)
+			log.Error("Failed to send notification", "error", err)
 		}
 		w.WriteHeader(http.StatusOK)
 		render.JSON(w, r, "Wishlist item updated")
@@ -0,0 +1,124 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+	"family-flow-app/pkg/postgres"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	absenceTable = "user_absences"
+)
+
+var absenceColumns = []string{
+	"id",
+	"user_id",
+	"starts_at",
+	"ends_at",
+	"created_by",
+	"created_at",
+}
+
+type AbsenceRepo struct {
+	*postgres.Database
+}
+
+func NewAbsenceRepo(db *postgres.Database) *AbsenceRepo {
+	return &AbsenceRepo{db}
+}
+
+func scanAbsence(row pgx.Row) (entity.UserAbsence, error) {
+	var absence entity.UserAbsence
+	err := row.Scan(
+		&absence.ID,
+		&absence.UserID,
+		&absence.StartsAt,
+		&absence.EndsAt,
+		&absence.CreatedBy,
+		&absence.CreatedAt,
+	)
+	return absence, err
+}
+
+// Create добавляет период отсутствия пользователя
+func (r *AbsenceRepo) Create(ctx context.Context, log *slog.Logger, absence entity.UserAbsence) (
+	entity.UserAbsence, error,
+) {
+	log.Info("AbsenceRepo - Create")
+	sql, args, _ := r.Builder.Insert(absenceTable).
+		Columns("user_id", "starts_at", "ends_at", "created_by").
+		Values(absence.UserID, absence.StartsAt, absence.EndsAt, absence.CreatedBy).
+		Suffix("RETURNING " + strings.Join(absenceColumns, ", ")).
+		ToSql()
+
+	return scanAbsence(r.Cluster.QueryRow(ctx, sql, args...))
+}
+
+// GetByID возвращает период отсутствия. repoerrs.ErrNotFound - период не найден
+func (r *AbsenceRepo) GetByID(ctx context.Context, log *slog.Logger, id string) (entity.UserAbsence, error) {
+	log.Info("AbsenceRepo - GetByID")
+	sql, args, _ := r.Builder.Select(absenceColumns...).From(absenceTable).Where("id = ?", id).ToSql()
+
+	absence, err := scanAbsence(r.Cluster.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.UserAbsence{}, repoerrs.ErrNotFound
+	}
+	return absence, err
+}
+
+// GetByUserIDs возвращает периоды отсутствия пользователей, которые заканчиваются позже from
+func (r *AbsenceRepo) GetByUserIDs(ctx context.Context, log *slog.Logger, userIDs []string, from time.Time) (
+	[]entity.UserAbsence, error,
+) {
+	log.Info("AbsenceRepo - GetByUserIDs")
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	sql, args, _ := r.Builder.Select(absenceColumns...).
+		From(absenceTable).
+		Where(squirrel.Eq{"user_id": userIDs}).
+		Where(squirrel.Gt{"ends_at": from}).
+		OrderBy("starts_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var absences []entity.UserAbsence
+	for rows.Next() {
+		absence, err := scanAbsence(rows)
+		if err != nil {
+			return nil, err
+		}
+		absences = append(absences, absence)
+	}
+	return absences, rows.Err()
+}
+
+// Delete удаляет период отсутствия. repoerrs.ErrNotFound - период не найден
+func (r *AbsenceRepo) Delete(ctx context.Context, log *slog.Logger, id string) error {
+	log.Info("AbsenceRepo - Delete")
+	sql, args, _ := r.Builder.Delete(absenceTable).Where("id = ?", id).ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+	"family-flow-app/pkg/postgres"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	achievementRulesTable = "achievement_rules"
+	userAchievementsTable = "user_achievements"
+	// achievementStreakDays - за сколько последних дней одобрения читаются для расчета серии
+	achievementStreakDays = 400
+)
+
+var achievementRuleColumns = []string{
+	"id",
+	"COALESCE(family_id::text, '')",
+	"code",
+	"title",
+	"description",
+	"event",
+	"threshold",
+	"bonus_points",
+	"enabled",
+	"updated_at",
+}
+
+var userAchievementColumns = []string{
+	"id",
+	"user_id",
+	"code",
+	"title",
+	"description",
+	"bonus_points",
+	"awarded_at",
+}
+
+type AchievementsRepo struct {
+	*postgres.Database
+}
+
+func NewAchievementsRepo(db *postgres.Database) *AchievementsRepo {
+	return &AchievementsRepo{db}
+}
+
+func scanAchievementRule(row pgx.Row) (entity.AchievementRule, error) {
+	var rule entity.AchievementRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.FamilyID,
+		&rule.Code,
+		&rule.Title,
+		&rule.Description,
+		&rule.Event,
+		&rule.Threshold,
+		&rule.BonusPoints,
+		&rule.Enabled,
+		&rule.UpdatedAt,
+	)
+	return rule, err
+}
+
+// GetRules возвращает действующие правила семьи: правила по умолчанию, замененные правилами семьи
+// с тем же code, включая выключенные. Пустой familyID - только правила по умолчанию
+func (r *AchievementsRepo) GetRules(ctx context.Context, log *slog.Logger, familyID string) (
+	[]entity.AchievementRule, error,
+) {
+	log.Info("AchievementsRepo - GetRules")
+	sql, args, _ := r.Builder.Select(achievementRuleColumns...).
+		Options("DISTINCT ON (code)").
+		From(achievementRulesTable).
+		Where("family_id IS NULL OR family_id = NULLIF(?, '')::uuid", familyID).
+		OrderBy("code", "family_id NULLS LAST").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []entity.AchievementRule
+	for rows.Next() {
+		rule, err := scanAchievementRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SaveRule создает или заменяет правило семьи rule.FamilyID с кодом rule.Code и возвращает его
+func (r *AchievementsRepo) SaveRule(ctx context.Context, log *slog.Logger, rule entity.AchievementRule) (
+	entity.AchievementRule, error,
+) {
+	log.Info("AchievementsRepo - SaveRule")
+	sql, args, _ := r.Builder.Insert(achievementRulesTable).
+		Columns("family_id", "code", "title", "description", "event", "threshold", "bonus_points", "enabled").
+		Values(
+			rule.FamilyID,
+			rule.Code,
+			rule.Title,
+			rule.Description,
+			rule.Event,
+			rule.Threshold,
+			rule.BonusPoints,
+			rule.Enabled,
+		).
+		Suffix(
+			"ON CONFLICT (family_id, code) WHERE family_id IS NOT NULL DO UPDATE SET title = EXCLUDED.title, " +
+				"description = EXCLUDED.description, event = EXCLUDED.event, threshold = EXCLUDED.threshold, " +
+				"bonus_points = EXCLUDED.bonus_points, enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP " +
+				"RETURNING " + strings.Join(achievementRuleColumns, ", "),
+		).
+		ToSql()
+
+	return scanAchievementRule(r.Cluster.QueryRow(ctx, sql, args...))
+}
+
+// DeleteRule удаляет правило семьи, после чего снова действует правило по умолчанию с тем же code.
+// repoerrs.ErrNotFound - у семьи нет такого правила
+func (r *AchievementsRepo) DeleteRule(ctx context.Context, log *slog.Logger, familyID, code string) error {
+	log.Info("AchievementsRepo - DeleteRule")
+	sql, args, _ := r.Builder.Delete(achievementRulesTable).
+		Where(squirrel.Eq{"family_id": familyID, "code": code}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// GetUserAchievements возвращает достижения пользователя от новых к старым
+func (r *AchievementsRepo) GetUserAchievements(ctx context.Context, log *slog.Logger, userID string) (
+	[]entity.UserAchievement, error,
+) {
+	log.Info("AchievementsRepo - GetUserAchievements")
+	sql, args, _ := r.Builder.Select(userAchievementColumns...).
+		From(userAchievementsTable).
+		Where("user_id = ?", userID).
+		OrderBy("awarded_at DESC").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var achievements []entity.UserAchievement
+	for rows.Next() {
+		var achievement entity.UserAchievement
+		err = rows.Scan(
+			&achievement.ID,
+			&achievement.UserID,
+			&achievement.Code,
+			&achievement.Title,
+			&achievement.Description,
+			&achievement.BonusPoints,
+			&achievement.AwardedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		achievements = append(achievements, achievement)
+	}
+	return achievements, rows.Err()
+}
+
+// Award выдает пользователю достижение по правилу и начисляет бонус в одной транзакции.
+// false - достижение уже было выдано раньше
+func (r *AchievementsRepo) Award(ctx context.Context, log *slog.Logger, userID string, rule entity.AchievementRule) (
+	bool, error,
+) {
+	log.Info("AchievementsRepo - Award")
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Insert(userAchievementsTable).
+		Columns("user_id", "code", "title", "description", "bonus_points").
+		Values(userID, rule.Code, rule.Title, rule.Description, rule.BonusPoints).
+		Suffix("ON CONFLICT (user_id, code) DO NOTHING RETURNING id").
+		ToSql()
+
+	var id string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if rule.BonusPoints > 0 {
+		_, err = applyPoints(
+			ctx, tx, r.Builder, entity.PointTransaction{
+				UserID:     userID,
+				Kind:       entity.PointTransactionEarn,
+				Amount:     rule.BonusPoints,
+				Reason:     rule.Title,
+				SourceType: entity.PointSourceAchievement,
+				SourceID:   id,
+			},
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+// GetStats возвращает показатели пользователя для правил достижений, кроме серии дней
+func (r *AchievementsRepo) GetStats(ctx context.Context, log *slog.Logger, userID string) (
+	entity.AchievementStats, error,
+) {
+	log.Info("AchievementsRepo - GetStats")
+	sql, args, _ := r.Builder.Select().
+		Column(
+			"(SELECT COUNT(*) FROM "+todoTable+" WHERE assigned_to = ? AND status = ? AND deleted_at IS NULL)",
+			userID, entity.TodoStatusApproved,
+		).
+		Column(
+			"(SELECT COUNT(*) FROM "+wishlistTable+" WHERE reserved_by = ? AND status = 'Completed')", userID,
+		).
+		Column(
+			"(SELECT COUNT(*) FROM "+rewardRedemptionsTable+" WHERE user_id = ? AND status <> ?)",
+			userID, entity.RedemptionStatusDeclined,
+		).
+		ToSql()
+
+	var stats entity.AchievementStats
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
+		&stats.TodosApproved, &stats.WishesFulfilled, &stats.RewardsRedeemed,
+	)
+	return stats, err
+}
+
+// GetApprovalDays возвращает дни (UTC), в которые у исполнителя одобрялись задания, от новых к старым
+func (r *AchievementsRepo) GetApprovalDays(ctx context.Context, log *slog.Logger, userID string) (
+	[]time.Time, error,
+) {
+	log.Info("AchievementsRepo - GetApprovalDays")
+	sql, args, _ := r.Builder.Select("DISTINCT reviewed_at::date AS day").
+		From(todoTable).
+		Where(squirrel.Eq{"assigned_to": userID, "status": entity.TodoStatusApproved, "deleted_at": nil}).
+		Where("reviewed_at IS NOT NULL").
+		OrderBy("day DESC").
+		Limit(achievementStreakDays).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err = rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
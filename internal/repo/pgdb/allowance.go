@@ -0,0 +1,107 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const allowanceSettingsTable = "allowance_settings"
+
+// GetAllowanceSettings возвращает настройки карманных денег семьи. repoerrs.ErrNotFound - семья их не настраивала
+func (r *RewardsRepo) GetAllowanceSettings(ctx context.Context, familyID string) (entity.AllowanceSettings, error) {
+	sql, args, _ := r.Builder.Select(
+		"family_id",
+		"enabled",
+		"minor_per_point",
+		"currency",
+		"COALESCE(updated_by::text, '')",
+		"updated_at",
+	).From(allowanceSettingsTable).
+		Where(squirrel.Eq{"family_id": familyID}).
+		ToSql()
+
+	var settings entity.AllowanceSettings
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
+		&settings.FamilyID,
+		&settings.Enabled,
+		&settings.MinorPerPoint,
+		&settings.Currency,
+		&settings.UpdatedBy,
+		&settings.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.AllowanceSettings{}, repoerrs.ErrNotFound
+		}
+		return entity.AllowanceSettings{}, fmt.Errorf("failed to get allowance settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveAllowanceSettings создает или заменяет настройки карманных денег семьи
+func (r *RewardsRepo) SaveAllowanceSettings(ctx context.Context, settings entity.AllowanceSettings) error {
+	sql, args, _ := r.Builder.Insert(allowanceSettingsTable).
+		Columns("family_id", "enabled", "minor_per_point", "currency", "updated_by").
+		Values(
+			settings.FamilyID,
+			settings.Enabled,
+			settings.MinorPerPoint,
+			settings.Currency,
+			squirrel.Expr("NULLIF(?, '')::uuid", settings.UpdatedBy),
+		).
+		Suffix(
+			"ON CONFLICT (family_id) DO UPDATE SET enabled = EXCLUDED.enabled, " +
+				"minor_per_point = EXCLUDED.minor_per_point, currency = EXCLUDED.currency, " +
+				"updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP",
+		).
+		ToSql()
+
+	if _, err := r.Cluster.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to save allowance settings: %w", err)
+	}
+	return nil
+}
+
+// GetEarnedPoints возвращает баллы, заработанные пользователями за задания в [from, to). Бонусы
+// за достижения не учитываются. Пользователи без начислений в ответ не попадают
+func (r *RewardsRepo) GetEarnedPoints(ctx context.Context, userIDs []string, from, to time.Time) (
+	map[string]int, error,
+) {
+	sql, args, _ := r.Builder.Select("user_id", "SUM(amount)").
+		From(pointTransactionsTable).
+		Where(
+			squirrel.Eq{
+				"user_id":     userIDs,
+				"kind":        entity.PointTransactionEarn,
+				"source_type": entity.PointSourceTodo,
+			},
+		).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		GroupBy("user_id").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get earned points: %w", err)
+	}
+	defer rows.Close()
+
+	earned := make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var points int
+		if err = rows.Scan(&userID, &points); err != nil {
+			return nil, fmt.Errorf("failed to scan earned points: %w", err)
+		}
+		earned[userID] = points
+	}
+	return earned, rows.Err()
+}
@@ -0,0 +1,67 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"family-flow-app/internal/repo/repoerrs"
+	"family-flow-app/pkg/postgres"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	calendarTokensTable = "calendar_tokens"
+)
+
+type CalendarRepo struct {
+	*postgres.Database
+}
+
+func NewCalendarRepo(db *postgres.Database) *CalendarRepo {
+	return &CalendarRepo{db}
+}
+
+// GetCalendarToken возвращает токен подписки пользователя. repoerrs.ErrNotFound - токен еще не выпущен
+func (r *CalendarRepo) GetCalendarToken(ctx context.Context, log *slog.Logger, userID string) (string, error) {
+	log.Info("CalendarRepo - GetCalendarToken")
+	sql, args, _ := r.Builder.Select("token").From(calendarTokensTable).Where("user_id = ?", userID).ToSql()
+
+	var token string
+	if err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&token); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", repoerrs.ErrNotFound
+		}
+		return "", err
+	}
+	return token, nil
+}
+
+// SaveCalendarToken сохраняет токен подписки пользователя, заменяя предыдущий
+func (r *CalendarRepo) SaveCalendarToken(ctx context.Context, log *slog.Logger, userID, token string) error {
+	log.Info("CalendarRepo - SaveCalendarToken")
+	sql, args, _ := r.Builder.Insert(calendarTokensTable).
+		Columns("user_id", "token").
+		Values(userID, token).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = CURRENT_TIMESTAMP").
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// GetUserIDByCalendarToken возвращает владельца токена подписки. repoerrs.ErrNotFound - токен не найден
+func (r *CalendarRepo) GetUserIDByCalendarToken(ctx context.Context, log *slog.Logger, token string) (string, error) {
+	log.Info("CalendarRepo - GetUserIDByCalendarToken")
+	sql, args, _ := r.Builder.Select("user_id").From(calendarTokensTable).Where("token = ?", token).ToSql()
+
+	var userID string
+	if err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", repoerrs.ErrNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
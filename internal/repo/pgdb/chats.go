@@ -2,11 +2,16 @@ package pgdb
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 
 	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
@@ -24,10 +29,17 @@ func NewChatsRepo(db *postgres.Database) *ChatsRepo {
 
 // Create создает новый чат
 func (r *ChatsRepo) Create(ctx context.Context, chat entity.Chat) (string, error) {
+	if chat.Type == "" {
+		chat.Type = entity.ChatTypeGroup
+	}
 	sql, args, _ := r.Builder.Insert(chatsTable).Columns(
 		"name",
+		"type",
+		"family_id",
 	).Values(
 		chat.Name,
+		chat.Type,
+		chat.FamilyID,
 	).Suffix("RETURNING id").ToSql()
 
 	var id string
@@ -40,12 +52,29 @@ func (r *ChatsRepo) Create(ctx context.Context, chat entity.Chat) (string, error
 
 // AddParticipant добавляет участника в чат
 func (r *ChatsRepo) AddParticipant(ctx context.Context, chatID, userID string) error {
+	return r.AddParticipantWithRole(ctx, chatID, userID, entity.ChatRoleMember)
+}
+
+// AddParticipantWithRole добавляет участника в чат с указанной ролью
+func (r *ChatsRepo) AddParticipantWithRole(ctx context.Context, chatID, userID, role string) error {
 	sql, args, _ := r.Builder.Insert(chatParticipantsTable).Columns(
 		"chat_id",
 		"user_id",
+		"role",
 	).Values(
 		chatID,
 		userID,
+		role,
+	).Suffix("ON CONFLICT (chat_id, user_id) DO NOTHING").ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// RemoveParticipant удаляет участника из чата
+func (r *ChatsRepo) RemoveParticipant(ctx context.Context, chatID, userID string) error {
+	sql, args, _ := r.Builder.Delete(chatParticipantsTable).Where(
+		squirrel.Eq{"chat_id": chatID, "user_id": userID},
 	).ToSql()
 
 	_, err := r.Cluster.Exec(ctx, sql, args...)
@@ -80,31 +109,151 @@ func (r *ChatsRepo) GetParticipants(ctx context.Context, chatID string) ([]strin
 
 // GetByID возвращает чат по его ID
 func (r *ChatsRepo) GetByID(ctx context.Context, id string) (entity.Chat, error) {
+	return r.getByField(ctx, "id", id)
+}
+
+// GetFamilyChat возвращает семейный чат
+func (r *ChatsRepo) GetFamilyChat(ctx context.Context, familyID string) (entity.Chat, error) {
 	sql, args, _ := r.Builder.Select(
 		"id",
 		"name",
+		"type",
+		"family_id",
+		"is_locked",
 		"created_at",
 	).From(chatsTable).Where(
-		"id = ?", id,
+		squirrel.Eq{"family_id": familyID, "type": entity.ChatTypeFamily},
 	).ToSql()
 
+	return r.scanChat(ctx, sql, args)
+}
+
+func (r *ChatsRepo) getByField(ctx context.Context, field, value string) (entity.Chat, error) {
+	sql, args, _ := r.Builder.Select(
+		"id",
+		"name",
+		"type",
+		"family_id",
+		"is_locked",
+		"created_at",
+	).From(chatsTable).Where(
+		field+" = ?", value,
+	).ToSql()
+
+	return r.scanChat(ctx, sql, args)
+}
+
+func (r *ChatsRepo) scanChat(ctx context.Context, sql string, args []interface{}) (entity.Chat, error) {
 	var chat entity.Chat
 	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
 		&chat.ID,
 		&chat.Name,
+		&chat.Type,
+		&chat.FamilyID,
+		&chat.IsLocked,
 		&chat.CreatedAt,
 	)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Chat{}, repoerrs.ErrNotFound
+		}
 		return entity.Chat{}, err
 	}
 	return chat, nil
 }
 
+// CreateFamilyChat создает семейный чат, если его еще нет, и возвращает его ID
+func (r *ChatsRepo) CreateFamilyChat(ctx context.Context, familyID, name string) (string, error) {
+	sql, args, _ := r.Builder.Insert(chatsTable).Columns(
+		"name",
+		"type",
+		"family_id",
+	).Values(
+		name,
+		entity.ChatTypeFamily,
+		familyID,
+	).Suffix("ON CONFLICT (family_id) WHERE type = 'Family' DO NOTHING RETURNING id").ToSql()
+
+	var id string
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Чат уже создан параллельным запросом
+		chat, err := r.GetFamilyChat(ctx, familyID)
+		if err != nil {
+			return "", err
+		}
+		return chat.ID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetOrCreateDirectChat возвращает личный чат двух пользователей, создавая его при необходимости
+func (r *ChatsRepo) GetOrCreateDirectChat(ctx context.Context, familyID, userID, otherUserID string) (string, error) {
+	users := []string{userID, otherUserID}
+	sort.Strings(users)
+	directKey := strings.Join(users, ":")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Insert(chatsTable).Columns(
+		"name",
+		"type",
+		"family_id",
+		"direct_key",
+	).Values(
+		"",
+		entity.ChatTypeDirect,
+		familyID,
+		directKey,
+	).Suffix("ON CONFLICT (direct_key) DO NOTHING RETURNING id").ToSql()
+
+	var id string
+	err = tx.QueryRow(ctx, sql, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		sql, args, _ = r.Builder.Select("id").From(chatsTable).Where(
+			squirrel.Eq{"direct_key": directKey},
+		).ToSql()
+		err = tx.QueryRow(ctx, sql, args...).Scan(&id)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, participant := range users {
+		sql, args, _ = r.Builder.Insert(chatParticipantsTable).Columns(
+			"chat_id",
+			"user_id",
+		).Values(
+			id,
+			participant,
+		).Suffix("ON CONFLICT (chat_id, user_id) DO NOTHING").ToSql()
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 // GetAll возвращает список всех чатов
 func (r *ChatsRepo) GetAll(ctx context.Context) ([]entity.Chat, error) {
 	sql, args, _ := r.Builder.Select(
 		"id",
 		"name",
+		"type",
+		"family_id",
+		"is_locked",
 		"created_at",
 	).From(chatsTable).ToSql()
 
@@ -120,6 +269,9 @@ func (r *ChatsRepo) GetAll(ctx context.Context) ([]entity.Chat, error) {
 		err := rows.Scan(
 			&chat.ID,
 			&chat.Name,
+			&chat.Type,
+			&chat.FamilyID,
+			&chat.IsLocked,
 			&chat.CreatedAt,
 		)
 		if err != nil {
@@ -136,6 +288,9 @@ func (r *ChatsRepo) GetChatsWithParticipants(ctx context.Context, userID string)
 	sql, args, _ := r.Builder.Select(
 		"c.id",
 		"c.name",
+		"c.type",
+		"c.family_id",
+		"c.is_locked",
 		"c.created_at",
 	).From(chatsTable + " c").Join(
 		chatParticipantsTable + " cp ON c.id = cp.chat_id",
@@ -155,6 +310,9 @@ func (r *ChatsRepo) GetChatsWithParticipants(ctx context.Context, userID string)
 		err := rows.Scan(
 			&chat.ID,
 			&chat.Name,
+			&chat.Type,
+			&chat.FamilyID,
+			&chat.IsLocked,
 			&chat.CreatedAt,
 		)
 		if err != nil {
@@ -174,11 +332,18 @@ func (r *ChatsRepo) GetChatsWithParticipants(ctx context.Context, userID string)
 }
 
 // GetParticipantsWithDetails возвращает список участников чата с подробной информацией
+func (r *ChatsRepo) GetParticipantsWithDetails(ctx context.Context, chatID string) ([]entity.ChatParticipant, error) {
+	return r.getParticipantsWithDetails(ctx, chatID)
+}
+
 func (r *ChatsRepo) getParticipantsWithDetails(ctx context.Context, chatID string) ([]entity.ChatParticipant, error) {
 	sql, args, _ := r.Builder.Select(
 		"id",
 		"chat_id",
 		"user_id",
+		"role",
+		"is_muted",
+		"notifications_muted",
 		"joined_at",
 	).From(chatParticipantsTable).Where(
 		squirrel.Eq{"chat_id": chatID},
@@ -197,6 +362,9 @@ func (r *ChatsRepo) getParticipantsWithDetails(ctx context.Context, chatID strin
 			&participant.ID,
 			&participant.ChatId,
 			&participant.UserId,
+			&participant.Role,
+			&participant.IsMuted,
+			&participant.NotificationsMuted,
 			&participant.JoinedAt,
 		)
 		if err != nil {
@@ -206,3 +374,86 @@ func (r *ChatsRepo) getParticipantsWithDetails(ctx context.Context, chatID strin
 	}
 	return participants, nil
 }
+
+// GetParticipant возвращает участника чата
+func (r *ChatsRepo) GetParticipant(ctx context.Context, chatID, userID string) (entity.ChatParticipant, error) {
+	sql, args, _ := r.Builder.Select(
+		"id",
+		"chat_id",
+		"user_id",
+		"role",
+		"is_muted",
+		"notifications_muted",
+		"joined_at",
+	).From(chatParticipantsTable).Where(
+		squirrel.Eq{"chat_id": chatID, "user_id": userID},
+	).ToSql()
+
+	var participant entity.ChatParticipant
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
+		&participant.ID,
+		&participant.ChatId,
+		&participant.UserId,
+		&participant.Role,
+		&participant.IsMuted,
+		&participant.NotificationsMuted,
+		&participant.JoinedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ChatParticipant{}, repoerrs.ErrNotFound
+		}
+		return entity.ChatParticipant{}, err
+	}
+	return participant, nil
+}
+
+// UpdateParticipantRole меняет роль участника чата
+func (r *ChatsRepo) UpdateParticipantRole(ctx context.Context, chatID, userID, role string) error {
+	sql, args, _ := r.Builder.Update(chatParticipantsTable).
+		Set("role", role).
+		Where(squirrel.Eq{"chat_id": chatID, "user_id": userID}).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// UpdateParticipantMuted включает или снимает заглушение участника
+func (r *ChatsRepo) UpdateParticipantMuted(ctx context.Context, chatID, userID string, muted bool) error {
+	sql, args, _ := r.Builder.Update(chatParticipantsTable).
+		Set("is_muted", muted).
+		Where(squirrel.Eq{"chat_id": chatID, "user_id": userID}).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// UpdateLocked переводит чат в режим только для чтения или возвращает обратно
+func (r *ChatsRepo) UpdateLocked(ctx context.Context, chatID string, locked bool) error {
+	sql, args, _ := r.Builder.Update(chatsTable).
+		Set("is_locked", locked).
+		Where(squirrel.Eq{"id": chatID}).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// UpdateNotificationsMuted включает или отключает пуш-уведомления участника для чата
+func (r *ChatsRepo) UpdateNotificationsMuted(ctx context.Context, chatID, userID string, muted bool) error {
+	sql, args, _ := r.Builder.Update(chatParticipantsTable).
+		Set("notifications_muted", muted).
+		Where(squirrel.Eq{"chat_id": chatID, "user_id": userID}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
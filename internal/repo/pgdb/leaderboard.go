@@ -0,0 +1,117 @@
+package pgdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"family-flow-app/internal/entity"
+
+	"github.com/Masterminds/squirrel"
+)
+
+const rewardDigestsTable = "reward_digests"
+
+// GetLeaderboard возвращает итоги членов семьи за [from, to): начисленные баллы, одобренные задания
+// и неотклоненные обмены. Нулевой from - без нижней границы. Каждая таблица агрегируется одним запросом
+// по членам семьи, члены семьи без активности попадают в ответ с нулями. Порядок и места - на стороне сервиса
+func (r *RewardsRepo) GetLeaderboard(ctx context.Context, familyID string, from, to time.Time) (
+	[]entity.LeaderboardEntry, error,
+) {
+	members := squirrel.Expr("user_id IN (SELECT id FROM "+userTable+" WHERE family_id = ?)", familyID)
+	period := func(column string) squirrel.Sqlizer {
+		if from.IsZero() {
+			return squirrel.Lt{column: to}
+		}
+		return squirrel.And{squirrel.GtOrEq{column: from}, squirrel.Lt{column: to}}
+	}
+
+	points, pointsArgs, _ := squirrel.Select("user_id", "SUM(amount) AS points").
+		From(pointTransactionsTable).
+		Where(members).
+		Where(squirrel.Eq{"kind": entity.PointTransactionEarn}).
+		Where(period("created_at")).
+		GroupBy("user_id").
+		ToSql()
+	chores, choresArgs, _ := squirrel.Select("assigned_to AS user_id", "COUNT(*) AS chores").
+		From(todoTable).
+		Where(
+			squirrel.Expr("assigned_to IN (SELECT id FROM "+userTable+" WHERE family_id = ?)", familyID),
+		).
+		Where(squirrel.Eq{"status": entity.TodoStatusApproved, "deleted_at": nil}).
+		Where(period("reviewed_at")).
+		GroupBy("assigned_to").
+		ToSql()
+	spent, spentArgs, _ := squirrel.Select("user_id", "SUM(cost) AS spent", "COUNT(*) AS redemptions").
+		From(rewardRedemptionsTable).
+		Where(members).
+		Where(squirrel.NotEq{"status": entity.RedemptionStatusDeclined}).
+		Where(period("redeemed_at")).
+		GroupBy("user_id").
+		ToSql()
+
+	sql, args, _ := r.Builder.Select(
+		"u.id",
+		"u.name",
+		"COALESCE(p.points, 0)",
+		"COALESCE(c.chores, 0)",
+		"COALESCE(s.spent, 0)",
+		"COALESCE(s.redemptions, 0)",
+	).From(userTable+" u").
+		LeftJoin("("+points+") p ON p.user_id = u.id", pointsArgs...).
+		LeftJoin("("+chores+") c ON c.user_id = u.id", choresArgs...).
+		LeftJoin("("+spent+") s ON s.user_id = u.id", spentArgs...).
+		Where(squirrel.Eq{"u.family_id": familyID}).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []entity.LeaderboardEntry
+	for rows.Next() {
+		var entry entity.LeaderboardEntry
+		err = rows.Scan(
+			&entry.UserID,
+			&entry.Name,
+			&entry.PointsEarned,
+			&entry.ChoresCompleted,
+			&entry.PointsSpent,
+			&entry.Redemptions,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ClaimDigestFamilies отмечает еженедельную сводку за неделю weekStart отправленной всем семьям, которым
+// ее еще не отправляли, и возвращает эти семьи. Отметка вставляется с ON CONFLICT DO NOTHING, поэтому
+// при нескольких репликах каждая семья достается только одной из них
+func (r *RewardsRepo) ClaimDigestFamilies(ctx context.Context, weekStart time.Time) ([]string, error) {
+	sql, args, _ := r.Builder.Insert(rewardDigestsTable).
+		Columns("family_id", "week_start").
+		Select(squirrel.Select("id").Column("?::date", weekStart).From(familyTable)).
+		Suffix("ON CONFLICT DO NOTHING RETURNING family_id").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digest families: %w", err)
+	}
+	defer rows.Close()
+
+	var familyIDs []string
+	for rows.Next() {
+		var familyID string
+		if err = rows.Scan(&familyID); err != nil {
+			return nil, fmt.Errorf("failed to scan digest family: %w", err)
+		}
+		familyIDs = append(familyIDs, familyID)
+	}
+	return familyIDs, rows.Err()
+}
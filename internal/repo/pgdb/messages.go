@@ -2,13 +2,21 @@ package pgdb
 
 import (
 	"context"
+	"errors"
 
 	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
 	messagesTable = "messages"
+
+	// messageHeadlineOptions - параметры фрагментов с подсветкой совпадений в результатах поиска
+	messageHeadlineOptions = "'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20'"
 )
 
 type MessagesRepo struct {
@@ -108,3 +116,177 @@ func (r *MessagesRepo) GetLastMessageByChatID(ctx context.Context, chatID string
 	}
 	return message, nil
 }
+
+// GetByID возвращает сообщение по его ID
+func (r *MessagesRepo) GetByID(ctx context.Context, id string) (entity.Message, error) {
+	sql, args, _ := r.Builder.Select(
+		"id",
+		"chat_id",
+		"sender_id",
+		"content",
+		"created_at",
+	).From(messagesTable).Where("id = ?", id).ToSql()
+
+	var message entity.Message
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
+		&message.ID,
+		&message.ChatID,
+		&message.SenderID,
+		&message.Content,
+		&message.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Message{}, repoerrs.ErrNotFound
+		}
+		return entity.Message{}, err
+	}
+	return message, nil
+}
+
+// Delete удаляет сообщение
+func (r *MessagesRepo) Delete(ctx context.Context, id string) error {
+	sql, args, _ := r.Builder.Delete(messagesTable).Where("id = ?", id).ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// Search ищет сообщения полнотекстовым поиском в чатах, где состоит пользователь.
+// Запрос разбирается и по-русски, и по-английски; фрагмент подсвечивается с конфигурацией того варианта,
+// который нашел сообщение, иначе совпадения по английским основам остались бы без подсветки
+func (r *MessagesRepo) Search(
+	ctx context.Context, userID, chatID, query string, limit, offset int,
+) ([]entity.MessageSearchResult, error) {
+	builder := r.Builder.Select(
+		"m.id",
+		"m.chat_id",
+		"m.sender_id",
+		"m.content",
+		"m.created_at",
+		"CASE WHEN to_tsvector('russian', m.content) @@ q.ru "+
+			"THEN ts_headline('russian', m.content, q.ru, "+messageHeadlineOptions+") "+
+			"ELSE ts_headline('english', m.content, q.en, "+messageHeadlineOptions+") END",
+		"ts_rank(m.content_tsv, q.query) AS rank",
+	).From(messagesTable+" m").
+		JoinClause(
+			"CROSS JOIN (SELECT ru, en, ru || en AS query FROM "+
+				"websearch_to_tsquery('russian', ?) ru, websearch_to_tsquery('english', ?) en) q",
+			query, query,
+		).
+		Join(chatParticipantsTable + " cp ON cp.chat_id = m.chat_id").
+		Where(squirrel.Eq{"cp.user_id": userID}).
+		Where("m.content_tsv @@ q.query")
+	if chatID != "" {
+		builder = builder.Where(squirrel.Eq{"m.chat_id": chatID})
+	}
+
+	sql, args, _ := builder.
+		OrderBy("rank DESC", "m.created_at DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]entity.MessageSearchResult, 0)
+	for rows.Next() {
+		var result entity.MessageSearchResult
+		err := rows.Scan(
+			&result.Message.ID,
+			&result.Message.ChatID,
+			&result.Message.SenderID,
+			&result.Message.Content,
+			&result.Message.CreatedAt,
+			&result.Snippet,
+			&result.Rank,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Cursor = result.Message.ID
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// GetAround возвращает сообщение и до limit сообщений до и после него в хронологическом порядке
+func (r *MessagesRepo) GetAround(ctx context.Context, chatID, messageID string, limit int) ([]entity.Message, error) {
+	target, err := r.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if target.ChatID != chatID {
+		return nil, repoerrs.ErrNotFound
+	}
+
+	before, err := r.getPage(
+		ctx, squirrel.And{
+			squirrel.Eq{"chat_id": chatID},
+			squirrel.Expr("(created_at, id) < (?, ?)", target.CreatedAt, target.ID),
+		}, "created_at DESC, id DESC", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	after, err := r.getPage(
+		ctx, squirrel.And{
+			squirrel.Eq{"chat_id": chatID},
+			squirrel.Expr("(created_at, id) > (?, ?)", target.CreatedAt, target.ID),
+		}, "created_at ASC, id ASC", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]entity.Message, 0, len(before)+len(after)+1)
+	for i := len(before) - 1; i >= 0; i-- {
+		messages = append(messages, before[i])
+	}
+	messages = append(messages, target)
+	messages = append(messages, after...)
+	return messages, nil
+}
+
+func (r *MessagesRepo) getPage(
+	ctx context.Context, where squirrel.Sqlizer, orderBy string, limit int,
+) ([]entity.Message, error) {
+	sql, args, _ := r.Builder.Select(
+		"id",
+		"chat_id",
+		"sender_id",
+		"content",
+		"created_at",
+	).From(messagesTable).
+		Where(where).
+		OrderBy(orderBy).
+		Limit(uint64(limit)).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []entity.Message
+	for rows.Next() {
+		var message entity.Message
+		err := rows.Scan(
+			&message.ID,
+			&message.ChatID,
+			&message.SenderID,
+			&message.Content,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
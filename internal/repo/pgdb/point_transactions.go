@@ -0,0 +1,162 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const pointTransactionsTable = "point_transactions"
+
+var pointTransactionColumns = []string{
+	"id",
+	"user_id",
+	"kind",
+	"amount",
+	"balance_after",
+	"reason",
+	"source_type",
+	"COALESCE(source_id::text, '')",
+	"COALESCE(created_by::text, '')",
+	"created_at",
+}
+
+func scanPointTransaction(row pgx.Row) (entity.PointTransaction, error) {
+	var transaction entity.PointTransaction
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.UserID,
+		&transaction.Kind,
+		&transaction.Amount,
+		&transaction.BalanceAfter,
+		&transaction.Reason,
+		&transaction.SourceType,
+		&transaction.SourceID,
+		&transaction.CreatedBy,
+		&transaction.CreatedAt,
+	)
+	return transaction, err
+}
+
+// applyPoints меняет баланс пользователя на entry.Amount и добавляет запись в журнал в транзакции tx.
+// Баланс меняется одним UPDATE, поэтому одновременные операции выполняются по очереди, а ограничение
+// users_point_non_negative не дает уйти в минус: в этом случае возвращается repoerrs.ErrNegativeBalance.
+// repoerrs.ErrAlreadyExists - запись нарушает уникальный индекс журнала, например повторная отмена корректировки
+func applyPoints(
+	ctx context.Context, tx pgx.Tx, builder squirrel.StatementBuilderType, entry entity.PointTransaction,
+) (entity.PointTransaction, error) {
+	sql, args, _ := builder.Update(userTable).
+		Set("point", squirrel.Expr("point + ?", entry.Amount)).
+		Where("id = ?", entry.UserID).
+		Suffix("RETURNING point").
+		ToSql()
+
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&entry.BalanceAfter); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.PointTransaction{}, repoerrs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+			return entity.PointTransaction{}, repoerrs.ErrNegativeBalance
+		}
+		return entity.PointTransaction{}, err
+	}
+
+	sql, args, _ = builder.Insert(pointTransactionsTable).
+		Columns("user_id", "kind", "amount", "balance_after", "reason", "source_type", "source_id", "created_by").
+		Values(
+			entry.UserID,
+			entry.Kind,
+			entry.Amount,
+			entry.BalanceAfter,
+			entry.Reason,
+			entry.SourceType,
+			squirrel.Expr("NULLIF(?, '')::uuid", entry.SourceID),
+			squirrel.Expr("NULLIF(?, '')::uuid", entry.CreatedBy),
+		).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&entry.ID, &entry.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return entity.PointTransaction{}, repoerrs.ErrAlreadyExists
+		}
+		return entity.PointTransaction{}, err
+	}
+	return entry, nil
+}
+
+// ApplyPoints проводит операцию с баллами и возвращает ее запись в журнале.
+// repoerrs.ErrNegativeBalance - у пользователя недостаточно баллов
+func (r *RewardsRepo) ApplyPoints(ctx context.Context, entry entity.PointTransaction) (
+	entity.PointTransaction, error,
+) {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return entity.PointTransaction{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	entry, err = applyPoints(ctx, tx, r.Builder, entry)
+	if err != nil {
+		return entity.PointTransaction{}, err
+	}
+	return entry, tx.Commit(ctx)
+}
+
+// GetPointTransactions возвращает до limit операций пользователя от новых к старым, начиная после операции afterID
+func (r *RewardsRepo) GetPointTransactions(ctx context.Context, userID, afterID string, limit uint64) (
+	[]entity.PointTransaction, error,
+) {
+	query := r.Builder.Select(pointTransactionColumns...).
+		From(pointTransactionsTable).
+		Where(squirrel.Eq{"user_id": userID})
+	if afterID != "" {
+		query = query.Where(
+			"(created_at, id) < (SELECT created_at, id FROM "+pointTransactionsTable+" WHERE id = ?)", afterID,
+		)
+	}
+	sql, args, _ := query.OrderBy("created_at DESC", "id DESC").Limit(limit).ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []entity.PointTransaction
+	for rows.Next() {
+		transaction, err := scanPointTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan point transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// GetPointTransactionByID возвращает операцию с баллами. repoerrs.ErrNotFound - операции нет
+func (r *RewardsRepo) GetPointTransactionByID(ctx context.Context, id string) (entity.PointTransaction, error) {
+	sql, args, _ := r.Builder.Select(pointTransactionColumns...).
+		From(pointTransactionsTable).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	transaction, err := scanPointTransaction(r.Cluster.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.PointTransaction{}, repoerrs.ErrNotFound
+		}
+		return entity.PointTransaction{}, fmt.Errorf("failed to get point transaction: %w", err)
+	}
+	return transaction, nil
+}
@@ -2,12 +2,16 @@ package pgdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
@@ -24,6 +28,54 @@ func NewRewardsRepo(db *postgres.Database) *RewardsRepo {
 	return &RewardsRepo{db}
 }
 
+var rewardColumns = []string{
+	"id",
+	"family_id",
+	"title",
+	"description",
+	"cost",
+	"created_at",
+	"updated_at",
+	"stock",
+	"per_user_limit",
+	"COALESCE(limit_period::text, '')",
+	"available_from",
+	"available_until",
+	"allowed_roles",
+	"min_age",
+	"max_age",
+}
+
+func scanReward(row pgx.Row) (entity.Reward, error) {
+	var reward entity.Reward
+	err := row.Scan(
+		&reward.ID,
+		&reward.FamilyID,
+		&reward.Title,
+		&reward.Description,
+		&reward.Cost,
+		&reward.CreatedAt,
+		&reward.UpdatedAt,
+		&reward.Stock,
+		&reward.PerUserLimit,
+		&reward.LimitPeriod,
+		&reward.AvailableFrom,
+		&reward.AvailableUntil,
+		&reward.AllowedRoles,
+		&reward.MinAge,
+		&reward.MaxAge,
+	)
+	return reward, err
+}
+
+// rewardRoles возвращает роли награды для записи в базу: пустой массив вместо nil
+func rewardRoles(reward entity.Reward) []string {
+	if reward.AllowedRoles == nil {
+		return []string{}
+	}
+	return reward.AllowedRoles
+}
+
 // CreateReward создает новое вознаграждение
 func (r *RewardsRepo) Create(ctx context.Context, reward entity.Reward) (string, error) {
 	sql, args, _ := r.Builder.Insert(rewardsTable).Columns(
@@ -31,11 +83,27 @@ func (r *RewardsRepo) Create(ctx context.Context, reward entity.Reward) (string,
 		"title",
 		"description",
 		"cost",
+		"stock",
+		"per_user_limit",
+		"limit_period",
+		"available_from",
+		"available_until",
+		"allowed_roles",
+		"min_age",
+		"max_age",
 	).Values(
 		reward.FamilyID,
 		reward.Title,
 		reward.Description,
 		reward.Cost,
+		reward.Stock,
+		reward.PerUserLimit,
+		squirrel.Expr("NULLIF(?, '')::reward_limit_period", reward.LimitPeriod),
+		reward.AvailableFrom,
+		reward.AvailableUntil,
+		rewardRoles(reward),
+		reward.MinAge,
+		reward.MaxAge,
 	).Suffix("RETURNING id").ToSql()
 
 	var id string
@@ -48,17 +116,9 @@ func (r *RewardsRepo) Create(ctx context.Context, reward entity.Reward) (string,
 
 // GetRewardsByFamilyID возвращает список вознаграждений для семьи
 func (r *RewardsRepo) GetByFamilyID(ctx context.Context, familyID string) ([]entity.Reward, error) {
-	sql, args, _ := r.Builder.Select(
-		"id",
-		"family_id",
-		"title",
-		"description",
-		"cost",
-		"created_at",
-		"updated_at",
-	).From(rewardsTable).Where(
+	sql, args, _ := r.Builder.Select(rewardColumns...).From(rewardsTable).Where(
 		squirrel.Eq{"family_id": familyID},
-	).ToSql()
+	).OrderBy("cost", "created_at").ToSql()
 
 	rows, err := r.Cluster.Query(ctx, sql, args...)
 	if err != nil {
@@ -68,16 +128,7 @@ func (r *RewardsRepo) GetByFamilyID(ctx context.Context, familyID string) ([]ent
 
 	var rewards []entity.Reward
 	for rows.Next() {
-		var reward entity.Reward
-		err := rows.Scan(
-			&reward.ID,
-			&reward.FamilyID,
-			&reward.Title,
-			&reward.Description,
-			&reward.Cost,
-			&reward.CreatedAt,
-			&reward.UpdatedAt,
-		)
+		reward, err := scanReward(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan reward: %w", err)
 		}
@@ -86,79 +137,190 @@ func (r *RewardsRepo) GetByFamilyID(ctx context.Context, familyID string) ([]ent
 	return rewards, nil
 }
 
-// AddPoints добавляет очки пользователю
-func (r *RewardsRepo) AddPoints(ctx context.Context, userID string, points int) error {
-	sql, args, _ := r.Builder.Update(userTable).
-		Set("point", squirrel.Expr("point + ?", points)).
+// GetPoints возвращает количество очков пользователя
+func (r *RewardsRepo) GetPoints(ctx context.Context, userID string) (int, error) {
+	sql, args, _ := r.Builder.Select("point").
+		From(userTable).
 		Where(squirrel.Eq{"id": userID}).
 		ToSql()
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
+	var points int
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&points)
 	if err != nil {
-		return fmt.Errorf("failed to add points: %w", err)
+		return 0, fmt.Errorf("failed to get points: %w", err)
 	}
-	return nil
+	return points, nil
 }
 
-// SubtractPoints списывает очки у пользователя
-func (r *RewardsRepo) SubtractPoints(ctx context.Context, userID string, points int) error {
-	sql, args, _ := r.Builder.Update(userTable).
-		Set("point", squirrel.Expr("point - ?", points)).
-		Where(squirrel.Eq{"id": userID}).
-		ToSql()
+// Redeem обменивает баллы на вознаграждение в одной транзакции: добавляет запрос на обмен в статусе Pending,
+// уменьшает остаток награды и списывает стоимость с баланса с записью в журнале. Строка награды блокируется,
+// поэтому check получает актуальные остаток и время неотклоненных обменов этой награды пользователем
+// и может отменить обмен своей ошибкой. repoerrs.ErrNotFound - вознаграждение не найдено,
+// repoerrs.ErrNegativeBalance - баллов недостаточно
+func (r *RewardsRepo) Redeem(
+	ctx context.Context, userID, rewardID string, check func(reward entity.Reward, redeemedAt []time.Time) error,
+) (string, error) {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
+	id, err := r.redeem(ctx, tx, userID, rewardID, check)
 	if err != nil {
-		return fmt.Errorf("failed to subtract points: %w", err)
+		return "", err
 	}
-	return nil
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit redemption: %w", err)
+	}
+	return id, nil
 }
 
-// GetPoints возвращает количество очков пользователя
-func (r *RewardsRepo) GetPoints(ctx context.Context, userID string) (int, error) {
-	sql, args, _ := r.Builder.Select("point").
-		From(userTable).
-		Where(squirrel.Eq{"id": userID}).
+// redeem выполняет обмен в транзакции tx и возвращает ID обмена
+func (r *RewardsRepo) redeem(
+	ctx context.Context, tx pgx.Tx, userID, rewardID string,
+	check func(reward entity.Reward, redeemedAt []time.Time) error,
+) (string, error) {
+	sql, args, _ := r.Builder.Select(rewardColumns...).
+		From(rewardsTable).
+		Where(squirrel.Eq{"id": rewardID}).
+		Suffix("FOR UPDATE").
 		ToSql()
 
-	var points int
-	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&points)
+	reward, err := scanReward(tx.QueryRow(ctx, sql, args...))
 	if err != nil {
-		return 0, fmt.Errorf("failed to get points: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", repoerrs.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	redeemedAt, err := r.redeemedAt(ctx, tx, userID, rewardID)
+	if err != nil {
+		return "", err
+	}
+	if err = check(reward, redeemedAt); err != nil {
+		return "", err
+	}
+
+	if reward.Stock != nil {
+		sql, args, _ = r.Builder.Update(rewardsTable).
+			Set("stock", squirrel.Expr("stock - 1")).
+			Where("id = ?", rewardID).
+			ToSql()
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return "", fmt.Errorf("failed to update reward stock: %w", err)
+		}
 	}
-	return points, nil
-}
 
-// RedeemReward обменивает очки на вознаграждение
-func (r *RewardsRepo) Redeem(ctx context.Context, userID, rewardID string) error {
-	sql, args, _ := r.Builder.Insert(rewardRedemptionsTable).Columns(
+	sql, args, _ = r.Builder.Insert(rewardRedemptionsTable).Columns(
 		"user_id",
 		"reward_id",
+		"cost",
 	).Values(
 		userID,
 		rewardID,
-	).ToSql()
+		reward.Cost,
+	).Suffix("RETURNING id").ToSql()
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
+	var id string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed to redeem reward: %w", err)
+	}
+
+	_, err = applyPoints(
+		ctx, tx, r.Builder, entity.PointTransaction{
+			UserID:     userID,
+			Kind:       entity.PointTransactionSpend,
+			Amount:     -reward.Cost,
+			Reason:     reward.Title,
+			SourceType: entity.PointSourceRedemption,
+			SourceID:   id,
+			CreatedBy:  userID,
+		},
+	)
 	if err != nil {
-		return fmt.Errorf("failed to redeem reward: %w", err)
+		return "", err
 	}
-	return nil
+	return id, nil
+}
+
+// redeemedAt возвращает время неотклоненных обменов награды пользователем
+func (r *RewardsRepo) redeemedAt(ctx context.Context, tx pgx.Tx, userID, rewardID string) ([]time.Time, error) {
+	sql, args, _ := r.Builder.Select("redeemed_at").
+		From(rewardRedemptionsTable).
+		Where(squirrel.Eq{"user_id": userID, "reward_id": rewardID}).
+		Where(squirrel.NotEq{"status": entity.RedemptionStatusDeclined}).
+		ToSql()
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions: %w", err)
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var at time.Time
+		if err = rows.Scan(&at); err != nil {
+			return nil, fmt.Errorf("failed to scan redemption: %w", err)
+		}
+		times = append(times, at)
+	}
+	return times, rows.Err()
 }
 
 // GetRedemptionsByUserID возвращает список вознаграждений, которые пользователь обменял
 func (r *RewardsRepo) GetRedemptionsByUserID(ctx context.Context, userID string) ([]entity.RewardRedemption, error) {
+	return r.getRedemptions(ctx, squirrel.Eq{"rr.user_id": userID})
+}
+
+// GetRedemptionsByFamilyID возвращает обмены членов семьи, пустой statuses - в любом статусе
+func (r *RewardsRepo) GetRedemptionsByFamilyID(ctx context.Context, familyID string, statuses []string) (
+	[]entity.RewardRedemption, error,
+) {
+	where := squirrel.And{squirrel.Eq{"r.family_id": familyID}}
+	if len(statuses) > 0 {
+		where = append(where, squirrel.Eq{"rr.status": statuses})
+	}
+	return r.getRedemptions(ctx, where)
+}
+
+// GetRedemptionByID возвращает обмен. repoerrs.ErrNotFound - обмен не найден
+func (r *RewardsRepo) GetRedemptionByID(ctx context.Context, id string) (entity.RewardRedemption, error) {
+	redemptions, err := r.getRedemptions(ctx, squirrel.Eq{"rr.id": id})
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+	if len(redemptions) == 0 {
+		return entity.RewardRedemption{}, repoerrs.ErrNotFound
+	}
+	return redemptions[0], nil
+}
+
+// getRedemptions возвращает обмены с данными вознаграждений от новых к старым
+func (r *RewardsRepo) getRedemptions(ctx context.Context, where squirrel.Sqlizer) ([]entity.RewardRedemption, error) {
 	sql, args, _ := r.Builder.Select(
 		"rr.id",
 		"rr.user_id",
 		"rr.reward_id",
 		"rr.redeemed_at",
+		"rr.status",
+		"rr.cost",
+		"COALESCE(rr.reviewed_by::text, '')",
+		"rr.reviewed_at",
+		"rr.review_comment",
+		"rr.fulfilled_at",
+		"r.id",
+		"r.family_id",
 		"r.title",
 		"r.description",
 		"r.cost",
 	).From(rewardRedemptionsTable + " rr").
 		Join(rewardsTable + " r ON rr.reward_id = r.id").
-		Where(squirrel.Eq{"rr.user_id": userID}).
+		Where(where).
+		OrderBy("rr.redeemed_at DESC").
 		ToSql()
 
 	rows, err := r.Cluster.Query(ctx, sql, args...)
@@ -175,6 +337,14 @@ func (r *RewardsRepo) GetRedemptionsByUserID(ctx context.Context, userID string)
 			&redemption.UserID,
 			&redemption.RewardID,
 			&redemption.RedeemedAt,
+			&redemption.Status,
+			&redemption.Cost,
+			&redemption.ReviewedBy,
+			&redemption.ReviewedAt,
+			&redemption.ReviewComment,
+			&redemption.FulfilledAt,
+			&redemption.Reward.ID,
+			&redemption.Reward.FamilyID,
 			&redemption.Reward.Title,
 			&redemption.Reward.Description,
 			&redemption.Reward.Cost,
@@ -184,30 +354,85 @@ func (r *RewardsRepo) GetRedemptionsByUserID(ctx context.Context, userID string)
 		}
 		redemptions = append(redemptions, redemption)
 	}
-	return redemptions, nil
+	return redemptions, rows.Err()
+}
+
+// TransitionRedemption переводит обмен из одного из статусов t.From в t.To. При отклонении списанные
+// баллы и остаток награды возвращаются в той же транзакции. repoerrs.ErrNotFound - обмен не найден или в другом статусе
+func (r *RewardsRepo) TransitionRedemption(ctx context.Context, t entity.RedemptionTransition) error {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	update := r.Builder.Update(rewardRedemptionsTable).Set("status", t.To)
+	if t.To == entity.RedemptionStatusFulfilled {
+		update = update.Set("fulfilled_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+	} else {
+		update = update.
+			Set("reviewed_by", t.ReviewerID).
+			Set("reviewed_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+			Set("review_comment", t.Comment)
+	}
+	sql, args, _ := update.
+		Where(squirrel.Eq{"id": t.ID, "status": t.From}).
+		Suffix("RETURNING user_id, reward_id, cost, (SELECT title FROM " + rewardsTable + " WHERE id = reward_id)").
+		ToSql()
+
+	var userID, rewardID, title string
+	var cost int
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&userID, &rewardID, &cost, &title); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoerrs.ErrNotFound
+		}
+		return fmt.Errorf("failed to update redemption: %w", err)
+	}
+
+	if t.To != entity.RedemptionStatusDeclined {
+		return tx.Commit(ctx)
+	}
+
+	// Отклоненный обмен возвращает награду в остаток
+	sql, args, _ = r.Builder.Update(rewardsTable).
+		Set("stock", squirrel.Expr("stock + 1")).
+		Where("id = ? AND stock IS NOT NULL", rewardID).
+		ToSql()
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to update reward stock: %w", err)
+	}
+
+	if cost > 0 {
+		_, err = applyPoints(
+			ctx, tx, r.Builder, entity.PointTransaction{
+				UserID:     userID,
+				Kind:       entity.PointTransactionRefund,
+				Amount:     cost,
+				Reason:     title,
+				SourceType: entity.PointSourceRedemption,
+				SourceID:   t.ID,
+				CreatedBy:  t.ReviewerID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 // get by id
 func (r *RewardsRepo) GetByID(ctx context.Context, id string) (entity.Reward, error) {
-	sql, args, _ := r.Builder.Select(
-		"id",
-		"family_id",
-		"title",
-		"description",
-		"cost",
-	).From(rewardsTable).Where(
+	sql, args, _ := r.Builder.Select(rewardColumns...).From(rewardsTable).Where(
 		squirrel.Eq{"id": id},
 	).ToSql()
 
-	var reward entity.Reward
-	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(
-		&reward.ID,
-		&reward.FamilyID,
-		&reward.Title,
-		&reward.Description,
-		&reward.Cost,
-	)
+	reward, err := scanReward(r.Cluster.QueryRow(ctx, sql, args...))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Reward{}, repoerrs.ErrNotFound
+		}
 		return entity.Reward{}, fmt.Errorf("failed to get reward: %w", err)
 	}
 	return reward, nil
@@ -218,6 +443,14 @@ func (r *RewardsRepo) Update(ctx context.Context, reward entity.Reward) error {
 		Set("title", reward.Title).
 		Set("description", reward.Description).
 		Set("cost", reward.Cost).
+		Set("stock", reward.Stock).
+		Set("per_user_limit", reward.PerUserLimit).
+		Set("limit_period", squirrel.Expr("NULLIF(?, '')::reward_limit_period", reward.LimitPeriod)).
+		Set("available_from", reward.AvailableFrom).
+		Set("available_until", reward.AvailableUntil).
+		Set("allowed_roles", rewardRoles(reward)).
+		Set("min_age", reward.MinAge).
+		Set("max_age", reward.MaxAge).
 		Set("updated_at", "NOW()").
 		Where(squirrel.Eq{"id": reward.ID}).
 		ToSql()
@@ -229,15 +462,88 @@ func (r *RewardsRepo) Update(ctx context.Context, reward entity.Reward) error {
 	return nil
 }
 
-// Delete удаляет вознаграждение
+// Delete удаляет вознаграждение. Баллы, отложенные на незавершенные цели накопления этой награды,
+// и баллы, списанные за еще не выполненные обмены, возвращаются на баланс в той же транзакции
 func (r *RewardsRepo) Delete(ctx context.Context, id string) error {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	goals := r.Builder.Select("g.id", "g.user_id", "g.saved", "r.title").
+		From(savingsGoalsTable+" g").
+		Join(rewardsTable+" r ON g.reward_id = r.id").
+		Where("g.reward_id = ? AND g.completed_at IS NULL AND g.saved > 0", id).
+		Suffix("FOR UPDATE OF g")
+	releases, err := lockPointEntries(
+		ctx, tx, goals, entity.PointTransaction{
+			Kind:       entity.PointTransactionRelease,
+			SourceType: entity.PointSourceSavingsGoal,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get savings goals: %w", err)
+	}
+
+	// Ожидающие и одобренные обмены удаляются вместе с наградой, поэтому их стоимость возвращается,
+	// как при отклонении обмена
+	redemptions := r.Builder.Select("rr.id", "rr.user_id", "rr.cost", "r.title").
+		From(rewardRedemptionsTable + " rr").
+		Join(rewardsTable + " r ON rr.reward_id = r.id").
+		Where(
+			squirrel.Eq{
+				"rr.reward_id": id,
+				"rr.status":    []string{entity.RedemptionStatusPending, entity.RedemptionStatusApproved},
+			},
+		).
+		Where("rr.cost > 0").
+		Suffix("FOR UPDATE OF rr")
+	refunds, err := lockPointEntries(
+		ctx, tx, redemptions, entity.PointTransaction{
+			Kind:       entity.PointTransactionRefund,
+			SourceType: entity.PointSourceRedemption,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get redemptions: %w", err)
+	}
+
+	for _, entry := range append(releases, refunds...) {
+		if _, err = applyPoints(ctx, tx, r.Builder, entry); err != nil {
+			return err
+		}
+	}
+
 	sql, args, _ := r.Builder.Delete(rewardsTable).Where(
 		squirrel.Eq{"id": id},
 	).ToSql()
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	if err != nil {
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
 		return fmt.Errorf("failed to delete reward: %w", err)
 	}
-	return nil
+	return tx.Commit(ctx)
+}
+
+// lockPointEntries выполняет запрос, возвращающий ID источника, пользователя, число баллов и причину,
+// и строит по каждой строке операцию с баллами по образцу entry
+func lockPointEntries(
+	ctx context.Context, tx pgx.Tx, query squirrel.SelectBuilder, entry entity.PointTransaction,
+) ([]entity.PointTransaction, error) {
+	sql, args, _ := query.ToSql()
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []entity.PointTransaction
+	for rows.Next() {
+		item := entry
+		if err = rows.Scan(&item.SourceID, &item.UserID, &item.Amount, &item.Reason); err != nil {
+			return nil, err
+		}
+		entries = append(entries, item)
+	}
+	return entries, rows.Err()
 }
@@ -0,0 +1,156 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/pkg/postgres"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// newTestDatabase подключается к базе из POSTGRES_TEST_CONN и применяет миграции.
+// Без переменной окружения тест пропускается
+func newTestDatabase(t *testing.T) *postgres.Database {
+	t.Helper()
+
+	conn, ok := os.LookupEnv("POSTGRES_TEST_CONN")
+	if !ok {
+		t.Skip("POSTGRES_TEST_CONN not set")
+	}
+
+	m, err := migrate.New("file://../../../migrations", conn)
+	if err != nil {
+		t.Fatalf("migrate.New: %v", err)
+	}
+	defer func() { _, _ = m.Close() }()
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	db, err := postgres.New(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("postgres.New: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// createTestUser создает семью и ребенка в ней, возвращает ID семьи и пользователя
+func createTestUser(t *testing.T, db *postgres.Database) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	var familyID, userID string
+	err := db.Cluster.QueryRow(ctx, "INSERT INTO families (name) VALUES ('Test family') RETURNING id").
+		Scan(&familyID)
+	if err != nil {
+		t.Fatalf("create family: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.Cluster.Exec(context.Background(), "DELETE FROM families WHERE id = $1", familyID) })
+
+	email := fmt.Sprintf("child-%d@example.com", time.Now().UnixNano())
+	err = db.Cluster.QueryRow(
+		ctx,
+		"INSERT INTO users (name, email, password, role, family_id) "+
+			"VALUES ('Child', $1, 'x', 'Child', $2) RETURNING id",
+		email, familyID,
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.Cluster.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID) })
+	return familyID, userID
+}
+
+func TestRewardsRepoDeleteRefundsOpenRedemptions(t *testing.T) {
+	db := newTestDatabase(t)
+	repo := NewRewardsRepo(db)
+	ctx := context.Background()
+	familyID, userID := createTestUser(t, db)
+
+	_, err := repo.ApplyPoints(
+		ctx, entity.PointTransaction{UserID: userID, Kind: entity.PointTransactionEarn, Amount: 100, Reason: "test"},
+	)
+	if err != nil {
+		t.Fatalf("ApplyPoints: %v", err)
+	}
+
+	rewardID, err := repo.Create(ctx, entity.Reward{FamilyID: familyID, Title: "Cinema", Cost: 20})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	allow := func(entity.Reward, []time.Time) error { return nil }
+	redemptions := make([]string, 3)
+	for i := range redemptions {
+		if redemptions[i], err = repo.Redeem(ctx, userID, rewardID, allow); err != nil {
+			t.Fatalf("Redeem: %v", err)
+		}
+	}
+
+	// Первый обмен выполнен и не возвращается, второй одобрен, третий ждет решения
+	transitions := []entity.RedemptionTransition{
+		{ID: redemptions[0], From: []string{entity.RedemptionStatusPending}, To: entity.RedemptionStatusApproved},
+		{ID: redemptions[0], From: []string{entity.RedemptionStatusApproved}, To: entity.RedemptionStatusFulfilled},
+		{ID: redemptions[1], From: []string{entity.RedemptionStatusPending}, To: entity.RedemptionStatusApproved},
+	}
+	for _, transition := range transitions {
+		transition.ReviewerID = userID
+		if err = repo.TransitionRedemption(ctx, transition); err != nil {
+			t.Fatalf("TransitionRedemption %s -> %s: %v", transition.ID, transition.To, err)
+		}
+	}
+
+	if points, _ := repo.GetPoints(ctx, userID); points != 40 {
+		t.Fatalf("points before delete = %d, want 40", points)
+	}
+
+	if err = repo.Delete(ctx, rewardID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	points, err := repo.GetPoints(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetPoints: %v", err)
+	}
+	if points != 80 {
+		t.Errorf("points after delete = %d, want 80", points)
+	}
+
+	entries, err := repo.GetPointTransactions(ctx, userID, "", 100)
+	if err != nil {
+		t.Fatalf("GetPointTransactions: %v", err)
+	}
+	refunded := make(map[string]entity.PointTransaction)
+	sum := 0
+	for _, entry := range entries {
+		sum += entry.Amount
+		if entry.Kind == entity.PointTransactionRefund {
+			refunded[entry.SourceID] = entry
+		}
+	}
+	if sum != points {
+		t.Errorf("ledger sum = %d, want balance %d", sum, points)
+	}
+	if len(refunded) != 2 {
+		t.Fatalf("refunds = %d, want 2", len(refunded))
+	}
+	for _, id := range redemptions[1:] {
+		entry, ok := refunded[id]
+		if !ok {
+			t.Errorf("redemption %s was not refunded", id)
+			continue
+		}
+		if entry.Amount != 20 || entry.SourceType != entity.PointSourceRedemption || entry.Reason != "Cinema" {
+			t.Errorf("refund for %s = %+v", id, entry)
+		}
+	}
+}
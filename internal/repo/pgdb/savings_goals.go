@@ -0,0 +1,255 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const savingsGoalsTable = "savings_goals"
+
+var savingsGoalColumns = []string{
+	"id",
+	"user_id",
+	"reward_id",
+	"saved",
+	"created_at",
+	"updated_at",
+	"completed_at",
+}
+
+func scanSavingsGoal(row pgx.Row) (entity.SavingsGoal, error) {
+	var goal entity.SavingsGoal
+	err := row.Scan(
+		&goal.ID,
+		&goal.UserID,
+		&goal.RewardID,
+		&goal.Saved,
+		&goal.CreatedAt,
+		&goal.UpdatedAt,
+		&goal.CompletedAt,
+	)
+	return goal, err
+}
+
+// CreateSavingsGoal создает цель накопления и возвращает ее ID.
+// repoerrs.ErrAlreadyExists - у пользователя уже есть незавершенная цель на эту награду
+func (r *RewardsRepo) CreateSavingsGoal(ctx context.Context, userID, rewardID string) (string, error) {
+	sql, args, _ := r.Builder.Insert(savingsGoalsTable).
+		Columns("user_id", "reward_id").
+		Values(userID, rewardID).
+		Suffix("RETURNING id").
+		ToSql()
+
+	var id string
+	if err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return "", repoerrs.ErrAlreadyExists
+		}
+		return "", fmt.Errorf("failed to create savings goal: %w", err)
+	}
+	return id, nil
+}
+
+// GetSavingsGoalsByUserID возвращает цели накопления пользователя: сначала незавершенные, затем от новых к старым
+func (r *RewardsRepo) GetSavingsGoalsByUserID(ctx context.Context, userID string) ([]entity.SavingsGoal, error) {
+	sql, args, _ := r.Builder.Select(savingsGoalColumns...).
+		From(savingsGoalsTable).
+		Where(squirrel.Eq{"user_id": userID}).
+		OrderBy("completed_at IS NOT NULL", "created_at DESC").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get savings goals: %w", err)
+	}
+	defer rows.Close()
+
+	var goals []entity.SavingsGoal
+	for rows.Next() {
+		goal, err := scanSavingsGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan savings goal: %w", err)
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// GetSavingsGoalByID возвращает цель накопления. repoerrs.ErrNotFound - цель не найдена
+func (r *RewardsRepo) GetSavingsGoalByID(ctx context.Context, id string) (entity.SavingsGoal, error) {
+	sql, args, _ := r.Builder.Select(savingsGoalColumns...).
+		From(savingsGoalsTable).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	goal, err := scanSavingsGoal(r.Cluster.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.SavingsGoal{}, repoerrs.ErrNotFound
+		}
+		return entity.SavingsGoal{}, fmt.Errorf("failed to get savings goal: %w", err)
+	}
+	return goal, nil
+}
+
+// MoveGoalPoints переводит points баллов с баланса на незавершенную цель (Reserve), а при отрицательном points -
+// с цели обратно на баланс (Release). repoerrs.ErrNotFound - незавершенная цель не найдена,
+// repoerrs.ErrNegativeBalance - на балансе или на цели недостаточно баллов
+func (r *RewardsRepo) MoveGoalPoints(ctx context.Context, goalID string, points int) error {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Update(savingsGoalsTable).
+		Set("saved", squirrel.Expr("saved + ?", points)).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ? AND completed_at IS NULL", goalID).
+		Suffix("RETURNING user_id, (SELECT title FROM " + rewardsTable + " WHERE id = reward_id)").
+		ToSql()
+
+	var userID, title string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&userID, &title); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoerrs.ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+			return repoerrs.ErrNegativeBalance
+		}
+		return fmt.Errorf("failed to update savings goal: %w", err)
+	}
+
+	kind := entity.PointTransactionReserve
+	if points < 0 {
+		kind = entity.PointTransactionRelease
+	}
+	_, err = applyPoints(
+		ctx, tx, r.Builder, entity.PointTransaction{
+			UserID:     userID,
+			Kind:       kind,
+			Amount:     -points,
+			Reason:     title,
+			SourceType: entity.PointSourceSavingsGoal,
+			SourceID:   goalID,
+			CreatedBy:  userID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteSavingsGoal удаляет цель накопления. Баллы незавершенной цели возвращаются на баланс
+// в той же транзакции. repoerrs.ErrNotFound - цель не найдена
+func (r *RewardsRepo) DeleteSavingsGoal(ctx context.Context, id string) error {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Delete(savingsGoalsTable).
+		Where("id = ?", id).
+		Suffix(
+			"RETURNING user_id, CASE WHEN completed_at IS NULL THEN saved ELSE 0 END, " +
+				"(SELECT title FROM " + rewardsTable + " WHERE id = reward_id)",
+		).
+		ToSql()
+
+	var userID, title string
+	var saved int
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&userID, &saved, &title); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoerrs.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete savings goal: %w", err)
+	}
+
+	if saved > 0 {
+		_, err = applyPoints(
+			ctx, tx, r.Builder, entity.PointTransaction{
+				UserID:     userID,
+				Kind:       entity.PointTransactionRelease,
+				Amount:     saved,
+				Reason:     title,
+				SourceType: entity.PointSourceSavingsGoal,
+				SourceID:   id,
+				CreatedBy:  userID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// RedeemSavingsGoal обменивает накопленное на награду цели в одной транзакции: возвращает отложенные баллы
+// на баланс, выполняет обмен как Redeem и завершает цель. Остаток сверх стоимости награды остается на балансе.
+// Возвращает ID обмена. repoerrs.ErrNotFound - незавершенная цель или награда не найдены
+func (r *RewardsRepo) RedeemSavingsGoal(
+	ctx context.Context, goalID string, check func(reward entity.Reward, redeemedAt []time.Time) error,
+) (string, error) {
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Update(savingsGoalsTable).
+		Set("completed_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ? AND completed_at IS NULL", goalID).
+		Suffix("RETURNING user_id, reward_id, saved, (SELECT title FROM " + rewardsTable + " WHERE id = reward_id)").
+		ToSql()
+
+	var userID, rewardID, title string
+	var saved int
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&userID, &rewardID, &saved, &title); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", repoerrs.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to complete savings goal: %w", err)
+	}
+
+	if saved > 0 {
+		_, err = applyPoints(
+			ctx, tx, r.Builder, entity.PointTransaction{
+				UserID:     userID,
+				Kind:       entity.PointTransactionRelease,
+				Amount:     saved,
+				Reason:     title,
+				SourceType: entity.PointSourceSavingsGoal,
+				SourceID:   goalID,
+				CreatedBy:  userID,
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	id, err := r.redeem(ctx, tx, userID, rewardID, check)
+	if err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit redemption: %w", err)
+	}
+	return id, nil
+}
@@ -2,18 +2,53 @@ package pgdb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
 	shoppingTable = "shopping_items"
 )
 
+// shoppingColumns - колонки покупки в порядке полей scanShoppingItem
+var shoppingColumns = []string{
+	"id",
+	"family_id",
+	"title",
+	"COALESCE(description, '')",
+	"status",
+	"visibility",
+	"created_by",
+	"reserved_by",
+	"buyer_id",
+	"is_archived",
+	"created_at",
+	"updated_at",
+	"COALESCE(list_id::text, '')",
+	"quantity",
+	"unit",
+	"category",
+	"estimated_price_minor",
+	"actual_price_minor",
+	"store",
+	"bought_at",
+	"version",
+}
+
+// shoppingListNotArchived отбрасывает покупки из архивированных списков
+const shoppingListNotArchived = "NOT EXISTS (SELECT 1 FROM " + shoppingListsTable + " l WHERE l.id = " +
+	shoppingTable + ".list_id AND l.is_archived)"
+
 type ShoppingRepo struct {
 	*postgres.Database
 }
@@ -22,6 +57,34 @@ func NewShoppingRepo(db *postgres.Database) *ShoppingRepo {
 	return &ShoppingRepo{db}
 }
 
+func scanShoppingItem(row pgx.Row) (entity.ShoppingItem, error) {
+	var item entity.ShoppingItem
+	err := row.Scan(
+		&item.ID,
+		&item.FamilyID,
+		&item.Title,
+		&item.Description,
+		&item.Status,
+		&item.Visibility,
+		&item.CreatedBy,
+		&item.ReservedBy,
+		&item.BuyerId,
+		&item.IsArchived,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.ListID,
+		&item.Quantity,
+		&item.Unit,
+		&item.Category,
+		&item.EstimatedPriceMinor,
+		&item.ActualPriceMinor,
+		&item.Store,
+		&item.BoughtAt,
+		&item.Version,
+	)
+	return item, err
+}
+
 func (r *ShoppingRepo) Create(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) (string, error) {
 	log.Info("ShoppingRepo - Create")
 	sql, args, _ := r.Builder.Insert(shoppingTable).Columns(
@@ -30,12 +93,24 @@ func (r *ShoppingRepo) Create(ctx context.Context, log *slog.Logger, item entity
 		"description",
 		"visibility",
 		"created_by",
+		"list_id",
+		"quantity",
+		"unit",
+		"category",
+		"estimated_price_minor",
+		"store",
 	).Values(
 		item.FamilyID,
 		item.Title,
 		item.Description,
 		item.Visibility,
 		item.CreatedBy,
+		squirrel.Expr("NULLIF(?, '')::uuid", item.ListID),
+		item.Quantity,
+		item.Unit,
+		item.Category,
+		item.EstimatedPriceMinor,
+		item.Store,
 	).Suffix("RETURNING id").ToSql()
 
 	var id string
@@ -46,18 +121,32 @@ func (r *ShoppingRepo) Create(ctx context.Context, log *slog.Logger, item entity
 	return id, nil
 }
 
-func (r *ShoppingRepo) Delete(ctx context.Context, log *slog.Logger, id string) error {
+// Delete удаляет покупку и возвращает ее последнее состояние. Ненулевой version - ожидаемая версия покупки
+func (r *ShoppingRepo) Delete(ctx context.Context, log *slog.Logger, id string, version int64) (
+	entity.ShoppingItem, error,
+) {
 	log.Info("ShoppingRepo - Delete")
-	sql, args, _ := r.Builder.Delete(shoppingTable).Where("id = ?", id).ToSql()
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	query := r.Builder.Delete(shoppingTable).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	sql, args, _ := query.Suffix("RETURNING " + strings.Join(shoppingColumns, ", ")).ToSql()
+
+	item, err := scanShoppingItem(r.Cluster.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ShoppingItem{}, r.missingItem(ctx, id)
+	}
+	return item, err
 }
 
-func (r *ShoppingRepo) Update(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) error {
+// Update меняет покупку и возвращает ее новое состояние. Ненулевой item.Version - ожидаемая версия покупки
+func (r *ShoppingRepo) Update(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) (
+	entity.ShoppingItem, error,
+) {
 	log.Info("ShoppingRepo - Update")
 
-	log.Info(fmt.Sprintf("ShoppingRepo - Update - IsArchived - %s", item.IsArchived))
-	sql, args, _ := r.Builder.Update(shoppingTable).Set(
+	log.Info(fmt.Sprintf("ShoppingRepo - Update - IsArchived - %t", item.IsArchived))
+	query := r.Builder.Update(shoppingTable).Set(
 		"title", item.Title,
 	).Set(
 		"description", item.Description,
@@ -66,11 +155,15 @@ func (r *ShoppingRepo) Update(ctx context.Context, log *slog.Logger, item entity
 	).Set(
 		"visibility", item.Visibility,
 	).Set("is_archived", item.IsArchived).
-		Set("updated_at", item.UpdatedAt).
-		Where("id = ?", item.ID).ToSql()
+		Set("quantity", item.Quantity).
+		Set("unit", item.Unit).
+		Set("category", item.Category).
+		Set("estimated_price_minor", item.EstimatedPriceMinor).
+		Set("actual_price_minor", item.ActualPriceMinor).
+		Set("store", item.Store).
+		Set("updated_at", item.UpdatedAt)
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	return r.updateItem(ctx, query, item.ID, item.Version)
 }
 
 // получить списки visibility - public family_id
@@ -78,9 +171,11 @@ func (r *ShoppingRepo) GetPublicByFamilyID(
 	ctx context.Context, log *slog.Logger, familyID string,
 ) ([]entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - GetPublicByFamilyID")
-	sql, args, _ := r.Builder.Select(
-		"*",
-	).From(shoppingTable).Where("family_id = ? AND visibility = 'Public' AND is_archived = false", familyID).ToSql()
+	sql, args, _ := r.Builder.Select(shoppingColumns...).
+		From(shoppingTable).
+		Where("family_id = ? AND visibility = 'Public' AND is_archived = false", familyID).
+		Where(shoppingListNotArchived).
+		ToSql()
 
 	rows, err := r.Cluster.Query(ctx, sql, args...)
 	if err != nil {
@@ -90,21 +185,7 @@ func (r *ShoppingRepo) GetPublicByFamilyID(
 
 	items := make([]entity.ShoppingItem, 0)
 	for rows.Next() {
-		var item entity.ShoppingItem
-		err = rows.Scan(
-			&item.ID,
-			&item.FamilyID,
-			&item.Title,
-			&item.Description,
-			&item.Status,
-			&item.Visibility,
-			&item.CreatedBy,
-			&item.ReservedBy,
-			&item.BuyerId,
-			&item.IsArchived,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		)
+		item, err := scanShoppingItem(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -119,10 +200,10 @@ func (r *ShoppingRepo) GetPrivateByCreatedBy(
 	ctx context.Context, log *slog.Logger, CreatedBy string,
 ) ([]entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - GetPrivateByCreatedBy")
-	sql, args, _ := r.Builder.Select("*").From(shoppingTable).Where(
+	sql, args, _ := r.Builder.Select(shoppingColumns...).From(shoppingTable).Where(
 		"created_by = ? AND visibility = 'Private' AND is_archived = false",
 		CreatedBy,
-	).ToSql()
+	).Where(shoppingListNotArchived).ToSql()
 
 	rows, err := r.Cluster.Query(ctx, sql, args...)
 	if err != nil {
@@ -132,21 +213,7 @@ func (r *ShoppingRepo) GetPrivateByCreatedBy(
 
 	var items []entity.ShoppingItem
 	for rows.Next() {
-		var item entity.ShoppingItem
-		err = rows.Scan(
-			&item.ID,
-			&item.FamilyID,
-			&item.Title,
-			&item.Description,
-			&item.Status,
-			&item.Visibility,
-			&item.CreatedBy,
-			&item.ReservedBy,
-			&item.BuyerId,
-			&item.IsArchived,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		)
+		item, err := scanShoppingItem(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -157,43 +224,43 @@ func (r *ShoppingRepo) GetPrivateByCreatedBy(
 
 // update reserved_by
 func (r *ShoppingRepo) UpdateReservedBy(
-	ctx context.Context, log *slog.Logger, id string, reservedBy string, updatedAt time.Time,
-) error {
+	ctx context.Context, log *slog.Logger, id string, reservedBy string, version int64, updatedAt time.Time,
+) (entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - UpdateReservedBy")
-	sql, args, _ := r.Builder.Update(shoppingTable).Set(
+	query := r.Builder.Update(shoppingTable).Set(
 		"reserved_by", reservedBy,
-	).Set("status", "Reserved").Set("updated_at", updatedAt).Where("id = ?", id).ToSql()
+	).Set("status", "Reserved").Set("updated_at", updatedAt)
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	return r.updateItem(ctx, query, id, version)
 }
 
 // update reserved_by
 func (r *ShoppingRepo) CancelUpdateReservedBy(
-	ctx context.Context, log *slog.Logger, id string, updatedAt time.Time,
-) error {
+	ctx context.Context, log *slog.Logger, id string, version int64, updatedAt time.Time,
+) (entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - UpdateReservedBy")
-	sql, args, _ := r.Builder.Update(shoppingTable).Set(
+	query := r.Builder.Update(shoppingTable).Set(
 		"reserved_by", nil,
-	).Set("status", "Active").Set("updated_at", updatedAt).Where("id = ?", id).ToSql()
+	).Set("status", "Active").Set("updated_at", updatedAt)
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	return r.updateItem(ctx, query, id, version)
 }
 
-// update buyer_id
+// update buyer_id. Пустые actualPriceMinor и store не меняют цену и магазин
 func (r *ShoppingRepo) UpdateBuyerId(
-	ctx context.Context, log *slog.Logger, id string, buyerId string, updatedAt time.Time,
-) error {
+	ctx context.Context, log *slog.Logger, id string, buyerId string, actualPriceMinor *int, store string,
+	version int64, updatedAt time.Time,
+) (entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - UpdateBuyerId")
-	sql, args, _ := r.Builder.Update(shoppingTable).Set(
+	query := r.Builder.Update(shoppingTable).Set(
 		"buyer_id", buyerId,
 	).Set("status", "Completed").
-		Set("updated_at", updatedAt).
-		Where("id = ?", id).ToSql()
+		Set("actual_price_minor", squirrel.Expr("COALESCE(?::int, actual_price_minor)", actualPriceMinor)).
+		Set("store", squirrel.Expr("COALESCE(NULLIF(?, ''), store)", store)).
+		Set("bought_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", updatedAt)
 
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	return r.updateItem(ctx, query, id, version)
 }
 
 // get archived items by user id
@@ -201,7 +268,7 @@ func (r *ShoppingRepo) GetArchivedByUserID(
 	ctx context.Context, log *slog.Logger, userID string,
 ) ([]entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - GetArchivedByUserID")
-	sql, args, _ := r.Builder.Select("*").From(shoppingTable).Where(
+	sql, args, _ := r.Builder.Select(shoppingColumns...).From(shoppingTable).Where(
 		"created_by = ? AND is_archived = true",
 		userID,
 	).ToSql()
@@ -214,21 +281,7 @@ func (r *ShoppingRepo) GetArchivedByUserID(
 
 	var items []entity.ShoppingItem
 	for rows.Next() {
-		var item entity.ShoppingItem
-		err = rows.Scan(
-			&item.ID,
-			&item.FamilyID,
-			&item.Title,
-			&item.Description,
-			&item.Status,
-			&item.Visibility,
-			&item.CreatedBy,
-			&item.ReservedBy,
-			&item.BuyerId,
-			&item.IsArchived,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		)
+		item, err := scanShoppingItem(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -242,30 +295,51 @@ func (r *ShoppingRepo) GetByID(
 	ctx context.Context, log *slog.Logger, id string,
 ) (entity.ShoppingItem, error) {
 	log.Info("ShoppingRepo - GetByID")
-	sql, args, _ := r.Builder.Select("*").From(shoppingTable).Where(
+	sql, args, _ := r.Builder.Select(shoppingColumns...).From(shoppingTable).Where(
 		"id = ?",
 		id,
 	).ToSql()
 
-	row := r.Cluster.QueryRow(ctx, sql, args...)
-
-	var item entity.ShoppingItem
-	err := row.Scan(
-		&item.ID,
-		&item.FamilyID,
-		&item.Title,
-		&item.Description,
-		&item.Status,
-		&item.Visibility,
-		&item.CreatedBy,
-		&item.ReservedBy,
-		&item.BuyerId,
-		&item.IsArchived,
-		&item.CreatedAt,
-		&item.UpdatedAt,
-	)
+	item, err := scanShoppingItem(r.Cluster.QueryRow(ctx, sql, args...))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ShoppingItem{}, repoerrs.ErrNotFound
+		}
 		return entity.ShoppingItem{}, err
 	}
 	return item, nil
 }
+
+// updateItem применяет query к покупке id, увеличивает ее версию и возвращает новое состояние.
+// Ненулевой version - ожидаемая версия покупки
+func (r *ShoppingRepo) updateItem(ctx context.Context, query squirrel.UpdateBuilder, id string, version int64) (
+	entity.ShoppingItem, error,
+) {
+	query = query.Set("version", squirrel.Expr("version + 1")).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	sql, args, _ := query.Suffix("RETURNING " + strings.Join(shoppingColumns, ", ")).ToSql()
+
+	item, err := scanShoppingItem(r.Cluster.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ShoppingItem{}, r.missingItem(ctx, id)
+	}
+	return item, err
+}
+
+// missingItem объясняет, почему изменение не затронуло покупку id: repoerrs.ErrNotFound - покупки нет,
+// repoerrs.ErrVersionConflict - ее версия уже другая
+func (r *ShoppingRepo) missingItem(ctx context.Context, id string) error {
+	sql, args, _ := r.Builder.Select("1").From(shoppingTable).Where("id = ?", id).Prefix("SELECT EXISTS (").
+		Suffix(")").ToSql()
+
+	var exists bool
+	if err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return repoerrs.ErrVersionConflict
+	}
+	return repoerrs.ErrNotFound
+}
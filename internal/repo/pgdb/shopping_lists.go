@@ -0,0 +1,308 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	shoppingListsTable      = "shopping_lists"
+	shoppingListSharesTable = "shopping_list_shares"
+)
+
+var shoppingListColumns = []string{
+	"l.id",
+	"COALESCE(l.family_id::text, '')",
+	"COALESCE(l.user_id::text, '')",
+	"l.title",
+	"COALESCE(l.created_by::text, '')",
+	"l.is_default",
+	"l.is_archived",
+	"l.archived_at",
+	"ARRAY(SELECT s.user_id::text FROM " + shoppingListSharesTable + " s WHERE s.list_id = l.id " +
+		"ORDER BY s.created_at)",
+	"(SELECT COUNT(*) FROM " + shoppingTable + " i WHERE i.list_id = l.id AND NOT i.is_archived)",
+	"(SELECT COUNT(*) FROM " + shoppingTable + " i WHERE i.list_id = l.id AND NOT i.is_archived " +
+		"AND i.status = 'Completed')",
+	"l.created_at",
+	"l.updated_at",
+}
+
+func scanShoppingList(row pgx.Row) (entity.ShoppingList, error) {
+	var list entity.ShoppingList
+	err := row.Scan(
+		&list.ID,
+		&list.FamilyID,
+		&list.UserID,
+		&list.Title,
+		&list.CreatedBy,
+		&list.IsDefault,
+		&list.IsArchived,
+		&list.ArchivedAt,
+		&list.SharedWith,
+		&list.ItemsTotal,
+		&list.ItemsCompleted,
+		&list.CreatedAt,
+		&list.UpdatedAt,
+	)
+	return list, err
+}
+
+// CreateList создает список покупок семьи list.FamilyID или пользователя list.UserID
+func (r *ShoppingRepo) CreateList(ctx context.Context, log *slog.Logger, list entity.ShoppingList) (string, error) {
+	log.Info("ShoppingRepo - CreateList")
+	sql, args, _ := r.Builder.Insert(shoppingListsTable).
+		Columns("family_id", "user_id", "title", "created_by").
+		Values(
+			squirrel.Expr("NULLIF(?, '')::uuid", list.FamilyID),
+			squirrel.Expr("NULLIF(?, '')::uuid", list.UserID),
+			list.Title,
+			squirrel.Expr("NULLIF(?, '')::uuid", list.CreatedBy),
+		).
+		Suffix("RETURNING id").
+		ToSql()
+
+	var id string
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+// GetDefaultListID возвращает список по умолчанию семьи familyID или, если familyID пуст, пользователя userID,
+// и создает его при первом обращении
+func (r *ShoppingRepo) GetDefaultListID(ctx context.Context, log *slog.Logger, familyID, userID string) (
+	string, error,
+) {
+	log.Info("ShoppingRepo - GetDefaultListID")
+	list := entity.ShoppingList{FamilyID: familyID, Title: "Покупки семьи"}
+	owner := squirrel.Eq{"family_id": familyID}
+	if familyID == "" {
+		list = entity.ShoppingList{UserID: userID, Title: "Мои покупки", CreatedBy: userID}
+		owner = squirrel.Eq{"user_id": userID}
+	}
+
+	// Одновременное создание упирается в уникальный индекс списка по умолчанию
+	sql, args, _ := r.Builder.Insert(shoppingListsTable).
+		Columns("family_id", "user_id", "title", "created_by", "is_default").
+		Values(
+			squirrel.Expr("NULLIF(?, '')::uuid", list.FamilyID),
+			squirrel.Expr("NULLIF(?, '')::uuid", list.UserID),
+			list.Title,
+			squirrel.Expr("NULLIF(?, '')::uuid", list.CreatedBy),
+			true,
+		).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if _, err := r.Cluster.Exec(ctx, sql, args...); err != nil {
+		return "", err
+	}
+
+	sql, args, _ = r.Builder.Select("id").
+		From(shoppingListsTable).
+		Where(owner).
+		Where("is_default").
+		ToSql()
+
+	var id string
+	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+// GetListByID возвращает список покупок. repoerrs.ErrNotFound - списка нет
+func (r *ShoppingRepo) GetListByID(ctx context.Context, log *slog.Logger, id string) (entity.ShoppingList, error) {
+	log.Info("ShoppingRepo - GetListByID")
+	sql, args, _ := r.Builder.Select(shoppingListColumns...).
+		From(shoppingListsTable+" l").
+		Where("l.id = ?", id).
+		ToSql()
+
+	list, err := scanShoppingList(r.Cluster.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ShoppingList{}, repoerrs.ErrNotFound
+		}
+		return entity.ShoppingList{}, err
+	}
+	return list, nil
+}
+
+// GetListsByUser возвращает списки, доступные пользователю: списки семьи familyID, его личные списки
+// и списки, которыми с ним поделились. Сначала списки по умолчанию, затем по времени создания
+func (r *ShoppingRepo) GetListsByUser(
+	ctx context.Context, log *slog.Logger, userID, familyID string, archived bool,
+) ([]entity.ShoppingList, error) {
+	log.Info("ShoppingRepo - GetListsByUser")
+	sql, args, _ := r.Builder.Select(shoppingListColumns...).
+		From(shoppingListsTable+" l").
+		Where(
+			"(l.family_id = NULLIF(?, '')::uuid OR l.user_id = ? OR EXISTS (SELECT 1 FROM "+
+				shoppingListSharesTable+" s WHERE s.list_id = l.id AND s.user_id = ?))",
+			familyID, userID, userID,
+		).
+		Where(squirrel.Eq{"l.is_archived": archived}).
+		OrderBy("l.is_default DESC", "l.created_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]entity.ShoppingList, 0)
+	for rows.Next() {
+		list, err := scanShoppingList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// UpdateList переименовывает список покупок
+func (r *ShoppingRepo) UpdateList(ctx context.Context, log *slog.Logger, id, title string) error {
+	log.Info("ShoppingRepo - UpdateList")
+	sql, args, _ := r.Builder.Update(shoppingListsTable).
+		Set("title", title).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// SetListArchived архивирует список покупок или возвращает его из архива
+func (r *ShoppingRepo) SetListArchived(ctx context.Context, log *slog.Logger, id string, archived bool) error {
+	log.Info("ShoppingRepo - SetListArchived")
+	archivedAt := squirrel.Expr("NULL")
+	if archived {
+		archivedAt = squirrel.Expr("CURRENT_TIMESTAMP")
+	}
+	sql, args, _ := r.Builder.Update(shoppingListsTable).
+		Set("is_archived", archived).
+		Set("archived_at", archivedAt).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ?", id).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteList удаляет список покупок вместе с его покупками
+func (r *ShoppingRepo) DeleteList(ctx context.Context, log *slog.Logger, id string) error {
+	log.Info("ShoppingRepo - DeleteList")
+	sql, args, _ := r.Builder.Delete(shoppingListsTable).Where("id = ?", id).ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// SetListShares заменяет пользователей, с которыми поделились списком, на userIDs
+func (r *ShoppingRepo) SetListShares(ctx context.Context, log *slog.Logger, listID string, userIDs []string) error {
+	log.Info("ShoppingRepo - SetListShares")
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Delete(shoppingListSharesTable).
+		Where(squirrel.Eq{"list_id": listID}).
+		Where(squirrel.NotEq{"user_id": userIDs}).
+		ToSql()
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+
+	if len(userIDs) > 0 {
+		insert := r.Builder.Insert(shoppingListSharesTable).Columns("list_id", "user_id")
+		for _, userID := range userIDs {
+			insert = insert.Values(listID, userID)
+		}
+		sql, args, _ = insert.Suffix("ON CONFLICT DO NOTHING").ToSql()
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetItemsByListID возвращает неархивные покупки списка от старых к новым
+func (r *ShoppingRepo) GetItemsByListID(ctx context.Context, log *slog.Logger, listID string) (
+	[]entity.ShoppingItem, error,
+) {
+	log.Info("ShoppingRepo - GetItemsByListID")
+	sql, args, _ := r.Builder.Select(shoppingColumns...).
+		From(shoppingTable).
+		Where(squirrel.Eq{"list_id": listID, "is_archived": false}).
+		OrderBy("created_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]entity.ShoppingItem, 0)
+	for rows.Next() {
+		item, err := scanShoppingItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetSpend возвращает купленные за [from, to) покупки списков listIDs и траты на них по каждому списку
+// и отделу магазина. Архивные покупки тоже учитываются
+func (r *ShoppingRepo) GetSpend(ctx context.Context, log *slog.Logger, listIDs []string, from, to time.Time) (
+	[]entity.ShoppingSpend, error,
+) {
+	log.Info("ShoppingRepo - GetSpend")
+	sql, args, _ := r.Builder.Select(
+		"list_id::text",
+		"category",
+		"COUNT(*)",
+		"COUNT(*) FILTER (WHERE actual_price_minor IS NULL)",
+		"COALESCE(SUM(actual_price_minor), 0)",
+		"COALESCE(SUM(estimated_price_minor), 0)",
+	).
+		From(shoppingTable).
+		Where(squirrel.Eq{"list_id": listIDs, "status": "Completed"}).
+		Where(squirrel.GtOrEq{"bought_at": from}).
+		Where(squirrel.Lt{"bought_at": to}).
+		GroupBy("list_id", "category").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spend := make([]entity.ShoppingSpend, 0)
+	for rows.Next() {
+		var row entity.ShoppingSpend
+		err = rows.Scan(
+			&row.ListID, &row.Category, &row.ItemsBought, &row.ItemsWithoutPrice, &row.ActualMinor,
+			&row.EstimatedMinor,
+		)
+		if err != nil {
+			return nil, err
+		}
+		spend = append(spend, row)
+	}
+	return spend, rows.Err()
+}
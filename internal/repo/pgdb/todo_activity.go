@@ -0,0 +1,191 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	todoActivityTable = "todo_activity"
+	todoCommentsTable = "todo_comments"
+)
+
+var todoActivityColumns = []string{
+	"id",
+	"todo_id",
+	"COALESCE(actor_id::text, '')",
+	"kind",
+	"details",
+	"created_at",
+}
+
+var todoCommentColumns = []string{
+	"id",
+	"todo_id",
+	"COALESCE(author_id::text, '')",
+	"body",
+	"mentions",
+	"created_at",
+}
+
+func scanTodoActivity(row pgx.Row) (entity.TodoActivity, error) {
+	var activity entity.TodoActivity
+	err := row.Scan(
+		&activity.ID,
+		&activity.TodoID,
+		&activity.ActorID,
+		&activity.Kind,
+		&activity.Details,
+		&activity.CreatedAt,
+	)
+	return activity, err
+}
+
+func scanTodoComment(row pgx.Row) (entity.TodoComment, error) {
+	var comment entity.TodoComment
+	err := row.Scan(
+		&comment.ID,
+		&comment.TodoID,
+		&comment.AuthorID,
+		&comment.Body,
+		&comment.Mentions,
+		&comment.CreatedAt,
+	)
+	return comment, err
+}
+
+// AddActivity добавляет события в ленты заданий. Пустой ActorID сохраняется как действие системы
+func (r *TodoRepo) AddActivity(ctx context.Context, log *slog.Logger, activities []entity.TodoActivity) error {
+	log.Info("TodoRepo - AddActivity")
+	if len(activities) == 0 {
+		return nil
+	}
+
+	insert := r.Builder.Insert(todoActivityTable).Columns("todo_id", "actor_id", "kind", "details")
+	for _, activity := range activities {
+		details := activity.Details
+		if details == nil {
+			details = map[string]string{}
+		}
+		insert = insert.Values(
+			activity.TodoID, squirrel.Expr("NULLIF(?, '')::uuid", activity.ActorID), activity.Kind, details,
+		)
+	}
+	sql, args, _ := insert.ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// GetActivity возвращает ленту задания от старых событий к новым
+func (r *TodoRepo) GetActivity(ctx context.Context, log *slog.Logger, todoID string) ([]entity.TodoActivity, error) {
+	log.Info("TodoRepo - GetActivity")
+	sql, args, _ := r.Builder.Select(todoActivityColumns...).
+		From(todoActivityTable).
+		Where("todo_id = ?", todoID).
+		OrderBy("created_at", "id").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []entity.TodoActivity
+	for rows.Next() {
+		activity, err := scanTodoActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+// CreateComment добавляет комментарий к заданию и возвращает его
+func (r *TodoRepo) CreateComment(ctx context.Context, log *slog.Logger, comment entity.TodoComment) (
+	entity.TodoComment, error,
+) {
+	log.Info("TodoRepo - CreateComment")
+	mentions := comment.Mentions
+	if mentions == nil {
+		mentions = []string{}
+	}
+
+	sql, args, _ := r.Builder.Insert(todoCommentsTable).
+		Columns("todo_id", "author_id", "body", "mentions").
+		Values(comment.TodoID, comment.AuthorID, comment.Body, mentions).
+		Suffix("RETURNING " + strings.Join(todoCommentColumns, ", ")).
+		ToSql()
+
+	return scanTodoComment(r.Cluster.QueryRow(ctx, sql, args...))
+}
+
+// GetComments возвращает комментарии к заданию от старых к новым
+func (r *TodoRepo) GetComments(ctx context.Context, log *slog.Logger, todoID string) ([]entity.TodoComment, error) {
+	log.Info("TodoRepo - GetComments")
+	sql, args, _ := r.Builder.Select(todoCommentColumns...).
+		From(todoCommentsTable).
+		Where("todo_id = ?", todoID).
+		OrderBy("created_at", "id").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []entity.TodoComment
+	for rows.Next() {
+		comment, err := scanTodoComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// GetCommentByID возвращает комментарий к заданию. repoerrs.ErrNotFound - комментарий не найден
+func (r *TodoRepo) GetCommentByID(ctx context.Context, log *slog.Logger, todoID, id string) (
+	entity.TodoComment, error,
+) {
+	log.Info("TodoRepo - GetCommentByID")
+	sql, args, _ := r.Builder.Select(todoCommentColumns...).
+		From(todoCommentsTable).
+		Where(squirrel.Eq{"id": id, "todo_id": todoID}).
+		ToSql()
+
+	comment, err := scanTodoComment(r.Cluster.QueryRow(ctx, sql, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.TodoComment{}, repoerrs.ErrNotFound
+	}
+	return comment, err
+}
+
+// DeleteComment удаляет комментарий к заданию. repoerrs.ErrNotFound - комментарий не найден
+func (r *TodoRepo) DeleteComment(ctx context.Context, log *slog.Logger, todoID, id string) error {
+	log.Info("TodoRepo - DeleteComment")
+	sql, args, _ := r.Builder.Delete(todoCommentsTable).
+		Where(squirrel.Eq{"id": id, "todo_id": todoID}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
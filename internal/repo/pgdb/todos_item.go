@@ -2,16 +2,85 @@ package pgdb
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
-	todoTable = "todo_items"
+	todoTable          = "todo_items"
+	todoSeriesTable    = "todo_series"
+	todoProofsTable    = "todo_proofs"
+	todoRemindersTable = "todo_reminders"
+	todoChecklistTable = "todo_checklist_items"
 )
 
+var todoColumns = []string{
+	"id",
+	"family_id",
+	"title",
+	"description",
+	"status",
+	"deadline",
+	"assigned_to",
+	"created_by",
+	"is_archived",
+	"created_at",
+	"updated_at",
+	"point",
+	"series_id",
+	"occurrence_at",
+	"is_detached",
+	"submitted_at",
+	"reviewed_by",
+	"reviewed_at",
+	"review_comment",
+	"points_awarded",
+	"requires_proof",
+	"is_overdue",
+	"points_rule",
+	"awarded_points",
+	"archived_at",
+	"deleted_at",
+}
+
+// todoOpenStatuses - статусы невыполненного задания, todoEditableStatuses - статусы, в которых
+// задание еще не отправлено на проверку
+var (
+	todoOpenStatuses     = []string{entity.TodoStatusActive, entity.TodoStatusSubmitted, entity.TodoStatusRejected}
+	todoEditableStatuses = []string{entity.TodoStatusActive, entity.TodoStatusRejected}
+)
+
+var todoSeriesColumns = []string{
+	"id",
+	"family_id",
+	"title",
+	"description",
+	"assigned_to",
+	"created_by",
+	"point",
+	"rrule",
+	"starts_at",
+	"last_occurrence_at",
+	"is_active",
+	"created_at",
+	"updated_at",
+	"requires_proof",
+	"points_rule",
+	"checklist",
+	"rotation_members",
+	"rotation_index",
+}
+
 type TodoRepo struct {
 	*postgres.Database
 }
@@ -22,6 +91,28 @@ func NewTodoRepo(db *postgres.Database) *TodoRepo {
 
 func (r *TodoRepo) Create(ctx context.Context, log *slog.Logger, item entity.TodoItem) (string, error) {
 	log.Info("TodoRepo - Create")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	id, err := r.insertTodo(ctx, tx, item, "")
+	if err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// insertTodo создает задание вместе с пунктами чек-листа в транзакции tx. suffix дополняет INSERT
+// перед RETURNING (например, ON CONFLICT ... DO NOTHING), пустой ID - задание не создано из-за конфликта
+func (r *TodoRepo) insertTodo(ctx context.Context, tx pgx.Tx, item entity.TodoItem, suffix string) (string, error) {
+	pointsRule := item.PointsRule
+	if pointsRule == "" {
+		pointsRule = entity.TodoPointsRuleAll
+	}
+
 	sql, args, _ := r.Builder.Insert(todoTable).Columns(
 		"family_id",
 		"title",
@@ -30,6 +121,10 @@ func (r *TodoRepo) Create(ctx context.Context, log *slog.Logger, item entity.Tod
 		"assigned_to",
 		"created_by",
 		"point",
+		"series_id",
+		"occurrence_at",
+		"requires_proof",
+		"points_rule",
 	).Values(
 		item.FamilyID,
 		item.Title,
@@ -38,44 +133,159 @@ func (r *TodoRepo) Create(ctx context.Context, log *slog.Logger, item entity.Tod
 		item.AssignedTo,
 		item.CreatedBy,
 		item.Point,
-	).Suffix("RETURNING id").ToSql()
+		item.SeriesID,
+		item.OccurrenceAt,
+		item.RequiresProof,
+		pointsRule,
+	).Suffix(strings.TrimSpace(suffix + " RETURNING id")).ToSql()
 
 	var id string
-	err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&id)
-	if err != nil {
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
 		return "", err
 	}
+
+	if len(item.Checklist) > 0 {
+		insert := r.Builder.Insert(todoChecklistTable).Columns("todo_id", "title", "position")
+		for i, entry := range item.Checklist {
+			insert = insert.Values(id, entry.Title, i)
+		}
+		sql, args, _ = insert.ToSql()
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return "", err
+		}
+	}
+
 	return id, nil
 }
 
+// Delete помечает задание удаленным. Окончательно задание удаляет PurgeDeleted после окна отмены.
+// repoerrs.ErrNotFound - задание не найдено или уже удалено
 func (r *TodoRepo) Delete(ctx context.Context, log *slog.Logger, id string) error {
 	log.Info("TodoRepo - Delete")
-	sql, args, _ := r.Builder.Delete(todoTable).Where("id = ?", id).ToSql()
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+	sql, args, _ := r.Builder.Update(todoTable).
+		Set("deleted_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
 }
 
+// Update изменяет поля задания. repoerrs.ErrNotFound - задание удалено или уже не в статусе Active или Rejected
 func (r *TodoRepo) Update(ctx context.Context, log *slog.Logger, item entity.TodoItem) error {
 	log.Info("TodoRepo - Update")
 	sql, args, _ := r.Builder.Update(todoTable).Set(
 		"title", item.Title,
 	).Set(
 		"description", item.Description,
-	).Set(
-		"status", item.Status,
 	).Set(
 		"deadline", item.Deadline,
 	).Set(
 		"assigned_to", item.AssignedTo,
 	).Set("point", item.Point).
-		Where("id = ?", item.ID).ToSql()
-	_, err := r.Cluster.Exec(ctx, sql, args...)
-	return err
+		Set("is_detached", item.IsDetached).
+		// После переноса срока задание снова считается непросроченным, пока планировщик не проверит новый срок
+		Set("is_overdue", squirrel.Expr("is_overdue AND deadline = ?", item.Deadline)).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": item.ID, "status": todoEditableStatuses, "deleted_at": nil}).ToSql()
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// List возвращает до filter.Limit заданий, подходящих под фильтр, в порядке filter.Sort.
+// Постраничная навигация - по ключу (поле сортировки, id), начиная после filter.After
+func (r *TodoRepo) List(ctx context.Context, log *slog.Logger, filter entity.TodoFilter) ([]entity.TodoItem, error) {
+	log.Info("TodoRepo - List")
+
+	query := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(squirrel.Eq{"is_archived": filter.Archived, "deleted_at": nil})
+
+	if filter.FamilyID != "" {
+		query = query.Where(squirrel.Eq{"family_id": filter.FamilyID})
+	}
+	if filter.VisibleTo != "" {
+		query = query.Where(squirrel.Or{
+			squirrel.Eq{"assigned_to": filter.VisibleTo},
+			squirrel.Eq{"created_by": filter.VisibleTo},
+		})
+	}
+	if filter.AssignedTo != "" {
+		query = query.Where(squirrel.Eq{"assigned_to": filter.AssignedTo})
+	}
+	if filter.CreatedBy != "" {
+		query = query.Where(squirrel.Eq{"created_by": filter.CreatedBy})
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where(squirrel.Eq{"status": filter.Statuses})
+	}
+	if filter.DueBefore != nil {
+		query = query.Where(squirrel.Lt{"deadline": *filter.DueBefore})
+	}
+	if filter.DueAfter != nil {
+		query = query.Where(squirrel.GtOrEq{"deadline": *filter.DueAfter})
+	}
+
+	sortColumn := entity.TodoSortDeadline
+	if filter.Sort == entity.TodoSortCreatedAt {
+		sortColumn = entity.TodoSortCreatedAt
+	}
+	direction, compare := "ASC", ">"
+	if filter.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if filter.After != nil {
+		query = query.Where(
+			"("+sortColumn+", id) "+compare+" (?, ?)", filter.After.Value, filter.After.ID,
+		)
+	}
+
+	sql, args, _ := query.
+		OrderBy(sortColumn+" "+direction, "id "+direction).
+		Limit(filter.Limit).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.TodoItem
+	for rows.Next() {
+		item, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
 }
 
 func (r *TodoRepo) getByField(ctx context.Context, log *slog.Logger, field, value string) ([]entity.TodoItem, error) {
 	log.Info("TodoRepo - getByField")
-	sql, args, _ := r.Builder.Select("*").From(todoTable).Where(field+" = ?", value).ToSql()
+	sql, args, _ := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(field+" = ?", value).
+		Where(squirrel.Eq{"deleted_at": nil}).
+		OrderBy("deadline", "id").
+		ToSql()
 
 	rows, err := r.Cluster.Query(ctx, sql, args...)
 	if err != nil {
@@ -85,21 +295,8 @@ func (r *TodoRepo) getByField(ctx context.Context, log *slog.Logger, field, valu
 
 	var items []entity.TodoItem
 	for rows.Next() {
-		var item entity.TodoItem
-		if err := rows.Scan(
-			&item.ID,
-			&item.FamilyID,
-			&item.Title,
-			&item.Description,
-			&item.Status,
-			&item.Deadline,
-			&item.AssignedTo,
-			&item.CreatedBy,
-			&item.IsArchived,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-			&item.Point,
-		); err != nil {
+		item, err := scanTodo(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -128,11 +325,25 @@ func (r *TodoRepo) GetByCreatedBy(ctx context.Context, log *slog.Logger, created
 // get by id
 func (r *TodoRepo) GetByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoItem, error) {
 	log.Info("TodoRepo - GetByID")
-	sql, args, _ := r.Builder.Select("*").From(todoTable).Where("id = ?", id).ToSql()
+	sql, args, _ := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(squirrel.Eq{"id": id, "deleted_at": nil}).
+		ToSql()
+
+	item, err := scanTodo(r.Cluster.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.TodoItem{}, repoerrs.ErrNotFound
+		}
+		return entity.TodoItem{}, err
+	}
+
+	return item, nil
+}
 
-	row := r.Cluster.QueryRow(ctx, sql, args...)
+func scanTodo(row pgx.Row) (entity.TodoItem, error) {
 	var item entity.TodoItem
-	if err := row.Scan(
+	err := row.Scan(
 		&item.ID,
 		&item.FamilyID,
 		&item.Title,
@@ -145,9 +356,941 @@ func (r *TodoRepo) GetByID(ctx context.Context, log *slog.Logger, id string) (en
 		&item.CreatedAt,
 		&item.UpdatedAt,
 		&item.Point,
-	); err != nil {
-		return entity.TodoItem{}, err
+		&item.SeriesID,
+		&item.OccurrenceAt,
+		&item.IsDetached,
+		&item.SubmittedAt,
+		&item.ReviewedBy,
+		&item.ReviewedAt,
+		&item.ReviewComment,
+		&item.PointsAwarded,
+		&item.RequiresProof,
+		&item.IsOverdue,
+		&item.PointsRule,
+		&item.AwardedPoints,
+		&item.ArchivedAt,
+		&item.DeletedAt,
+	)
+	return item, err
+}
+
+func scanTodoSeries(row pgx.Row) (entity.TodoSeries, error) {
+	var series entity.TodoSeries
+	err := row.Scan(
+		&series.ID,
+		&series.FamilyID,
+		&series.Title,
+		&series.Description,
+		&series.AssignedTo,
+		&series.CreatedBy,
+		&series.Point,
+		&series.RRule,
+		&series.StartsAt,
+		&series.LastOccurrenceAt,
+		&series.IsActive,
+		&series.CreatedAt,
+		&series.UpdatedAt,
+		&series.RequiresProof,
+		&series.PointsRule,
+		&series.Checklist,
+		&series.RotationMembers,
+		&series.RotationIndex,
+	)
+	return series, err
+}
+
+// CreateSeries создает серию повторяющихся заданий вместе с первым повторением и возвращает ID повторения
+func (r *TodoRepo) CreateSeries(ctx context.Context, log *slog.Logger, series entity.TodoSeries, first entity.TodoItem) (
+	string, error,
+) {
+	log.Info("TodoRepo - CreateSeries")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return "", err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	return item, nil
+	sql, args, _ := r.Builder.Insert(todoSeriesTable).Columns(
+		"family_id",
+		"title",
+		"description",
+		"assigned_to",
+		"created_by",
+		"point",
+		"rrule",
+		"starts_at",
+		"last_occurrence_at",
+		"requires_proof",
+		"points_rule",
+		"checklist",
+		"rotation_members",
+		"rotation_index",
+	).Values(
+		series.FamilyID,
+		series.Title,
+		series.Description,
+		series.AssignedTo,
+		series.CreatedBy,
+		series.Point,
+		series.RRule,
+		series.StartsAt,
+		series.LastOccurrenceAt,
+		series.RequiresProof,
+		series.PointsRule,
+		series.Checklist,
+		series.RotationMembers,
+		series.RotationIndex,
+	).Suffix("RETURNING id").ToSql()
+
+	var seriesID string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&seriesID); err != nil {
+		return "", err
+	}
+
+	first.SeriesID.String, first.SeriesID.Valid = seriesID, true
+	id, err := r.insertTodo(ctx, tx, first, "")
+	if err != nil {
+		return "", err
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// GetSeriesByID возвращает серию повторяющихся заданий
+func (r *TodoRepo) GetSeriesByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoSeries, error) {
+	log.Info("TodoRepo - GetSeriesByID")
+	sql, args, _ := r.Builder.Select(todoSeriesColumns...).From(todoSeriesTable).Where("id = ?", id).ToSql()
+
+	series, err := scanTodoSeries(r.Cluster.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.TodoSeries{}, repoerrs.ErrNotFound
+		}
+		return entity.TodoSeries{}, err
+	}
+	return series, nil
+}
+
+// GetActiveSeriesIDs возвращает ID всех активных серий
+func (r *TodoRepo) GetActiveSeriesIDs(ctx context.Context, log *slog.Logger) ([]string, error) {
+	log.Info("TodoRepo - GetActiveSeriesIDs")
+	sql, args, _ := r.Builder.Select("id").From(todoSeriesTable).Where("is_active = TRUE").ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateSeries обновляет шаблон и правило серии
+func (r *TodoRepo) UpdateSeries(ctx context.Context, log *slog.Logger, series entity.TodoSeries) error {
+	log.Info("TodoRepo - UpdateSeries")
+	sql, args, _ := r.Builder.Update(todoSeriesTable).
+		Set("title", series.Title).
+		Set("description", series.Description).
+		Set("assigned_to", series.AssignedTo).
+		Set("point", series.Point).
+		Set("rrule", series.RRule).
+		Set("starts_at", series.StartsAt).
+		Set("last_occurrence_at", series.LastOccurrenceAt).
+		Set("is_active", series.IsActive).
+		Set("rotation_members", series.RotationMembers).
+		Set("rotation_index", series.RotationIndex).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ?", series.ID).ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateSeriesOccurrences переносит изменения шаблона серии на невыполненные повторения,
+// которые не были изменены отдельно
+func (r *TodoRepo) UpdateSeriesOccurrences(ctx context.Context, log *slog.Logger, series entity.TodoSeries) error {
+	log.Info("TodoRepo - UpdateSeriesOccurrences")
+	update := r.Builder.Update(todoTable).
+		Set("title", series.Title).
+		Set("description", series.Description).
+		Set("point", series.Point).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+	// При ротации исполнителя каждого повторения выбирает ротация, а не шаблон
+	if len(series.RotationMembers) == 0 {
+		update = update.Set("assigned_to", series.AssignedTo)
+	}
+	sql, args, _ := update.
+		Where(squirrel.Eq{"series_id": series.ID, "status": todoEditableStatuses, "is_detached": false}).
+		ToSql()
+
+	_, err := r.Cluster.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteSeries останавливает серию и помечает удаленными ее невыполненные повторения, как Delete,
+// и возвращает их ID. Выполненные повторения остаются в истории
+func (r *TodoRepo) DeleteSeries(ctx context.Context, log *slog.Logger, id string) ([]string, error) {
+	log.Info("TodoRepo - DeleteSeries")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Update(todoSeriesTable).
+		Set("is_active", false).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id = ?", id).ToSql()
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repoerrs.ErrNotFound
+	}
+
+	sql, args, _ = r.Builder.Update(todoTable).
+		Set("deleted_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"series_id": id, "status": todoEditableStatuses, "deleted_at": nil}).
+		Suffix("RETURNING id").
+		ToSql()
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var todoID string
+		if err = rows.Scan(&todoID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, todoID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit(ctx)
+}
+
+// HasOpenOccurrence проверяет, есть ли у серии невыполненное повторение со сроком позже after
+func (r *TodoRepo) HasOpenOccurrence(ctx context.Context, log *slog.Logger, seriesID string, after time.Time) (
+	bool, error,
+) {
+	log.Info("TodoRepo - HasOpenOccurrence")
+	sql, args, _ := r.Builder.Select("1").From(todoTable).
+		Where(squirrel.Eq{"series_id": seriesID, "status": todoOpenStatuses, "deleted_at": nil}).
+		Where(squirrel.Gt{"deadline": after}).
+		Prefix("SELECT EXISTS (").Suffix(")").ToSql()
+
+	var exists bool
+	if err := r.Cluster.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// CreateOccurrence создает следующее повторение серии, сдвигает last_occurrence_at серии и запоминает
+// индекс участника ротации, которому назначено повторение.
+// Повторение создается, только если last_occurrence_at серии все еще равен prevOccurrenceAt,
+// поэтому параллельные генераторы не создадут одно повторение дважды. false - повторение уже создано
+func (r *TodoRepo) CreateOccurrence(
+	ctx context.Context, log *slog.Logger, item entity.TodoItem, prevOccurrenceAt time.Time, rotationIndex int,
+) (bool, error) {
+	log.Info("TodoRepo - CreateOccurrence")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Update(todoSeriesTable).
+		Set("last_occurrence_at", item.OccurrenceAt).
+		Set("rotation_index", rotationIndex).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": item.SeriesID, "last_occurrence_at": prevOccurrenceAt, "is_active": true}).
+		ToSql()
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err = r.insertTodo(ctx, tx, item, "ON CONFLICT (series_id, occurrence_at) DO NOTHING"); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Submit отправляет задание на проверку вместе с фото выполнения.
+// repoerrs.ErrNotFound - задание удалено или не в статусе Active или Rejected
+func (r *TodoRepo) Submit(ctx context.Context, log *slog.Logger, id string, proofs []entity.TodoProof) error {
+	log.Info("TodoRepo - Submit")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, _ := r.Builder.Update(todoTable).
+		Set("status", entity.TodoStatusSubmitted).
+		Set("submitted_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": id, "status": todoEditableStatuses, "deleted_at": nil}).
+		ToSql()
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+
+	if len(proofs) > 0 {
+		insert := r.Builder.Insert(todoProofsTable).Columns("todo_id", "uploaded_by", "path", "url")
+		for _, proof := range proofs {
+			insert = insert.Values(id, proof.UploadedBy, proof.Path, proof.URL)
+		}
+		sql, args, _ = insert.ToSql()
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetProofs возвращает фото выполнения заданий в порядке загрузки
+func (r *TodoRepo) GetProofs(ctx context.Context, log *slog.Logger, todoIDs []string) ([]entity.TodoProof, error) {
+	log.Info("TodoRepo - GetProofs")
+	if len(todoIDs) == 0 {
+		return nil, nil
+	}
+
+	sql, args, _ := r.Builder.Select("id", "todo_id", "COALESCE(uploaded_by::text, '')", "path", "url", "created_at").
+		From(todoProofsTable).
+		Where(squirrel.Eq{"todo_id": todoIDs}).
+		OrderBy("created_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var proofs []entity.TodoProof
+	for rows.Next() {
+		var proof entity.TodoProof
+		if err := rows.Scan(
+			&proof.ID, &proof.TodoID, &proof.UploadedBy, &proof.Path, &proof.URL, &proof.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, rows.Err()
+}
+
+// GetForReview возвращает задания на проверке, созданные пользователем или, если familyID не пуст,
+// относящиеся к семье
+func (r *TodoRepo) GetForReview(ctx context.Context, log *slog.Logger, userID, familyID string) (
+	[]entity.TodoItem, error,
+) {
+	log.Info("TodoRepo - GetForReview")
+
+	reviewer := squirrel.Or{squirrel.Eq{"created_by": userID}}
+	if familyID != "" {
+		reviewer = append(reviewer, squirrel.Eq{"family_id": familyID})
+	}
+	sql, args, _ := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(squirrel.Eq{"status": entity.TodoStatusSubmitted, "deleted_at": nil}).
+		Where(reviewer).
+		OrderBy("submitted_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.TodoItem
+	for rows.Next() {
+		item, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Reject возвращает задание исполнителю с комментарием. repoerrs.ErrNotFound - задание удалено или не на проверке
+func (r *TodoRepo) Reject(ctx context.Context, log *slog.Logger, id, reviewerID, comment string) error {
+	log.Info("TodoRepo - Reject")
+	sql, args, _ := r.Builder.Update(todoTable).
+		Set("status", entity.TodoStatusRejected).
+		Set("reviewed_by", reviewerID).
+		Set("reviewed_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("review_comment", comment).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": id, "status": entity.TodoStatusSubmitted, "deleted_at": nil}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// Approve одобряет задание и в той же транзакции начисляет исполнителю points баллов.
+// Баллы начисляются только если они еще не были начислены. repoerrs.ErrNotFound - задание не на проверке
+func (r *TodoRepo) Approve(ctx context.Context, log *slog.Logger, id, reviewerID string, points int) error {
+	log.Info("TodoRepo - Approve")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = r.approveTodo(ctx, tx, id, reviewerID, points, []string{entity.TodoStatusSubmitted}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// approveTodo одобряет задание в одном из статусов statuses и начисляет исполнителю баллы в транзакции tx.
+// repoerrs.ErrNotFound - задание не найдено, удалено или в другом статусе
+func (r *TodoRepo) approveTodo(
+	ctx context.Context, tx pgx.Tx, id, reviewerID string, points int, statuses []string,
+) error {
+	sql, args, _ := r.Builder.Select("assigned_to", "title", "points_awarded").
+		From(todoTable).
+		Where(squirrel.Eq{"id": id, "status": statuses, "deleted_at": nil}).
+		Suffix("FOR UPDATE").
+		ToSql()
+
+	var assignedTo, title string
+	var alreadyAwarded bool
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&assignedTo, &title, &alreadyAwarded); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoerrs.ErrNotFound
+		}
+		return err
+	}
+
+	update := r.Builder.Update(todoTable).
+		Set("status", entity.TodoStatusApproved).
+		Set("reviewed_by", reviewerID).
+		Set("reviewed_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("review_comment", "").
+		Set("points_awarded", true).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+	if !alreadyAwarded {
+		update = update.Set("awarded_points", points)
+	}
+	sql, args, _ = update.Where("id = ?", id).ToSql()
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+
+	if !alreadyAwarded && points != 0 {
+		_, err := applyPoints(
+			ctx, tx, r.Builder, entity.PointTransaction{
+				UserID:     assignedTo,
+				Kind:       entity.PointTransactionEarn,
+				Amount:     points,
+				Reason:     title,
+				SourceType: entity.PointSourceTodo,
+				SourceID:   id,
+				CreatedBy:  reviewerID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimDeadlineReminders выбирает до limit заданий, срок которых наступает в интервале (now, now+offset]
+// и о которых еще не напоминали за offset до этого срока, и отмечает напоминание отправленным.
+// Строки блокируются через FOR UPDATE SKIP LOCKED, а отметка вставляется с ON CONFLICT DO NOTHING,
+// поэтому при нескольких репликах каждое напоминание достается только одной из них
+func (r *TodoRepo) ClaimDeadlineReminders(
+	ctx context.Context, log *slog.Logger, offset time.Duration, now time.Time, limit uint64,
+) ([]entity.TodoItem, error) {
+	log.Info("TodoRepo - ClaimDeadlineReminders", "offset", offset)
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	offsetSeconds := int(offset.Seconds())
+	sql, args, _ := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(squirrel.Eq{"status": todoEditableStatuses, "is_archived": false, "deleted_at": nil}).
+		Where(squirrel.Gt{"deadline": now}).
+		Where(squirrel.LtOrEq{"deadline": now.Add(offset)}).
+		Where(
+			"NOT EXISTS (SELECT 1 FROM "+todoRemindersTable+" tr WHERE tr.todo_id = "+todoTable+
+				".id AND tr.deadline = "+todoTable+".deadline AND tr.offset_seconds = ?)", offsetSeconds,
+		).
+		OrderBy("deadline").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []entity.TodoItem
+	for rows.Next() {
+		item, err := scanTodo(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	insert := r.Builder.Insert(todoRemindersTable).Columns("todo_id", "deadline", "offset_seconds")
+	for _, item := range candidates {
+		insert = insert.Values(item.ID, item.Deadline, offsetSeconds)
+	}
+	sql, args, _ = insert.Suffix("ON CONFLICT DO NOTHING RETURNING todo_id").ToSql()
+
+	rows, err = tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	claimed := make(map[string]bool, len(candidates))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		claimed[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var items []entity.TodoItem
+	for _, item := range candidates {
+		if claimed[item.ID] {
+			items = append(items, item)
+		}
+	}
+	return items, tx.Commit(ctx)
+}
+
+// MarkOverdue помечает просроченными до limit невыполненных заданий со сроком не позже now
+// и возвращает их. Каждое задание помечается один раз, даже если планировщик запущен на нескольких репликах
+func (r *TodoRepo) MarkOverdue(ctx context.Context, log *slog.Logger, now time.Time, limit uint64) (
+	[]entity.TodoItem, error,
+) {
+	log.Info("TodoRepo - MarkOverdue")
+
+	subSQL, subArgs, _ := squirrel.Select("id").
+		From(todoTable).
+		Where(squirrel.Eq{
+			"status":      todoEditableStatuses,
+			"is_archived": false,
+			"is_overdue":  false,
+			"deleted_at":  nil,
+		}).
+		Where(squirrel.LtOrEq{"deadline": now}).
+		OrderBy("deadline").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+
+	sql, args, _ := r.Builder.Update(todoTable).
+		Set("is_overdue", true).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id IN ("+subSQL+")", subArgs...).
+		Where(squirrel.Eq{"is_overdue": false}).
+		Suffix("RETURNING " + strings.Join(todoColumns, ", ")).
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.TodoItem
+	for rows.Next() {
+		item, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetByIDs возвращает задания по списку ID. deleted - вернуть только удаленные задания, иначе только неудаленные
+func (r *TodoRepo) GetByIDs(ctx context.Context, log *slog.Logger, ids []string, deleted bool) (
+	[]entity.TodoItem, error,
+) {
+	log.Info("TodoRepo - GetByIDs")
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := r.Builder.Select(todoColumns...).
+		From(todoTable).
+		Where(squirrel.Eq{"id": ids})
+	if deleted {
+		query = query.Where(squirrel.NotEq{"deleted_at": nil})
+	} else {
+		query = query.Where(squirrel.Eq{"deleted_at": nil})
+	}
+	sql, args, _ := query.OrderBy("deadline", "id").ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.TodoItem
+	for rows.Next() {
+		item, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Bulk выполняет действие над заданиями op.IDs (без повторов) в одной транзакции.
+// repoerrs.ErrNotFound - действие нельзя применить хотя бы к одному заданию, изменения отменяются
+func (r *TodoRepo) Bulk(ctx context.Context, log *slog.Logger, op entity.TodoBulkOperation) error {
+	log.Info("TodoRepo - Bulk", "action", op.Action, "count", len(op.IDs))
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if op.Action == entity.TodoBulkComplete {
+		for _, id := range op.IDs {
+			if err = r.approveTodo(ctx, tx, id, op.UserID, op.Points[id], todoOpenStatuses); err != nil {
+				return err
+			}
+		}
+		return tx.Commit(ctx)
+	}
+
+	update := r.Builder.Update(todoTable).Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+	where := squirrel.And{squirrel.Eq{"id": op.IDs}}
+	switch op.Action {
+	case entity.TodoBulkDelete:
+		update = update.Set("deleted_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+		where = append(where, squirrel.Eq{"deleted_at": nil})
+	case entity.TodoBulkRestore:
+		update = update.Set("deleted_at", nil)
+		where = append(where, squirrel.Gt{"deleted_at": op.DeletedAfter})
+	case entity.TodoBulkReassign:
+		// Повторение с другим исполнителем больше не следует шаблону серии
+		update = update.Set("assigned_to", op.AssignedTo).
+			Set("is_detached", squirrel.Expr("series_id IS NOT NULL"))
+		where = append(where, squirrel.Eq{"status": todoEditableStatuses, "deleted_at": nil})
+	case entity.TodoBulkArchive:
+		update = update.Set("is_archived", true).Set("archived_at", squirrel.Expr("CURRENT_TIMESTAMP"))
+		where = append(where, squirrel.Eq{"is_archived": false, "deleted_at": nil})
+	case entity.TodoBulkUnarchive:
+		update = update.Set("is_archived", false).Set("archived_at", nil)
+		where = append(where, squirrel.Eq{"is_archived": true, "deleted_at": nil})
+	default:
+		return fmt.Errorf("unknown bulk action %q", op.Action)
+	}
+
+	sql, args, _ := update.Where(where).ToSql()
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != int64(len(op.IDs)) {
+		return repoerrs.ErrNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ArchiveCompleted архивирует до limit одобренных заданий, проверенных не позже before, и возвращает их число.
+// Строки блокируются через FOR UPDATE SKIP LOCKED, поэтому задание не обрабатывается дважды на нескольких репликах
+func (r *TodoRepo) ArchiveCompleted(ctx context.Context, log *slog.Logger, before time.Time, limit uint64) (
+	int64, error,
+) {
+	log.Info("TodoRepo - ArchiveCompleted")
+
+	subSQL, subArgs, _ := squirrel.Select("id").
+		From(todoTable).
+		Where(squirrel.Eq{"status": entity.TodoStatusApproved, "is_archived": false, "deleted_at": nil}).
+		Where(squirrel.LtOrEq{"reviewed_at": before}).
+		OrderBy("reviewed_at").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+
+	sql, args, _ := r.Builder.Update(todoTable).
+		Set("is_archived", true).
+		Set("archived_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where("id IN ("+subSQL+")", subArgs...).
+		Where(squirrel.Eq{"is_archived": false}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeDeleted окончательно удаляет до limit заданий, удаленных не позже before, и возвращает их число
+func (r *TodoRepo) PurgeDeleted(ctx context.Context, log *slog.Logger, before time.Time, limit uint64) (
+	int64, error,
+) {
+	log.Info("TodoRepo - PurgeDeleted")
+
+	subSQL, subArgs, _ := squirrel.Select("id").
+		From(todoTable).
+		Where(squirrel.LtOrEq{"deleted_at": before}).
+		OrderBy("deleted_at").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+
+	sql, args, _ := r.Builder.Delete(todoTable).
+		Where("id IN ("+subSQL+")", subArgs...).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var todoChecklistColumns = []string{
+	"id",
+	"todo_id",
+	"title",
+	"is_done",
+	"position",
+	"done_by",
+	"done_at",
+	"created_at",
+}
+
+func scanTodoChecklistItem(row pgx.Row) (entity.TodoChecklistItem, error) {
+	var item entity.TodoChecklistItem
+	err := row.Scan(
+		&item.ID,
+		&item.TodoID,
+		&item.Title,
+		&item.IsDone,
+		&item.Position,
+		&item.DoneBy,
+		&item.DoneAt,
+		&item.CreatedAt,
+	)
+	return item, err
+}
+
+// GetChecklists возвращает пункты чек-листов заданий по порядку
+func (r *TodoRepo) GetChecklists(ctx context.Context, log *slog.Logger, todoIDs []string) (
+	[]entity.TodoChecklistItem, error,
+) {
+	log.Info("TodoRepo - GetChecklists")
+	if len(todoIDs) == 0 {
+		return nil, nil
+	}
+
+	sql, args, _ := r.Builder.Select(todoChecklistColumns...).
+		From(todoChecklistTable).
+		Where(squirrel.Eq{"todo_id": todoIDs}).
+		OrderBy("todo_id", "position", "created_at").
+		ToSql()
+
+	rows, err := r.Cluster.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.TodoChecklistItem
+	for rows.Next() {
+		item, err := scanTodoChecklistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddChecklistItem добавляет пункт в конец чек-листа задания
+func (r *TodoRepo) AddChecklistItem(ctx context.Context, log *slog.Logger, todoID, title string) (
+	entity.TodoChecklistItem, error,
+) {
+	log.Info("TodoRepo - AddChecklistItem")
+	sql, args, _ := r.Builder.Insert(todoChecklistTable).
+		Columns("todo_id", "title", "position").
+		Values(
+			todoID, title,
+			squirrel.Expr(
+				"(SELECT COALESCE(MAX(position) + 1, 0) FROM "+todoChecklistTable+" WHERE todo_id = ?)", todoID,
+			),
+		).
+		Suffix("RETURNING " + strings.Join(todoChecklistColumns, ", ")).
+		ToSql()
+
+	return scanTodoChecklistItem(r.Cluster.QueryRow(ctx, sql, args...))
+}
+
+// UpdateChecklistItem изменяет название и отметку пункта чек-листа. repoerrs.ErrNotFound - пункт не найден
+func (r *TodoRepo) UpdateChecklistItem(ctx context.Context, log *slog.Logger, item entity.TodoChecklistItem) error {
+	log.Info("TodoRepo - UpdateChecklistItem")
+	sql, args, _ := r.Builder.Update(todoChecklistTable).
+		Set("title", item.Title).
+		Set("is_done", item.IsDone).
+		Set("done_by", item.DoneBy).
+		Set("done_at", item.DoneAt).
+		Where(squirrel.Eq{"id": item.ID, "todo_id": item.TodoID}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteChecklistItem удаляет пункт чек-листа. repoerrs.ErrNotFound - пункт не найден
+func (r *TodoRepo) DeleteChecklistItem(ctx context.Context, log *slog.Logger, todoID, id string) error {
+	log.Info("TodoRepo - DeleteChecklistItem")
+	sql, args, _ := r.Builder.Delete(todoChecklistTable).
+		Where(squirrel.Eq{"id": id, "todo_id": todoID}).
+		ToSql()
+
+	tag, err := r.Cluster.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repoerrs.ErrNotFound
+	}
+	return nil
+}
+
+// ReorderChecklist задает порядок пунктов чек-листа: пункт ids[i] получает позицию i.
+// ids должен содержать все пункты чек-листа, иначе возвращается repoerrs.ErrNotFound
+func (r *TodoRepo) ReorderChecklist(ctx context.Context, log *slog.Logger, todoID string, ids []string) error {
+	log.Info("TodoRepo - ReorderChecklist")
+
+	tx, err := r.Cluster.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Блокируем чек-лист, чтобы параллельное добавление пункта не нарушило порядок
+	sql, args, _ := r.Builder.Select("id").
+		From(todoChecklistTable).
+		Where("todo_id = ?", todoID).
+		Suffix("FOR UPDATE").
+		ToSql()
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(existing) != len(ids) {
+		return repoerrs.ErrNotFound
+	}
+	for position, id := range ids {
+		if !existing[id] {
+			return repoerrs.ErrNotFound
+		}
+		delete(existing, id)
+
+		sql, args, _ = r.Builder.Update(todoChecklistTable).
+			Set("position", position).
+			Where("id = ?", id).
+			ToSql()
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
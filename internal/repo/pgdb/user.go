@@ -10,7 +10,6 @@ import (
 	"family-flow-app/internal/repo/repoerrs"
 	"family-flow-app/pkg/postgres"
 
-	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -243,17 +242,3 @@ func (u *UserRepo) UpdateLocation(ctx context.Context, userID string, latitude,
 	}
 	return nil
 }
-
-// update point
-func (u *UserRepo) UpdatePoint(ctx context.Context, userID string, point int) error {
-	sql, args, _ := u.Builder.Update(userTable).
-		Set("point", squirrel.Expr("point + ?", point)). // Используем выражение для изменения значения
-		Where("id = ?", userID).
-		ToSql()
-
-	_, err := u.Cluster.Exec(ctx, sql, args...)
-	if err != nil {
-		return fmt.Errorf("UserRepo - UpdatePoint - r.Cluster.Exec: %v", err)
-	}
-	return nil
-}
@@ -22,7 +22,6 @@ type User interface {
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	UpdateRole(ctx context.Context, email, role string) error
 	UpdateLocation(ctx context.Context, userID string, latitude, longitude float64) error
-	UpdatePoint(ctx context.Context, userID string, point int) error
 }
 
 type Family interface {
@@ -33,8 +32,8 @@ type Family interface {
 
 type ShoppingItem interface {
 	Create(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) (string, error)
-	Delete(ctx context.Context, log *slog.Logger, id string) error
-	Update(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) error
+	Delete(ctx context.Context, log *slog.Logger, id string, version int64) (entity.ShoppingItem, error)
+	Update(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) (entity.ShoppingItem, error)
 	GetPublicByFamilyID(
 		ctx context.Context, log *slog.Logger, familyID string,
 	) ([]entity.ShoppingItem, error)
@@ -42,20 +41,35 @@ type ShoppingItem interface {
 		ctx context.Context, log *slog.Logger, createdBy string,
 	) ([]entity.ShoppingItem, error)
 	UpdateReservedBy(
-		ctx context.Context, log *slog.Logger, id string, reservedBy string, updatedAt time.Time,
-	) error
+		ctx context.Context, log *slog.Logger, id string, reservedBy string, version int64, updatedAt time.Time,
+	) (entity.ShoppingItem, error)
 	UpdateBuyerId(
-		ctx context.Context, log *slog.Logger, id string, buyerId string, updatedAt time.Time,
-	) error
+		ctx context.Context, log *slog.Logger, id string, buyerId string, actualPriceMinor *int, store string,
+		version int64, updatedAt time.Time,
+	) (entity.ShoppingItem, error)
 	GetArchivedByUserID(
 		ctx context.Context, log *slog.Logger, userID string,
 	) ([]entity.ShoppingItem, error)
 	CancelUpdateReservedBy(
-		ctx context.Context, log *slog.Logger, id string, updatedAt time.Time,
-	) error
+		ctx context.Context, log *slog.Logger, id string, version int64, updatedAt time.Time,
+	) (entity.ShoppingItem, error)
 	GetByID(
 		ctx context.Context, log *slog.Logger, id string,
 	) (entity.ShoppingItem, error)
+	CreateList(ctx context.Context, log *slog.Logger, list entity.ShoppingList) (string, error)
+	GetDefaultListID(ctx context.Context, log *slog.Logger, familyID, userID string) (string, error)
+	GetListByID(ctx context.Context, log *slog.Logger, id string) (entity.ShoppingList, error)
+	GetListsByUser(
+		ctx context.Context, log *slog.Logger, userID, familyID string, archived bool,
+	) ([]entity.ShoppingList, error)
+	UpdateList(ctx context.Context, log *slog.Logger, id, title string) error
+	SetListArchived(ctx context.Context, log *slog.Logger, id string, archived bool) error
+	DeleteList(ctx context.Context, log *slog.Logger, id string) error
+	SetListShares(ctx context.Context, log *slog.Logger, listID string, userIDs []string) error
+	GetItemsByListID(ctx context.Context, log *slog.Logger, listID string) ([]entity.ShoppingItem, error)
+	GetSpend(
+		ctx context.Context, log *slog.Logger, listIDs []string, from, to time.Time,
+	) ([]entity.ShoppingSpend, error)
 }
 
 type TodosItem interface {
@@ -67,6 +81,41 @@ type TodosItem interface {
 	)
 	GetByCreatedBy(ctx context.Context, log *slog.Logger, createdBy string) ([]entity.TodoItem, error)
 	GetByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoItem, error)
+	List(ctx context.Context, log *slog.Logger, filter entity.TodoFilter) ([]entity.TodoItem, error)
+	CreateSeries(ctx context.Context, log *slog.Logger, series entity.TodoSeries, first entity.TodoItem) (string, error)
+	GetSeriesByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoSeries, error)
+	GetActiveSeriesIDs(ctx context.Context, log *slog.Logger) ([]string, error)
+	UpdateSeries(ctx context.Context, log *slog.Logger, series entity.TodoSeries) error
+	UpdateSeriesOccurrences(ctx context.Context, log *slog.Logger, series entity.TodoSeries) error
+	DeleteSeries(ctx context.Context, log *slog.Logger, id string) ([]string, error)
+	HasOpenOccurrence(ctx context.Context, log *slog.Logger, seriesID string, after time.Time) (bool, error)
+	CreateOccurrence(
+		ctx context.Context, log *slog.Logger, item entity.TodoItem, prevOccurrenceAt time.Time, rotationIndex int,
+	) (bool, error)
+	Submit(ctx context.Context, log *slog.Logger, id string, proofs []entity.TodoProof) error
+	GetProofs(ctx context.Context, log *slog.Logger, todoIDs []string) ([]entity.TodoProof, error)
+	GetForReview(ctx context.Context, log *slog.Logger, userID, familyID string) ([]entity.TodoItem, error)
+	ClaimDeadlineReminders(
+		ctx context.Context, log *slog.Logger, offset time.Duration, now time.Time, limit uint64,
+	) ([]entity.TodoItem, error)
+	MarkOverdue(ctx context.Context, log *slog.Logger, now time.Time, limit uint64) ([]entity.TodoItem, error)
+	GetChecklists(ctx context.Context, log *slog.Logger, todoIDs []string) ([]entity.TodoChecklistItem, error)
+	AddChecklistItem(ctx context.Context, log *slog.Logger, todoID, title string) (entity.TodoChecklistItem, error)
+	UpdateChecklistItem(ctx context.Context, log *slog.Logger, item entity.TodoChecklistItem) error
+	DeleteChecklistItem(ctx context.Context, log *slog.Logger, todoID, id string) error
+	ReorderChecklist(ctx context.Context, log *slog.Logger, todoID string, ids []string) error
+	Reject(ctx context.Context, log *slog.Logger, id, reviewerID, comment string) error
+	Approve(ctx context.Context, log *slog.Logger, id, reviewerID string, points int) error
+	GetByIDs(ctx context.Context, log *slog.Logger, ids []string, deleted bool) ([]entity.TodoItem, error)
+	Bulk(ctx context.Context, log *slog.Logger, op entity.TodoBulkOperation) error
+	ArchiveCompleted(ctx context.Context, log *slog.Logger, before time.Time, limit uint64) (int64, error)
+	PurgeDeleted(ctx context.Context, log *slog.Logger, before time.Time, limit uint64) (int64, error)
+	AddActivity(ctx context.Context, log *slog.Logger, activities []entity.TodoActivity) error
+	GetActivity(ctx context.Context, log *slog.Logger, todoID string) ([]entity.TodoActivity, error)
+	CreateComment(ctx context.Context, log *slog.Logger, comment entity.TodoComment) (entity.TodoComment, error)
+	GetComments(ctx context.Context, log *slog.Logger, todoID string) ([]entity.TodoComment, error)
+	GetCommentByID(ctx context.Context, log *slog.Logger, todoID, id string) (entity.TodoComment, error)
+	DeleteComment(ctx context.Context, log *slog.Logger, todoID, id string) error
 }
 
 type WishlistItem interface {
@@ -94,22 +143,56 @@ type Chat interface {
 	AddParticipant(ctx context.Context, chatID, userID string) error
 	GetParticipants(ctx context.Context, chatID string) ([]string, error)
 	GetChatsWithParticipants(ctx context.Context, userID string) ([]entity.Chat, error)
+	RemoveParticipant(ctx context.Context, chatID, userID string) error
+	GetFamilyChat(ctx context.Context, familyID string) (entity.Chat, error)
+	CreateFamilyChat(ctx context.Context, familyID, name string) (string, error)
+	GetOrCreateDirectChat(ctx context.Context, familyID, userID, otherUserID string) (string, error)
+	AddParticipantWithRole(ctx context.Context, chatID, userID, role string) error
+	GetParticipant(ctx context.Context, chatID, userID string) (entity.ChatParticipant, error)
+	UpdateParticipantRole(ctx context.Context, chatID, userID, role string) error
+	UpdateParticipantMuted(ctx context.Context, chatID, userID string, muted bool) error
+	UpdateLocked(ctx context.Context, chatID string, locked bool) error
+	GetParticipantsWithDetails(ctx context.Context, chatID string) ([]entity.ChatParticipant, error)
+	UpdateNotificationsMuted(ctx context.Context, chatID, userID string, muted bool) error
 }
 
 type Message interface {
 	Create(ctx context.Context, message entity.Message) (entity.Message, error)
 	GetByChatID(ctx context.Context, chatID string) ([]entity.Message, error)
 	GetLastMessageByChatID(ctx context.Context, chatID string) (entity.Message, error)
+	GetByID(ctx context.Context, id string) (entity.Message, error)
+	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, userID, chatID, query string, limit, offset int) ([]entity.MessageSearchResult, error)
+	GetAround(ctx context.Context, chatID, messageID string, limit int) ([]entity.Message, error)
 }
 
 type Rewards interface {
 	Create(ctx context.Context, reward entity.Reward) (string, error)
 	GetByFamilyID(ctx context.Context, familyID string) ([]entity.Reward, error)
-	AddPoints(ctx context.Context, userID string, points int) error
-	SubtractPoints(ctx context.Context, userID string, points int) error
+	ApplyPoints(ctx context.Context, entry entity.PointTransaction) (entity.PointTransaction, error)
+	GetPointTransactions(ctx context.Context, userID, afterID string, limit uint64) ([]entity.PointTransaction, error)
+	GetPointTransactionByID(ctx context.Context, id string) (entity.PointTransaction, error)
 	GetPoints(ctx context.Context, userID string) (int, error)
-	Redeem(ctx context.Context, userID, rewardID string) error
+	Redeem(
+		ctx context.Context, userID, rewardID string, check func(reward entity.Reward, redeemedAt []time.Time) error,
+	) (string, error)
 	GetRedemptionsByUserID(ctx context.Context, userID string) ([]entity.RewardRedemption, error)
+	GetRedemptionsByFamilyID(ctx context.Context, familyID string, statuses []string) ([]entity.RewardRedemption, error)
+	GetRedemptionByID(ctx context.Context, id string) (entity.RewardRedemption, error)
+	TransitionRedemption(ctx context.Context, t entity.RedemptionTransition) error
+	CreateSavingsGoal(ctx context.Context, userID, rewardID string) (string, error)
+	GetSavingsGoalsByUserID(ctx context.Context, userID string) ([]entity.SavingsGoal, error)
+	GetSavingsGoalByID(ctx context.Context, id string) (entity.SavingsGoal, error)
+	MoveGoalPoints(ctx context.Context, goalID string, points int) error
+	DeleteSavingsGoal(ctx context.Context, id string) error
+	RedeemSavingsGoal(
+		ctx context.Context, goalID string, check func(reward entity.Reward, redeemedAt []time.Time) error,
+	) (string, error)
+	GetAllowanceSettings(ctx context.Context, familyID string) (entity.AllowanceSettings, error)
+	SaveAllowanceSettings(ctx context.Context, settings entity.AllowanceSettings) error
+	GetEarnedPoints(ctx context.Context, userIDs []string, from, to time.Time) (map[string]int, error)
+	GetLeaderboard(ctx context.Context, familyID string, from, to time.Time) ([]entity.LeaderboardEntry, error)
+	ClaimDigestFamilies(ctx context.Context, weekStart time.Time) ([]string, error)
 	GetByID(ctx context.Context, id string) (entity.Reward, error)
 	Update(ctx context.Context, reward entity.Reward) error
 	Delete(ctx context.Context, id string) error
@@ -127,6 +210,29 @@ type NotificationToken interface {
 	GetByUserID(ctx context.Context, log *slog.Logger, userID string) (*entity.NotificationToken, error)
 }
 
+type Absence interface {
+	Create(ctx context.Context, log *slog.Logger, absence entity.UserAbsence) (entity.UserAbsence, error)
+	GetByID(ctx context.Context, log *slog.Logger, id string) (entity.UserAbsence, error)
+	GetByUserIDs(ctx context.Context, log *slog.Logger, userIDs []string, from time.Time) ([]entity.UserAbsence, error)
+	Delete(ctx context.Context, log *slog.Logger, id string) error
+}
+
+type Calendar interface {
+	GetCalendarToken(ctx context.Context, log *slog.Logger, userID string) (string, error)
+	SaveCalendarToken(ctx context.Context, log *slog.Logger, userID, token string) error
+	GetUserIDByCalendarToken(ctx context.Context, log *slog.Logger, token string) (string, error)
+}
+
+type Achievements interface {
+	GetRules(ctx context.Context, log *slog.Logger, familyID string) ([]entity.AchievementRule, error)
+	SaveRule(ctx context.Context, log *slog.Logger, rule entity.AchievementRule) (entity.AchievementRule, error)
+	DeleteRule(ctx context.Context, log *slog.Logger, familyID, code string) error
+	GetUserAchievements(ctx context.Context, log *slog.Logger, userID string) ([]entity.UserAchievement, error)
+	Award(ctx context.Context, log *slog.Logger, userID string, rule entity.AchievementRule) (bool, error)
+	GetStats(ctx context.Context, log *slog.Logger, userID string) (entity.AchievementStats, error)
+	GetApprovalDays(ctx context.Context, log *slog.Logger, userID string) ([]time.Time, error)
+}
+
 type Repositories struct {
 	User
 	Family
@@ -139,6 +245,9 @@ type Repositories struct {
 	Rewards
 	Diary
 	NotificationToken
+	Absence
+	Calendar
+	Achievements
 }
 
 func NewRepositories(db *postgres.Database) *Repositories {
@@ -154,5 +263,8 @@ func NewRepositories(db *postgres.Database) *Repositories {
 		Rewards:           pgdb.NewRewardsRepo(db),
 		Diary:             pgdb.NewDiaryRepo(db),
 		NotificationToken: pgdb.NewNotificationTokenRepo(db),
+		Absence:           pgdb.NewAbsenceRepo(db),
+		Calendar:          pgdb.NewCalendarRepo(db),
+		Achievements:      pgdb.NewAchievementsRepo(db),
 	}
 }
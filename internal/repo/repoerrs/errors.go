@@ -7,4 +7,10 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 
 	ErrForbidden = errors.New("forbidden")
+
+	// ErrNegativeBalance - операция сделала бы баланс баллов отрицательным
+	ErrNegativeBalance = errors.New("negative balance")
+
+	// ErrVersionConflict - запись изменилась после того, как клиент получил ее версию
+	ErrVersionConflict = errors.New("version conflict")
 )
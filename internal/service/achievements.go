@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// achievementTriggers - показатели, правила по которым проверяются после события
+var achievementTriggers = map[string][]string{
+	entity.AchievementEventTodoApproved:   {entity.AchievementEventTodoApproved, entity.AchievementEventTodoStreak},
+	entity.AchievementEventWishFulfilled:  {entity.AchievementEventWishFulfilled},
+	entity.AchievementEventRewardRedeemed: {entity.AchievementEventRewardRedeemed},
+}
+
+type AchievementService struct {
+	achievementsRepo repo.Achievements
+	userRepo         repo.User
+	notification     Notification
+}
+
+func NewAchievementService(
+	achievementsRepo repo.Achievements, userRepo repo.User, notification Notification,
+) *AchievementService {
+	return &AchievementService{
+		achievementsRepo: achievementsRepo,
+		userRepo:         userRepo,
+		notification:     notification,
+	}
+}
+
+// AchievementRuleInput - правило достижения семьи родителя
+type AchievementRuleInput struct {
+	UserID      string
+	Code        string
+	Title       string
+	Description string
+	Event       string
+	Threshold   int
+	BonusPoints int
+	Enabled     bool
+}
+
+// Evaluate проверяет после события event правила семьи пользователя, зависящие от него, выдает
+// достигнутые достижения с бонусными баллами и уведомляет о них. Ошибки только логируются:
+// достижения не должны мешать действию, которое их вызвало
+func (s *AchievementService) Evaluate(ctx context.Context, log *slog.Logger, userID, event string) {
+	log.Info("Service - AchievementService - Evaluate", "user_id", userID, "event", event)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		log.Error("Service - AchievementService - Evaluate - GetByID", "error", err)
+		return
+	}
+	rules, err := s.achievementsRepo.GetRules(ctx, log, user.FamilyId.String)
+	if err != nil {
+		log.Error("Service - AchievementService - Evaluate - GetRules", "error", err)
+		return
+	}
+	earned, err := s.achievementsRepo.GetUserAchievements(ctx, log, userID)
+	if err != nil {
+		log.Error("Service - AchievementService - Evaluate - GetUserAchievements", "error", err)
+		return
+	}
+	awarded := make(map[string]bool, len(earned))
+	for _, achievement := range earned {
+		awarded[achievement.Code] = true
+	}
+
+	triggered := make(map[string]bool)
+	for _, trigger := range achievementTriggers[event] {
+		triggered[trigger] = true
+	}
+	var pending []entity.AchievementRule
+	for _, rule := range rules {
+		if rule.Enabled && triggered[rule.Event] && !awarded[rule.Code] {
+			pending = append(pending, rule)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	stats, err := s.stats(ctx, log, userID)
+	if err != nil {
+		log.Error("Service - AchievementService - Evaluate - stats", "error", err)
+		return
+	}
+	for _, rule := range pending {
+		if stats.Value(rule.Event) < rule.Threshold {
+			continue
+		}
+		ok, err := s.achievementsRepo.Award(ctx, log, userID, rule)
+		if err != nil {
+			log.Error("Service - AchievementService - Evaluate - Award", "code", rule.Code, "error", err)
+			continue
+		}
+		if ok {
+			s.sendAchievementNotification(ctx, log, userID, rule)
+		}
+	}
+}
+
+// GetUserAchievements возвращает полученные достижения пользователя и прогресс к остальным достижениям
+// его семьи. Посмотреть достижения можно свои и членов своей семьи
+func (s *AchievementService) GetUserAchievements(ctx context.Context, log *slog.Logger, viewerID, userID string) (
+	entity.UserAchievements, error,
+) {
+	log.Info("Service - AchievementService - GetUserAchievements", "user_id", userID)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.UserAchievements{}, ErrUserNotFound
+	}
+	if viewerID != userID {
+		viewer, err := s.userRepo.GetByID(ctx, viewerID)
+		if err != nil {
+			return entity.UserAchievements{}, ErrUserNotFound
+		}
+		if !viewer.FamilyId.Valid || viewer.FamilyId != user.FamilyId {
+			return entity.UserAchievements{}, ErrForbidden
+		}
+	}
+
+	rules, err := s.achievementsRepo.GetRules(ctx, log, user.FamilyId.String)
+	if err != nil {
+		log.Error("Service - AchievementService - GetUserAchievements - GetRules", "error", err)
+		return entity.UserAchievements{}, fmt.Errorf("failed to get achievement rules: %w", err)
+	}
+	earned, err := s.achievementsRepo.GetUserAchievements(ctx, log, userID)
+	if err != nil {
+		log.Error("Service - AchievementService - GetUserAchievements", "error", err)
+		return entity.UserAchievements{}, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	stats, err := s.stats(ctx, log, userID)
+	if err != nil {
+		log.Error("Service - AchievementService - GetUserAchievements - stats", "error", err)
+		return entity.UserAchievements{}, fmt.Errorf("failed to get achievement stats: %w", err)
+	}
+
+	byCode := make(map[string]entity.AchievementRule, len(rules))
+	for _, rule := range rules {
+		byCode[rule.Code] = rule
+	}
+
+	result := entity.UserAchievements{
+		UserID: userID,
+		Stats:  stats,
+		Items:  make([]entity.AchievementProgress, 0, len(rules)+len(earned)),
+	}
+	awarded := make(map[string]bool, len(earned))
+	for _, achievement := range earned {
+		awarded[achievement.Code] = true
+		awardedAt := achievement.AwardedAt
+		rule := byCode[achievement.Code]
+		result.Items = append(
+			result.Items, entity.AchievementProgress{
+				Code:        achievement.Code,
+				Title:       achievement.Title,
+				Description: achievement.Description,
+				Event:       rule.Event,
+				Threshold:   rule.Threshold,
+				BonusPoints: achievement.BonusPoints,
+				Current:     rule.Threshold,
+				Earned:      true,
+				AwardedAt:   &awardedAt,
+			},
+		)
+	}
+
+	var locked []entity.AchievementProgress
+	for _, rule := range rules {
+		if !rule.Enabled || awarded[rule.Code] {
+			continue
+		}
+		locked = append(
+			locked, entity.AchievementProgress{
+				Code:        rule.Code,
+				Title:       rule.Title,
+				Description: rule.Description,
+				Event:       rule.Event,
+				Threshold:   rule.Threshold,
+				BonusPoints: rule.BonusPoints,
+				Current:     min(stats.Value(rule.Event), rule.Threshold),
+			},
+		)
+	}
+	sort.SliceStable(
+		locked, func(i, j int) bool {
+			if locked[i].Event != locked[j].Event {
+				return locked[i].Event < locked[j].Event
+			}
+			return locked[i].Threshold < locked[j].Threshold
+		},
+	)
+	result.Items = append(result.Items, locked...)
+	return result, nil
+}
+
+// GetRules возвращает действующие правила достижений семьи пользователя, включая выключенные
+func (s *AchievementService) GetRules(ctx context.Context, log *slog.Logger, userID string) (
+	[]entity.AchievementRule, error,
+) {
+	log.Info("Service - AchievementService - GetRules", "user_id", userID)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	rules, err := s.achievementsRepo.GetRules(ctx, log, user.FamilyId.String)
+	if err != nil {
+		log.Error("Service - AchievementService - GetRules", "error", err)
+		return nil, fmt.Errorf("failed to get achievement rules: %w", err)
+	}
+	if rules == nil {
+		rules = []entity.AchievementRule{}
+	}
+	return rules, nil
+}
+
+// SaveRule создает правило семьи или заменяет им правило по умолчанию с тем же кодом. Доступно родителю.
+// Уже выданные достижения при изменении правила не отзываются
+func (s *AchievementService) SaveRule(ctx context.Context, log *slog.Logger, input AchievementRuleInput) (
+	entity.AchievementRule, error,
+) {
+	log.Info("Service - AchievementService - SaveRule", "code", input.Code)
+
+	familyID, err := s.parentFamily(ctx, input.UserID)
+	if err != nil {
+		return entity.AchievementRule{}, err
+	}
+
+	rule, err := s.achievementsRepo.SaveRule(
+		ctx, log, entity.AchievementRule{
+			FamilyID:    familyID,
+			Code:        input.Code,
+			Title:       input.Title,
+			Description: input.Description,
+			Event:       input.Event,
+			Threshold:   input.Threshold,
+			BonusPoints: input.BonusPoints,
+			Enabled:     input.Enabled,
+		},
+	)
+	if err != nil {
+		log.Error("Service - AchievementService - SaveRule", "error", err)
+		return entity.AchievementRule{}, fmt.Errorf("failed to save achievement rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule удаляет правило семьи: снова действует правило по умолчанию с тем же кодом, если оно есть.
+// Доступно родителю
+func (s *AchievementService) DeleteRule(ctx context.Context, log *slog.Logger, userID, code string) error {
+	log.Info("Service - AchievementService - DeleteRule", "code", code)
+
+	familyID, err := s.parentFamily(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.achievementsRepo.DeleteRule(ctx, log, familyID, code); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return ErrAchievementRuleNotFound
+		}
+		log.Error("Service - AchievementService - DeleteRule", "error", err)
+		return fmt.Errorf("failed to delete achievement rule: %w", err)
+	}
+	return nil
+}
+
+// parentFamily возвращает семью пользователя, если он в ней родитель
+func (s *AchievementService) parentFamily(ctx context.Context, userID string) (string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid {
+		return "", ErrForbidden
+	}
+	return user.FamilyId.String, nil
+}
+
+// stats возвращает показатели пользователя вместе с текущей серией дней
+func (s *AchievementService) stats(ctx context.Context, log *slog.Logger, userID string) (
+	entity.AchievementStats, error,
+) {
+	stats, err := s.achievementsRepo.GetStats(ctx, log, userID)
+	if err != nil {
+		return entity.AchievementStats{}, err
+	}
+	days, err := s.achievementsRepo.GetApprovalDays(ctx, log, userID)
+	if err != nil {
+		return entity.AchievementStats{}, err
+	}
+	stats.StreakDays = currentStreak(days, time.Now())
+	return stats, nil
+}
+
+// sendAchievementNotification уведомляет пользователя о новом достижении
+func (s *AchievementService) sendAchievementNotification(
+	ctx context.Context, log *slog.Logger, userID string, rule entity.AchievementRule,
+) {
+	data, _ := json.Marshal(map[string]string{"type": "achievement_awarded", "code": rule.Code})
+
+	body := fmt.Sprintf("Получено достижение '%s'", rule.Title)
+	if rule.BonusPoints > 0 {
+		body += fmt.Sprintf(", начислено баллов: %d", rule.BonusPoints)
+	}
+	err := s.notification.SendNotification(
+		ctx, log, NotificationCreateInput{
+			UserID:      userID,
+			Title:       "Новое достижение",
+			Body:        body,
+			Data:        string(data),
+			CollapseKey: "achievement_" + rule.Code,
+		},
+	)
+	if err != nil {
+		log.Error(
+			"Service - AchievementService - sendAchievementNotification", "user_id", userID, "code", rule.Code,
+			"error", err,
+		)
+	}
+}
+
+// currentStreak возвращает число дней подряд с одобренными заданиями по дням от новых к старым.
+// Серия не прерывается, пока не закончился день после последнего одобрения
+func currentStreak(days []time.Time, now time.Time) int {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if len(days) == 0 || days[0].Before(today.AddDate(0, 0, -1)) {
+		return 0
+	}
+
+	streak := 1
+	for i := 1; i < len(days); i++ {
+		if !days[i].Equal(days[i-1].AddDate(0, 0, -1)) {
+			break
+		}
+		streak++
+	}
+	return streak
+}
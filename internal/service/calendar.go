@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo"
+	"family-flow-app/internal/repo/repoerrs"
+	"family-flow-app/pkg/ical"
+)
+
+const (
+	// maxCalendarRange - максимальный период, за который можно запросить события
+	maxCalendarRange = 366 * 24 * time.Hour
+	// calendarMaxTodos - сколько заданий с ближайшими сроками попадает в календарь
+	calendarMaxTodos = 1000
+	// calendarFeedPast и calendarFeedFuture - период заданий и отсутствий в ленте подписки относительно текущего времени
+	calendarFeedPast   = 30 * 24 * time.Hour
+	calendarFeedFuture = 365 * 24 * time.Hour
+	calendarTokenBytes = 32
+	// calendarUIDDomain - правая часть UID событий, делает их уникальными среди других календарей
+	calendarUIDDomain = "family-flow-app"
+	calendarProdID    = "-//Family Flow//Calendar//RU"
+)
+
+type CalendarService struct {
+	calendarRepo repo.Calendar
+	todoRepo     repo.TodosItem
+	userRepo     repo.User
+	absenceRepo  repo.Absence
+}
+
+func NewCalendarService(
+	calendarRepo repo.Calendar, todoRepo repo.TodosItem, userRepo repo.User, absenceRepo repo.Absence,
+) *CalendarService {
+	return &CalendarService{
+		calendarRepo: calendarRepo,
+		todoRepo:     todoRepo,
+		userRepo:     userRepo,
+		absenceRepo:  absenceRepo,
+	}
+}
+
+// CalendarInput - период календаря пользователя [From, To)
+type CalendarInput struct {
+	UserID string
+	From   time.Time
+	To     time.Time
+}
+
+// GetEvents возвращает события за период по порядку: сроки видимых пользователю заданий, дни рождения
+// и отсутствия членов семьи. Задания видны так же, как в списке заданий
+func (s *CalendarService) GetEvents(ctx context.Context, log *slog.Logger, input CalendarInput) (
+	[]entity.CalendarEvent, error,
+) {
+	log.Info("Service - CalendarService - GetEvents")
+
+	if !input.To.After(input.From) || input.To.Sub(input.From) > maxCalendarRange {
+		return nil, ErrInvalidCalendarRange
+	}
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	members, err := s.members(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.todoAndAbsenceEvents(ctx, log, user, members, input.From, input.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		events = append(events, birthdayEvents(member, input.From, input.To)...)
+	}
+
+	sortCalendarEvents(events)
+	return events, nil
+}
+
+// GetSubscription возвращает токен подписки пользователя на календарь, выпуская его при первом запросе
+func (s *CalendarService) GetSubscription(ctx context.Context, log *slog.Logger, userID string) (string, error) {
+	log.Info("Service - CalendarService - GetSubscription")
+
+	token, err := s.calendarRepo.GetCalendarToken(ctx, log, userID)
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, repoerrs.ErrNotFound) {
+		log.Error("Service - CalendarService - GetSubscription", "error", err)
+		return "", fmt.Errorf("failed to get calendar token: %w", err)
+	}
+	return s.ResetSubscription(ctx, log, userID)
+}
+
+// ResetSubscription выпускает новый токен подписки. Старая ссылка на календарь перестает работать
+func (s *CalendarService) ResetSubscription(ctx context.Context, log *slog.Logger, userID string) (string, error) {
+	log.Info("Service - CalendarService - ResetSubscription")
+
+	raw := make([]byte, calendarTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := s.calendarRepo.SaveCalendarToken(ctx, log, userID, token); err != nil {
+		log.Error("Service - CalendarService - ResetSubscription", "error", err)
+		return "", fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return token, nil
+}
+
+// GetFeed возвращает календарь владельца токена в формате iCalendar: задания и отсутствия за период
+// от calendarFeedPast назад до calendarFeedFuture вперед и ежегодно повторяющиеся дни рождения
+func (s *CalendarService) GetFeed(ctx context.Context, log *slog.Logger, token string) (string, error) {
+	log.Info("Service - CalendarService - GetFeed")
+
+	userID, err := s.calendarRepo.GetUserIDByCalendarToken(ctx, log, token)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return "", ErrCalendarTokenNotFound
+		}
+		log.Error("Service - CalendarService - GetFeed", "error", err)
+		return "", fmt.Errorf("failed to get calendar token: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	members, err := s.members(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	from, to := now.Add(-calendarFeedPast), now.Add(calendarFeedFuture)
+	events, err := s.todoAndAbsenceEvents(ctx, log, user, members, from, to)
+	if err != nil {
+		return "", err
+	}
+	sortCalendarEvents(events)
+
+	calendar := ical.Calendar{ProdID: calendarProdID, Name: "Family Flow", Stamp: now}
+	for _, event := range events {
+		calendar.Events = append(calendar.Events, icalEvent(event))
+	}
+	for _, member := range members {
+		if !member.BirthDate.Valid {
+			continue
+		}
+		birthDate := member.BirthDate.Time
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:        calendarUID(entity.CalendarEventBirthday, member.Id),
+			Summary:    birthdayTitle(member),
+			Categories: []string{entity.CalendarEventBirthday},
+			Start:      birthDate,
+			End:        birthDate.AddDate(0, 0, 1),
+			AllDay:     true,
+			RRule:      "FREQ=YEARLY",
+		})
+	}
+
+	return calendar.String(), nil
+}
+
+// members возвращает членов семьи пользователя, а если семьи нет - только его самого
+func (s *CalendarService) members(ctx context.Context, user entity.User) ([]entity.User, error) {
+	if !user.FamilyId.Valid {
+		return []entity.User{user}, nil
+	}
+
+	members, err := s.userRepo.GetByFamilyID(ctx, user.FamilyId.String)
+	if err != nil {
+		return nil, ErrCannotGetFamilyMembers
+	}
+	return members, nil
+}
+
+// todoAndAbsenceEvents возвращает сроки заданий и отсутствия членов семьи, пересекающиеся с периодом [from, to)
+func (s *CalendarService) todoAndAbsenceEvents(
+	ctx context.Context, log *slog.Logger, user entity.User, members []entity.User, from, to time.Time,
+) ([]entity.CalendarEvent, error) {
+	filter := entity.TodoFilter{
+		DueAfter:  &from,
+		DueBefore: &to,
+		Sort:      entity.TodoSortDeadline,
+		Limit:     calendarMaxTodos,
+	}
+	if user.Role == "Parent" && user.FamilyId.Valid {
+		filter.FamilyID = user.FamilyId.String
+	} else {
+		filter.VisibleTo = user.Id
+	}
+
+	items, err := s.todoRepo.List(ctx, log, filter)
+	if err != nil {
+		log.Error("Service - CalendarService - todoAndAbsenceEvents - List", "error", err)
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	events := make([]entity.CalendarEvent, 0, len(items))
+	for _, item := range items {
+		events = append(events, entity.CalendarEvent{
+			UID:         calendarUID(entity.CalendarEventTodo, item.ID),
+			Type:        entity.CalendarEventTodo,
+			Title:       item.Title,
+			Description: item.Description,
+			StartsAt:    item.Deadline,
+			SourceID:    item.ID,
+			UserID:      item.AssignedTo,
+			Status:      item.Status,
+		})
+	}
+
+	names := make(map[string]string, len(members))
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		names[member.Id] = member.Name
+		ids = append(ids, member.Id)
+	}
+
+	absences, err := s.absenceRepo.GetByUserIDs(ctx, log, ids, from)
+	if err != nil {
+		log.Error("Service - CalendarService - todoAndAbsenceEvents - GetByUserIDs", "error", err)
+		return nil, fmt.Errorf("failed to get absences: %w", err)
+	}
+	for _, absence := range absences {
+		if !absence.StartsAt.Before(to) {
+			continue
+		}
+		endsAt := absence.EndsAt
+		events = append(events, entity.CalendarEvent{
+			UID:      calendarUID(entity.CalendarEventAbsence, absence.ID),
+			Type:     entity.CalendarEventAbsence,
+			Title:    "Отсутствует: " + names[absence.UserID],
+			StartsAt: absence.StartsAt,
+			EndsAt:   &endsAt,
+			SourceID: absence.ID,
+			UserID:   absence.UserID,
+		})
+	}
+
+	return events, nil
+}
+
+// birthdayEvents возвращает дни рождения участника, приходящиеся на период [from, to).
+// День рождения 29 февраля в невисокосные годы пропускается, как и в ежегодном правиле iCalendar
+func birthdayEvents(member entity.User, from, to time.Time) []entity.CalendarEvent {
+	if !member.BirthDate.Valid {
+		return nil
+	}
+
+	birthDate := member.BirthDate.Time
+	var events []entity.CalendarEvent
+	for year := from.Year(); year <= to.Year(); year++ {
+		day := time.Date(year, birthDate.Month(), birthDate.Day(), 0, 0, 0, 0, time.UTC)
+		if day.Month() != birthDate.Month() || day.Year() < birthDate.Year() {
+			continue
+		}
+		next := day.AddDate(0, 0, 1)
+		if !day.Before(to) || !next.After(from) {
+			continue
+		}
+		events = append(events, entity.CalendarEvent{
+			UID:      calendarUID(entity.CalendarEventBirthday, member.Id),
+			Type:     entity.CalendarEventBirthday,
+			Title:    birthdayTitle(member),
+			StartsAt: day,
+			EndsAt:   &next,
+			AllDay:   true,
+			SourceID: member.Id,
+			UserID:   member.Id,
+		})
+	}
+	return events
+}
+
+func birthdayTitle(member entity.User) string {
+	return "День рождения: " + member.Name
+}
+
+// icalEvent переводит событие календаря в событие iCalendar
+func icalEvent(event entity.CalendarEvent) ical.Event {
+	result := ical.Event{
+		UID:         event.UID,
+		Summary:     event.Title,
+		Description: event.Description,
+		Categories:  []string{event.Type},
+		Start:       event.StartsAt,
+		AllDay:      event.AllDay,
+	}
+	if event.EndsAt != nil {
+		result.End = *event.EndsAt
+	}
+	if event.Type == entity.CalendarEventTodo && event.Status == entity.TodoStatusApproved {
+		result.Summary = "✓ " + result.Summary
+	}
+	return result
+}
+
+// calendarUID строит постоянный UID события из его типа и ID источника
+func calendarUID(eventType, sourceID string) string {
+	return eventType + "-" + sourceID + "@" + calendarUIDDomain
+}
+
+func sortCalendarEvents(events []entity.CalendarEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt) {
+			return events[i].StartsAt.Before(events[j].StartsAt)
+		}
+		return events[i].UID < events[j].UID
+	})
+}
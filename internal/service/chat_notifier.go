@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+// Состояния WebSocket-соединения пользователя
+const (
+	ChatPresenceActive     = "active"
+	ChatPresenceBackground = "background"
+	ChatPresenceOffline    = "offline"
+)
+
+const (
+	// chatPushDelay - окно, в течение которого сообщения одного чата собираются в одно уведомление
+	chatPushDelay = 5 * time.Second
+	// chatPushPreviewLen - максимальная длина превью сообщения в уведомлении (в символах)
+	chatPushPreviewLen = 100
+)
+
+// chatPresence хранит состояние WebSocket-соединений пользователей.
+// Пользователь считается онлайн, если хотя бы одно его соединение активно (не в фоне).
+// Состояние живет в памяти процесса: соединение с другой репликой API здесь не видно, и такой пользователь
+// получает пуш как офлайн. Поэтому API рассчитан на запуск в одном экземпляре, см. config.HTTP
+type chatPresence struct {
+	mu    sync.Mutex
+	conns map[string]map[string]bool // userID -> connID -> active
+}
+
+func newChatPresence() *chatPresence {
+	return &chatPresence{conns: make(map[string]map[string]bool)}
+}
+
+func (p *chatPresence) set(userID, connID, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if state == ChatPresenceOffline {
+		delete(p.conns[userID], connID)
+		if len(p.conns[userID]) == 0 {
+			delete(p.conns, userID)
+		}
+		return
+	}
+
+	if p.conns[userID] == nil {
+		p.conns[userID] = make(map[string]bool)
+	}
+	p.conns[userID][connID] = state == ChatPresenceActive
+}
+
+func (p *chatPresence) isActive(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, active := range p.conns[userID] {
+		if active {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingChatPush - накопленные, но еще не отправленные сообщения чата для одного получателя
+type pendingChatPush struct {
+	userID     string
+	chat       entity.Chat
+	count      int
+	senderName string
+	preview    string
+	messageID  string
+	timer      *time.Timer
+}
+
+// chatPushNotifier отправляет пуш-уведомления о новых сообщениях пользователям, которые
+// не подключены к чату. Сообщения, пришедшие в течение chatPushDelay, объединяются в одно уведомление
+type chatPushNotifier struct {
+	ctx          context.Context
+	notification Notification
+	presence     *chatPresence
+
+	mu      sync.Mutex
+	pending map[string]*pendingChatPush
+}
+
+func newChatPushNotifier(ctx context.Context, notification Notification, presence *chatPresence) *chatPushNotifier {
+	return &chatPushNotifier{
+		ctx:          ctx,
+		notification: notification,
+		presence:     presence,
+		pending:      make(map[string]*pendingChatPush),
+	}
+}
+
+func chatPushKey(userID, chatID string) string {
+	return userID + ":" + chatID
+}
+
+// enqueue добавляет сообщение в очередь уведомлений получателя
+func (n *chatPushNotifier) enqueue(
+	log *slog.Logger, userID string, chat entity.Chat, message entity.Message, senderName string,
+) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := chatPushKey(userID, chat.ID)
+	push, ok := n.pending[key]
+	if !ok {
+		push = &pendingChatPush{userID: userID, chat: chat}
+		n.pending[key] = push
+		push.timer = time.AfterFunc(chatPushDelay, func() { n.flush(log, key) })
+	}
+
+	push.count++
+	push.senderName = senderName
+	push.preview = messagePreview(message.Content)
+	push.messageID = message.ID
+}
+
+// cancelUser отменяет отложенные уведомления пользователя, который вернулся в приложение
+func (n *chatPushNotifier) cancelUser(userID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for key, push := range n.pending {
+		if push.userID == userID {
+			push.timer.Stop()
+			delete(n.pending, key)
+		}
+	}
+}
+
+func (n *chatPushNotifier) flush(log *slog.Logger, key string) {
+	n.mu.Lock()
+	push, ok := n.pending[key]
+	delete(n.pending, key)
+	n.mu.Unlock()
+
+	if !ok || n.presence.isActive(push.userID) {
+		return
+	}
+
+	data, _ := json.Marshal(
+		map[string]interface{}{
+			"type":       "chat_message",
+			"chat_id":    push.chat.ID,
+			"message_id": push.messageID,
+			"count":      push.count,
+		},
+	)
+
+	title := push.chat.Name
+	body := fmt.Sprintf("%s: %s", push.senderName, push.preview)
+	if push.chat.Type == entity.ChatTypeDirect {
+		title = push.senderName
+		body = push.preview
+	}
+	if push.count > 1 {
+		body = fmt.Sprintf("Новых сообщений: %d. %s", push.count, body)
+	}
+
+	err := n.notification.SendNotification(
+		n.ctx, log, NotificationCreateInput{
+			UserID:      push.userID,
+			Title:       title,
+			Body:        body,
+			Data:        string(data),
+			CollapseKey: "chat_" + push.chat.ID,
+		},
+	)
+	if err != nil {
+		log.Error(
+			"Service - chatPushNotifier - flush - SendNotification", "user_id", push.userID, "chat_id",
+			push.chat.ID, "error", err,
+		)
+	}
+}
+
+// messagePreview обрезает текст сообщения до chatPushPreviewLen символов
+func messagePreview(content string) string {
+	runes := []rune(content)
+	if len(runes) <= chatPushPreviewLen {
+		return content
+	}
+	return string(runes[:chatPushPreviewLen]) + "…"
+}
@@ -2,22 +2,38 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"family-flow-app/internal/entity"
 	"family-flow-app/internal/repo"
+	"family-flow-app/internal/repo/repoerrs"
 )
 
+// familyChatName название, с которым создается семейный чат
+const familyChatName = "Семейный чат"
+
 type ChatMessageService struct {
 	chatsRepo    repo.Chat
 	messagesRepo repo.Message
+	userRepo     repo.User
+	presence     *chatPresence
+	notifier     *chatPushNotifier
 }
 
-func NewChatMessageService(chatsRepo repo.Chat, messagesRepo repo.Message) *ChatMessageService {
+func NewChatMessageService(
+	ctx context.Context, chatsRepo repo.Chat, messagesRepo repo.Message, userRepo repo.User,
+	notification Notification,
+) *ChatMessageService {
+	presence := newChatPresence()
 	return &ChatMessageService{
 		chatsRepo:    chatsRepo,
 		messagesRepo: messagesRepo,
+		userRepo:     userRepo,
+		presence:     presence,
+		notifier:     newChatPushNotifier(ctx, notification, presence),
 	}
 }
 
@@ -28,14 +44,17 @@ type CreateChatInput struct {
 
 // Input для добавления участника в чат
 type AddParticipantInput struct {
-	ChatID string
-	UserID string
+	ChatID  string
+	UserID  string
+	ActorID string
 }
 
 // Input для создания чата с участниками
 type CreateChatWithParticipantsInput struct {
 	Name         string
 	Participants []string
+	OwnerID      string
+	FamilyID     string
 }
 
 // Input для создания сообщения
@@ -67,7 +86,16 @@ func (s *ChatMessageService) CreateChat(ctx context.Context, log *slog.Logger, i
 func (s *ChatMessageService) AddParticipant(ctx context.Context, log *slog.Logger, input AddParticipantInput) error {
 	log.Info("Service - ChatMessageService - AddParticipant")
 
-	err := s.chatsRepo.AddParticipant(ctx, input.ChatID, input.UserID)
+	chat, err := s.checkModerator(ctx, input.ChatID, input.ActorID)
+	if err != nil {
+		log.Error(fmt.Sprintf("Service - ChatMessageService - AddParticipant - checkModerator: %v", err))
+		return err
+	}
+	if chat.Type == entity.ChatTypeFamily {
+		return ErrFamilyChatMembership
+	}
+
+	err = s.chatsRepo.AddParticipant(ctx, input.ChatID, input.UserID)
 	if err != nil {
 		log.Error(fmt.Sprintf("Service - ChatMessageService - AddParticipant: %v", err))
 		return fmt.Errorf("failed to add participant to chat: %w", err)
@@ -91,7 +119,8 @@ func (s *ChatMessageService) CreateChatWithParticipants(
 
 	// Создание чата
 	chat := entity.Chat{
-		Name: input.Name,
+		Name:     input.Name,
+		FamilyID: sql.NullString{String: input.FamilyID, Valid: input.FamilyID != ""},
 	}
 
 	chatID, err := s.chatsRepo.Create(ctx, chat)
@@ -100,6 +129,20 @@ func (s *ChatMessageService) CreateChatWithParticipants(
 		return "", fmt.Errorf("failed to create chat: %w", err)
 	}
 
+	// Создатель чата становится его владельцем
+	if input.OwnerID != "" {
+		err = s.chatsRepo.AddParticipantWithRole(ctx, chatID, input.OwnerID, entity.ChatRoleOwner)
+		if err != nil {
+			log.Error(
+				fmt.Sprintf(
+					"Service - ChatMessageService - CreateChatWithParticipants - AddOwner: %v",
+					err,
+				),
+			)
+			return "", fmt.Errorf("failed to add owner to chat: %w", err)
+		}
+	}
+
 	// Добавление участников
 	for _, userID := range input.Participants {
 		err := s.chatsRepo.AddParticipant(ctx, chatID, userID)
@@ -124,6 +167,11 @@ func (s *ChatMessageService) CreateMessage(ctx context.Context, log *slog.Logger
 ) {
 	log.Info("Service - ChatMessageService - CreateMessage")
 
+	if err := s.checkCanWrite(ctx, input.ChatID, input.SenderID); err != nil {
+		log.Error(fmt.Sprintf("Service - ChatMessageService - CreateMessage - checkCanWrite: %v", err))
+		return entity.Message{}, err
+	}
+
 	message := entity.Message{
 		ChatID:   input.ChatID,
 		SenderID: input.SenderID,
@@ -137,9 +185,72 @@ func (s *ChatMessageService) CreateMessage(ctx context.Context, log *slog.Logger
 	}
 
 	log.Info(fmt.Sprintf("Service - ChatMessageService - CreateMessage - entity.Message: %s", output))
+	s.notifyOfflineParticipants(ctx, log, output)
 	return output, nil
 }
 
+// notifyOfflineParticipants ставит в очередь пуш-уведомления для участников чата, у которых нет
+// активного WebSocket-соединения и не отключены уведомления для этого чата
+func (s *ChatMessageService) notifyOfflineParticipants(
+	ctx context.Context, log *slog.Logger, message entity.Message,
+) {
+	chat, err := s.getChat(ctx, message.ChatID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - notifyOfflineParticipants - getChat", "error", err)
+		return
+	}
+
+	participants, err := s.chatsRepo.GetParticipantsWithDetails(ctx, message.ChatID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - notifyOfflineParticipants - GetParticipants", "error", err)
+		return
+	}
+
+	sender, err := s.userRepo.GetByID(ctx, message.SenderID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - notifyOfflineParticipants - GetByID", "error", err)
+		return
+	}
+
+	for _, participant := range participants {
+		if participant.UserId == message.SenderID || participant.NotificationsMuted {
+			continue
+		}
+		if s.presence.isActive(participant.UserId) {
+			continue
+		}
+		s.notifier.enqueue(log, participant.UserId, chat, message, sender.Name)
+	}
+}
+
+// SetPresence обновляет состояние WebSocket-соединения пользователя. Пока у пользователя есть
+// активное соединение, пуш-уведомления о новых сообщениях ему не отправляются
+func (s *ChatMessageService) SetPresence(log *slog.Logger, userID, connID, state string) {
+	log.Info("Service - ChatMessageService - SetPresence", "user_id", userID, "state", state)
+
+	s.presence.set(userID, connID, state)
+	if state == ChatPresenceActive {
+		s.notifier.cancelUser(userID)
+	}
+}
+
+// SetNotificationsMuted включает или отключает пуш-уведомления пользователя для чата
+func (s *ChatMessageService) SetNotificationsMuted(
+	ctx context.Context, log *slog.Logger, chatID, userID string, muted bool,
+) error {
+	log.Info("Service - ChatMessageService - SetNotificationsMuted", "chat_id", chatID, "muted", muted)
+
+	err := s.chatsRepo.UpdateNotificationsMuted(ctx, chatID, userID, muted)
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return ErrNotChatParticipant
+	}
+	if err != nil {
+		log.Error("Service - ChatMessageService - SetNotificationsMuted", "error", err)
+		return fmt.Errorf("failed to update notification settings: %w", err)
+	}
+	return nil
+}
+
 // GetParticipants возвращает список участников чата
 func (s *ChatMessageService) GetParticipants(ctx context.Context, log *slog.Logger, chatID string) ([]string, error) {
 	log.Info("Service - ChatMessageService - GetParticipants")
@@ -206,3 +317,431 @@ func (s *ChatMessageService) GetChatsWithLastMessage(ctx context.Context, log *s
 
 	return chats, nil
 }
+
+// syncFamilyChat создает семейный чат, если его нет, и приводит список его участников
+// в соответствие с текущим составом семьи
+func syncFamilyChat(
+	ctx context.Context, log *slog.Logger, chatsRepo repo.Chat, userRepo repo.User, familyID string,
+) (string, error) {
+	log.Info("Service - syncFamilyChat", "familyID", familyID)
+
+	chatID, err := chatsRepo.CreateFamilyChat(ctx, familyID, familyChatName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create family chat: %w", err)
+	}
+
+	members, err := userRepo.GetByFamilyID(ctx, familyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get family members: %w", err)
+	}
+
+	participants, err := chatsRepo.GetParticipants(ctx, chatID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	current := make(map[string]bool, len(participants))
+	for _, userID := range participants {
+		current[userID] = true
+	}
+
+	for _, member := range members {
+		if current[member.Id] {
+			delete(current, member.Id)
+			continue
+		}
+		role := entity.ChatRoleMember
+		if member.Role == "Parent" {
+			role = entity.ChatRoleAdmin
+		}
+		if err = chatsRepo.AddParticipantWithRole(ctx, chatID, member.Id, role); err != nil {
+			return "", fmt.Errorf("failed to add participant: %w", err)
+		}
+	}
+
+	// Оставшиеся участники больше не состоят в семье
+	for userID := range current {
+		if err = chatsRepo.RemoveParticipant(ctx, chatID, userID); err != nil {
+			return "", fmt.Errorf("failed to remove participant: %w", err)
+		}
+	}
+
+	return chatID, nil
+}
+
+// GetFamilyChat возвращает семейный чат, синхронизируя его участников с составом семьи
+func (s *ChatMessageService) GetFamilyChat(ctx context.Context, log *slog.Logger, familyID string) (entity.Chat, error) {
+	log.Info("Service - ChatMessageService - GetFamilyChat", "familyID", familyID)
+
+	chatID, err := syncFamilyChat(ctx, log, s.chatsRepo, s.userRepo, familyID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - GetFamilyChat - Failed to sync family chat", "error", err)
+		return entity.Chat{}, ErrCannotCreateChat
+	}
+
+	chat, err := s.chatsRepo.GetByID(ctx, chatID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - GetFamilyChat - Failed to get chat", "error", err)
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.Chat{}, ErrChatNotFound
+		}
+		return entity.Chat{}, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	return chat, nil
+}
+
+// Input для получения личного чата
+type DirectChatInput struct {
+	UserID      string
+	OtherUserID string
+}
+
+// GetOrCreateDirectChat возвращает личный чат двух членов семьи, создавая его при первом обращении
+func (s *ChatMessageService) GetOrCreateDirectChat(
+	ctx context.Context, log *slog.Logger, input DirectChatInput,
+) (string, error) {
+	log.Info("Service - ChatMessageService - GetOrCreateDirectChat", "userID", input.UserID, "otherUserID", input.OtherUserID)
+
+	if input.UserID == input.OtherUserID {
+		return "", ErrDirectChatWithSelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - GetOrCreateDirectChat - Failed to get user", "error", err)
+		return "", ErrUserNotFound
+	}
+	other, err := s.userRepo.GetByID(ctx, input.OtherUserID)
+	if err != nil {
+		log.Error("Service - ChatMessageService - GetOrCreateDirectChat - Failed to get other user", "error", err)
+		return "", ErrUserNotFound
+	}
+
+	if !user.FamilyId.Valid || user.FamilyId != other.FamilyId {
+		return "", ErrNotFamilyMember
+	}
+
+	chatID, err := s.chatsRepo.GetOrCreateDirectChat(ctx, user.FamilyId.String, user.Id, other.Id)
+	if err != nil {
+		log.Error("Service - ChatMessageService - GetOrCreateDirectChat - Failed to get chat", "error", err)
+		return "", ErrCannotCreateChat
+	}
+
+	log.Info("Service - ChatMessageService - GetOrCreateDirectChat - chatID", "chatID", chatID)
+	return chatID, nil
+}
+
+// getChat возвращает чат, приводя ошибку репозитория к ошибке сервиса
+func (s *ChatMessageService) getChat(ctx context.Context, chatID string) (entity.Chat, error) {
+	chat, err := s.chatsRepo.GetByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.Chat{}, ErrChatNotFound
+		}
+		return entity.Chat{}, fmt.Errorf("failed to get chat: %w", err)
+	}
+	return chat, nil
+}
+
+// isModerator проверяет, может ли пользователь модерировать чат: владелец и администраторы чата,
+// а также родители семьи, к которой относится чат
+func (s *ChatMessageService) isModerator(ctx context.Context, chat entity.Chat, userID string) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, ErrUserNotFound
+	}
+	if user.Role == "Parent" && chat.FamilyID.Valid && user.FamilyId == chat.FamilyID {
+		return true, nil
+	}
+
+	participant, err := s.chatsRepo.GetParticipant(ctx, chat.ID, userID)
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get participant: %w", err)
+	}
+	return participant.Role == entity.ChatRoleOwner || participant.Role == entity.ChatRoleAdmin, nil
+}
+
+// checkModerator возвращает чат, если пользователь может его модерировать
+func (s *ChatMessageService) checkModerator(ctx context.Context, chatID, userID string) (entity.Chat, error) {
+	chat, err := s.getChat(ctx, chatID)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+	ok, err := s.isModerator(ctx, chat, userID)
+	if err != nil {
+		return entity.Chat{}, err
+	}
+	if !ok {
+		return entity.Chat{}, ErrForbidden
+	}
+	return chat, nil
+}
+
+// checkCanWrite проверяет, что пользователь может писать в чат
+func (s *ChatMessageService) checkCanWrite(ctx context.Context, chatID, userID string) error {
+	chat, err := s.getChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	participant, err := s.chatsRepo.GetParticipant(ctx, chatID, userID)
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return ErrNotChatParticipant
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get participant: %w", err)
+	}
+	if participant.IsMuted {
+		return ErrParticipantMuted
+	}
+
+	if chat.IsLocked {
+		ok, err := s.isModerator(ctx, chat, userID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrChatLocked
+		}
+	}
+	return nil
+}
+
+// Input для действий модератора над участником чата
+type ModerateParticipantInput struct {
+	ChatID  string
+	ActorID string
+	UserID  string
+}
+
+// checkTarget проверяет, что участник существует и модератор вправе изменять его:
+// владельца чата может модерировать только родитель семьи
+func (s *ChatMessageService) checkTarget(
+	ctx context.Context, chat entity.Chat, input ModerateParticipantInput,
+) (entity.ChatParticipant, error) {
+	participant, err := s.chatsRepo.GetParticipant(ctx, chat.ID, input.UserID)
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return entity.ChatParticipant{}, ErrNotChatParticipant
+	}
+	if err != nil {
+		return entity.ChatParticipant{}, fmt.Errorf("failed to get participant: %w", err)
+	}
+
+	if participant.Role == entity.ChatRoleOwner && input.ActorID != input.UserID {
+		actor, err := s.userRepo.GetByID(ctx, input.ActorID)
+		if err != nil {
+			return entity.ChatParticipant{}, ErrUserNotFound
+		}
+		if actor.Role != "Parent" || !chat.FamilyID.Valid || actor.FamilyId != chat.FamilyID {
+			return entity.ChatParticipant{}, ErrForbidden
+		}
+	}
+	return participant, nil
+}
+
+// SetParticipantMuted заглушает участника чата или снимает заглушение
+func (s *ChatMessageService) SetParticipantMuted(
+	ctx context.Context, log *slog.Logger, input ModerateParticipantInput, muted bool,
+) error {
+	log.Info("Service - ChatMessageService - SetParticipantMuted", "chatID", input.ChatID, "userID", input.UserID, "muted", muted)
+
+	chat, err := s.checkModerator(ctx, input.ChatID, input.ActorID)
+	if err != nil {
+		return err
+	}
+	if _, err = s.checkTarget(ctx, chat, input); err != nil {
+		return err
+	}
+
+	if err = s.chatsRepo.UpdateParticipantMuted(ctx, input.ChatID, input.UserID, muted); err != nil {
+		log.Error("Service - ChatMessageService - SetParticipantMuted - Failed to update participant", "error", err)
+		return fmt.Errorf("failed to update participant: %w", err)
+	}
+	return nil
+}
+
+// RemoveParticipant удаляет участника из чата
+func (s *ChatMessageService) RemoveParticipant(
+	ctx context.Context, log *slog.Logger, input ModerateParticipantInput,
+) error {
+	log.Info("Service - ChatMessageService - RemoveParticipant", "chatID", input.ChatID, "userID", input.UserID)
+
+	chat, err := s.checkModerator(ctx, input.ChatID, input.ActorID)
+	if err != nil {
+		return err
+	}
+	if chat.Type == entity.ChatTypeFamily {
+		return ErrFamilyChatMembership
+	}
+	if _, err = s.checkTarget(ctx, chat, input); err != nil {
+		return err
+	}
+
+	if err = s.chatsRepo.RemoveParticipant(ctx, input.ChatID, input.UserID); err != nil {
+		log.Error("Service - ChatMessageService - RemoveParticipant - Failed to remove participant", "error", err)
+		return fmt.Errorf("failed to remove participant: %w", err)
+	}
+	return nil
+}
+
+// SetParticipantRole назначает роль участнику. Менять роли могут владелец чата и родители семьи
+func (s *ChatMessageService) SetParticipantRole(
+	ctx context.Context, log *slog.Logger, input ModerateParticipantInput, role string,
+) error {
+	log.Info("Service - ChatMessageService - SetParticipantRole", "chatID", input.ChatID, "userID", input.UserID, "role", role)
+
+	if role != entity.ChatRoleAdmin && role != entity.ChatRoleMember {
+		return ErrInvalidChatRole
+	}
+
+	chat, err := s.checkModerator(ctx, input.ChatID, input.ActorID)
+	if err != nil {
+		return err
+	}
+
+	// Администратор не может раздавать роли
+	actor, err := s.chatsRepo.GetParticipant(ctx, input.ChatID, input.ActorID)
+	if err == nil && actor.Role == entity.ChatRoleAdmin {
+		user, err := s.userRepo.GetByID(ctx, input.ActorID)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		if user.Role != "Parent" || user.FamilyId != chat.FamilyID {
+			return ErrForbidden
+		}
+	}
+
+	target, err := s.checkTarget(ctx, chat, input)
+	if err != nil {
+		return err
+	}
+	if target.Role == entity.ChatRoleOwner {
+		return ErrForbidden
+	}
+
+	if err = s.chatsRepo.UpdateParticipantRole(ctx, input.ChatID, input.UserID, role); err != nil {
+		log.Error("Service - ChatMessageService - SetParticipantRole - Failed to update role", "error", err)
+		return fmt.Errorf("failed to update participant role: %w", err)
+	}
+	return nil
+}
+
+// SetChatLocked переводит чат в режим только для чтения или снимает этот режим
+func (s *ChatMessageService) SetChatLocked(
+	ctx context.Context, log *slog.Logger, chatID, actorID string, locked bool,
+) error {
+	log.Info("Service - ChatMessageService - SetChatLocked", "chatID", chatID, "locked", locked)
+
+	if _, err := s.checkModerator(ctx, chatID, actorID); err != nil {
+		return err
+	}
+
+	if err := s.chatsRepo.UpdateLocked(ctx, chatID, locked); err != nil {
+		log.Error("Service - ChatMessageService - SetChatLocked - Failed to update chat", "error", err)
+		return fmt.Errorf("failed to update chat: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage удаляет сообщение. Автор может удалить свое сообщение, модератор - любое
+func (s *ChatMessageService) DeleteMessage(
+	ctx context.Context, log *slog.Logger, chatID, messageID, actorID string,
+) (entity.Message, error) {
+	log.Info("Service - ChatMessageService - DeleteMessage", "messageID", messageID)
+
+	message, err := s.messagesRepo.GetByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.Message{}, ErrMessageNotFound
+		}
+		log.Error("Service - ChatMessageService - DeleteMessage - Failed to get message", "error", err)
+		return entity.Message{}, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.ChatID != chatID {
+		return entity.Message{}, ErrMessageNotFound
+	}
+
+	if message.SenderID != actorID {
+		if _, err = s.checkModerator(ctx, message.ChatID, actorID); err != nil {
+			return entity.Message{}, err
+		}
+	}
+
+	if err = s.messagesRepo.Delete(ctx, messageID); err != nil {
+		log.Error("Service - ChatMessageService - DeleteMessage - Failed to delete message", "error", err)
+		return entity.Message{}, fmt.Errorf("failed to delete message: %w", err)
+	}
+	return message, nil
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Input для поиска сообщений
+type SearchMessagesInput struct {
+	UserID string
+	ChatID string
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SearchMessages ищет сообщения в чатах пользователя
+func (s *ChatMessageService) SearchMessages(
+	ctx context.Context, log *slog.Logger, input SearchMessagesInput,
+) ([]entity.MessageSearchResult, error) {
+	log.Info("Service - ChatMessageService - SearchMessages", "userID", input.UserID, "chatID", input.ChatID)
+
+	if input.Limit <= 0 || input.Limit > maxSearchLimit {
+		input.Limit = defaultSearchLimit
+	}
+	if input.Offset < 0 {
+		input.Offset = 0
+	}
+
+	results, err := s.messagesRepo.Search(ctx, input.UserID, input.ChatID, input.Query, input.Limit, input.Offset)
+	if err != nil {
+		log.Error("Service - ChatMessageService - SearchMessages - Failed to search messages", "error", err)
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	log.Info("Service - ChatMessageService - SearchMessages - Messages found", "count", len(results))
+	return results, nil
+}
+
+// GetMessagesAround возвращает сообщения вокруг указанного, для перехода к результату поиска
+func (s *ChatMessageService) GetMessagesAround(
+	ctx context.Context, log *slog.Logger, userID, chatID, messageID string, limit int,
+) ([]entity.Message, error) {
+	log.Info("Service - ChatMessageService - GetMessagesAround", "chatID", chatID, "messageID", messageID)
+
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	_, err := s.chatsRepo.GetParticipant(ctx, chatID, userID)
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		if _, err = s.checkModerator(ctx, chatID, userID); err != nil {
+			return nil, ErrNotChatParticipant
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get participant: %w", err)
+	}
+
+	messages, err := s.messagesRepo.GetAround(ctx, chatID, messageID, limit)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		log.Error("Service - ChatMessageService - GetMessagesAround - Failed to get messages", "error", err)
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	return messages, nil
+}
@@ -36,7 +36,7 @@ func (s *DiaryService) Create(ctx context.Context, log *slog.Logger, input Diary
 
 	id, err := s.diaryRepo.Create(ctx, log, item)
 	if err != nil {
-		log.Error("Service - DiaryService - Create: %v", err)
+		log.Error("Service - DiaryService - Create", "error", err)
 		return "", err
 	}
 
@@ -48,7 +48,7 @@ func (s *DiaryService) GetByUserID(ctx context.Context, log *slog.Logger, userID
 
 	items, err := s.diaryRepo.GetByUserID(ctx, log, userID)
 	if err != nil {
-		log.Error("Service - DiaryService - GetByUserID: %v", err)
+		log.Error("Service - DiaryService - GetByUserID", "error", err)
 		return nil, err
 	}
 
@@ -75,7 +75,7 @@ func (s *DiaryService) Update(ctx context.Context, log *slog.Logger, input Diary
 
 	err := s.diaryRepo.Update(ctx, log, item)
 	if err != nil {
-		log.Error("Service - DiaryService - Update: %v", err)
+		log.Error("Service - DiaryService - Update", "error", err)
 		return err
 	}
 
@@ -87,7 +87,7 @@ func (s *DiaryService) Delete(ctx context.Context, log *slog.Logger, id string)
 
 	err := s.diaryRepo.Delete(ctx, log, id)
 	if err != nil {
-		log.Error("Service - DiaryService - Delete: %v", err)
+		log.Error("Service - DiaryService - Delete", "error", err)
 		return err
 	}
 
@@ -96,3 +96,17 @@ func (e *EmailService) SendInvite(ctx context.Context, invite InputSendInvite) e
 
 	return nil
 }
+
+// SendDigest отправляет письмо с еженедельной сводкой по баллам семьи
+func (e *EmailService) SendDigest(ctx context.Context, digest InputSendDigest) error {
+	subject := "Family Flow App - Итоги недели"
+	message := "Subject: " + subject + "\r\n" + "Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n" + digest.Body
+
+	return smtp.SendMail(
+		e.config.Addr,
+		e.auth,
+		e.config.FromEmail,
+		digest.To,
+		[]byte(message),
+	)
+}
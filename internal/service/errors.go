@@ -30,4 +30,58 @@ var (
 	ErrCannotUpdateUser        = fmt.Errorf("cannot update user")
 	ErrCannotResetFamilyID     = fmt.Errorf("cannot reset family id")
 	ErrInsufficientPermissions = fmt.Errorf("insufficient permissions")
+
+	ErrChatNotFound       = fmt.Errorf("chat not found")
+	ErrCannotCreateChat   = fmt.Errorf("cannot create chat")
+	ErrNotFamilyMember    = fmt.Errorf("user is not a member of the family")
+	ErrDirectChatWithSelf = fmt.Errorf("cannot create direct chat with yourself")
+
+	ErrNotChatParticipant   = fmt.Errorf("user is not a chat participant")
+	ErrParticipantMuted     = fmt.Errorf("participant is muted")
+	ErrChatLocked           = fmt.Errorf("chat is locked")
+	ErrMessageNotFound      = fmt.Errorf("message not found")
+	ErrFamilyChatMembership = fmt.Errorf("family chat membership follows the family")
+	ErrInvalidChatRole      = fmt.Errorf("invalid chat role")
+
+	ErrInvalidRecurrenceRule = fmt.Errorf("invalid recurrence rule")
+	ErrTodoNotRecurring      = fmt.Errorf("todo is not recurring")
+	ErrInvalidTodoTransition = fmt.Errorf("invalid todo status transition")
+	ErrProofRequired         = fmt.Errorf("photo proof is required")
+	ErrChecklistItemNotFound = fmt.Errorf("checklist item not found")
+	ErrInvalidChecklistOrder = fmt.Errorf("checklist order must list every item once")
+	ErrInvalidCursor         = fmt.Errorf("invalid cursor")
+
+	ErrRotationRequiresRecurrence = fmt.Errorf("rotation requires a recurrence rule")
+	ErrDuplicateRotationMember    = fmt.Errorf("rotation member is listed twice")
+	ErrAbsenceNotFound            = fmt.Errorf("absence not found")
+	ErrUndoWindowExpired          = fmt.Errorf("undo window has expired")
+	ErrCommentNotFound            = fmt.Errorf("comment not found")
+
+	ErrInvalidCalendarRange  = fmt.Errorf("invalid calendar range")
+	ErrCalendarTokenNotFound = fmt.Errorf("calendar token not found")
+
+	ErrRewardNotFound  = fmt.Errorf("reward not found")
+	ErrNotEnoughPoints = fmt.Errorf("not enough points")
+	// ErrRewardUnavailable оборачивается вместе с причиной из entity.RewardUnavailable*
+	ErrRewardUnavailable = fmt.Errorf("reward is unavailable")
+
+	ErrRedemptionNotFound          = fmt.Errorf("redemption not found")
+	ErrInvalidRedemptionTransition = fmt.Errorf("invalid redemption status transition")
+
+	ErrSavingsGoalNotFound  = fmt.Errorf("savings goal not found")
+	ErrSavingsGoalExists    = fmt.Errorf("savings goal for this reward already exists")
+	ErrSavingsGoalCompleted = fmt.Errorf("savings goal is completed")
+	ErrAllowanceDisabled    = fmt.Errorf("allowance is disabled")
+
+	ErrAdjustmentNotFound = fmt.Errorf("point adjustment not found")
+	ErrAdjustmentUndone   = fmt.Errorf("point adjustment is already undone")
+
+	ErrAchievementRuleNotFound = fmt.Errorf("achievement rule not found")
+
+	ErrShoppingListNotFound    = fmt.Errorf("shopping list not found")
+	ErrShoppingListArchived    = fmt.Errorf("shopping list is archived")
+	ErrShoppingListDefault     = fmt.Errorf("default shopping list cannot be archived or deleted")
+	ErrShoppingListNotPersonal = fmt.Errorf("only personal shopping lists can be shared")
+	ErrShoppingItemNotFound    = fmt.Errorf("shopping item not found")
+	ErrShoppingItemConflict    = fmt.Errorf("shopping item was changed by someone else")
 )
@@ -12,10 +12,11 @@ import (
 type FamilyService struct {
 	familyRepo repo.Family
 	userRepo   repo.User
+	chatsRepo  repo.Chat
 }
 
-func NewFamilyService(familyRepo repo.Family, userRepo repo.User) *FamilyService {
-	return &FamilyService{familyRepo: familyRepo, userRepo: userRepo}
+func NewFamilyService(familyRepo repo.Family, userRepo repo.User, chatsRepo repo.Chat) *FamilyService {
+	return &FamilyService{familyRepo: familyRepo, userRepo: userRepo, chatsRepo: chatsRepo}
 }
 
 func (f *FamilyService) Create(ctx context.Context, log *slog.Logger, input FamilyCreateInput) (string, error) {
@@ -36,6 +37,11 @@ func (f *FamilyService) Create(ctx context.Context, log *slog.Logger, input Fami
 		return "", ErrCannotCreateFamily
 	}
 
+	// Семейный чат досоздается при следующем обращении, поэтому ошибка не прерывает создание семьи
+	if _, err = syncFamilyChat(ctx, log, f.chatsRepo, f.userRepo, id); err != nil {
+		log.Error(fmt.Sprintf("Service - FamilyService - Create - syncFamilyChat: %v", err))
+	}
+
 	log.Info(fmt.Sprintf("Service - FamilyService - familyRepo.Create - id: %s", id))
 	return id, nil
 }
@@ -72,6 +78,16 @@ func (f *FamilyService) AddMember(ctx context.Context, log *slog.Logger, input A
 		return ErrCannotAddMemberToFamily
 	}
 
+	if _, err = syncFamilyChat(ctx, log, f.chatsRepo, f.userRepo, input.FamilyId); err != nil {
+		log.Error(fmt.Sprintf("Service - FamilyService - AddMember - syncFamilyChat: %v", err))
+	}
+	// Пользователь мог перейти из другой семьи
+	if user.FamilyId.Valid && user.FamilyId.String != input.FamilyId {
+		if _, err = syncFamilyChat(ctx, log, f.chatsRepo, f.userRepo, user.FamilyId.String); err != nil {
+			log.Error(fmt.Sprintf("Service - FamilyService - AddMember - syncFamilyChat: %v", err))
+		}
+	}
+
 	return nil
 }
 
@@ -70,6 +70,8 @@ type NotificationCreateInput struct {
 	Title  string
 	Body   string
 	Data   string
+	// CollapseKey - уведомления с одинаковым ключом заменяют друг друга на устройстве
+	CollapseKey string
 }
 
 // SendNotification отправляет пуш-уведомление
@@ -92,6 +94,15 @@ func (n *NotificationService) SendNotification(
 			Body:  input.Body,
 		},
 	}
+	if input.CollapseKey != "" {
+		message.Android = &messaging.AndroidConfig{
+			CollapseKey:  input.CollapseKey,
+			Notification: &messaging.AndroidNotification{Tag: input.CollapseKey},
+		}
+		message.APNS = &messaging.APNSConfig{
+			Headers: map[string]string{"apns-collapse-id": input.CollapseKey},
+		}
+	}
 
 	// Отправляем уведомление
 	str, err := n.Client.Send(ctx, message)
@@ -2,22 +2,41 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"family-flow-app/internal/entity"
 	"family-flow-app/internal/repo"
+	"family-flow-app/internal/repo/repoerrs"
+
+	"github.com/patrickmn/go-cache"
 )
 
 type RewardsService struct {
-	rewardsRepo repo.Rewards
-	userRepo    repo.User
+	rewardsRepo  repo.Rewards
+	userRepo     repo.User
+	notification Notification
+	achievements Achievements
+	email        Email
+	// leaderboards - таблицы лидеров по ключу "семья:период", живут leaderboardTTL
+	leaderboards   *cache.Cache
+	leaderboardTTL time.Duration
 }
 
-func NewRewardsService(rewardsRepo repo.Rewards, userRepo repo.User) *RewardsService {
+func NewRewardsService(
+	rewardsRepo repo.Rewards, userRepo repo.User, notification Notification, achievements Achievements, email Email,
+	leaderboardTTL time.Duration,
+) *RewardsService {
 	return &RewardsService{
-		rewardsRepo: rewardsRepo,
-		userRepo:    userRepo,
+		rewardsRepo:    rewardsRepo,
+		userRepo:       userRepo,
+		notification:   notification,
+		achievements:   achievements,
+		email:          email,
+		leaderboards:   cache.New(leaderboardTTL, 10*time.Minute),
+		leaderboardTTL: leaderboardTTL,
 	}
 }
 
@@ -35,18 +54,46 @@ func (s *RewardsService) Create(ctx context.Context, log *slog.Logger, input ent
 	return id, nil
 }
 
-// GetRewardsByFamilyID возвращает список вознаграждений для семьи
-func (s *RewardsService) GetRewardsByFamilyID(ctx context.Context, log *slog.Logger, familyID string) (
+// GetRewardsByFamilyID возвращает список вознаграждений для семьи. Для каждой награды указано,
+// может ли пользователь userID обменять ее сейчас, и если нет - почему
+func (s *RewardsService) GetRewardsByFamilyID(ctx context.Context, log *slog.Logger, familyID, userID string) (
 	[]entity.Reward, error,
 ) {
 	log.Info("Service - RewardsService - GetRewardsByFamilyID", "familyID", familyID)
 
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
 	rewards, err := s.rewardsRepo.GetByFamilyID(ctx, familyID)
 	if err != nil {
 		log.Error("Service - RewardsService - GetRewardsByFamilyID - Failed to get rewards", "error", err)
 		return nil, fmt.Errorf("failed to get rewards: %w", err)
 	}
 
+	redemptions, err := s.rewardsRepo.GetRedemptionsByUserID(ctx, userID)
+	if err != nil {
+		log.Error("Service - RewardsService - GetRewardsByFamilyID - Failed to get redemptions", "error", err)
+		return nil, fmt.Errorf("failed to get redemptions: %w", err)
+	}
+	redeemedAt := make(map[string][]time.Time)
+	for _, redemption := range redemptions {
+		if redemption.Status != entity.RedemptionStatusDeclined {
+			redeemedAt[redemption.RewardID] = append(redeemedAt[redemption.RewardID], redemption.RedeemedAt)
+		}
+	}
+
+	now := time.Now().UTC()
+	for i := range rewards {
+		reason := rewardUnavailableReason(rewards[i], user, redeemedAt[rewards[i].ID], now)
+		if reason == "" && user.Point < rewards[i].Cost {
+			reason = entity.RewardUnavailablePoints
+		}
+		rewards[i].Available = reason == ""
+		rewards[i].UnavailableReason = reason
+	}
+
 	log.Info("Service - RewardsService - GetRewardsByFamilyID - Rewards retrieved successfully", "count", len(rewards))
 	return rewards, nil
 }
@@ -55,10 +102,12 @@ func (s *RewardsService) GetRewardsByFamilyID(ctx context.Context, log *slog.Log
 func (s *RewardsService) AddPoints(ctx context.Context, log *slog.Logger, userID string, points int) error {
 	log.Info("Service - RewardsService - AddPoints", "userID", userID, "points", points)
 
-	err := s.rewardsRepo.AddPoints(ctx, userID, points)
+	_, err := s.rewardsRepo.ApplyPoints(
+		ctx, entity.PointTransaction{UserID: userID, Kind: entity.PointTransactionEarn, Amount: points},
+	)
 	if err != nil {
 		log.Error("Service - RewardsService - AddPoints - Failed to add points", "error", err)
-		return fmt.Errorf("failed to add points: %w", err)
+		return pointsError(err, "failed to add points")
 	}
 
 	log.Info("Service - RewardsService - AddPoints - Points added successfully")
@@ -69,10 +118,12 @@ func (s *RewardsService) AddPoints(ctx context.Context, log *slog.Logger, userID
 func (s *RewardsService) SubtractPoints(ctx context.Context, log *slog.Logger, userID string, points int) error {
 	log.Info("Service - RewardsService - SubtractPoints", "userID", userID, "points", points)
 
-	err := s.rewardsRepo.SubtractPoints(ctx, userID, points)
+	_, err := s.rewardsRepo.ApplyPoints(
+		ctx, entity.PointTransaction{UserID: userID, Kind: entity.PointTransactionSpend, Amount: -points},
+	)
 	if err != nil {
 		log.Error("Service - RewardsService - SubtractPoints - Failed to subtract points", "error", err)
-		return fmt.Errorf("failed to subtract points: %w", err)
+		return pointsError(err, "failed to subtract points")
 	}
 
 	log.Info("Service - RewardsService - SubtractPoints - Points subtracted successfully")
@@ -93,53 +144,60 @@ func (s *RewardsService) GetPoints(ctx context.Context, log *slog.Logger, userID
 	return points, nil
 }
 
-// RedeemReward обменивает очки на вознаграждение
-func (s *RewardsService) Redeem(ctx context.Context, log *slog.Logger, userID, rewardID string) error {
+// RedeemReward обменивает очки на вознаграждение. Проверка ограничений награды и баланса, списание
+// и запись об обмене выполняются в одной транзакции, поэтому повторное нажатие не уводит баланс в минус
+// и не обходит остаток и лимиты.
+// Обмен ждет решения родителя в статусе Pending, родители семьи получают уведомление о запросе
+func (s *RewardsService) Redeem(ctx context.Context, log *slog.Logger, userID, rewardID string) (
+	entity.RewardRedemption, error,
+) {
 	log.Info("Service - RewardsService - RedeemReward", "userID", userID, "rewardID", rewardID)
 
-	// Получаем информацию о вознаграждении
-	reward, err := s.rewardsRepo.GetByID(ctx, rewardID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		log.Error("Service - RewardsService - RedeemReward - Failed to get reward", "error", err)
-		return fmt.Errorf("failed to get reward: %w", err)
+		return entity.RewardRedemption{}, ErrUserNotFound
 	}
 
-	// Проверяем, достаточно ли очков у пользователя
-	points, err := s.rewardsRepo.GetPoints(ctx, userID)
+	// Ограничения проверяются внутри транзакции обмена по заблокированной строке награды
+	redemptionID, err := s.rewardsRepo.Redeem(ctx, userID, rewardID, redeemCheck(user))
 	if err != nil {
-		log.Error("Service - RewardsService - RedeemReward - Failed to get points", "error", err)
-		return fmt.Errorf("failed to get points: %w", err)
-	}
-
-	if points < reward.Cost {
-		log.Error(
-			"Service - RewardsService - RedeemReward - Not enough points",
-			"userID",
-			userID,
-			"points",
-			points,
-			"cost",
-			reward.Cost,
-		)
-		return fmt.Errorf("not enough points to redeem reward")
+		log.Error("Service - RewardsService - RedeemReward - Failed to redeem reward", "error", err)
+		return entity.RewardRedemption{}, redeemError(err)
 	}
 
-	// Списываем очки
-	err = s.rewardsRepo.SubtractPoints(ctx, userID, reward.Cost)
+	redemption, err := s.getRedemption(ctx, log, redemptionID)
 	if err != nil {
-		log.Error("Service - RewardsService - RedeemReward - Failed to subtract points", "error", err)
-		return fmt.Errorf("failed to subtract points: %w", err)
+		return entity.RewardRedemption{}, err
 	}
+	s.notifyParents(ctx, log, redemption)
+	s.achievements.Evaluate(ctx, log, userID, entity.AchievementEventRewardRedeemed)
 
-	// Добавляем запись об обмене
-	err = s.rewardsRepo.Redeem(ctx, userID, rewardID)
-	if err != nil {
-		log.Error("Service - RewardsService - RedeemReward - Failed to redeem reward", "error", err)
-		return fmt.Errorf("failed to redeem reward: %w", err)
+	log.Info("Service - RewardsService - RedeemReward - Reward redeemed successfully", "redemptionID", redemptionID)
+	return redemption, nil
+}
+
+// redeemCheck проверяет внутри транзакции обмена, что награда принадлежит семье пользователя и доступна ему
+func redeemCheck(user entity.User) func(reward entity.Reward, redeemedAt []time.Time) error {
+	return func(reward entity.Reward, redeemedAt []time.Time) error {
+		if !user.FamilyId.Valid || user.FamilyId.String != reward.FamilyID {
+			return ErrRewardNotFound
+		}
+		if reason := rewardUnavailableReason(reward, user, redeemedAt, time.Now().UTC()); reason != "" {
+			return fmt.Errorf("%w: %s", ErrRewardUnavailable, reason)
+		}
+		return nil
 	}
+}
 
-	log.Info("Service - RewardsService - RedeemReward - Reward redeemed successfully")
-	return nil
+// redeemError переводит ошибку обмена в ошибку сервиса
+func redeemError(err error) error {
+	switch {
+	case errors.Is(err, repoerrs.ErrNotFound):
+		return ErrRewardNotFound
+	case errors.Is(err, ErrRewardNotFound), errors.Is(err, ErrRewardUnavailable):
+		return err
+	}
+	return pointsError(err, "failed to redeem reward")
 }
 
 // GetRedemptionsByUserID возвращает список вознаграждений, которые пользователь обменял
@@ -176,12 +234,17 @@ func (s *RewardsService) GetByID(ctx context.Context, log *slog.Logger, id strin
 	return reward, nil
 }
 
-// Update обновляет существующую награду
-func (s *RewardsService) Update(ctx context.Context, log *slog.Logger, reward entity.Reward) error {
-	log.Info("Service - RewardsService - Update", "rewardID", reward.ID)
+// Update обновляет существующую награду. Доступно родителю семьи награды, семья награды не меняется
+func (s *RewardsService) Update(ctx context.Context, log *slog.Logger, userID string, reward entity.Reward) error {
+	log.Info("Service - RewardsService - Update", "userID", userID, "rewardID", reward.ID)
 
-	err := s.rewardsRepo.Update(ctx, reward)
+	current, err := s.managedReward(ctx, log, userID, reward.ID)
 	if err != nil {
+		return err
+	}
+	reward.FamilyID = current.FamilyID
+
+	if err = s.rewardsRepo.Update(ctx, reward); err != nil {
 		log.Error("Service - RewardsService - Update - Failed to update reward", "error", err)
 		return fmt.Errorf("failed to update reward: %w", err)
 	}
@@ -190,12 +253,16 @@ func (s *RewardsService) Update(ctx context.Context, log *slog.Logger, reward en
 	return nil
 }
 
-// Delete удаляет награду
-func (s *RewardsService) Delete(ctx context.Context, log *slog.Logger, id string) error {
-	log.Info("Service - RewardsService - Delete", "rewardID", id)
+// Delete удаляет награду. Доступно родителю семьи награды.
+// Баллы целей накопления и незавершенных обменов этой награды возвращаются владельцам
+func (s *RewardsService) Delete(ctx context.Context, log *slog.Logger, userID, id string) error {
+	log.Info("Service - RewardsService - Delete", "userID", userID, "rewardID", id)
 
-	err := s.rewardsRepo.Delete(ctx, id)
-	if err != nil {
+	if _, err := s.managedReward(ctx, log, userID, id); err != nil {
+		return err
+	}
+
+	if err := s.rewardsRepo.Delete(ctx, id); err != nil {
 		log.Error("Service - RewardsService - Delete - Failed to delete reward", "error", err)
 		return fmt.Errorf("failed to delete reward: %w", err)
 	}
@@ -203,3 +270,30 @@ func (s *RewardsService) Delete(ctx context.Context, log *slog.Logger, id string
 	log.Info("Service - RewardsService - Delete - Reward deleted successfully")
 	return nil
 }
+
+// managedReward возвращает награду, которой может управлять пользователь. ErrRewardNotFound - награды нет
+// или она из другой семьи, ErrForbidden - пользователь не родитель
+func (s *RewardsService) managedReward(ctx context.Context, log *slog.Logger, userID, rewardID string) (
+	entity.Reward, error,
+) {
+	reward, err := s.rewardsRepo.GetByID(ctx, rewardID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.Reward{}, ErrRewardNotFound
+		}
+		log.Error("Service - RewardsService - managedReward", "error", err)
+		return entity.Reward{}, fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.Reward{}, ErrUserNotFound
+	}
+	if !user.FamilyId.Valid || user.FamilyId.String != reward.FamilyID {
+		return entity.Reward{}, ErrRewardNotFound
+	}
+	if user.Role != "Parent" {
+		return entity.Reward{}, ErrForbidden
+	}
+	return reward, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// PointAdjustmentInput - ручная корректировка баланса члена семьи родителем
+type PointAdjustmentInput struct {
+	ParentID string
+	UserID   string
+	// Points - изменение баланса: положительное начисляет баллы, отрицательное списывает
+	Points int
+	Reason string
+}
+
+// AdjustPoints меняет баланс члена семьи на input.Points с обязательной причиной. Доступно родителю.
+// Операция попадает в журнал с родителем в created_by, пользователь получает уведомление
+func (s *RewardsService) AdjustPoints(ctx context.Context, log *slog.Logger, input PointAdjustmentInput) (
+	entity.PointTransaction, error,
+) {
+	log.Info(
+		"Service - RewardsService - AdjustPoints", "parentID", input.ParentID, "userID", input.UserID,
+		"points", input.Points,
+	)
+
+	if err := s.checkAdjustable(ctx, input.ParentID, input.UserID); err != nil {
+		return entity.PointTransaction{}, err
+	}
+
+	transaction, err := s.rewardsRepo.ApplyPoints(
+		ctx, entity.PointTransaction{
+			UserID:     input.UserID,
+			Kind:       entity.PointTransactionAdjust,
+			Amount:     input.Points,
+			Reason:     input.Reason,
+			SourceType: entity.PointSourceAdjustment,
+			CreatedBy:  input.ParentID,
+		},
+	)
+	if err != nil {
+		log.Error("Service - RewardsService - AdjustPoints", "error", err)
+		return entity.PointTransaction{}, pointsError(err, "failed to adjust points")
+	}
+
+	body := fmt.Sprintf("Начислено баллов: %d. Причина: %s", transaction.Amount, transaction.Reason)
+	if transaction.Amount < 0 {
+		body = fmt.Sprintf("Списано баллов: %d. Причина: %s", -transaction.Amount, transaction.Reason)
+	}
+	s.sendAdjustmentNotification(ctx, log, transaction, "points_adjusted", "Баланс изменен", body)
+	return transaction, nil
+}
+
+// UndoAdjustment отменяет ручную корректировку обратной операцией в журнале. Доступно родителю семьи.
+// Корректировку можно отменить один раз; отмена начисления, которое уже потрачено, не проходит
+func (s *RewardsService) UndoAdjustment(ctx context.Context, log *slog.Logger, parentID, transactionID string) (
+	entity.PointTransaction, error,
+) {
+	log.Info("Service - RewardsService - UndoAdjustment", "parentID", parentID, "transactionID", transactionID)
+
+	adjustment, err := s.rewardsRepo.GetPointTransactionByID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.PointTransaction{}, ErrAdjustmentNotFound
+		}
+		log.Error("Service - RewardsService - UndoAdjustment - GetPointTransactionByID", "error", err)
+		return entity.PointTransaction{}, fmt.Errorf("failed to get point transaction: %w", err)
+	}
+	if adjustment.SourceType != entity.PointSourceAdjustment {
+		return entity.PointTransaction{}, ErrAdjustmentNotFound
+	}
+	if err = s.checkAdjustable(ctx, parentID, adjustment.UserID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return entity.PointTransaction{}, ErrAdjustmentNotFound
+		}
+		return entity.PointTransaction{}, err
+	}
+
+	transaction, err := s.rewardsRepo.ApplyPoints(
+		ctx, entity.PointTransaction{
+			UserID:     adjustment.UserID,
+			Kind:       entity.PointTransactionAdjust,
+			Amount:     -adjustment.Amount,
+			Reason:     "Отмена корректировки: " + adjustment.Reason,
+			SourceType: entity.PointSourceAdjustmentUndo,
+			SourceID:   adjustment.ID,
+			CreatedBy:  parentID,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrAlreadyExists) {
+			return entity.PointTransaction{}, ErrAdjustmentUndone
+		}
+		log.Error("Service - RewardsService - UndoAdjustment", "error", err)
+		return entity.PointTransaction{}, pointsError(err, "failed to undo adjustment")
+	}
+
+	s.sendAdjustmentNotification(
+		ctx, log, transaction, "points_adjustment_undone", "Корректировка отменена",
+		fmt.Sprintf("Отменена корректировка баланса на %d баллов: %s", adjustment.Amount, adjustment.Reason),
+	)
+	return transaction, nil
+}
+
+// checkAdjustable проверяет, что parentID - родитель в семье пользователя userID.
+// ErrForbidden - parentID не родитель, ErrUserNotFound - пользователя нет или он из другой семьи
+func (s *RewardsService) checkAdjustable(ctx context.Context, parentID, userID string) error {
+	parent, err := s.userRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if parent.Role != "Parent" || !parent.FamilyId.Valid {
+		return ErrForbidden
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user.FamilyId != parent.FamilyId {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// sendAdjustmentNotification уведомляет пользователя о корректировке его баланса. Ошибка только логируется
+func (s *RewardsService) sendAdjustmentNotification(
+	ctx context.Context, log *slog.Logger, transaction entity.PointTransaction, kind, title, body string,
+) {
+	if transaction.UserID == transaction.CreatedBy {
+		return
+	}
+	data, _ := json.Marshal(map[string]string{"type": kind, "transaction_id": transaction.ID})
+
+	err := s.notification.SendNotification(
+		ctx, log, NotificationCreateInput{
+			UserID:      transaction.UserID,
+			Title:       title,
+			Body:        body,
+			Data:        string(data),
+			CollapseKey: "points_" + transaction.ID,
+		},
+	)
+	if err != nil {
+		log.Error(
+			"Service - RewardsService - sendAdjustmentNotification", "user_id", transaction.UserID,
+			"transaction_id", transaction.ID, "error", err,
+		)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// defaultAllowanceCurrency - валюта карманных денег, пока семья ее не выбрала
+const defaultAllowanceCurrency = "RUB"
+
+// AllowanceSettingsInput - новые настройки карманных денег семьи
+type AllowanceSettingsInput struct {
+	UserID        string
+	Enabled       bool
+	MinorPerPoint int
+	Currency      string
+}
+
+// GetAllowanceSettings возвращает настройки карманных денег семьи пользователя.
+// Пока родитель их не сохранил, перевод баллов выключен
+func (s *RewardsService) GetAllowanceSettings(ctx context.Context, log *slog.Logger, userID string) (
+	entity.AllowanceSettings, error,
+) {
+	log.Info("Service - RewardsService - GetAllowanceSettings", "userID", userID)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.AllowanceSettings{}, ErrUserNotFound
+	}
+	if !user.FamilyId.Valid {
+		return entity.AllowanceSettings{}, ErrFamilyNotFound
+	}
+	return s.allowanceSettings(ctx, log, user.FamilyId.String)
+}
+
+// UpdateAllowanceSettings сохраняет настройки карманных денег семьи. Доступно родителю
+func (s *RewardsService) UpdateAllowanceSettings(ctx context.Context, log *slog.Logger, input AllowanceSettingsInput) (
+	entity.AllowanceSettings, error,
+) {
+	log.Info("Service - RewardsService - UpdateAllowanceSettings", "userID", input.UserID, "enabled", input.Enabled)
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return entity.AllowanceSettings{}, ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid {
+		return entity.AllowanceSettings{}, ErrForbidden
+	}
+
+	currency := input.Currency
+	if currency == "" {
+		currency = defaultAllowanceCurrency
+	}
+	err = s.rewardsRepo.SaveAllowanceSettings(
+		ctx, entity.AllowanceSettings{
+			FamilyID:      user.FamilyId.String,
+			Enabled:       input.Enabled,
+			MinorPerPoint: input.MinorPerPoint,
+			Currency:      currency,
+			UpdatedBy:     user.Id,
+		},
+	)
+	if err != nil {
+		log.Error("Service - RewardsService - UpdateAllowanceSettings", "error", err)
+		return entity.AllowanceSettings{}, fmt.Errorf("failed to save allowance settings: %w", err)
+	}
+	return s.allowanceSettings(ctx, log, user.FamilyId.String)
+}
+
+// GetAllowanceReport возвращает карманные деньги за баллы, заработанные за задания в неделю с понедельника
+// по воскресенье (UTC), в которую попадает week. Родитель видит выплаты всем членам семьи, остальные - только себе
+func (s *RewardsService) GetAllowanceReport(ctx context.Context, log *slog.Logger, userID string, week time.Time) (
+	entity.AllowanceReport, error,
+) {
+	log.Info("Service - RewardsService - GetAllowanceReport", "userID", userID, "week", week)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.AllowanceReport{}, ErrUserNotFound
+	}
+	if !user.FamilyId.Valid {
+		return entity.AllowanceReport{}, ErrFamilyNotFound
+	}
+	settings, err := s.allowanceSettings(ctx, log, user.FamilyId.String)
+	if err != nil {
+		return entity.AllowanceReport{}, err
+	}
+	if !settings.Enabled {
+		return entity.AllowanceReport{}, ErrAllowanceDisabled
+	}
+
+	members := []entity.User{user}
+	if user.Role == "Parent" {
+		if members, err = s.userRepo.GetByFamilyID(ctx, user.FamilyId.String); err != nil {
+			log.Error("Service - RewardsService - GetAllowanceReport - GetByFamilyID", "error", err)
+			return entity.AllowanceReport{}, ErrCannotGetFamilyMembers
+		}
+	}
+	userIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.Id)
+	}
+
+	start := weekStart(week)
+	report := entity.AllowanceReport{
+		WeekStart:     start,
+		WeekEnd:       start.AddDate(0, 0, 7),
+		Currency:      settings.Currency,
+		MinorPerPoint: settings.MinorPerPoint,
+		Payouts:       make([]entity.AllowancePayout, 0, len(members)),
+	}
+	earned, err := s.rewardsRepo.GetEarnedPoints(ctx, userIDs, report.WeekStart, report.WeekEnd)
+	if err != nil {
+		log.Error("Service - RewardsService - GetAllowanceReport - GetEarnedPoints", "error", err)
+		return entity.AllowanceReport{}, fmt.Errorf("failed to get earned points: %w", err)
+	}
+
+	for _, member := range members {
+		payout := entity.AllowancePayout{
+			UserID:      member.Id,
+			Name:        member.Name,
+			Points:      earned[member.Id],
+			AmountMinor: earned[member.Id] * settings.MinorPerPoint,
+		}
+		report.Payouts = append(report.Payouts, payout)
+		report.TotalMinor += payout.AmountMinor
+	}
+	return report, nil
+}
+
+// allowanceSettings возвращает настройки семьи или выключенные настройки по умолчанию
+func (s *RewardsService) allowanceSettings(ctx context.Context, log *slog.Logger, familyID string) (
+	entity.AllowanceSettings, error,
+) {
+	settings, err := s.rewardsRepo.GetAllowanceSettings(ctx, familyID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.AllowanceSettings{FamilyID: familyID, Currency: defaultAllowanceCurrency}, nil
+		}
+		log.Error("Service - RewardsService - allowanceSettings", "error", err)
+		return entity.AllowanceSettings{}, fmt.Errorf("failed to get allowance settings: %w", err)
+	}
+	return settings, nil
+}
+
+// weekStart возвращает полночь понедельника (UTC) недели, в которую попадает t
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
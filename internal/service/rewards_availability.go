@@ -0,0 +1,72 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+// rewardUnavailableReason возвращает причину из entity.RewardUnavailable*, по которой пользователь не может
+// обменять награду в момент now, или пустую строку. redeemedAt - время неотклоненных обменов этой награды
+// пользователем. Баланс не проверяется: его ограничивает транзакция обмена
+func rewardUnavailableReason(reward entity.Reward, user entity.User, redeemedAt []time.Time, now time.Time) string {
+	if reward.AvailableFrom != nil && now.Before(*reward.AvailableFrom) {
+		return entity.RewardUnavailableNotStarted
+	}
+	if reward.AvailableUntil != nil && !now.Before(*reward.AvailableUntil) {
+		return entity.RewardUnavailableEnded
+	}
+	if len(reward.AllowedRoles) > 0 && !slices.Contains(reward.AllowedRoles, user.Role) {
+		return entity.RewardUnavailableRole
+	}
+	if reward.MinAge > 0 || reward.MaxAge > 0 {
+		// Без даты рождения возраст не проверить, поэтому награда с возрастным ограничением недоступна
+		if !user.BirthDate.Valid {
+			return entity.RewardUnavailableAge
+		}
+		age := fullYears(user.BirthDate.Time, now)
+		if (reward.MinAge > 0 && age < reward.MinAge) || (reward.MaxAge > 0 && age > reward.MaxAge) {
+			return entity.RewardUnavailableAge
+		}
+	}
+	if reward.Stock != nil && *reward.Stock <= 0 {
+		return entity.RewardUnavailableOutOfStock
+	}
+	if reward.PerUserLimit > 0 {
+		since := limitPeriodStart(reward.LimitPeriod, now)
+		count := 0
+		for _, at := range redeemedAt {
+			if !at.Before(since) {
+				count++
+			}
+		}
+		if count >= reward.PerUserLimit {
+			return entity.RewardUnavailableLimitReached
+		}
+	}
+	return ""
+}
+
+// limitPeriodStart возвращает начало скользящего окна лимита обменов, для Ever - нулевое время
+func limitPeriodStart(period string, now time.Time) time.Time {
+	switch period {
+	case entity.RewardLimitDay:
+		return now.AddDate(0, 0, -1)
+	case entity.RewardLimitWeek:
+		return now.AddDate(0, 0, -7)
+	case entity.RewardLimitMonth:
+		return now.AddDate(0, -1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// fullYears возвращает число полных лет от birthDate до now
+func fullYears(birthDate, now time.Time) int {
+	years := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+// GetLeaderboard возвращает таблицу лидеров семьи пользователя за период: текущую неделю, текущий месяц (UTC)
+// или все время. Таблица кэшируется на семью и период, поэтому может отставать на время жизни кэша
+func (s *RewardsService) GetLeaderboard(ctx context.Context, log *slog.Logger, userID, period string) (
+	entity.Leaderboard, error,
+) {
+	log.Info("Service - RewardsService - GetLeaderboard", "userID", userID, "period", period)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.Leaderboard{}, ErrUserNotFound
+	}
+	if !user.FamilyId.Valid {
+		return entity.Leaderboard{}, ErrFamilyNotFound
+	}
+
+	key := user.FamilyId.String + ":" + period
+	if cached, ok := s.leaderboards.Get(key); ok {
+		return cached.(entity.Leaderboard), nil
+	}
+
+	now := time.Now().UTC()
+	board := entity.Leaderboard{Period: period, To: now, GeneratedAt: now}
+	var from time.Time
+	switch period {
+	case entity.LeaderboardPeriodWeek:
+		from = weekStart(now)
+		board.To = from.AddDate(0, 0, 7)
+	case entity.LeaderboardPeriodMonth:
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		board.To = from.AddDate(0, 1, 0)
+	}
+	if !from.IsZero() {
+		board.From = &from
+	}
+
+	entries, err := s.rewardsRepo.GetLeaderboard(ctx, user.FamilyId.String, from, board.To)
+	if err != nil {
+		log.Error("Service - RewardsService - GetLeaderboard", "error", err)
+		return entity.Leaderboard{}, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	board.Entries = rankLeaderboard(entries)
+
+	if s.leaderboardTTL > 0 {
+		s.leaderboards.Set(key, board, s.leaderboardTTL)
+	}
+	return board, nil
+}
+
+// RunWeeklyDigest периодически рассылает семьям итоги прошедшей недели до отмены ctx: пуш-уведомление
+// каждому члену семьи и письмо родителям. Безопасен при запуске на нескольких репликах
+func (s *RewardsService) RunWeeklyDigest(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	log.Info("Service - RewardsService - RunWeeklyDigest", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = s.SendWeeklyDigests(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendWeeklyDigests рассылает итоги прошедшей недели семьям, которым их еще не отправляли.
+// Семьям без активности за неделю сводка не отправляется
+func (s *RewardsService) SendWeeklyDigests(ctx context.Context, log *slog.Logger) error {
+	start := weekStart(time.Now()).AddDate(0, 0, -7)
+	end := start.AddDate(0, 0, 7)
+
+	familyIDs, err := s.rewardsRepo.ClaimDigestFamilies(ctx, start)
+	if err != nil {
+		log.Error("Service - RewardsService - SendWeeklyDigests - ClaimDigestFamilies", "error", err)
+		return fmt.Errorf("failed to claim digest families: %w", err)
+	}
+
+	for _, familyID := range familyIDs {
+		entries, err := s.rewardsRepo.GetLeaderboard(ctx, familyID, start, end)
+		if err != nil {
+			log.Error(
+				"Service - RewardsService - SendWeeklyDigests - GetLeaderboard", "familyID", familyID, "error", err,
+			)
+			continue
+		}
+		entries = rankLeaderboard(entries)
+		if !hasLeaderboardActivity(entries) {
+			continue
+		}
+		s.sendDigest(ctx, log, familyID, start, entries)
+	}
+	return nil
+}
+
+// sendDigest отправляет итоги недели членам семьи. Ошибки отправки только логируются
+func (s *RewardsService) sendDigest(
+	ctx context.Context, log *slog.Logger, familyID string, start time.Time, entries []entity.LeaderboardEntry,
+) {
+	members, err := s.userRepo.GetByFamilyID(ctx, familyID)
+	if err != nil {
+		log.Error("Service - RewardsService - sendDigest - GetByFamilyID", "familyID", familyID, "error", err)
+		return
+	}
+
+	week := start.Format("2006-01-02")
+	data, _ := json.Marshal(map[string]string{"type": "rewards_digest", "week_start": week})
+	chores := 0
+	for _, entry := range entries {
+		chores += entry.ChoresCompleted
+	}
+	leader := entries[0]
+	body := fmt.Sprintf(
+		"Лидер недели: %s, баллов: %d. Всего выполнено заданий: %d", leader.Name, leader.PointsEarned, chores,
+	)
+
+	var parents []string
+	for _, member := range members {
+		if member.Role == "Parent" && member.Email != "" {
+			parents = append(parents, member.Email)
+		}
+		err = s.notification.SendNotification(
+			ctx, log, NotificationCreateInput{
+				UserID:      member.Id,
+				Title:       "Итоги недели",
+				Body:        body,
+				Data:        string(data),
+				CollapseKey: "rewards_digest_" + week,
+			},
+		)
+		if err != nil {
+			log.Error("Service - RewardsService - sendDigest - SendNotification", "user_id", member.Id, "error", err)
+		}
+	}
+
+	if len(parents) == 0 {
+		return
+	}
+	err = s.email.SendDigest(ctx, InputSendDigest{To: parents, Body: digestText(start, entries)})
+	if err != nil {
+		log.Error("Service - RewardsService - sendDigest - SendDigest", "familyID", familyID, "error", err)
+	}
+}
+
+// rankLeaderboard сортирует итоги по баллам, затем по заданиям и имени и расставляет места.
+// Одинаковые баллы и задания делят место, следующее место пропускается
+func rankLeaderboard(entries []entity.LeaderboardEntry) []entity.LeaderboardEntry {
+	sort.SliceStable(
+		entries, func(i, j int) bool {
+			if entries[i].PointsEarned != entries[j].PointsEarned {
+				return entries[i].PointsEarned > entries[j].PointsEarned
+			}
+			if entries[i].ChoresCompleted != entries[j].ChoresCompleted {
+				return entries[i].ChoresCompleted > entries[j].ChoresCompleted
+			}
+			return entries[i].Name < entries[j].Name
+		},
+	)
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].PointsEarned == entries[i-1].PointsEarned &&
+			entries[i].ChoresCompleted == entries[i-1].ChoresCompleted {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	if entries == nil {
+		entries = []entity.LeaderboardEntry{}
+	}
+	return entries
+}
+
+// hasLeaderboardActivity сообщает, было ли у кого-то из семьи за период хоть что-то
+func hasLeaderboardActivity(entries []entity.LeaderboardEntry) bool {
+	for _, entry := range entries {
+		if entry.PointsEarned > 0 || entry.ChoresCompleted > 0 || entry.Redemptions > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// digestText формирует текст письма с итогами недели, начинающейся в start
+func digestText(start time.Time, entries []entity.LeaderboardEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(
+		&b, "Итоги недели %s - %s\n\n", start.Format("02.01.2006"), start.AddDate(0, 0, 6).Format("02.01.2006"),
+	)
+	for _, entry := range entries {
+		fmt.Fprintf(
+			&b, "%d. %s: заработано баллов %d, выполнено заданий %d, потрачено баллов %d (обменов: %d)\n",
+			entry.Rank, entry.Name, entry.PointsEarned, entry.ChoresCompleted, entry.PointsSpent, entry.Redemptions,
+		)
+	}
+	return b.String()
+}
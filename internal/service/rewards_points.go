@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+const (
+	defaultPointHistoryLimit = 20
+	maxPointHistoryLimit     = 100
+)
+
+// PointHistoryInput - параметры страницы журнала баллов
+type PointHistoryInput struct {
+	UserID string
+	// Cursor - next_cursor предыдущей страницы
+	Cursor string
+	Limit  int
+}
+
+// GetPointHistory возвращает журнал операций с баллами пользователя от новых к старым
+func (s *RewardsService) GetPointHistory(ctx context.Context, log *slog.Logger, input PointHistoryInput) (
+	entity.PointHistoryPage, error,
+) {
+	log.Info("Service - RewardsService - GetPointHistory", "userID", input.UserID)
+
+	limit := input.Limit
+	if limit <= 0 || limit > maxPointHistoryLimit {
+		limit = defaultPointHistoryLimit
+	}
+
+	// Лишняя запись показывает, что есть следующая страница
+	transactions, err := s.rewardsRepo.GetPointTransactions(ctx, input.UserID, input.Cursor, uint64(limit)+1)
+	if err != nil {
+		log.Error("Service - RewardsService - GetPointHistory", "error", err)
+		return entity.PointHistoryPage{}, fmt.Errorf("failed to get point history: %w", err)
+	}
+
+	page := entity.PointHistoryPage{Items: transactions}
+	if len(transactions) > limit {
+		page.Items = transactions[:limit]
+		page.NextCursor = page.Items[limit-1].ID
+	}
+	if page.Items == nil {
+		page.Items = []entity.PointTransaction{}
+	}
+	return page, nil
+}
+
+// pointsError переводит ошибку операции с баллами в ошибку сервиса
+func pointsError(err error, message string) error {
+	switch {
+	case errors.Is(err, repoerrs.ErrNegativeBalance):
+		return ErrNotEnoughPoints
+	case errors.Is(err, repoerrs.ErrNotFound):
+		return ErrUserNotFound
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+}
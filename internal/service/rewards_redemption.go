@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// RedemptionReviewInput - решение родителя по запросу на обмен
+type RedemptionReviewInput struct {
+	ID     string
+	UserID string
+	// Comment - комментарий родителя, например причина отказа
+	Comment string
+}
+
+// GetFamilyRedemptions возвращает обмены всех членов семьи родителя, пустой status - в любом статусе
+func (s *RewardsService) GetFamilyRedemptions(ctx context.Context, log *slog.Logger, userID, status string) (
+	[]entity.RewardRedemption, error,
+) {
+	log.Info("Service - RewardsService - GetFamilyRedemptions", "userID", userID, "status", status)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid {
+		return nil, ErrForbidden
+	}
+
+	var statuses []string
+	if status != "" {
+		statuses = []string{status}
+	}
+	redemptions, err := s.rewardsRepo.GetRedemptionsByFamilyID(ctx, user.FamilyId.String, statuses)
+	if err != nil {
+		log.Error("Service - RewardsService - GetFamilyRedemptions", "error", err)
+		return nil, fmt.Errorf("failed to get family redemptions: %w", err)
+	}
+	if redemptions == nil {
+		redemptions = []entity.RewardRedemption{}
+	}
+	return redemptions, nil
+}
+
+// ApproveRedemption одобряет запрос на обмен в статусе Pending
+func (s *RewardsService) ApproveRedemption(ctx context.Context, log *slog.Logger, input RedemptionReviewInput) (
+	entity.RewardRedemption, error,
+) {
+	log.Info("Service - RewardsService - ApproveRedemption", "id", input.ID)
+
+	redemption, err := s.transitionRedemption(
+		ctx, log, input, []string{entity.RedemptionStatusPending}, entity.RedemptionStatusApproved,
+	)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+	s.sendRewardNotification(
+		ctx, log, redemption.UserID, redemption, "reward_approved", "Награда одобрена",
+		fmt.Sprintf("Запрос на награду '%s' одобрен", redemption.Reward.Title),
+	)
+	return redemption, nil
+}
+
+// DeclineRedemption отклоняет запрос на обмен в статусе Pending или Approved и возвращает списанные баллы
+func (s *RewardsService) DeclineRedemption(ctx context.Context, log *slog.Logger, input RedemptionReviewInput) (
+	entity.RewardRedemption, error,
+) {
+	log.Info("Service - RewardsService - DeclineRedemption", "id", input.ID)
+
+	redemption, err := s.transitionRedemption(
+		ctx, log, input, []string{entity.RedemptionStatusPending, entity.RedemptionStatusApproved},
+		entity.RedemptionStatusDeclined,
+	)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+
+	body := fmt.Sprintf(
+		"Запрос на награду '%s' отклонен, возвращено баллов: %d", redemption.Reward.Title, redemption.Cost,
+	)
+	if redemption.ReviewComment != "" {
+		body += ". " + redemption.ReviewComment
+	}
+	s.sendRewardNotification(ctx, log, redemption.UserID, redemption, "reward_declined", "Награда отклонена", body)
+	return redemption, nil
+}
+
+// FulfillRedemption отмечает одобренный обмен выполненным: награда получена
+func (s *RewardsService) FulfillRedemption(ctx context.Context, log *slog.Logger, input RedemptionReviewInput) (
+	entity.RewardRedemption, error,
+) {
+	log.Info("Service - RewardsService - FulfillRedemption", "id", input.ID)
+
+	redemption, err := s.transitionRedemption(
+		ctx, log, input, []string{entity.RedemptionStatusApproved}, entity.RedemptionStatusFulfilled,
+	)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+	s.sendRewardNotification(
+		ctx, log, redemption.UserID, redemption, "reward_fulfilled", "Награда выдана",
+		fmt.Sprintf("Награда '%s' выдана", redemption.Reward.Title),
+	)
+	return redemption, nil
+}
+
+// transitionRedemption проверяет, что пользователь - родитель семьи вознаграждения, переводит обмен
+// из одного из статусов from в to и возвращает обновленный обмен
+func (s *RewardsService) transitionRedemption(
+	ctx context.Context, log *slog.Logger, input RedemptionReviewInput, from []string, to string,
+) (entity.RewardRedemption, error) {
+	redemption, err := s.getRedemption(ctx, log, input.ID)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return entity.RewardRedemption{}, ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid || user.FamilyId.String != redemption.Reward.FamilyID {
+		return entity.RewardRedemption{}, ErrForbidden
+	}
+
+	err = s.rewardsRepo.TransitionRedemption(
+		ctx, entity.RedemptionTransition{
+			ID:         input.ID,
+			ReviewerID: input.UserID,
+			From:       from,
+			To:         to,
+			Comment:    input.Comment,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.RewardRedemption{}, ErrInvalidRedemptionTransition
+		}
+		log.Error("Service - RewardsService - transitionRedemption", "error", err)
+		return entity.RewardRedemption{}, fmt.Errorf("failed to change redemption status: %w", err)
+	}
+
+	return s.getRedemption(ctx, log, input.ID)
+}
+
+func (s *RewardsService) getRedemption(ctx context.Context, log *slog.Logger, id string) (
+	entity.RewardRedemption, error,
+) {
+	redemption, err := s.rewardsRepo.GetRedemptionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.RewardRedemption{}, ErrRedemptionNotFound
+		}
+		log.Error("Service - RewardsService - getRedemption", "error", err)
+		return entity.RewardRedemption{}, fmt.Errorf("failed to get redemption: %w", err)
+	}
+	return redemption, nil
+}
+
+// notifyParents уведомляет родителей семьи о новом запросе на обмен
+func (s *RewardsService) notifyParents(ctx context.Context, log *slog.Logger, redemption entity.RewardRedemption) {
+	requester, err := s.userRepo.GetByID(ctx, redemption.UserID)
+	if err != nil {
+		log.Error("Service - RewardsService - notifyParents - GetByID", "error", err)
+		return
+	}
+	members, err := s.userRepo.GetByFamilyID(ctx, redemption.Reward.FamilyID)
+	if err != nil {
+		log.Error("Service - RewardsService - notifyParents - GetByFamilyID", "error", err)
+		return
+	}
+
+	for _, member := range members {
+		if member.Role != "Parent" || member.Id == redemption.UserID {
+			continue
+		}
+		s.sendRewardNotification(
+			ctx, log, member.Id, redemption, "reward_requested", "Запрос на награду",
+			fmt.Sprintf("%s хочет получить награду '%s'", requester.Name, redemption.Reward.Title),
+		)
+	}
+}
+
+// sendRewardNotification отправляет пользователю уведомление об обмене
+func (s *RewardsService) sendRewardNotification(
+	ctx context.Context, log *slog.Logger, userID string, redemption entity.RewardRedemption, kind, title, body string,
+) {
+	data, _ := json.Marshal(map[string]string{"type": kind, "redemption_id": redemption.ID})
+
+	err := s.notification.SendNotification(
+		ctx, log, NotificationCreateInput{
+			UserID:      userID,
+			Title:       title,
+			Body:        body,
+			Data:        string(data),
+			CollapseKey: "reward_" + redemption.ID,
+		},
+	)
+	if err != nil {
+		log.Error(
+			"Service - RewardsService - sendRewardNotification", "user_id", userID, "redemption_id", redemption.ID,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// SavingsGoalInput - действие пользователя с целью накопления
+type SavingsGoalInput struct {
+	GoalID string
+	UserID string
+	// Points - сколько баллов отложить на цель или снять с нее
+	Points int
+}
+
+// CreateSavingsGoal создает цель накопления на награду семьи пользователя.
+// На одну награду у пользователя может быть одна незавершенная цель
+func (s *RewardsService) CreateSavingsGoal(ctx context.Context, log *slog.Logger, userID, rewardID string) (
+	entity.SavingsGoal, error,
+) {
+	log.Info("Service - RewardsService - CreateSavingsGoal", "userID", userID, "rewardID", rewardID)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.SavingsGoal{}, ErrUserNotFound
+	}
+	reward, err := s.rewardsRepo.GetByID(ctx, rewardID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.SavingsGoal{}, ErrRewardNotFound
+		}
+		log.Error("Service - RewardsService - CreateSavingsGoal - GetByID", "error", err)
+		return entity.SavingsGoal{}, fmt.Errorf("failed to get reward: %w", err)
+	}
+	if !user.FamilyId.Valid || user.FamilyId.String != reward.FamilyID {
+		return entity.SavingsGoal{}, ErrRewardNotFound
+	}
+
+	id, err := s.rewardsRepo.CreateSavingsGoal(ctx, userID, rewardID)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrAlreadyExists) {
+			return entity.SavingsGoal{}, ErrSavingsGoalExists
+		}
+		log.Error("Service - RewardsService - CreateSavingsGoal", "error", err)
+		return entity.SavingsGoal{}, fmt.Errorf("failed to create savings goal: %w", err)
+	}
+
+	goal, err := s.getSavingsGoal(ctx, log, id, userID)
+	if err != nil {
+		return entity.SavingsGoal{}, err
+	}
+	return withGoalProgress(goal, reward), nil
+}
+
+// GetSavingsGoals возвращает цели накопления пользователя с прогрессом: сначала незавершенные
+func (s *RewardsService) GetSavingsGoals(ctx context.Context, log *slog.Logger, userID string) (
+	[]entity.SavingsGoal, error,
+) {
+	log.Info("Service - RewardsService - GetSavingsGoals", "userID", userID)
+
+	goals, err := s.rewardsRepo.GetSavingsGoalsByUserID(ctx, userID)
+	if err != nil {
+		log.Error("Service - RewardsService - GetSavingsGoals", "error", err)
+		return nil, fmt.Errorf("failed to get savings goals: %w", err)
+	}
+
+	rewards := make(map[string]entity.Reward)
+	for i, goal := range goals {
+		reward, ok := rewards[goal.RewardID]
+		if !ok {
+			if reward, err = s.rewardsRepo.GetByID(ctx, goal.RewardID); err != nil {
+				log.Error("Service - RewardsService - GetSavingsGoals - GetByID", "error", err)
+				return nil, fmt.Errorf("failed to get reward: %w", err)
+			}
+			rewards[goal.RewardID] = reward
+		}
+		goals[i] = withGoalProgress(goal, reward)
+	}
+	if goals == nil {
+		goals = []entity.SavingsGoal{}
+	}
+	return goals, nil
+}
+
+// DepositSavingsGoal откладывает input.Points баллов с баланса на незавершенную цель
+func (s *RewardsService) DepositSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) (
+	entity.SavingsGoal, error,
+) {
+	log.Info("Service - RewardsService - DepositSavingsGoal", "goalID", input.GoalID, "points", input.Points)
+	return s.moveGoalPoints(ctx, log, input, input.Points)
+}
+
+// WithdrawSavingsGoal возвращает input.Points баллов с незавершенной цели на баланс
+func (s *RewardsService) WithdrawSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) (
+	entity.SavingsGoal, error,
+) {
+	log.Info("Service - RewardsService - WithdrawSavingsGoal", "goalID", input.GoalID, "points", input.Points)
+	return s.moveGoalPoints(ctx, log, input, -input.Points)
+}
+
+// RedeemSavingsGoal обменивает накопленные на цель баллы на ее награду и завершает цель. Недостающие баллы
+// списываются с баланса, лишние остаются на нем. Обмен проходит те же проверки и то же согласование
+// родителем, что и обычный обмен
+func (s *RewardsService) RedeemSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) (
+	entity.RewardRedemption, error,
+) {
+	log.Info("Service - RewardsService - RedeemSavingsGoal", "goalID", input.GoalID)
+
+	goal, err := s.getSavingsGoal(ctx, log, input.GoalID, input.UserID)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+	if goal.CompletedAt != nil {
+		return entity.RewardRedemption{}, ErrSavingsGoalCompleted
+	}
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return entity.RewardRedemption{}, ErrUserNotFound
+	}
+
+	redemptionID, err := s.rewardsRepo.RedeemSavingsGoal(ctx, goal.ID, redeemCheck(user))
+	if err != nil {
+		log.Error("Service - RewardsService - RedeemSavingsGoal", "error", err)
+		return entity.RewardRedemption{}, redeemError(err)
+	}
+
+	redemption, err := s.getRedemption(ctx, log, redemptionID)
+	if err != nil {
+		return entity.RewardRedemption{}, err
+	}
+	s.notifyParents(ctx, log, redemption)
+	s.achievements.Evaluate(ctx, log, input.UserID, entity.AchievementEventRewardRedeemed)
+	return redemption, nil
+}
+
+// DeleteSavingsGoal удаляет цель накопления, отложенные баллы возвращаются на баланс
+func (s *RewardsService) DeleteSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) error {
+	log.Info("Service - RewardsService - DeleteSavingsGoal", "goalID", input.GoalID)
+
+	if _, err := s.getSavingsGoal(ctx, log, input.GoalID, input.UserID); err != nil {
+		return err
+	}
+	if err := s.rewardsRepo.DeleteSavingsGoal(ctx, input.GoalID); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return ErrSavingsGoalNotFound
+		}
+		log.Error("Service - RewardsService - DeleteSavingsGoal", "error", err)
+		return pointsError(err, "failed to delete savings goal")
+	}
+	return nil
+}
+
+// moveGoalPoints переводит points баллов на цель пользователя, отрицательное значение - с цели на баланс
+func (s *RewardsService) moveGoalPoints(ctx context.Context, log *slog.Logger, input SavingsGoalInput, points int) (
+	entity.SavingsGoal, error,
+) {
+	goal, err := s.getSavingsGoal(ctx, log, input.GoalID, input.UserID)
+	if err != nil {
+		return entity.SavingsGoal{}, err
+	}
+	if goal.CompletedAt != nil {
+		return entity.SavingsGoal{}, ErrSavingsGoalCompleted
+	}
+
+	if err = s.rewardsRepo.MoveGoalPoints(ctx, goal.ID, points); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.SavingsGoal{}, ErrSavingsGoalCompleted
+		}
+		log.Error("Service - RewardsService - moveGoalPoints", "error", err)
+		return entity.SavingsGoal{}, pointsError(err, "failed to move savings goal points")
+	}
+
+	goal, err = s.getSavingsGoal(ctx, log, goal.ID, input.UserID)
+	if err != nil {
+		return entity.SavingsGoal{}, err
+	}
+	reward, err := s.rewardsRepo.GetByID(ctx, goal.RewardID)
+	if err != nil {
+		log.Error("Service - RewardsService - moveGoalPoints - GetByID", "error", err)
+		return entity.SavingsGoal{}, fmt.Errorf("failed to get reward: %w", err)
+	}
+	return withGoalProgress(goal, reward), nil
+}
+
+// getSavingsGoal возвращает цель накопления пользователя. Чужая цель считается не найденной
+func (s *RewardsService) getSavingsGoal(ctx context.Context, log *slog.Logger, id, userID string) (
+	entity.SavingsGoal, error,
+) {
+	goal, err := s.rewardsRepo.GetSavingsGoalByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.SavingsGoal{}, ErrSavingsGoalNotFound
+		}
+		log.Error("Service - RewardsService - getSavingsGoal", "error", err)
+		return entity.SavingsGoal{}, fmt.Errorf("failed to get savings goal: %w", err)
+	}
+	if goal.UserID != userID {
+		return entity.SavingsGoal{}, ErrSavingsGoalNotFound
+	}
+	return goal, nil
+}
+
+// withGoalProgress добавляет к цели награду и прогресс накопления
+func withGoalProgress(goal entity.SavingsGoal, reward entity.Reward) entity.SavingsGoal {
+	goal.Reward = reward
+	goal.Progress = 100
+	goal.Remaining = 0
+	if goal.CompletedAt == nil && goal.Saved < reward.Cost {
+		goal.Progress = goal.Saved * 100 / reward.Cost
+		goal.Remaining = reward.Cost - goal.Saved
+	}
+	return goal
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"family-flow-app/config"
 	"family-flow-app/internal/entity"
@@ -43,6 +44,9 @@ type User interface {
 	ResetFamilyID(ctx context.Context, log *slog.Logger, id string) error
 	ExistsByEmail(ctx context.Context, log *slog.Logger, email string) (bool, error)
 	UpdateLocation(ctx context.Context, log *slog.Logger, input UpdateLocationInput) error
+	AddAbsence(ctx context.Context, log *slog.Logger, input AbsenceCreateInput) (entity.UserAbsence, error)
+	GetAbsences(ctx context.Context, log *slog.Logger, userID string) ([]entity.UserAbsence, error)
+	DeleteAbsence(ctx context.Context, log *slog.Logger, userID, id string) error
 }
 
 type InputSendInvite struct {
@@ -52,11 +56,18 @@ type InputSendInvite struct {
 	FamilyName string
 }
 
+// InputSendDigest - письмо с еженедельной сводкой по баллам семьи
+type InputSendDigest struct {
+	To   []string
+	Body string
+}
+
 type Email interface {
 	SendCode(ctx context.Context, to []string) error
 	CompareCode(ctx context.Context, email, code string) (bool, error)
 	GetAllKeys(ctx context.Context) ([]string, error)
 	SendInvite(ctx context.Context, invite InputSendInvite) error
+	SendDigest(ctx context.Context, digest InputSendDigest) error
 }
 
 type FamilyCreateInput struct {
@@ -97,7 +108,7 @@ type WishlistItem interface {
 
 type ShoppingItem interface {
 	Create(ctx context.Context, log *slog.Logger, input ShoppingCreateInput) (string, error)
-	Delete(ctx context.Context, log *slog.Logger, id string) error
+	Delete(ctx context.Context, log *slog.Logger, input ShoppingDeleteInput) error
 	Update(ctx context.Context, log *slog.Logger, input ShoppingUpdateInput) error
 	GetPublicByFamilyID(
 		ctx context.Context, log *slog.Logger, familyID string,
@@ -118,15 +129,54 @@ type ShoppingItem interface {
 		ctx context.Context, log *slog.Logger, input ShoppingCancelUpdateReservedByInput,
 	) error
 	GetByID(ctx context.Context, log *slog.Logger, id string) (entity.ShoppingItem, error)
+	CreateList(ctx context.Context, log *slog.Logger, input ShoppingListCreateInput) (entity.ShoppingList, error)
+	GetLists(ctx context.Context, log *slog.Logger, userID string, archived bool) ([]entity.ShoppingList, error)
+	GetList(ctx context.Context, log *slog.Logger, userID, listID string) (entity.ShoppingListWithItems, error)
+	UpdateList(ctx context.Context, log *slog.Logger, input ShoppingListUpdateInput) (entity.ShoppingList, error)
+	ArchiveList(
+		ctx context.Context, log *slog.Logger, userID, listID string, archived bool,
+	) (entity.ShoppingList, error)
+	DeleteList(ctx context.Context, log *slog.Logger, userID, listID string) error
+	ShareList(ctx context.Context, log *slog.Logger, input ShoppingListShareInput) (entity.ShoppingList, error)
+	GetSpend(
+		ctx context.Context, log *slog.Logger, userID, listID string, month time.Time,
+	) (entity.ShoppingSpendSummary, error)
+	SubscribeEvents(userID string) (<-chan entity.ShoppingEvent, func())
 }
 
 type TodoItem interface {
 	Create(ctx context.Context, log *slog.Logger, input TodoCreateInput) (string, error)
-	Delete(ctx context.Context, log *slog.Logger, id string) error
+	Delete(ctx context.Context, log *slog.Logger, id, userID string) error
 	Update(ctx context.Context, log *slog.Logger, input TodoUpdateInput) error
 	GetByAssignedTo(ctx context.Context, log *slog.Logger, assignedTo string) ([]entity.TodoItem, error)
 	GetByCreatedBy(ctx context.Context, log *slog.Logger, createdBy string) ([]entity.TodoItem, error)
 	GetByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoItem, error)
+	List(ctx context.Context, log *slog.Logger, input TodoListInput) (entity.TodoPage, error)
+	GetSeries(ctx context.Context, log *slog.Logger, userID, todoID string) (entity.TodoSeries, error)
+	DeleteSeries(ctx context.Context, log *slog.Logger, userID, todoID string) (entity.TodoBulkResult, error)
+	SetRotation(ctx context.Context, log *slog.Logger, input TodoRotationInput) (entity.TodoSeries, error)
+	GetRotationSchedule(ctx context.Context, log *slog.Logger, userID, todoID string, count int) (
+		[]entity.TodoRotationSlot, error,
+	)
+	GenerateOccurrences(ctx context.Context, log *slog.Logger) error
+	RunRecurrenceGenerator(ctx context.Context, log *slog.Logger, interval time.Duration)
+	RunDeadlineScheduler(ctx context.Context, log *slog.Logger, interval time.Duration, offsets []time.Duration)
+	RunArchiveScheduler(ctx context.Context, log *slog.Logger, interval time.Duration, archiveAfter time.Duration)
+	Bulk(ctx context.Context, log *slog.Logger, input TodoBulkInput) (entity.TodoBulkResult, error)
+	AddComment(ctx context.Context, log *slog.Logger, input TodoCommentInput) (entity.TodoComment, error)
+	GetComments(ctx context.Context, log *slog.Logger, todoID, userID string) ([]entity.TodoComment, error)
+	DeleteComment(ctx context.Context, log *slog.Logger, input TodoCommentInput) error
+	GetActivity(ctx context.Context, log *slog.Logger, todoID, userID string) ([]entity.TodoActivity, error)
+	Submit(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error)
+	Approve(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error)
+	Reject(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error)
+	GetForReview(ctx context.Context, log *slog.Logger, userID string) ([]entity.TodoItem, error)
+	AddChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (entity.TodoItem, error)
+	UpdateChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (entity.TodoItem, error)
+	DeleteChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (entity.TodoItem, error)
+	ReorderChecklist(
+		ctx context.Context, log *slog.Logger, input TodoChecklistInput, ids []string,
+	) (entity.TodoItem, error)
 }
 
 type Notification interface {
@@ -154,19 +204,66 @@ type Chats interface {
 		ctx context.Context, log *slog.Logger, userID string,
 	) ([]entity.Chat, error)
 	GetChatsWithLastMessage(ctx context.Context, log *slog.Logger, userID string) ([]entity.Chat, error)
+	GetFamilyChat(ctx context.Context, log *slog.Logger, familyID string) (entity.Chat, error)
+	GetOrCreateDirectChat(ctx context.Context, log *slog.Logger, input DirectChatInput) (string, error)
+	SetParticipantMuted(ctx context.Context, log *slog.Logger, input ModerateParticipantInput, muted bool) error
+	RemoveParticipant(ctx context.Context, log *slog.Logger, input ModerateParticipantInput) error
+	SetParticipantRole(ctx context.Context, log *slog.Logger, input ModerateParticipantInput, role string) error
+	SetChatLocked(ctx context.Context, log *slog.Logger, chatID, actorID string, locked bool) error
+	DeleteMessage(ctx context.Context, log *slog.Logger, chatID, messageID, actorID string) (entity.Message, error)
+	SearchMessages(ctx context.Context, log *slog.Logger, input SearchMessagesInput) ([]entity.MessageSearchResult, error)
+	GetMessagesAround(
+		ctx context.Context, log *slog.Logger, userID, chatID, messageID string, limit int,
+	) ([]entity.Message, error)
+	SetPresence(log *slog.Logger, userID, connID, state string)
+	SetNotificationsMuted(ctx context.Context, log *slog.Logger, chatID, userID string, muted bool) error
 }
 
 type Rewards interface {
 	Create(ctx context.Context, log *slog.Logger, input entity.Reward) (string, error)
-	GetRewardsByFamilyID(ctx context.Context, log *slog.Logger, familyID string) ([]entity.Reward, error)
+	GetRewardsByFamilyID(ctx context.Context, log *slog.Logger, familyID, userID string) ([]entity.Reward, error)
 	AddPoints(ctx context.Context, log *slog.Logger, userID string, points int) error
 	SubtractPoints(ctx context.Context, log *slog.Logger, userID string, points int) error
 	GetPoints(ctx context.Context, log *slog.Logger, userID string) (int, error)
-	Redeem(ctx context.Context, log *slog.Logger, userID, rewardID string) error
+	GetPointHistory(ctx context.Context, log *slog.Logger, input PointHistoryInput) (entity.PointHistoryPage, error)
+	AdjustPoints(ctx context.Context, log *slog.Logger, input PointAdjustmentInput) (entity.PointTransaction, error)
+	UndoAdjustment(
+		ctx context.Context, log *slog.Logger, parentID, transactionID string,
+	) (entity.PointTransaction, error)
+	Redeem(ctx context.Context, log *slog.Logger, userID, rewardID string) (entity.RewardRedemption, error)
 	GetRedemptionsByUserID(ctx context.Context, log *slog.Logger, userID string) ([]entity.RewardRedemption, error)
-	Update(ctx context.Context, log *slog.Logger, reward entity.Reward) error
+	GetFamilyRedemptions(
+		ctx context.Context, log *slog.Logger, userID, status string,
+	) ([]entity.RewardRedemption, error)
+	ApproveRedemption(
+		ctx context.Context, log *slog.Logger, input RedemptionReviewInput,
+	) (entity.RewardRedemption, error)
+	DeclineRedemption(
+		ctx context.Context, log *slog.Logger, input RedemptionReviewInput,
+	) (entity.RewardRedemption, error)
+	FulfillRedemption(
+		ctx context.Context, log *slog.Logger, input RedemptionReviewInput,
+	) (entity.RewardRedemption, error)
+	CreateSavingsGoal(ctx context.Context, log *slog.Logger, userID, rewardID string) (entity.SavingsGoal, error)
+	GetSavingsGoals(ctx context.Context, log *slog.Logger, userID string) ([]entity.SavingsGoal, error)
+	DepositSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) (entity.SavingsGoal, error)
+	WithdrawSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) (entity.SavingsGoal, error)
+	RedeemSavingsGoal(
+		ctx context.Context, log *slog.Logger, input SavingsGoalInput,
+	) (entity.RewardRedemption, error)
+	DeleteSavingsGoal(ctx context.Context, log *slog.Logger, input SavingsGoalInput) error
+	GetAllowanceSettings(ctx context.Context, log *slog.Logger, userID string) (entity.AllowanceSettings, error)
+	UpdateAllowanceSettings(
+		ctx context.Context, log *slog.Logger, input AllowanceSettingsInput,
+	) (entity.AllowanceSettings, error)
+	GetAllowanceReport(
+		ctx context.Context, log *slog.Logger, userID string, week time.Time,
+	) (entity.AllowanceReport, error)
+	GetLeaderboard(ctx context.Context, log *slog.Logger, userID, period string) (entity.Leaderboard, error)
+	RunWeeklyDigest(ctx context.Context, log *slog.Logger, interval time.Duration)
+	Update(ctx context.Context, log *slog.Logger, userID string, reward entity.Reward) error
 	GetByID(ctx context.Context, log *slog.Logger, id string) (entity.Reward, error)
-	Delete(ctx context.Context, log *slog.Logger, id string) error
+	Delete(ctx context.Context, log *slog.Logger, userID, id string) error
 }
 
 type File interface {
@@ -182,6 +279,23 @@ type Diary interface {
 	Delete(ctx context.Context, log *slog.Logger, id string) error
 }
 
+type Calendar interface {
+	GetEvents(ctx context.Context, log *slog.Logger, input CalendarInput) ([]entity.CalendarEvent, error)
+	GetSubscription(ctx context.Context, log *slog.Logger, userID string) (string, error)
+	ResetSubscription(ctx context.Context, log *slog.Logger, userID string) (string, error)
+	GetFeed(ctx context.Context, log *slog.Logger, token string) (string, error)
+}
+
+type Achievements interface {
+	Evaluate(ctx context.Context, log *slog.Logger, userID, event string)
+	GetUserAchievements(
+		ctx context.Context, log *slog.Logger, viewerID, userID string,
+	) (entity.UserAchievements, error)
+	GetRules(ctx context.Context, log *slog.Logger, userID string) ([]entity.AchievementRule, error)
+	SaveRule(ctx context.Context, log *slog.Logger, input AchievementRuleInput) (entity.AchievementRule, error)
+	DeleteRule(ctx context.Context, log *slog.Logger, userID, code string) error
+}
+
 type Services struct {
 	User         User
 	Email        Email
@@ -194,6 +308,8 @@ type Services struct {
 	Rewards      Rewards
 	File         File
 	Diary        Diary
+	Calendar     Calendar
+	Achievements Achievements
 }
 
 type ServicesDependencies struct {
@@ -208,17 +324,28 @@ type ServicesDependencies struct {
 }
 
 func NewServices(ctx context.Context, dep ServicesDependencies) *Services {
+	notification := NewNotificationService(ctx, dep.App, dep.Repos.Notification, dep.Repos.NotificationToken)
+	achievements := NewAchievementService(dep.Repos.Achievements, dep.Repos.User, notification)
+	email := NewEmailService(dep.Config.Email)
 	return &Services{
-		User:         NewUserService(dep.Repos.User),
-		Email:        NewEmailService(dep.Config.Email),
-		Family:       NewFamilyService(dep.Repos.Family, dep.Repos.User),
-		WishlistItem: NewWishlistService(dep.Repos.WishlistItem),
-		ShoppingItem: NewShoppingService(dep.Repos.ShoppingItem),
-		TodoItem:     NewTodoService(dep.Repos.TodosItem, dep.Repos.User),
-		Notification: NewNotificationService(ctx, dep.App, dep.Repos.Notification, dep.Repos.NotificationToken),
-		Chats:        NewChatMessageService(dep.Repos.Chat, dep.Repos.Message),
-		Rewards:      NewRewardsService(dep.Repos.Rewards, dep.Repos.User),
+		User:         NewUserService(dep.Repos.User, dep.Repos.Chat, dep.Repos.Absence),
+		Email:        email,
+		Family:       NewFamilyService(dep.Repos.Family, dep.Repos.User, dep.Repos.Chat),
+		WishlistItem: NewWishlistService(dep.Repos.WishlistItem, achievements),
+		ShoppingItem: NewShoppingService(dep.Repos.ShoppingItem, dep.Repos.User),
+		TodoItem: NewTodoService(
+			dep.Repos.TodosItem, dep.Repos.User, dep.Repos.Absence, notification, achievements,
+			dep.Config.Todo.DeleteUndoWindow,
+		),
+		Notification: notification,
+		Chats:        NewChatMessageService(ctx, dep.Repos.Chat, dep.Repos.Message, dep.Repos.User, notification),
+		Rewards: NewRewardsService(
+			dep.Repos.Rewards, dep.Repos.User, notification, achievements, email,
+			dep.Config.Rewards.LeaderboardTTL,
+		),
 		File:         NewFileService(ctx, dep.BucketName, dep.Region, dep.EndpointResolver),
 		Diary:        NewDiaryService(dep.Repos.Diary),
+		Calendar:     NewCalendarService(dep.Repos.Calendar, dep.Repos.TodosItem, dep.Repos.User, dep.Repos.Absence),
+		Achievements: achievements,
 	}
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// shoppingEventBuffer - сколько событий может ждать отправки одному подписчику. События для переполненного
+// подписчика отбрасываются, клиент замечает это по пропуску версии покупки
+const shoppingEventBuffer = 64
+
+// shoppingEventHub рассылает события покупок подписчикам текущего процесса. Клиенты, подключенные к другой
+// реплике API, эти события не получают, поэтому API рассчитан на запуск в одном экземпляре, см. config.HTTP
+type shoppingEventHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan entity.ShoppingEvent]struct{} // userID -> подписки
+}
+
+func newShoppingEventHub() *shoppingEventHub {
+	return &shoppingEventHub{subscribers: make(map[string]map[chan entity.ShoppingEvent]struct{})}
+}
+
+func (h *shoppingEventHub) subscribe(userID string) (<-chan entity.ShoppingEvent, func()) {
+	events := make(chan entity.ShoppingEvent, shoppingEventBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan entity.ShoppingEvent]struct{})
+	}
+	h.subscribers[userID][events] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(
+			func() {
+				h.mu.Lock()
+				defer h.mu.Unlock()
+				delete(h.subscribers[userID], events)
+				if len(h.subscribers[userID]) == 0 {
+					delete(h.subscribers, userID)
+				}
+				close(events)
+			},
+		)
+	}
+}
+
+// publish отправляет событие подпискам пользователей userIDs, не дожидаясь медленных подписчиков
+func (h *shoppingEventHub) publish(log *slog.Logger, userIDs []string, event entity.ShoppingEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, userID := range userIDs {
+		for events := range h.subscribers[userID] {
+			select {
+			case events <- event:
+			default:
+				log.Warn(
+					"Service - shoppingEventHub - publish - Subscriber is too slow, event dropped",
+					"user_id", userID, "item_id", event.ItemID, "version", event.Version,
+				)
+			}
+		}
+	}
+}
+
+// SubscribeEvents подписывает пользователя на изменения покупок во всех видимых ему списках.
+// Возвращенная функция отменяет подписку и закрывает канал
+func (s *ShoppingService) SubscribeEvents(userID string) (<-chan entity.ShoppingEvent, func()) {
+	return s.events.subscribe(userID)
+}
+
+// publishItemEvent сообщает об изменении покупки всем, кому виден ее список, включая автора изменения:
+// его другие устройства тоже должны обновиться. Ошибки только логируются
+func (s *ShoppingService) publishItemEvent(
+	ctx context.Context, log *slog.Logger, eventType, change string, item entity.ShoppingItem, actorID string,
+) {
+	recipients, err := s.itemRecipients(ctx, log, item)
+	if err != nil {
+		log.Error("Service - ShoppingService - publishItemEvent", "item_id", item.ID, "error", err)
+		return
+	}
+
+	event := entity.ShoppingEvent{
+		Type:      eventType,
+		Change:    change,
+		ItemID:    item.ID,
+		ListID:    item.ListID,
+		Version:   item.Version,
+		ActorID:   actorID,
+		Item:      item,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.events.publish(log, recipients, event)
+}
+
+// itemRecipients возвращает пользователей, которым видна покупка: членов семьи для списка семьи,
+// владельца и тех, с кем он поделился, для личного списка
+func (s *ShoppingService) itemRecipients(ctx context.Context, log *slog.Logger, item entity.ShoppingItem) (
+	[]string, error,
+) {
+	familyID := ""
+	userIDs := []string{item.CreatedBy}
+	if item.ListID != "" {
+		list, err := s.shoppingRepo.GetListByID(ctx, log, item.ListID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shopping list: %w", err)
+		}
+		familyID = list.FamilyID
+		userIDs = append([]string{list.UserID}, list.SharedWith...)
+	} else if item.Visibility == "Public" {
+		familyID = item.FamilyID
+	}
+	if familyID == "" {
+		return userIDs, nil
+	}
+
+	members, err := s.userRepo.GetByFamilyID(ctx, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get family members: %w", err)
+	}
+	userIDs = make([]string, 0, len(members))
+	for _, member := range members {
+		userIDs = append(userIDs, member.Id)
+	}
+	return userIDs, nil
+}
+
+// shoppingItemError переводит ошибки изменения покупки из репозитория в ошибки сервиса
+func shoppingItemError(err error, message string) error {
+	switch {
+	case errors.Is(err, repoerrs.ErrNotFound):
+		return ErrShoppingItemNotFound
+	case errors.Is(err, repoerrs.ErrVersionConflict):
+		return ErrShoppingItemConflict
+	default:
+		return fmt.Errorf("%s: %w", message, err)
+	}
+}
@@ -12,10 +12,13 @@ import (
 
 type ShoppingService struct {
 	shoppingRepo repo.ShoppingItem
+	userRepo     repo.User
+	// events - подписки на изменения покупок для синхронизации клиентов
+	events *shoppingEventHub
 }
 
-func NewShoppingService(shoppingRepo repo.ShoppingItem) *ShoppingService {
-	return &ShoppingService{shoppingRepo: shoppingRepo}
+func NewShoppingService(shoppingRepo repo.ShoppingItem, userRepo repo.User) *ShoppingService {
+	return &ShoppingService{shoppingRepo: shoppingRepo, userRepo: userRepo, events: newShoppingEventHub()}
 }
 
 type ShoppingCreateInput struct {
@@ -24,8 +27,18 @@ type ShoppingCreateInput struct {
 	Description string
 	Visibility  string
 	CreatedBy   string
+	// ListID - список покупки. Пустой - список по умолчанию семьи для Public или создателя для Private
+	ListID string
+	// Quantity - количество, по умолчанию 1. Category - отдел магазина, по умолчанию entity.ShoppingCategoryOther
+	Quantity            float64
+	Unit                string
+	Category            string
+	EstimatedPriceMinor *int
+	Store               string
 }
 
+// Create добавляет покупку в список. Видимость покупки определяется списком: Public в списке семьи,
+// Private в личном списке
 func (s *ShoppingService) Create(ctx context.Context, log *slog.Logger, input ShoppingCreateInput) (string, error) {
 	log.Info("Service - ShoppingService - Create")
 
@@ -35,58 +48,129 @@ func (s *ShoppingService) Create(ctx context.Context, log *slog.Logger, input Sh
 		Description: input.Description,
 		Visibility:  input.Visibility,
 		CreatedBy:   input.CreatedBy,
+		ListID:      input.ListID,
+
+		Quantity:            shoppingQuantity(input.Quantity),
+		Unit:                input.Unit,
+		Category:            shoppingCategory(input.Category),
+		EstimatedPriceMinor: input.EstimatedPriceMinor,
+		Store:               input.Store,
+	}
+
+	if item.ListID != "" {
+		list, _, err := s.accessibleList(ctx, log, input.CreatedBy, input.ListID, false)
+		if err != nil {
+			return "", err
+		}
+		if list.IsArchived {
+			return "", ErrShoppingListArchived
+		}
+		item.Visibility = "Private"
+		if list.FamilyID != "" {
+			item.FamilyID = list.FamilyID
+			item.Visibility = "Public"
+		}
+	} else {
+		familyID, userID := input.FamilyID, ""
+		if input.Visibility == "Private" {
+			familyID, userID = "", input.CreatedBy
+		}
+		listID, err := s.shoppingRepo.GetDefaultListID(ctx, log, familyID, userID)
+		if err != nil {
+			log.Error("Service - ShoppingService - Create - GetDefaultListID", "error", err)
+			return "", fmt.Errorf("failed to get default shopping list: %w", err)
+		}
+		item.ListID = listID
 	}
 
 	id, err := s.shoppingRepo.Create(ctx, log, item)
 	if err != nil {
-		log.Error("Service - ShoppingService - Create: %v", err)
+		log.Error("Service - ShoppingService - Create", "error", err)
 		return "", err
 	}
 
+	created, err := s.shoppingRepo.GetByID(ctx, log, id)
+	if err != nil {
+		log.Error("Service - ShoppingService - Create - GetByID", "error", err)
+		return id, nil
+	}
+	s.publishItemEvent(
+		ctx, log, entity.ShoppingEventItemCreated, entity.ShoppingChangeCreated, created, input.CreatedBy,
+	)
 	return id, nil
 }
 
-func (s *ShoppingService) Delete(ctx context.Context, log *slog.Logger, id string) error {
+// ShoppingDeleteInput - удаление покупки. Ненулевой Version - версия покупки, известная клиенту
+type ShoppingDeleteInput struct {
+	Id      string
+	UserID  string
+	Version int64
+}
+
+func (s *ShoppingService) Delete(ctx context.Context, log *slog.Logger, input ShoppingDeleteInput) error {
 	log.Info("Service - ShoppingService - Delete")
 
-	err := s.shoppingRepo.Delete(ctx, log, id)
+	item, err := s.shoppingRepo.Delete(ctx, log, input.Id, input.Version)
 	if err != nil {
-		log.Error("Service - ShoppingService - Delete: %v", err)
-		return err
+		log.Error("Service - ShoppingService - Delete", "error", err)
+		return shoppingItemError(err, "failed to delete shopping item")
 	}
 
+	// Удаление - тоже изменение: клиенты отличают его от более ранних событий по версии
+	item.Version++
+	s.publishItemEvent(ctx, log, entity.ShoppingEventItemDeleted, entity.ShoppingChangeDeleted, item, input.UserID)
 	return nil
 }
 
+// ShoppingUpdateInput - новые поля покупки. Ненулевой Version - версия покупки, известная клиенту:
+// если покупку успели изменить, обновление не выполняется
 type ShoppingUpdateInput struct {
 	ID          string
+	UserID      string
+	Version     int64
 	Title       string
 	Description string
 	Status      string
 	Visibility  string
 	IsArchived  bool
+
+	Quantity            float64
+	Unit                string
+	Category            string
+	EstimatedPriceMinor *int
+	ActualPriceMinor    *int
+	Store               string
 }
 
 func (s *ShoppingService) Update(ctx context.Context, log *slog.Logger, input ShoppingUpdateInput) error {
 	log.Info("Service - ShoppingService - Update")
-	log.Info(fmt.Sprintf("ShoppingService - Update - IsArchived - %s", input.IsArchived))
+	log.Info(fmt.Sprintf("ShoppingService - Update - IsArchived - %t", input.IsArchived))
 
-	err := s.shoppingRepo.Update(
+	item, err := s.shoppingRepo.Update(
 		ctx, log, entity.ShoppingItem{
 			ID:          input.ID,
+			Version:     input.Version,
 			Title:       input.Title,
 			Description: input.Description,
 			Status:      input.Status,
 			Visibility:  input.Visibility,
 			IsArchived:  input.IsArchived,
 			UpdatedAt:   time.Now().Add(time.Hour * 3),
+
+			Quantity:            shoppingQuantity(input.Quantity),
+			Unit:                input.Unit,
+			Category:            shoppingCategory(input.Category),
+			EstimatedPriceMinor: input.EstimatedPriceMinor,
+			ActualPriceMinor:    input.ActualPriceMinor,
+			Store:               input.Store,
 		},
 	)
 	if err != nil {
-		log.Error("Service - ShoppingService - Update: %v", err)
-		return err
+		log.Error("Service - ShoppingService - Update", "error", err)
+		return shoppingItemError(err, "failed to update shopping item")
 	}
 
+	s.publishItemEvent(ctx, log, entity.ShoppingEventItemUpdated, entity.ShoppingChangeEdited, item, input.UserID)
 	return nil
 }
 
@@ -97,7 +181,7 @@ func (s *ShoppingService) GetPublicByFamilyID(
 
 	items, err := s.shoppingRepo.GetPublicByFamilyID(ctx, log, familyID)
 	if err != nil {
-		log.Error("Service - ShoppingService - GetPublicByFamilyID: %v", err)
+		log.Error("Service - ShoppingService - GetPublicByFamilyID", "error", err)
 		return nil, err
 	}
 
@@ -111,7 +195,7 @@ func (s *ShoppingService) GetPrivateByCreatedBy(
 
 	items, err := s.shoppingRepo.GetPrivateByCreatedBy(ctx, log, createdBy)
 	if err != nil {
-		log.Error("Service - ShoppingService - GetPrivateByCreatedBy: %v", err)
+		log.Error("Service - ShoppingService - GetPrivateByCreatedBy", "error", err)
 		return nil, err
 	}
 
@@ -121,6 +205,7 @@ func (s *ShoppingService) GetPrivateByCreatedBy(
 type ShoppingUpdateReservedByInput struct {
 	Id         string
 	ReservedBy string
+	Version    int64
 }
 
 func (s *ShoppingService) UpdateReservedBy(
@@ -128,18 +213,27 @@ func (s *ShoppingService) UpdateReservedBy(
 ) error {
 	log.Info("Service - ShoppingService - UpdateReservedBy")
 
-	err := s.shoppingRepo.UpdateReservedBy(ctx, log, input.Id, input.ReservedBy, time.Now().Add(time.Hour*3))
+	item, err := s.shoppingRepo.UpdateReservedBy(
+		ctx, log, input.Id, input.ReservedBy, input.Version, time.Now().Add(time.Hour*3),
+	)
 	if err != nil {
-		log.Error("Service - ShoppingService - UpdateReservedBy: %v", err)
-		return err
+		log.Error("Service - ShoppingService - UpdateReservedBy", "error", err)
+		return shoppingItemError(err, "failed to reserve shopping item")
 	}
 
+	s.publishItemEvent(
+		ctx, log, entity.ShoppingEventItemUpdated, entity.ShoppingChangeReserved, item, input.ReservedBy,
+	)
 	return nil
 }
 
 type ShoppingUpdateBuyerIdInput struct {
 	Id      string
 	BuyerId string
+	// ActualPriceMinor и Store - сколько заплатили и где, пустые не меняют указанные ранее
+	ActualPriceMinor *int
+	Store            string
+	Version          int64
 }
 
 func (s *ShoppingService) UpdateBuyerId(
@@ -147,12 +241,16 @@ func (s *ShoppingService) UpdateBuyerId(
 ) error {
 	log.Info("Service - ShoppingService - UpdateBuyerId")
 
-	err := s.shoppingRepo.UpdateBuyerId(ctx, log, input.Id, input.BuyerId, time.Now().Add(time.Hour*3))
+	item, err := s.shoppingRepo.UpdateBuyerId(
+		ctx, log, input.Id, input.BuyerId, input.ActualPriceMinor, input.Store, input.Version,
+		time.Now().Add(time.Hour*3),
+	)
 	if err != nil {
-		log.Error("Service - ShoppingService - UpdateBuyerId: %v", err)
-		return err
+		log.Error("Service - ShoppingService - UpdateBuyerId", "error", err)
+		return shoppingItemError(err, "failed to mark shopping item bought")
 	}
 
+	s.publishItemEvent(ctx, log, entity.ShoppingEventItemUpdated, entity.ShoppingChangeBought, item, input.BuyerId)
 	return nil
 }
 
@@ -164,7 +262,7 @@ func (s *ShoppingService) GetArchivedByUserID(
 
 	items, err := s.shoppingRepo.GetArchivedByUserID(ctx, log, userID)
 	if err != nil {
-		log.Error("Service - ShoppingService - GetArchivedByUserID: %v", err)
+		log.Error("Service - ShoppingService - GetArchivedByUserID", "error", err)
 		return nil, err
 	}
 
@@ -172,7 +270,9 @@ func (s *ShoppingService) GetArchivedByUserID(
 }
 
 type ShoppingCancelUpdateReservedByInput struct {
-	Id string
+	Id      string
+	UserID  string
+	Version int64
 }
 
 func (s *ShoppingService) CancelUpdateReservedBy(
@@ -180,12 +280,15 @@ func (s *ShoppingService) CancelUpdateReservedBy(
 ) error {
 	log.Info("Service - ShoppingService - UpdateReservedBy")
 
-	err := s.shoppingRepo.CancelUpdateReservedBy(ctx, log, input.Id, time.Now().Add(time.Hour*3))
+	item, err := s.shoppingRepo.CancelUpdateReservedBy(ctx, log, input.Id, input.Version, time.Now().Add(time.Hour*3))
 	if err != nil {
-		log.Error("Service - ShoppingService - UpdateReservedBy: %v", err)
-		return err
+		log.Error("Service - ShoppingService - UpdateReservedBy", "error", err)
+		return shoppingItemError(err, "failed to cancel shopping item reservation")
 	}
 
+	s.publishItemEvent(
+		ctx, log, entity.ShoppingEventItemUpdated, entity.ShoppingChangeUnreserved, item, input.UserID,
+	)
 	return nil
 }
 
@@ -195,9 +298,25 @@ func (s *ShoppingService) GetByID(ctx context.Context, log *slog.Logger, id stri
 
 	items, err := s.shoppingRepo.GetByID(ctx, log, id)
 	if err != nil {
-		log.Error("Service - ShoppingService - GetByID: %v", err)
+		log.Error("Service - ShoppingService - GetByID", "error", err)
 		return entity.ShoppingItem{}, err
 	}
 
 	return items, nil
 }
+
+// shoppingQuantity возвращает количество покупки, по умолчанию 1
+func shoppingQuantity(quantity float64) float64 {
+	if quantity <= 0 {
+		return 1
+	}
+	return quantity
+}
+
+// shoppingCategory возвращает отдел магазина покупки, по умолчанию entity.ShoppingCategoryOther
+func shoppingCategory(category string) string {
+	if category == "" {
+		return entity.ShoppingCategoryOther
+	}
+	return category
+}
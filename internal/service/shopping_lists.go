@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// Владельцы списка покупок
+const (
+	ShoppingListOwnerFamily = "family"
+	ShoppingListOwnerUser   = "user"
+)
+
+// ShoppingListCreateInput - новый список покупок семьи пользователя или его личный
+type ShoppingListCreateInput struct {
+	UserID string
+	Title  string
+	// Owner - ShoppingListOwnerFamily или ShoppingListOwnerUser
+	Owner string
+	// SharedWith - члены семьи, которым виден личный список
+	SharedWith []string
+}
+
+// ShoppingListUpdateInput - новое название списка покупок
+type ShoppingListUpdateInput struct {
+	UserID string
+	ListID string
+	Title  string
+}
+
+// ShoppingListShareInput - члены семьи, которым должен быть виден личный список
+type ShoppingListShareInput struct {
+	UserID  string
+	ListID  string
+	UserIDs []string
+}
+
+// CreateList создает список покупок. Список семьи виден всем ее членам, личный - владельцу
+// и тем, с кем он поделился
+func (s *ShoppingService) CreateList(ctx context.Context, log *slog.Logger, input ShoppingListCreateInput) (
+	entity.ShoppingList, error,
+) {
+	log.Info("Service - ShoppingService - CreateList", "user_id", input.UserID, "owner", input.Owner)
+
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return entity.ShoppingList{}, ErrUserNotFound
+	}
+
+	list := entity.ShoppingList{UserID: user.Id, Title: input.Title, CreatedBy: user.Id}
+	if input.Owner == ShoppingListOwnerFamily {
+		if !user.FamilyId.Valid {
+			return entity.ShoppingList{}, ErrFamilyNotFound
+		}
+		list = entity.ShoppingList{FamilyID: user.FamilyId.String, Title: input.Title, CreatedBy: user.Id}
+	} else if len(input.SharedWith) > 0 {
+		if err = s.checkShareMembers(ctx, user, input.SharedWith); err != nil {
+			return entity.ShoppingList{}, err
+		}
+	}
+
+	id, err := s.shoppingRepo.CreateList(ctx, log, list)
+	if err != nil {
+		log.Error("Service - ShoppingService - CreateList", "error", err)
+		return entity.ShoppingList{}, fmt.Errorf("failed to create shopping list: %w", err)
+	}
+	if list.UserID != "" && len(input.SharedWith) > 0 {
+		if err = s.shoppingRepo.SetListShares(ctx, log, id, uniqueStrings(input.SharedWith)); err != nil {
+			log.Error("Service - ShoppingService - CreateList - SetListShares", "error", err)
+			return entity.ShoppingList{}, fmt.Errorf("failed to share shopping list: %w", err)
+		}
+	}
+	return s.getList(ctx, log, id)
+}
+
+// GetLists возвращает списки покупок, доступные пользователю: архивные или действующие
+func (s *ShoppingService) GetLists(ctx context.Context, log *slog.Logger, userID string, archived bool) (
+	[]entity.ShoppingList, error,
+) {
+	log.Info("Service - ShoppingService - GetLists", "user_id", userID, "archived", archived)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	lists, err := s.shoppingRepo.GetListsByUser(ctx, log, user.Id, user.FamilyId.String, archived)
+	if err != nil {
+		log.Error("Service - ShoppingService - GetLists", "error", err)
+		return nil, fmt.Errorf("failed to get shopping lists: %w", err)
+	}
+	return lists, nil
+}
+
+// GetList возвращает доступный пользователю список покупок вместе с его покупками
+func (s *ShoppingService) GetList(ctx context.Context, log *slog.Logger, userID, listID string) (
+	entity.ShoppingListWithItems, error,
+) {
+	log.Info("Service - ShoppingService - GetList", "list_id", listID)
+
+	list, _, err := s.accessibleList(ctx, log, userID, listID, false)
+	if err != nil {
+		return entity.ShoppingListWithItems{}, err
+	}
+	items, err := s.shoppingRepo.GetItemsByListID(ctx, log, list.ID)
+	if err != nil {
+		log.Error("Service - ShoppingService - GetList - GetItemsByListID", "error", err)
+		return entity.ShoppingListWithItems{}, fmt.Errorf("failed to get shopping items: %w", err)
+	}
+	return entity.ShoppingListWithItems{ShoppingList: list, Groups: groupShoppingItems(items)}, nil
+}
+
+// UpdateList переименовывает список покупок
+func (s *ShoppingService) UpdateList(ctx context.Context, log *slog.Logger, input ShoppingListUpdateInput) (
+	entity.ShoppingList, error,
+) {
+	log.Info("Service - ShoppingService - UpdateList", "list_id", input.ListID)
+
+	list, _, err := s.accessibleList(ctx, log, input.UserID, input.ListID, true)
+	if err != nil {
+		return entity.ShoppingList{}, err
+	}
+	if err = s.shoppingRepo.UpdateList(ctx, log, list.ID, input.Title); err != nil {
+		log.Error("Service - ShoppingService - UpdateList", "error", err)
+		return entity.ShoppingList{}, fmt.Errorf("failed to update shopping list: %w", err)
+	}
+	return s.getList(ctx, log, list.ID)
+}
+
+// ArchiveList архивирует список покупок или возвращает его из архива. Покупки архивного списка
+// не показываются в общих списках покупок. Список по умолчанию архивировать нельзя
+func (s *ShoppingService) ArchiveList(
+	ctx context.Context, log *slog.Logger, userID, listID string, archived bool,
+) (entity.ShoppingList, error) {
+	log.Info("Service - ShoppingService - ArchiveList", "list_id", listID, "archived", archived)
+
+	list, _, err := s.accessibleList(ctx, log, userID, listID, true)
+	if err != nil {
+		return entity.ShoppingList{}, err
+	}
+	if list.IsDefault {
+		return entity.ShoppingList{}, ErrShoppingListDefault
+	}
+	if err = s.shoppingRepo.SetListArchived(ctx, log, list.ID, archived); err != nil {
+		log.Error("Service - ShoppingService - ArchiveList", "error", err)
+		return entity.ShoppingList{}, fmt.Errorf("failed to archive shopping list: %w", err)
+	}
+	return s.getList(ctx, log, list.ID)
+}
+
+// DeleteList удаляет список покупок вместе с покупками. Список по умолчанию удалить нельзя
+func (s *ShoppingService) DeleteList(ctx context.Context, log *slog.Logger, userID, listID string) error {
+	log.Info("Service - ShoppingService - DeleteList", "list_id", listID)
+
+	list, _, err := s.accessibleList(ctx, log, userID, listID, true)
+	if err != nil {
+		return err
+	}
+	if list.IsDefault {
+		return ErrShoppingListDefault
+	}
+	if err = s.shoppingRepo.DeleteList(ctx, log, list.ID); err != nil {
+		log.Error("Service - ShoppingService - DeleteList", "error", err)
+		return fmt.Errorf("failed to delete shopping list: %w", err)
+	}
+	return nil
+}
+
+// ShareList заменяет членов семьи, которым виден личный список. Пустой input.UserIDs закрывает доступ всем
+func (s *ShoppingService) ShareList(ctx context.Context, log *slog.Logger, input ShoppingListShareInput) (
+	entity.ShoppingList, error,
+) {
+	log.Info("Service - ShoppingService - ShareList", "list_id", input.ListID, "users", len(input.UserIDs))
+
+	list, user, err := s.accessibleList(ctx, log, input.UserID, input.ListID, true)
+	if err != nil {
+		return entity.ShoppingList{}, err
+	}
+	if list.UserID == "" {
+		return entity.ShoppingList{}, ErrShoppingListNotPersonal
+	}
+	if err = s.checkShareMembers(ctx, user, input.UserIDs); err != nil {
+		return entity.ShoppingList{}, err
+	}
+	if err = s.shoppingRepo.SetListShares(ctx, log, list.ID, uniqueStrings(input.UserIDs)); err != nil {
+		log.Error("Service - ShoppingService - ShareList", "error", err)
+		return entity.ShoppingList{}, fmt.Errorf("failed to share shopping list: %w", err)
+	}
+	return s.getList(ctx, log, list.ID)
+}
+
+// accessibleList возвращает список покупок и пользователя, если пользователь видит список, а при manage -
+// может им управлять: личным списком управляет владелец, списком семьи - его создатель и родители семьи.
+// Недоступный список считается не найденным
+func (s *ShoppingService) accessibleList(
+	ctx context.Context, log *slog.Logger, userID, listID string, manage bool,
+) (entity.ShoppingList, entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.ShoppingList{}, entity.User{}, ErrUserNotFound
+	}
+	list, err := s.getList(ctx, log, listID)
+	if err != nil {
+		return entity.ShoppingList{}, entity.User{}, err
+	}
+
+	inFamily := list.FamilyID != "" && user.FamilyId.Valid && user.FamilyId.String == list.FamilyID
+	if !inFamily && list.UserID != user.Id && !slices.Contains(list.SharedWith, user.Id) {
+		return entity.ShoppingList{}, entity.User{}, ErrShoppingListNotFound
+	}
+	if manage && list.UserID != user.Id && !(inFamily && (user.Role == "Parent" || list.CreatedBy == user.Id)) {
+		return entity.ShoppingList{}, entity.User{}, ErrForbidden
+	}
+	return list, user, nil
+}
+
+// getList возвращает список покупок по ID
+func (s *ShoppingService) getList(ctx context.Context, log *slog.Logger, id string) (entity.ShoppingList, error) {
+	list, err := s.shoppingRepo.GetListByID(ctx, log, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.ShoppingList{}, ErrShoppingListNotFound
+		}
+		log.Error("Service - ShoppingService - getList", "error", err)
+		return entity.ShoppingList{}, fmt.Errorf("failed to get shopping list: %w", err)
+	}
+	return list, nil
+}
+
+// checkShareMembers проверяет, что личным списком делятся только с другими членами семьи владельца
+func (s *ShoppingService) checkShareMembers(ctx context.Context, owner entity.User, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	if !owner.FamilyId.Valid {
+		return ErrFamilyNotFound
+	}
+	members, err := s.userRepo.GetByFamilyID(ctx, owner.FamilyId.String)
+	if err != nil {
+		return ErrCannotGetFamilyMembers
+	}
+	family := make(map[string]bool, len(members))
+	for _, member := range members {
+		family[member.Id] = member.Id != owner.Id
+	}
+	for _, id := range userIDs {
+		if !family[id] {
+			return ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+// groupShoppingItems раскладывает покупки по отделам магазина в порядке entity.ShoppingCategories, отделы
+// без покупок пропускаются. Внутри отдела некупленные покупки идут раньше купленных, порядок items сохраняется
+func groupShoppingItems(items []entity.ShoppingItem) []entity.ShoppingCategoryGroup {
+	byCategory := make(map[string][]entity.ShoppingItem)
+	for _, item := range items {
+		category := item.Category
+		if !slices.Contains(entity.ShoppingCategories, category) {
+			category = entity.ShoppingCategoryOther
+		}
+		byCategory[category] = append(byCategory[category], item)
+	}
+
+	groups := make([]entity.ShoppingCategoryGroup, 0, len(byCategory))
+	for _, category := range entity.ShoppingCategories {
+		group, ok := byCategory[category]
+		if !ok {
+			continue
+		}
+		slices.SortStableFunc(
+			group, func(a, b entity.ShoppingItem) int {
+				switch {
+				case a.Status == b.Status || (a.Status != "Completed" && b.Status != "Completed"):
+					return 0
+				case a.Status == "Completed":
+					return 1
+				}
+				return -1
+			},
+		)
+		groups = append(groups, entity.ShoppingCategoryGroup{Category: category, Items: group})
+	}
+	return groups
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+// GetSpend возвращает траты на покупки, отмеченные купленными в месяце month (UTC): всего, по спискам
+// и по отделам магазина. Пустой listID - по всем доступным пользователю спискам, включая архивные
+func (s *ShoppingService) GetSpend(ctx context.Context, log *slog.Logger, userID, listID string, month time.Time) (
+	entity.ShoppingSpendSummary, error,
+) {
+	log.Info("Service - ShoppingService - GetSpend", "user_id", userID, "list_id", listID)
+
+	var lists []entity.ShoppingList
+	if listID != "" {
+		list, _, err := s.accessibleList(ctx, log, userID, listID, false)
+		if err != nil {
+			return entity.ShoppingSpendSummary{}, err
+		}
+		lists = []entity.ShoppingList{list}
+	} else {
+		active, err := s.GetLists(ctx, log, userID, false)
+		if err != nil {
+			return entity.ShoppingSpendSummary{}, err
+		}
+		archived, err := s.GetLists(ctx, log, userID, true)
+		if err != nil {
+			return entity.ShoppingSpendSummary{}, err
+		}
+		lists = append(active, archived...)
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	summary := entity.ShoppingSpendSummary{
+		Month:      from.Format("2006-01"),
+		From:       from,
+		To:         from.AddDate(0, 1, 0),
+		Lists:      make([]entity.ShoppingSpend, 0, len(lists)),
+		Categories: make([]entity.ShoppingSpend, 0),
+	}
+	if len(lists) == 0 {
+		return summary, nil
+	}
+
+	listIDs := make([]string, 0, len(lists))
+	for _, list := range lists {
+		listIDs = append(listIDs, list.ID)
+	}
+	rows, err := s.shoppingRepo.GetSpend(ctx, log, listIDs, summary.From, summary.To)
+	if err != nil {
+		log.Error("Service - ShoppingService - GetSpend", "error", err)
+		return entity.ShoppingSpendSummary{}, fmt.Errorf("failed to get shopping spend: %w", err)
+	}
+
+	byList := make(map[string]entity.ShoppingSpend, len(lists))
+	byCategory := make(map[string]entity.ShoppingSpend)
+	for _, row := range rows {
+		summary.ShoppingSpend = addShoppingSpend(summary.ShoppingSpend, row)
+		byList[row.ListID] = addShoppingSpend(byList[row.ListID], row)
+		byCategory[row.Category] = addShoppingSpend(byCategory[row.Category], row)
+	}
+
+	for _, list := range lists {
+		spend := byList[list.ID]
+		spend.ListID, spend.Title = list.ID, list.Title
+		summary.Lists = append(summary.Lists, spend)
+	}
+	for category, spend := range byCategory {
+		spend.Category = category
+		summary.Categories = append(summary.Categories, spend)
+	}
+	slices.SortFunc(
+		summary.Categories, func(a, b entity.ShoppingSpend) int {
+			return shoppingCategoryOrder(a.Category) - shoppingCategoryOrder(b.Category)
+		},
+	)
+	return summary, nil
+}
+
+// addShoppingSpend складывает покупки и траты row в total
+func addShoppingSpend(total, row entity.ShoppingSpend) entity.ShoppingSpend {
+	total.ItemsBought += row.ItemsBought
+	total.ItemsWithoutPrice += row.ItemsWithoutPrice
+	total.ActualMinor += row.ActualMinor
+	total.EstimatedMinor += row.EstimatedMinor
+	return total
+}
+
+// shoppingCategoryOrder возвращает место отдела в обходе магазина, неизвестные отделы идут последними
+func shoppingCategoryOrder(category string) int {
+	if i := slices.Index(entity.ShoppingCategories, category); i >= 0 {
+		return i
+	}
+	return len(entity.ShoppingCategories)
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// todoCommentPreview - сколько символов комментария показывается в уведомлении
+const todoCommentPreview = 100
+
+// TodoCommentInput - комментарий пользователя к заданию
+type TodoCommentInput struct {
+	TodoID string
+	UserID string
+	// CommentID - комментарий для удаления
+	CommentID string
+	Body      string
+	// Mentions - упомянутые члены семьи задания
+	Mentions []string
+}
+
+// AddComment добавляет комментарий к заданию. Комментировать может любой член семьи задания.
+// Упомянутые получают уведомление об упоминании, остальные участники задания - о новом комментарии
+func (t *TodoService) AddComment(ctx context.Context, log *slog.Logger, input TodoCommentInput) (
+	entity.TodoComment, error,
+) {
+	log.Info("Service - TodoService - AddComment", "todo_id", input.TodoID)
+
+	item, err := t.getTodo(ctx, log, input.TodoID)
+	if err != nil {
+		return entity.TodoComment{}, err
+	}
+	author, err := t.checkTodoViewer(ctx, item, input.UserID)
+	if err != nil {
+		return entity.TodoComment{}, err
+	}
+
+	mentions := uniqueStrings(input.Mentions)
+	for _, userID := range mentions {
+		user, err := t.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return entity.TodoComment{}, ErrUserNotFound
+		}
+		if !user.FamilyId.Valid || user.FamilyId.String != item.FamilyID {
+			return entity.TodoComment{}, ErrNotFamilyMember
+		}
+	}
+
+	// Участники обсуждения до нового комментария
+	comments, err := t.todoRepo.GetComments(ctx, log, item.ID)
+	if err != nil {
+		log.Error("Service - TodoService - AddComment - GetComments", "error", err)
+		return entity.TodoComment{}, fmt.Errorf("failed to get todo comments: %w", err)
+	}
+
+	comment, err := t.todoRepo.CreateComment(
+		ctx, log, entity.TodoComment{TodoID: item.ID, AuthorID: input.UserID, Body: input.Body, Mentions: mentions},
+	)
+	if err != nil {
+		log.Error("Service - TodoService - AddComment", "error", err)
+		return entity.TodoComment{}, fmt.Errorf("failed to create todo comment: %w", err)
+	}
+	t.recordActivity(
+		ctx, log, item, input.UserID, entity.TodoActivityCommented, map[string]string{"comment_id": comment.ID},
+	)
+
+	notified := map[string]bool{input.UserID: true}
+	preview := commentPreview(comment.Body)
+	for _, userID := range mentions {
+		if notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		t.sendTodoNotification(
+			ctx, log, userID, item, "todo_mention", "Вас упомянули",
+			fmt.Sprintf("%s в задании '%s': %s", author.Name, item.Title, preview),
+		)
+	}
+
+	involved := []string{item.CreatedBy, item.AssignedTo}
+	for _, previous := range comments {
+		involved = append(involved, previous.AuthorID)
+	}
+	for _, userID := range involved {
+		if userID == "" || notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		t.sendTodoNotification(
+			ctx, log, userID, item, "todo_comment", "Новый комментарий",
+			fmt.Sprintf("%s к заданию '%s': %s", author.Name, item.Title, preview),
+		)
+	}
+
+	return comment, nil
+}
+
+// GetComments возвращает комментарии к заданию для члена семьи задания
+func (t *TodoService) GetComments(ctx context.Context, log *slog.Logger, todoID, userID string) (
+	[]entity.TodoComment, error,
+) {
+	log.Info("Service - TodoService - GetComments", "todo_id", todoID)
+
+	item, err := t.getTodo(ctx, log, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = t.checkTodoViewer(ctx, item, userID); err != nil {
+		return nil, err
+	}
+
+	comments, err := t.todoRepo.GetComments(ctx, log, todoID)
+	if err != nil {
+		log.Error("Service - TodoService - GetComments", "error", err)
+		return nil, fmt.Errorf("failed to get todo comments: %w", err)
+	}
+	return comments, nil
+}
+
+// DeleteComment удаляет комментарий. Удалить может автор комментария или родитель семьи
+func (t *TodoService) DeleteComment(ctx context.Context, log *slog.Logger, input TodoCommentInput) error {
+	log.Info("Service - TodoService - DeleteComment", "todo_id", input.TodoID)
+
+	item, err := t.getTodo(ctx, log, input.TodoID)
+	if err != nil {
+		return err
+	}
+	user, err := t.checkTodoViewer(ctx, item, input.UserID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := t.todoRepo.GetCommentByID(ctx, log, input.TodoID, input.CommentID)
+	if err != nil {
+		return t.commentError(log, err)
+	}
+	if comment.AuthorID != input.UserID && (user.Role != "Parent" || user.FamilyId.String != item.FamilyID) {
+		return ErrForbidden
+	}
+
+	if err = t.todoRepo.DeleteComment(ctx, log, input.TodoID, input.CommentID); err != nil {
+		return t.commentError(log, err)
+	}
+	return nil
+}
+
+// GetActivity возвращает ленту событий задания для члена семьи задания
+func (t *TodoService) GetActivity(ctx context.Context, log *slog.Logger, todoID, userID string) (
+	[]entity.TodoActivity, error,
+) {
+	log.Info("Service - TodoService - GetActivity", "todo_id", todoID)
+
+	item, err := t.getTodo(ctx, log, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = t.checkTodoViewer(ctx, item, userID); err != nil {
+		return nil, err
+	}
+
+	activities, err := t.todoRepo.GetActivity(ctx, log, todoID)
+	if err != nil {
+		log.Error("Service - TodoService - GetActivity", "error", err)
+		return nil, fmt.Errorf("failed to get todo activity: %w", err)
+	}
+	return activities, nil
+}
+
+// checkTodoViewer проверяет, что пользователь - создатель, исполнитель или член семьи задания, и возвращает его
+func (t *TodoService) checkTodoViewer(ctx context.Context, item entity.TodoItem, userID string) (entity.User, error) {
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.User{}, ErrUserNotFound
+	}
+	if item.CreatedBy == userID || item.AssignedTo == userID {
+		return user, nil
+	}
+	if user.FamilyId.Valid && user.FamilyId.String == item.FamilyID {
+		return user, nil
+	}
+	return entity.User{}, ErrForbidden
+}
+
+func (t *TodoService) commentError(log *slog.Logger, err error) error {
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return ErrCommentNotFound
+	}
+	log.Error("Service - TodoService - comment", "error", err)
+	return fmt.Errorf("failed to change todo comment: %w", err)
+}
+
+// recordActivity добавляет событие в ленту задания и уведомляет участников задания, которых оно касается.
+// Ошибка записи только логируется: лента не должна мешать самому действию
+func (t *TodoService) recordActivity(
+	ctx context.Context, log *slog.Logger, item entity.TodoItem, actorID, kind string, details map[string]string,
+) {
+	err := t.todoRepo.AddActivity(
+		ctx, log, []entity.TodoActivity{{TodoID: item.ID, ActorID: actorID, Kind: kind, Details: details}},
+	)
+	if err != nil {
+		log.Error("Service - TodoService - recordActivity", "todo_id", item.ID, "kind", kind, "error", err)
+	}
+
+	// О создании, проверке и комментариях уведомления отправляются отдельно
+	switch kind {
+	case entity.TodoActivityReassigned:
+		if item.AssignedTo != actorID {
+			t.sendTodoNotification(
+				ctx, log, item.AssignedTo, item, "todo_assigned", "Новое задание",
+				fmt.Sprintf("Вам назначено новое задание: '%s'", item.Title),
+			)
+		}
+		if previous := details["from"]; previous != "" && previous != actorID {
+			t.sendTodoNotification(
+				ctx, log, previous, item, "todo_unassigned", "Задание переназначено",
+				fmt.Sprintf("Задание '%s' передано другому участнику", item.Title),
+			)
+		}
+	case entity.TodoActivityDeadlineChanged:
+		if item.AssignedTo != actorID {
+			t.sendTodoNotification(
+				ctx, log, item.AssignedTo, item, "todo_deadline_changed", "Срок задания изменен",
+				fmt.Sprintf("Новый срок задания '%s': %s", item.Title, item.Deadline.Format(todoDeadlineLayout)),
+			)
+		}
+	case entity.TodoActivityUpdated:
+		if item.AssignedTo != actorID {
+			t.sendTodoNotification(
+				ctx, log, item.AssignedTo, item, "todo_updated", "Задание изменено",
+				fmt.Sprintf("Задание '%s' изменено", item.Title),
+			)
+		}
+	case entity.TodoActivityDeleted:
+		if item.AssignedTo != actorID {
+			t.sendTodoNotification(
+				ctx, log, item.AssignedTo, item, "todo_deleted", "Задание удалено",
+				fmt.Sprintf("Задание '%s' удалено", item.Title),
+			)
+		}
+	}
+}
+
+// recordUpdateActivity записывает в ленту изменения задания: переназначение, перенос срока и прочие правки
+func (t *TodoService) recordUpdateActivity(
+	ctx context.Context, log *slog.Logger, current, updated entity.TodoItem, actorID string,
+) {
+	if current.AssignedTo != updated.AssignedTo {
+		t.recordActivity(
+			ctx, log, updated, actorID, entity.TodoActivityReassigned,
+			map[string]string{"from": current.AssignedTo, "to": updated.AssignedTo},
+		)
+	}
+	if !current.Deadline.Equal(updated.Deadline) {
+		t.recordActivity(
+			ctx, log, updated, actorID, entity.TodoActivityDeadlineChanged,
+			map[string]string{
+				"from": current.Deadline.UTC().Format(time.RFC3339),
+				"to":   updated.Deadline.UTC().Format(time.RFC3339),
+			},
+		)
+	}
+	if current.Title != updated.Title || current.Description != updated.Description || current.Point != updated.Point {
+		t.recordActivity(ctx, log, updated, actorID, entity.TodoActivityUpdated, nil)
+	}
+}
+
+// commentPreview обрезает комментарий до todoCommentPreview символов
+func commentPreview(body string) string {
+	if utf8.RuneCountInString(body) <= todoCommentPreview {
+		return body
+	}
+	return string([]rune(body)[:todoCommentPreview]) + "…"
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// todoCleanupBatch - число заданий, которое архивируется или окончательно удаляется за один запрос к базе
+const todoCleanupBatch = 100
+
+// todoBulkActivity - событие ленты задания для каждого действия Bulk
+var todoBulkActivity = map[string]string{
+	entity.TodoBulkComplete:  entity.TodoActivityApproved,
+	entity.TodoBulkDelete:    entity.TodoActivityDeleted,
+	entity.TodoBulkRestore:   entity.TodoActivityRestored,
+	entity.TodoBulkReassign:  entity.TodoActivityReassigned,
+	entity.TodoBulkArchive:   entity.TodoActivityArchived,
+	entity.TodoBulkUnarchive: entity.TodoActivityUnarchived,
+}
+
+// TodoBulkInput - действие пользователя над несколькими заданиями
+type TodoBulkInput struct {
+	UserID string
+	// Action - complete, delete, restore, reassign, archive или unarchive
+	Action string
+	IDs    []string
+	// AssignedTo - новый исполнитель для reassign
+	AssignedTo string
+}
+
+// Bulk выполняет действие над всеми заданиями из списка в одной транзакции: либо действие применяется
+// ко всем заданиям, либо ни к одному. Отметить задания выполненными может проверяющий, остальные действия
+// доступны создателю задания и родителю семьи. Удаленные задания можно восстановить в течение окна отмены
+func (t *TodoService) Bulk(ctx context.Context, log *slog.Logger, input TodoBulkInput) (entity.TodoBulkResult, error) {
+	log.Info("Service - TodoService - Bulk", "action", input.Action, "count", len(input.IDs))
+
+	ids := uniqueStrings(input.IDs)
+	items, err := t.todoRepo.GetByIDs(ctx, log, ids, input.Action == entity.TodoBulkRestore)
+	if err != nil {
+		log.Error("Service - TodoService - Bulk - GetByIDs", "error", err)
+		return entity.TodoBulkResult{}, fmt.Errorf("failed to get todos: %w", err)
+	}
+	if len(items) != len(ids) {
+		return entity.TodoBulkResult{}, ErrItemNotFound
+	}
+	if err = t.checkBulkAccess(ctx, input.UserID, input.Action, items); err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+
+	previousAssignees := make(map[string]string, len(items))
+	for _, item := range items {
+		previousAssignees[item.ID] = item.AssignedTo
+	}
+
+	op := entity.TodoBulkOperation{
+		Action:     input.Action,
+		IDs:        ids,
+		UserID:     input.UserID,
+		AssignedTo: input.AssignedTo,
+	}
+	switch input.Action {
+	case entity.TodoBulkComplete:
+		if err = t.attachChecklists(ctx, log, items); err != nil {
+			return entity.TodoBulkResult{}, err
+		}
+		op.Points = make(map[string]int, len(items))
+		for _, item := range items {
+			op.Points[item.ID] = checklistPoints(item)
+		}
+	case entity.TodoBulkRestore:
+		op.DeletedAfter = time.Now().UTC().Add(-t.deleteUndoWindow)
+		for _, item := range items {
+			if item.DeletedAt == nil || !item.DeletedAt.After(op.DeletedAfter) {
+				return entity.TodoBulkResult{}, ErrUndoWindowExpired
+			}
+		}
+	case entity.TodoBulkReassign:
+		checked := make(map[string]bool)
+		for _, item := range items {
+			if checked[item.FamilyID] {
+				continue
+			}
+			if err = t.checkRotationMembers(ctx, item.FamilyID, []string{input.AssignedTo}); err != nil {
+				return entity.TodoBulkResult{}, err
+			}
+			checked[item.FamilyID] = true
+		}
+	}
+
+	if err = t.todoRepo.Bulk(ctx, log, op); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.TodoBulkResult{}, ErrInvalidTodoTransition
+		}
+		log.Error("Service - TodoService - Bulk", "error", err)
+		return entity.TodoBulkResult{}, fmt.Errorf("failed to apply bulk action: %w", err)
+	}
+
+	items, err = t.todoRepo.GetByIDs(ctx, log, ids, input.Action == entity.TodoBulkDelete)
+	if err != nil {
+		log.Error("Service - TodoService - Bulk - GetByIDs", "error", err)
+		return entity.TodoBulkResult{}, fmt.Errorf("failed to get todos: %w", err)
+	}
+	if err = t.attachDetails(ctx, log, items); err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+
+	result := entity.TodoBulkResult{Items: items}
+	for _, item := range items {
+		var details map[string]string
+		switch input.Action {
+		case entity.TodoBulkComplete:
+			details = map[string]string{"points": strconv.Itoa(item.AwardedPoints)}
+			t.sendTodoNotification(
+				ctx, log, item.AssignedTo, item, "todo_approved", "Задание принято",
+				fmt.Sprintf("Задание '%s' принято, начислено баллов: %d", item.Title, item.AwardedPoints),
+			)
+			t.achievements.Evaluate(ctx, log, item.AssignedTo, entity.AchievementEventTodoApproved)
+			// Выполненное повторение сразу порождает следующее
+			if item.SeriesID.Valid {
+				if err = t.ensureNextOccurrence(ctx, log, item.SeriesID.String, time.Now().UTC()); err != nil {
+					log.Error("Service - TodoService - Bulk - ensureNextOccurrence", "error", err)
+				}
+			}
+		case entity.TodoBulkReassign:
+			details = map[string]string{"from": previousAssignees[item.ID], "to": item.AssignedTo}
+		case entity.TodoBulkDelete:
+			if item.DeletedAt != nil {
+				undoUntil := item.DeletedAt.Add(t.deleteUndoWindow)
+				if result.UndoUntil == nil || undoUntil.Before(*result.UndoUntil) {
+					result.UndoUntil = &undoUntil
+				}
+			}
+		}
+		t.recordActivity(ctx, log, item, input.UserID, todoBulkActivity[input.Action], details)
+	}
+
+	return result, nil
+}
+
+// checkBulkAccess проверяет, может ли пользователь выполнить действие над каждым из заданий
+func (t *TodoService) checkBulkAccess(ctx context.Context, userID, action string, items []entity.TodoItem) error {
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	for _, item := range items {
+		parent := user.Role == "Parent" && user.FamilyId.Valid && user.FamilyId.String == item.FamilyID
+		creator := item.CreatedBy == userID
+		// Как и при проверке, исполнитель не может сам принять свое задание
+		if action == entity.TodoBulkComplete {
+			creator = creator && item.AssignedTo != userID
+		}
+		if !parent && !creator {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// ArchiveCompleted архивирует задания, одобренные раньше чем after назад
+func (t *TodoService) ArchiveCompleted(ctx context.Context, log *slog.Logger, after time.Duration) error {
+	before := time.Now().UTC().Add(-after)
+	for {
+		archived, err := t.todoRepo.ArchiveCompleted(ctx, log, before, todoCleanupBatch)
+		if err != nil {
+			log.Error("Service - TodoService - ArchiveCompleted", "error", err)
+			return fmt.Errorf("failed to archive completed todos: %w", err)
+		}
+		if archived < todoCleanupBatch {
+			return nil
+		}
+	}
+}
+
+// PurgeDeleted окончательно удаляет задания, окно отмены удаления которых истекло
+func (t *TodoService) PurgeDeleted(ctx context.Context, log *slog.Logger) error {
+	before := time.Now().UTC().Add(-t.deleteUndoWindow)
+	for {
+		purged, err := t.todoRepo.PurgeDeleted(ctx, log, before, todoCleanupBatch)
+		if err != nil {
+			log.Error("Service - TodoService - PurgeDeleted", "error", err)
+			return fmt.Errorf("failed to purge deleted todos: %w", err)
+		}
+		if purged < todoCleanupBatch {
+			return nil
+		}
+	}
+}
+
+// RunArchiveScheduler периодически архивирует выполненные задания старше archiveAfter и окончательно
+// удаляет задания после окна отмены до отмены ctx. archiveAfter <= 0 отключает автоархивацию
+func (t *TodoService) RunArchiveScheduler(
+	ctx context.Context, log *slog.Logger, interval time.Duration, archiveAfter time.Duration,
+) {
+	log.Info("Service - TodoService - RunArchiveScheduler", "interval", interval, "archive_after", archiveAfter)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if archiveAfter > 0 {
+			_ = t.ArchiveCompleted(ctx, log, archiveAfter)
+		}
+		_ = t.PurgeDeleted(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// uniqueStrings возвращает значения без повторов в порядке первого появления
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/internal/repo/repoerrs"
+)
+
+// TodoChecklistInput - изменение чек-листа задания пользователем
+type TodoChecklistInput struct {
+	TodoID string
+	UserID string
+	// ItemID - пункт чек-листа для UpdateChecklistItem и DeleteChecklistItem
+	ItemID string
+	// Title и IsDone - новые значения пункта, nil - оставить без изменений
+	Title  *string
+	IsDone *bool
+}
+
+// AddChecklistItem добавляет пункт в конец чек-листа задания
+func (t *TodoService) AddChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (
+	entity.TodoItem, error,
+) {
+	log.Info("Service - TodoService - AddChecklistItem", "todo_id", input.TodoID)
+
+	if _, err := t.checklistTodo(ctx, log, input); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	title := ""
+	if input.Title != nil {
+		title = *input.Title
+	}
+	if _, err := t.todoRepo.AddChecklistItem(ctx, log, input.TodoID, title); err != nil {
+		log.Error("Service - TodoService - AddChecklistItem", "error", err)
+		return entity.TodoItem{}, fmt.Errorf("failed to add checklist item: %w", err)
+	}
+	return t.GetByID(ctx, log, input.TodoID)
+}
+
+// UpdateChecklistItem переименовывает пункт чек-листа или меняет его отметку
+func (t *TodoService) UpdateChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (
+	entity.TodoItem, error,
+) {
+	log.Info("Service - TodoService - UpdateChecklistItem", "todo_id", input.TodoID, "item_id", input.ItemID)
+
+	item, err := t.checklistTodo(ctx, log, input)
+	if err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	var entry *entity.TodoChecklistItem
+	for i := range item.Checklist {
+		if item.Checklist[i].ID == input.ItemID {
+			entry = &item.Checklist[i]
+			break
+		}
+	}
+	if entry == nil {
+		return entity.TodoItem{}, ErrChecklistItemNotFound
+	}
+
+	if input.Title != nil {
+		entry.Title = *input.Title
+	}
+	if input.IsDone != nil && *input.IsDone != entry.IsDone {
+		entry.IsDone = *input.IsDone
+		entry.DoneBy, entry.DoneAt = sql.NullString{}, nil
+		if entry.IsDone {
+			now := time.Now().UTC()
+			entry.DoneBy = sql.NullString{String: input.UserID, Valid: true}
+			entry.DoneAt = &now
+		}
+	}
+
+	if err = t.todoRepo.UpdateChecklistItem(ctx, log, *entry); err != nil {
+		return entity.TodoItem{}, t.checklistError(log, err)
+	}
+	return t.GetByID(ctx, log, input.TodoID)
+}
+
+// DeleteChecklistItem удаляет пункт чек-листа
+func (t *TodoService) DeleteChecklistItem(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (
+	entity.TodoItem, error,
+) {
+	log.Info("Service - TodoService - DeleteChecklistItem", "todo_id", input.TodoID, "item_id", input.ItemID)
+
+	if _, err := t.checklistTodo(ctx, log, input); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	if err := t.todoRepo.DeleteChecklistItem(ctx, log, input.TodoID, input.ItemID); err != nil {
+		return entity.TodoItem{}, t.checklistError(log, err)
+	}
+	return t.GetByID(ctx, log, input.TodoID)
+}
+
+// ReorderChecklist задает новый порядок пунктов чек-листа. ids должен содержать все пункты ровно один раз
+func (t *TodoService) ReorderChecklist(
+	ctx context.Context, log *slog.Logger, input TodoChecklistInput, ids []string,
+) (entity.TodoItem, error) {
+	log.Info("Service - TodoService - ReorderChecklist", "todo_id", input.TodoID)
+
+	if _, err := t.checklistTodo(ctx, log, input); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	if err := t.todoRepo.ReorderChecklist(ctx, log, input.TodoID, ids); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.TodoItem{}, ErrInvalidChecklistOrder
+		}
+		log.Error("Service - TodoService - ReorderChecklist", "error", err)
+		return entity.TodoItem{}, fmt.Errorf("failed to reorder checklist: %w", err)
+	}
+	return t.GetByID(ctx, log, input.TodoID)
+}
+
+// checklistTodo возвращает задание с чек-листом, если пользователь может менять чек-лист:
+// исполнитель, создатель или родитель семьи. Чек-лист можно менять, пока задание не отправлено на проверку
+func (t *TodoService) checklistTodo(ctx context.Context, log *slog.Logger, input TodoChecklistInput) (
+	entity.TodoItem, error,
+) {
+	item, err := t.GetByID(ctx, log, input.TodoID)
+	if err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	if item.AssignedTo != input.UserID && item.CreatedBy != input.UserID {
+		user, err := t.userRepo.GetByID(ctx, input.UserID)
+		if err != nil {
+			return entity.TodoItem{}, ErrUserNotFound
+		}
+		if user.Role != "Parent" || !user.FamilyId.Valid || user.FamilyId.String != item.FamilyID {
+			return entity.TodoItem{}, ErrForbidden
+		}
+	}
+
+	if item.Status != entity.TodoStatusActive && item.Status != entity.TodoStatusRejected {
+		return entity.TodoItem{}, ErrInvalidTodoTransition
+	}
+	return item, nil
+}
+
+func (t *TodoService) checklistError(log *slog.Logger, err error) error {
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return ErrChecklistItemNotFound
+	}
+	log.Error("Service - TodoService - checklist", "error", err)
+	return fmt.Errorf("failed to update checklist: %w", err)
+}
+
+// attachChecklists добавляет к заданиям пункты чек-листа и процент выполнения
+func (t *TodoService) attachChecklists(ctx context.Context, log *slog.Logger, items []entity.TodoItem) error {
+	entries, err := t.todoRepo.GetChecklists(ctx, log, todoIDs(items))
+	if err != nil {
+		log.Error("Service - TodoService - attachChecklists", "error", err)
+		return fmt.Errorf("failed to get todo checklists: %w", err)
+	}
+
+	byTodo := make(map[string][]entity.TodoChecklistItem, len(items))
+	for _, entry := range entries {
+		byTodo[entry.TodoID] = append(byTodo[entry.TodoID], entry)
+	}
+	for i := range items {
+		items[i].Checklist = byTodo[items[i].ID]
+		items[i].Progress = checklistProgress(items[i])
+	}
+	return nil
+}
+
+// checklistProgress возвращает процент отмеченных пунктов чек-листа.
+// Задание без чек-листа выполнено на 100%, только когда оно одобрено
+func checklistProgress(item entity.TodoItem) int {
+	if len(item.Checklist) == 0 {
+		if item.Status == entity.TodoStatusApproved {
+			return 100
+		}
+		return 0
+	}
+	return checklistDone(item.Checklist) * 100 / len(item.Checklist)
+}
+
+// checklistPoints возвращает баллы за задание с учетом правила начисления и отмеченных пунктов чек-листа
+func checklistPoints(item entity.TodoItem) int {
+	total := len(item.Checklist)
+	if total == 0 {
+		return item.Point
+	}
+
+	done := checklistDone(item.Checklist)
+	if item.PointsRule == entity.TodoPointsRuleProportional {
+		return item.Point * done / total
+	}
+	if done < total {
+		return 0
+	}
+	return item.Point
+}
+
+func checklistDone(checklist []entity.TodoChecklistItem) int {
+	done := 0
+	for _, entry := range checklist {
+		if entry.IsDone {
+			done++
+		}
+	}
+	return done
+}
+
+// newChecklist создает пункты чек-листа по названиям
+func newChecklist(titles []string) []entity.TodoChecklistItem {
+	checklist := make([]entity.TodoChecklistItem, 0, len(titles))
+	for _, title := range titles {
+		checklist = append(checklist, entity.TodoChecklistItem{Title: title})
+	}
+	return checklist
+}
+
+// checklistTitles возвращает названия пунктов чек-листа, пустой список вместо nil
+func checklistTitles(checklist []entity.TodoChecklistItem) []string {
+	titles := make([]string, 0, len(checklist))
+	for _, entry := range checklist {
+		titles = append(titles, entry.Title)
+	}
+	return titles
+}
+
+func todoIDs(items []entity.TodoItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+// todoDeadlineBatch - число заданий, которое планировщик обрабатывает за один запрос к базе
+const todoDeadlineBatch = 100
+
+// todoDeadlineLayout - формат срока задания в уведомлениях
+const todoDeadlineLayout = "02.01.2006 15:04"
+
+// SendDeadlineReminders отправляет исполнителям напоминания о приближающемся сроке задания.
+// Для каждого смещения из offsets напоминание о задании отправляется один раз
+func (t *TodoService) SendDeadlineReminders(ctx context.Context, log *slog.Logger, offsets []time.Duration) error {
+	now := time.Now().UTC()
+	for _, offset := range offsets {
+		if offset <= 0 {
+			continue
+		}
+
+		for {
+			items, err := t.todoRepo.ClaimDeadlineReminders(ctx, log, offset, now, todoDeadlineBatch)
+			if err != nil {
+				log.Error("Service - TodoService - SendDeadlineReminders", "offset", offset, "error", err)
+				return fmt.Errorf("failed to claim deadline reminders: %w", err)
+			}
+
+			for _, item := range items {
+				t.sendTodoNotification(
+					ctx, log, item.AssignedTo, item, "todo_reminder", "Напоминание о задании",
+					fmt.Sprintf("Задание '%s' нужно выполнить до %s", item.Title, item.Deadline.Format(todoDeadlineLayout)),
+				)
+			}
+
+			if len(items) < todoDeadlineBatch {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// EscalateOverdue помечает просроченные задания и сообщает о них создателю задания
+func (t *TodoService) EscalateOverdue(ctx context.Context, log *slog.Logger) error {
+	now := time.Now().UTC()
+	for {
+		items, err := t.todoRepo.MarkOverdue(ctx, log, now, todoDeadlineBatch)
+		if err != nil {
+			log.Error("Service - TodoService - EscalateOverdue", "error", err)
+			return fmt.Errorf("failed to mark overdue todos: %w", err)
+		}
+
+		for _, item := range items {
+			body := fmt.Sprintf("Задание '%s' не выполнено в срок", item.Title)
+			if assignee, err := t.userRepo.GetByID(ctx, item.AssignedTo); err == nil {
+				body = fmt.Sprintf("%s не выполнил(а) задание '%s' в срок", assignee.Name, item.Title)
+			}
+			t.sendTodoNotification(ctx, log, item.CreatedBy, item, "todo_overdue", "Задание просрочено", body)
+		}
+
+		if len(items) < todoDeadlineBatch {
+			return nil
+		}
+	}
+}
+
+// RunDeadlineScheduler периодически отправляет напоминания о сроках и помечает просроченные задания
+// до отмены ctx. Безопасен при запуске на нескольких репликах
+func (t *TodoService) RunDeadlineScheduler(
+	ctx context.Context, log *slog.Logger, interval time.Duration, offsets []time.Duration,
+) {
+	log.Info("Service - TodoService - RunDeadlineScheduler", "interval", interval, "offsets", offsets)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = t.SendDeadlineReminders(ctx, log, offsets)
+		_ = t.EscalateOverdue(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendTodoNotification отправляет пуш-уведомление о задании. Ошибка отправки только логируется
+func (t *TodoService) sendTodoNotification(
+	ctx context.Context, log *slog.Logger, userID string, item entity.TodoItem, kind, title, body string,
+) {
+	data, _ := json.Marshal(map[string]string{"type": kind, "todo_id": item.ID})
+
+	err := t.notification.SendNotification(
+		ctx, log, NotificationCreateInput{
+			UserID:      userID,
+			Title:       title,
+			Body:        body,
+			Data:        string(data),
+			CollapseKey: kind + "_" + item.ID,
+		},
+	)
+	if err != nil {
+		log.Error(
+			"Service - TodoService - sendTodoNotification", "user_id", userID, "todo_id", item.ID, "error", err,
+		)
+	}
+}
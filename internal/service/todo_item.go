@@ -2,20 +2,47 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"family-flow-app/internal/entity"
 	"family-flow-app/internal/repo"
+	"family-flow-app/internal/repo/repoerrs"
+	"family-flow-app/pkg/rrule"
+)
+
+// Область изменения повторяющегося задания
+const (
+	TodoScopeThis   = "this"
+	TodoScopeSeries = "series"
 )
 
 type TodoService struct {
-	todoRepo repo.TodosItem
-	userRepo repo.User
+	todoRepo     repo.TodosItem
+	userRepo     repo.User
+	absenceRepo  repo.Absence
+	notification Notification
+	achievements Achievements
+	// deleteUndoWindow - сколько удаленное задание можно восстановить
+	deleteUndoWindow time.Duration
 }
 
-func NewTodoService(todoRepo repo.TodosItem, userRepo repo.User) *TodoService {
-	return &TodoService{todoRepo: todoRepo, userRepo: userRepo}
+func NewTodoService(
+	todoRepo repo.TodosItem, userRepo repo.User, absenceRepo repo.Absence, notification Notification,
+	achievements Achievements, deleteUndoWindow time.Duration,
+) *TodoService {
+	return &TodoService{
+		todoRepo:         todoRepo,
+		userRepo:         userRepo,
+		absenceRepo:      absenceRepo,
+		notification:     notification,
+		achievements:     achievements,
+		deleteUndoWindow: deleteUndoWindow,
+	}
 }
 
 type TodoCreateInput struct {
@@ -26,81 +53,627 @@ type TodoCreateInput struct {
 	AssignedTo  string
 	CreatedBy   string
 	Point       int
+	// RecurrenceRule - правило повторения (RRULE), пустое для разового задания
+	RecurrenceRule string
+	RequiresProof  bool
+	// Checklist - названия пунктов чек-листа по порядку, PointsRule - правило начисления баллов за чек-лист
+	Checklist  []string
+	PointsRule string
+	// Rotation - участники, которым повторения назначаются по очереди. Только вместе с RecurrenceRule
+	Rotation []string
 }
 
 func (t *TodoService) Create(ctx context.Context, log *slog.Logger, input TodoCreateInput) (string, error) {
 	log.Info("Service - TodoService - Create")
 
 	item := entity.TodoItem{
-		FamilyID:    input.FamilyId,
-		Title:       input.Title,
-		Description: input.Description,
-		Deadline:    input.Deadline,
-		AssignedTo:  input.AssignedTo,
-		CreatedBy:   input.CreatedBy,
-		Point:       input.Point,
+		FamilyID:      input.FamilyId,
+		Title:         input.Title,
+		Description:   input.Description,
+		Deadline:      input.Deadline,
+		AssignedTo:    input.AssignedTo,
+		CreatedBy:     input.CreatedBy,
+		Point:         input.Point,
+		RequiresProof: input.RequiresProof,
+		PointsRule:    input.PointsRule,
+		Checklist:     newChecklist(input.Checklist),
+	}
+	if item.PointsRule == "" {
+		item.PointsRule = entity.TodoPointsRuleAll
 	}
 
-	id, err := t.todoRepo.Create(ctx, log, item)
+	var id string
+	var err error
+	if input.RecurrenceRule != "" {
+		id, err = t.createSeries(ctx, log, item, input.RecurrenceRule, input.Rotation)
+	} else if len(input.Rotation) > 0 {
+		return "", ErrRotationRequiresRecurrence
+	} else {
+		id, err = t.todoRepo.Create(ctx, log, item)
+		if err != nil {
+			log.Error("Service - TodoService - Create", "error", err)
+		}
+	}
 	if err != nil {
-		log.Error("Service - TodoService - Create: %v", err)
 		return "", err
 	}
 
+	item.ID = id
+	t.recordActivity(ctx, log, item, input.CreatedBy, entity.TodoActivityCreated, nil)
+	return id, nil
+}
+
+// createSeries создает серию повторяющихся заданий, первое повторение приходится на срок задания.
+// При ротации первое повторение получает первый участник, который не отсутствует в этот срок
+func (t *TodoService) createSeries(
+	ctx context.Context, log *slog.Logger, item entity.TodoItem, rule string, rotation []string,
+) (string, error) {
+	parsed, err := rrule.Parse(rule)
+	if err != nil {
+		log.Error("Service - TodoService - createSeries - Parse", "error", err)
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+	}
+
+	rotationIndex := 0
+	if len(rotation) > 0 {
+		if err = t.checkRotationMembers(ctx, item.FamilyID, rotation); err != nil {
+			return "", err
+		}
+		absences, err := t.rotationAbsences(ctx, log, rotation, item.Deadline)
+		if err != nil {
+			return "", err
+		}
+		rotationIndex, _ = nextRotationMember(rotation, -1, item.Deadline, absences)
+		item.AssignedTo = rotation[rotationIndex]
+	}
+
+	occurrenceAt := item.Deadline
+	item.OccurrenceAt = &occurrenceAt
+	series := entity.TodoSeries{
+		FamilyID:         item.FamilyID,
+		Title:            item.Title,
+		Description:      item.Description,
+		AssignedTo:       item.AssignedTo,
+		CreatedBy:        item.CreatedBy,
+		Point:            item.Point,
+		RRule:            parsed.String(),
+		StartsAt:         item.Deadline,
+		LastOccurrenceAt: item.Deadline,
+		RequiresProof:    item.RequiresProof,
+		PointsRule:       item.PointsRule,
+		Checklist:        checklistTitles(item.Checklist),
+		RotationMembers:  append([]string{}, rotation...),
+		RotationIndex:    rotationIndex,
+	}
+
+	id, err := t.todoRepo.CreateSeries(ctx, log, series, item)
+	if err != nil {
+		log.Error("Service - TodoService - createSeries", "error", err)
+		return "", fmt.Errorf("failed to create todo series: %w", err)
+	}
+
 	return id, nil
 }
 
-func (t *TodoService) Delete(ctx context.Context, log *slog.Logger, id string) error {
+// Delete удаляет задание. Доступно создателю и родителю семьи задания.
+// В течение окна отмены задание можно восстановить через Bulk с действием restore
+func (t *TodoService) Delete(ctx context.Context, log *slog.Logger, id, userID string) error {
 	log.Info("Service - TodoService - Delete")
 
-	err := t.todoRepo.Delete(ctx, log, id)
+	item, err := t.getTodo(ctx, log, id)
 	if err != nil {
-		log.Error("Service - TodoService - Delete: %v", err)
+		return err
+	}
+	// Как и в Bulk, удалить задание может его создатель или родитель семьи
+	if err = t.checkTodoManager(ctx, item, userID); err != nil {
 		return err
 	}
 
+	err = t.todoRepo.Delete(ctx, log, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return ErrItemNotFound
+		}
+		log.Error("Service - TodoService - Delete", "error", err)
+		return err
+	}
+
+	t.recordActivity(ctx, log, item, userID, entity.TodoActivityDeleted, nil)
 	return nil
 }
 
 type TodoUpdateInput struct {
 	ID          string
+	UserID      string
 	Title       string
 	Description string
-	Status      string
 	Deadline    time.Time
 	AssignedTo  string
 	Point       int
+	// Scope - для повторяющегося задания: изменить только это повторение (this) или всю серию (series)
+	Scope string
+	// RecurrenceRule - новое правило повторения, учитывается только при Scope = series
+	RecurrenceRule string
 }
 
+// Update изменяет задание. Доступно создателю и родителю семьи задания, пока задание не отправлено на проверку:
+// иначе исполнитель мог бы поднять баллы за уже отправленное задание
 func (t *TodoService) Update(ctx context.Context, log *slog.Logger, input TodoUpdateInput) error {
-	log.Info("Service - TodoService - Update")
+	log.Info("Service - TodoService - Update", "id", input.ID, "user_id", input.UserID)
 
-	err := t.todoRepo.Update(
-		ctx, log, entity.TodoItem{
-			ID:          input.ID,
-			Title:       input.Title,
-			Description: input.Description,
-			Status:      input.Status,
-			Deadline:    input.Deadline,
-			AssignedTo:  input.AssignedTo,
-			Point:       input.Point,
-		},
+	current, err := t.getTodo(ctx, log, input.ID)
+	if err != nil {
+		return err
+	}
+	if err = t.checkTodoManager(ctx, current, input.UserID); err != nil {
+		return err
+	}
+	if current.Status != entity.TodoStatusActive && current.Status != entity.TodoStatusRejected {
+		return ErrInvalidTodoTransition
+	}
+
+	item := entity.TodoItem{
+		ID:          input.ID,
+		Title:       input.Title,
+		Description: input.Description,
+		Deadline:    input.Deadline,
+		AssignedTo:  input.AssignedTo,
+		Point:       input.Point,
+		IsDetached:  current.IsDetached,
+	}
+
+	if input.Scope == TodoScopeSeries {
+		if err = t.updateSeries(ctx, log, current, input); err != nil {
+			return err
+		}
+		item.IsDetached = false
+	} else if current.SeriesID.Valid && todoChanged(current, item) {
+		// Повторение изменено отдельно, изменения серии на него больше не распространяются
+		item.IsDetached = true
+	}
+
+	if err = t.todoRepo.Update(ctx, log, item); err != nil {
+		return t.transitionError(log, err)
+	}
+
+	updated := current
+	updated.Title, updated.Description, updated.Point = item.Title, item.Description, item.Point
+	updated.Deadline, updated.AssignedTo = item.Deadline, item.AssignedTo
+	t.recordUpdateActivity(ctx, log, current, updated, input.UserID)
+	return nil
+}
+
+// TodoReviewInput - действие пользователя в процессе проверки задания
+type TodoReviewInput struct {
+	ID     string
+	UserID string
+	// Comment - причина отклонения, обязательна для Reject
+	Comment string
+	// Proofs - загруженные фото выполнения для Submit
+	Proofs []entity.TodoProof
+}
+
+// Submit отправляет задание на проверку. Отправить может только исполнитель.
+// Если задание требует подтверждения, нужно приложить хотя бы одно фото
+func (t *TodoService) Submit(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error) {
+	log.Info("Service - TodoService - Submit", "id", input.ID)
+
+	item, err := t.getTodo(ctx, log, input.ID)
+	if err != nil {
+		return entity.TodoItem{}, err
+	}
+	if item.AssignedTo != input.UserID {
+		return entity.TodoItem{}, ErrForbidden
+	}
+	if item.RequiresProof && len(input.Proofs) == 0 {
+		return entity.TodoItem{}, ErrProofRequired
+	}
+
+	for i := range input.Proofs {
+		input.Proofs[i].UploadedBy = input.UserID
+	}
+	if err = t.todoRepo.Submit(ctx, log, input.ID, input.Proofs); err != nil {
+		return entity.TodoItem{}, t.transitionError(log, err)
+	}
+	t.recordActivity(ctx, log, item, input.UserID, entity.TodoActivitySubmitted, nil)
+	return t.GetByID(ctx, log, input.ID)
+}
+
+// Approve одобряет выполненное задание и начисляет исполнителю баллы с учетом чек-листа
+func (t *TodoService) Approve(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error) {
+	log.Info("Service - TodoService - Approve", "id", input.ID)
+
+	item, err := t.GetByID(ctx, log, input.ID)
+	if err != nil {
+		return entity.TodoItem{}, err
+	}
+	if err = t.checkReviewer(ctx, item, input.UserID); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	// Чек-лист задания на проверке изменить нельзя, поэтому баллы можно посчитать до одобрения
+	points := checklistPoints(item)
+	if err = t.todoRepo.Approve(ctx, log, input.ID, input.UserID, points); err != nil {
+		return entity.TodoItem{}, t.transitionError(log, err)
+	}
+	t.recordActivity(
+		ctx, log, item, input.UserID, entity.TodoActivityApproved, map[string]string{"points": strconv.Itoa(points)},
 	)
+	t.achievements.Evaluate(ctx, log, item.AssignedTo, entity.AchievementEventTodoApproved)
+
+	// Выполненное повторение сразу порождает следующее
+	if item.SeriesID.Valid {
+		if err = t.ensureNextOccurrence(ctx, log, item.SeriesID.String, time.Now().UTC()); err != nil {
+			log.Error("Service - TodoService - Approve - ensureNextOccurrence", "error", err)
+		}
+	}
+
+	return t.GetByID(ctx, log, input.ID)
+}
+
+// Reject возвращает задание исполнителю на доработку с комментарием
+func (t *TodoService) Reject(ctx context.Context, log *slog.Logger, input TodoReviewInput) (entity.TodoItem, error) {
+	log.Info("Service - TodoService - Reject", "id", input.ID)
 
+	item, err := t.getTodo(ctx, log, input.ID)
 	if err != nil {
-		log.Error("Service - TodoService - Update: %v", err)
+		return entity.TodoItem{}, err
+	}
+	if err = t.checkReviewer(ctx, item, input.UserID); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	if err = t.todoRepo.Reject(ctx, log, input.ID, input.UserID, input.Comment); err != nil {
+		return entity.TodoItem{}, t.transitionError(log, err)
+	}
+	t.recordActivity(
+		ctx, log, item, input.UserID, entity.TodoActivityRejected, map[string]string{"comment": input.Comment},
+	)
+	return t.GetByID(ctx, log, input.ID)
+}
+
+// GetForReview возвращает задания, ожидающие проверки пользователем, вместе с фото выполнения.
+// Родителю возвращаются все задания семьи на проверке
+func (t *TodoService) GetForReview(ctx context.Context, log *slog.Logger, userID string) ([]entity.TodoItem, error) {
+	log.Info("Service - TodoService - GetForReview")
+
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	familyID := ""
+	if user.Role == "Parent" && user.FamilyId.Valid {
+		familyID = user.FamilyId.String
+	}
+
+	items, err := t.todoRepo.GetForReview(ctx, log, userID, familyID)
+	if err != nil {
+		log.Error("Service - TodoService - GetForReview", "error", err)
+		return nil, fmt.Errorf("failed to get todos for review: %w", err)
+	}
+
+	if err = t.attachDetails(ctx, log, items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// attachDetails добавляет к заданиям фото выполнения и чек-листы с прогрессом
+func (t *TodoService) attachDetails(ctx context.Context, log *slog.Logger, items []entity.TodoItem) error {
+	if err := t.attachChecklists(ctx, log, items); err != nil {
 		return err
 	}
 
-	err = t.userRepo.UpdatePoint(ctx, input.AssignedTo, input.Point)
+	proofs, err := t.todoRepo.GetProofs(ctx, log, todoIDs(items))
+	if err != nil {
+		log.Error("Service - TodoService - attachDetails - GetProofs", "error", err)
+		return fmt.Errorf("failed to get todo proofs: %w", err)
+	}
+
+	byTodo := make(map[string][]entity.TodoProof, len(items))
+	for _, proof := range proofs {
+		byTodo[proof.TodoID] = append(byTodo[proof.TodoID], proof)
+	}
+	for i := range items {
+		items[i].Proofs = byTodo[items[i].ID]
+	}
+	return nil
+}
+
+// checkReviewer проверяет, может ли пользователь проверять задание: родитель семьи задания
+// или создатель задания, если он не является его исполнителем
+func (t *TodoService) checkReviewer(ctx context.Context, item entity.TodoItem, userID string) error {
+	if item.CreatedBy == userID && item.AssignedTo != userID {
+		return nil
+	}
+
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Role == "Parent" && user.FamilyId.Valid && user.FamilyId.String == item.FamilyID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+func (t *TodoService) transitionError(log *slog.Logger, err error) error {
+	if errors.Is(err, repoerrs.ErrNotFound) {
+		return ErrInvalidTodoTransition
+	}
+	log.Error("Service - TodoService - transition", "error", err)
+	return fmt.Errorf("failed to change todo status: %w", err)
+}
+
+// todoChanged проверяет, изменены ли поля задания, которые задаются шаблоном серии
+func todoChanged(current, updated entity.TodoItem) bool {
+	return current.Title != updated.Title ||
+		current.Description != updated.Description ||
+		!current.Deadline.Equal(updated.Deadline) ||
+		current.AssignedTo != updated.AssignedTo ||
+		current.Point != updated.Point
+}
+
+func (t *TodoService) getTodo(ctx context.Context, log *slog.Logger, id string) (entity.TodoItem, error) {
+	item, err := t.todoRepo.GetByID(ctx, log, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.TodoItem{}, ErrItemNotFound
+		}
+		log.Error("Service - TodoService - getTodo", "error", err)
+		return entity.TodoItem{}, fmt.Errorf("failed to get todo: %w", err)
+	}
+	return item, nil
+}
+
+// checkTodoManager проверяет, что пользователь может изменять задание: он создатель или родитель семьи задания
+func (t *TodoService) checkTodoManager(ctx context.Context, item entity.TodoItem, userID string) error {
+	if item.CreatedBy == userID {
+		return nil
+	}
+
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid || user.FamilyId.String != item.FamilyID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (t *TodoService) getSeries(ctx context.Context, log *slog.Logger, item entity.TodoItem) (
+	entity.TodoSeries, error,
+) {
+	if !item.SeriesID.Valid {
+		return entity.TodoSeries{}, ErrTodoNotRecurring
+	}
+
+	series, err := t.todoRepo.GetSeriesByID(ctx, log, item.SeriesID.String)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return entity.TodoSeries{}, ErrTodoNotRecurring
+		}
+		log.Error("Service - TodoService - getSeries", "error", err)
+		return entity.TodoSeries{}, fmt.Errorf("failed to get todo series: %w", err)
+	}
+	return series, nil
+}
+
+// updateSeries переносит изменения повторения на шаблон серии и ее невыполненные повторения.
+// Новый срок задания становится точкой отсчета правила повторения
+func (t *TodoService) updateSeries(
+	ctx context.Context, log *slog.Logger, current entity.TodoItem, input TodoUpdateInput,
+) error {
+	series, err := t.getSeries(ctx, log, current)
 	if err != nil {
-		log.Error("Service - TodoService - UpdatePoint: %v", err)
 		return err
 	}
 
+	if input.RecurrenceRule != "" {
+		parsed, err := rrule.Parse(input.RecurrenceRule)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+		}
+		series.RRule = parsed.String()
+	}
+	if !input.Deadline.Equal(current.Deadline) {
+		series.StartsAt = input.Deadline
+		// Следующее повторение должно идти после перенесенного
+		if input.Deadline.After(series.LastOccurrenceAt) {
+			series.LastOccurrenceAt = input.Deadline
+		}
+	}
+	series.Title = input.Title
+	series.Description = input.Description
+	series.AssignedTo = input.AssignedTo
+	series.Point = input.Point
+
+	if err = t.todoRepo.UpdateSeries(ctx, log, series); err != nil {
+		log.Error("Service - TodoService - updateSeries - UpdateSeries", "error", err)
+		return fmt.Errorf("failed to update todo series: %w", err)
+	}
+	if err = t.todoRepo.UpdateSeriesOccurrences(ctx, log, series); err != nil {
+		log.Error("Service - TodoService - updateSeries - UpdateSeriesOccurrences", "error", err)
+		return fmt.Errorf("failed to update todo series occurrences: %w", err)
+	}
+	return nil
+}
+
+// GetSeries возвращает серию повторяющегося задания. Доступно членам семьи серии
+func (t *TodoService) GetSeries(ctx context.Context, log *slog.Logger, userID, todoID string) (
+	entity.TodoSeries, error,
+) {
+	log.Info("Service - TodoService - GetSeries", "user_id", userID, "todo_id", todoID)
+
+	item, err := t.getTodo(ctx, log, todoID)
+	if err != nil {
+		return entity.TodoSeries{}, err
+	}
+	series, err := t.getSeries(ctx, log, item)
+	if err != nil {
+		return entity.TodoSeries{}, err
+	}
+	if err = t.checkSeriesMember(ctx, series, userID); err != nil {
+		return entity.TodoSeries{}, err
+	}
+	return series, nil
+}
+
+// DeleteSeries останавливает серию задания и удаляет ее невыполненные повторения. Доступно создателю серии
+// и родителю семьи. Повторения удаляются так же, как одиночные задания, и их можно восстановить через Bulk
+// в течение окна отмены
+func (t *TodoService) DeleteSeries(ctx context.Context, log *slog.Logger, userID, todoID string) (
+	entity.TodoBulkResult, error,
+) {
+	log.Info("Service - TodoService - DeleteSeries", "user_id", userID, "todo_id", todoID)
+
+	item, err := t.getTodo(ctx, log, todoID)
+	if err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+	series, err := t.getSeries(ctx, log, item)
+	if err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+	if err = t.checkSeriesManager(ctx, series, userID); err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+
+	ids, err := t.todoRepo.DeleteSeries(ctx, log, series.ID)
+	if err != nil {
+		log.Error("Service - TodoService - DeleteSeries", "error", err)
+		return entity.TodoBulkResult{}, fmt.Errorf("failed to delete todo series: %w", err)
+	}
+
+	items, err := t.todoRepo.GetByIDs(ctx, log, ids, true)
+	if err != nil {
+		log.Error("Service - TodoService - DeleteSeries - GetByIDs", "error", err)
+		return entity.TodoBulkResult{}, fmt.Errorf("failed to get todos: %w", err)
+	}
+	if err = t.attachDetails(ctx, log, items); err != nil {
+		return entity.TodoBulkResult{}, err
+	}
+
+	result := entity.TodoBulkResult{Items: items}
+	for _, deleted := range items {
+		if deleted.DeletedAt != nil && result.UndoUntil == nil {
+			undoUntil := deleted.DeletedAt.Add(t.deleteUndoWindow)
+			result.UndoUntil = &undoUntil
+		}
+		t.recordActivity(ctx, log, deleted, userID, entity.TodoActivityDeleted, nil)
+	}
+	return result, nil
+}
+
+// ensureNextOccurrence создает следующее повторение серии, если у нее не осталось невыполненных
+// повторений со сроком в будущем. Пропущенные повторения не создаются задним числом
+func (t *TodoService) ensureNextOccurrence(ctx context.Context, log *slog.Logger, seriesID string, now time.Time) error {
+	series, err := t.todoRepo.GetSeriesByID(ctx, log, seriesID)
+	if err != nil {
+		return fmt.Errorf("failed to get todo series: %w", err)
+	}
+	if !series.IsActive {
+		return nil
+	}
+
+	open, err := t.todoRepo.HasOpenOccurrence(ctx, log, seriesID, now)
+	if err != nil {
+		return fmt.Errorf("failed to check open occurrences: %w", err)
+	}
+	if open {
+		return nil
+	}
+
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+	}
+
+	after := series.LastOccurrenceAt
+	if now.After(after) {
+		after = now
+	}
+	next, ok := rule.Next(series.StartsAt, after)
+	if !ok {
+		// Правило больше не дает повторений, серия завершена
+		log.Info("Service - TodoService - ensureNextOccurrence - series finished", "series_id", seriesID)
+		series.IsActive = false
+		return t.todoRepo.UpdateSeries(ctx, log, series)
+	}
+
+	assignedTo, rotationIndex := series.AssignedTo, series.RotationIndex
+	if len(series.RotationMembers) > 0 {
+		absences, err := t.rotationAbsences(ctx, log, series.RotationMembers, next)
+		if err != nil {
+			return err
+		}
+		rotationIndex, _ = nextRotationMember(series.RotationMembers, series.RotationIndex, next, absences)
+		assignedTo = series.RotationMembers[rotationIndex]
+	}
+
+	created, err := t.todoRepo.CreateOccurrence(
+		ctx, log, entity.TodoItem{
+			FamilyID:      series.FamilyID,
+			Title:         series.Title,
+			Description:   series.Description,
+			Deadline:      next,
+			AssignedTo:    assignedTo,
+			CreatedBy:     series.CreatedBy,
+			Point:         series.Point,
+			SeriesID:      sql.NullString{String: series.ID, Valid: true},
+			OccurrenceAt:  &next,
+			RequiresProof: series.RequiresProof,
+			PointsRule:    series.PointsRule,
+			Checklist:     newChecklist(series.Checklist),
+		}, series.LastOccurrenceAt, rotationIndex,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create occurrence: %w", err)
+	}
+	if created {
+		log.Info("Service - TodoService - ensureNextOccurrence - created", "series_id", seriesID, "deadline", next)
+	}
+	return nil
+}
+
+// GenerateOccurrences создает очередные повторения для всех активных серий
+func (t *TodoService) GenerateOccurrences(ctx context.Context, log *slog.Logger) error {
+	log.Info("Service - TodoService - GenerateOccurrences")
+
+	ids, err := t.todoRepo.GetActiveSeriesIDs(ctx, log)
+	if err != nil {
+		log.Error("Service - TodoService - GenerateOccurrences - GetActiveSeriesIDs", "error", err)
+		return fmt.Errorf("failed to get active series: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		if err = t.ensureNextOccurrence(ctx, log, id, now); err != nil {
+			log.Error("Service - TodoService - GenerateOccurrences", "series_id", id, "error", err)
+		}
+	}
 	return nil
 }
 
+// RunRecurrenceGenerator периодически создает повторения серий до отмены ctx
+func (t *TodoService) RunRecurrenceGenerator(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	log.Info("Service - TodoService - RunRecurrenceGenerator", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = t.GenerateOccurrences(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // get by assigned to
 func (t *TodoService) GetByAssignedTo(ctx context.Context, log *slog.Logger, assignedTo string) (
 	[]entity.TodoItem, error,
@@ -109,10 +682,13 @@ func (t *TodoService) GetByAssignedTo(ctx context.Context, log *slog.Logger, ass
 
 	items, err := t.todoRepo.GetByAssignedTo(ctx, log, assignedTo)
 	if err != nil {
-		log.Error("Service - TodoService - GetByAssignedTo: %v", err)
+		log.Error("Service - TodoService - GetByAssignedTo", "error", err)
 		return nil, err
 	}
 
+	if err = t.attachChecklists(ctx, log, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
@@ -124,10 +700,13 @@ func (t *TodoService) GetByCreatedBy(ctx context.Context, log *slog.Logger, crea
 
 	items, err := t.todoRepo.GetByCreatedBy(ctx, log, createdBy)
 	if err != nil {
-		log.Error("Service - TodoService - GetByCreatedBy: %v", err)
+		log.Error("Service - TodoService - GetByCreatedBy", "error", err)
 		return nil, err
 	}
 
+	if err = t.attachChecklists(ctx, log, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
@@ -135,11 +714,15 @@ func (t *TodoService) GetByCreatedBy(ctx context.Context, log *slog.Logger, crea
 func (t *TodoService) GetByID(ctx context.Context, log *slog.Logger, id string) (entity.TodoItem, error) {
 	log.Info("Service - TodoService - GetByID")
 
-	item, err := t.todoRepo.GetByID(ctx, log, id)
+	item, err := t.getTodo(ctx, log, id)
 	if err != nil {
-		log.Error("Service - TodoService - GetByID: %v", err)
 		return entity.TodoItem{}, err
 	}
 
-	return item, nil
+	items := []entity.TodoItem{item}
+	if err = t.attachDetails(ctx, log, items); err != nil {
+		return entity.TodoItem{}, err
+	}
+
+	return items[0], nil
 }
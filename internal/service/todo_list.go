@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"family-flow-app/internal/entity"
+)
+
+const (
+	defaultTodoListLimit = 20
+	maxTodoListLimit     = 100
+)
+
+// TodoListInput - параметры списка заданий
+type TodoListInput struct {
+	UserID     string
+	AssignedTo string
+	CreatedBy  string
+	Statuses   []string
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Archived   bool
+	// Sort - deadline, -deadline, created_at или -created_at. По умолчанию deadline
+	Sort string
+	// Cursor - next_cursor предыдущей страницы
+	Cursor string
+	Limit  int
+}
+
+// List возвращает страницу заданий, видимых пользователю: родителю - все задания семьи,
+// остальным - задания, где он исполнитель или создатель
+func (t *TodoService) List(ctx context.Context, log *slog.Logger, input TodoListInput) (entity.TodoPage, error) {
+	log.Info("Service - TodoService - List")
+
+	user, err := t.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return entity.TodoPage{}, ErrUserNotFound
+	}
+
+	limit := input.Limit
+	if limit <= 0 || limit > maxTodoListLimit {
+		limit = defaultTodoListLimit
+	}
+	filter := entity.TodoFilter{
+		AssignedTo: input.AssignedTo,
+		CreatedBy:  input.CreatedBy,
+		Statuses:   input.Statuses,
+		DueBefore:  input.DueBefore,
+		DueAfter:   input.DueAfter,
+		Archived:   input.Archived,
+		Sort:       strings.TrimPrefix(input.Sort, "-"),
+		Desc:       strings.HasPrefix(input.Sort, "-"),
+		// Лишнее задание показывает, что есть следующая страница
+		Limit: uint64(limit) + 1,
+	}
+	if filter.Sort == "" {
+		filter.Sort = entity.TodoSortDeadline
+	}
+	if user.Role == "Parent" && user.FamilyId.Valid {
+		filter.FamilyID = user.FamilyId.String
+	} else {
+		filter.VisibleTo = input.UserID
+	}
+	if input.Cursor != "" {
+		cursor, err := decodeTodoCursor(input.Cursor)
+		if err != nil {
+			return entity.TodoPage{}, err
+		}
+		filter.After = &cursor
+	}
+
+	items, err := t.todoRepo.List(ctx, log, filter)
+	if err != nil {
+		log.Error("Service - TodoService - List", "error", err)
+		return entity.TodoPage{}, fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	page := entity.TodoPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		value := last.Deadline
+		if filter.Sort == entity.TodoSortCreatedAt {
+			value = last.CreatedAt
+		}
+		page.NextCursor = encodeTodoCursor(entity.TodoCursor{Value: value, ID: last.ID})
+	}
+	if page.Items == nil {
+		page.Items = []entity.TodoItem{}
+	}
+
+	if err = t.attachChecklists(ctx, log, page.Items); err != nil {
+		return entity.TodoPage{}, err
+	}
+	return page, nil
+}
+
+// encodeTodoCursor кодирует позицию в списке в непрозрачную для клиента строку
+func encodeTodoCursor(cursor entity.TodoCursor) string {
+	raw := cursor.Value.Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTodoCursor(s string) (entity.TodoCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return entity.TodoCursor{}, ErrInvalidCursor
+	}
+	value, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return entity.TodoCursor{}, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return entity.TodoCursor{}, ErrInvalidCursor
+	}
+	return entity.TodoCursor{Value: at, ID: id}, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"family-flow-app/internal/entity"
+	"family-flow-app/pkg/rrule"
+)
+
+const (
+	defaultRotationScheduleSize = 10
+	maxRotationScheduleSize     = 50
+)
+
+// TodoRotationInput - изменение ротации серии, к которой относится задание
+type TodoRotationInput struct {
+	TodoID string
+	UserID string
+	// Members - участники ротации по порядку. Пустой список отключает ротацию
+	Members []string
+}
+
+// SetRotation задает участников ротации серии. Менять ротацию может создатель задания или родитель семьи.
+// Очередь продолжается с участника, следующего за исполнителем последнего повторения
+func (t *TodoService) SetRotation(ctx context.Context, log *slog.Logger, input TodoRotationInput) (
+	entity.TodoSeries, error,
+) {
+	log.Info("Service - TodoService - SetRotation", "todo_id", input.TodoID)
+
+	item, err := t.getTodo(ctx, log, input.TodoID)
+	if err != nil {
+		return entity.TodoSeries{}, err
+	}
+	series, err := t.getSeries(ctx, log, item)
+	if err != nil {
+		return entity.TodoSeries{}, err
+	}
+	if err = t.checkSeriesManager(ctx, series, input.UserID); err != nil {
+		return entity.TodoSeries{}, err
+	}
+	if err = t.checkRotationMembers(ctx, series.FamilyID, input.Members); err != nil {
+		return entity.TodoSeries{}, err
+	}
+
+	current := series.AssignedTo
+	if series.RotationIndex < len(series.RotationMembers) {
+		current = series.RotationMembers[series.RotationIndex]
+	}
+
+	series.RotationMembers = append([]string{}, input.Members...)
+	// Если текущего исполнителя нет в новом списке, следующее повторение получит первый участник
+	series.RotationIndex = len(input.Members) - 1
+	for i, member := range input.Members {
+		if member == current {
+			series.RotationIndex = i
+			break
+		}
+	}
+	if series.RotationIndex < 0 {
+		series.RotationIndex = 0
+	}
+
+	if err = t.todoRepo.UpdateSeries(ctx, log, series); err != nil {
+		log.Error("Service - TodoService - SetRotation - UpdateSeries", "error", err)
+		return entity.TodoSeries{}, fmt.Errorf("failed to update todo series: %w", err)
+	}
+	return series, nil
+}
+
+// GetRotationSchedule возвращает расписание ближайших count повторений серии задания с исполнителями,
+// начиная с последнего созданного повторения. Отсутствия участников учитываются так же, как при создании повторений.
+// Расписание доступно только членам семьи серии
+func (t *TodoService) GetRotationSchedule(ctx context.Context, log *slog.Logger, userID, todoID string, count int) (
+	[]entity.TodoRotationSlot, error,
+) {
+	log.Info("Service - TodoService - GetRotationSchedule", "user_id", userID, "todo_id", todoID)
+
+	if count <= 0 || count > maxRotationScheduleSize {
+		count = defaultRotationScheduleSize
+	}
+
+	item, err := t.getTodo(ctx, log, todoID)
+	if err != nil {
+		return nil, err
+	}
+	series, err := t.getSeries(ctx, log, item)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.checkSeriesMember(ctx, series, userID); err != nil {
+		return nil, err
+	}
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrenceRule, err)
+	}
+
+	members := series.RotationMembers
+	absences, err := t.rotationAbsences(ctx, log, members, series.LastOccurrenceAt)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make([]entity.TodoRotationSlot, 0, count)
+	at, index := series.LastOccurrenceAt, series.RotationIndex
+	assignedTo := series.AssignedTo
+	if index < len(members) {
+		assignedTo = members[index]
+	}
+	schedule = append(schedule, entity.TodoRotationSlot{OccurrenceAt: at, AssignedTo: assignedTo, Skipped: []string{}})
+
+	for series.IsActive && len(schedule) < count {
+		next, ok := rule.Next(series.StartsAt, at)
+		if !ok {
+			break
+		}
+		at = next
+
+		slot := entity.TodoRotationSlot{OccurrenceAt: at, AssignedTo: series.AssignedTo, Skipped: []string{}}
+		if len(members) > 0 {
+			var skipped []string
+			index, skipped = nextRotationMember(members, index, at, absences)
+			slot.AssignedTo = members[index]
+			slot.Skipped = append(slot.Skipped, skipped...)
+		}
+		schedule = append(schedule, slot)
+	}
+	return schedule, nil
+}
+
+// checkSeriesManager проверяет, может ли пользователь управлять серией: создатель или родитель семьи
+func (t *TodoService) checkSeriesManager(ctx context.Context, series entity.TodoSeries, userID string) error {
+	if series.CreatedBy == userID {
+		return nil
+	}
+
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Role == "Parent" && user.FamilyId.Valid && user.FamilyId.String == series.FamilyID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// checkSeriesMember проверяет, что пользователь состоит в семье серии
+func (t *TodoService) checkSeriesMember(ctx context.Context, series entity.TodoSeries, userID string) error {
+	user, err := t.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.FamilyId.Valid || user.FamilyId.String != series.FamilyID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkRotationMembers проверяет, что участники ротации - разные члены семьи задания
+func (t *TodoService) checkRotationMembers(ctx context.Context, familyID string, members []string) error {
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		if seen[member] {
+			return ErrDuplicateRotationMember
+		}
+		seen[member] = true
+
+		user, err := t.userRepo.GetByID(ctx, member)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		if !user.FamilyId.Valid || user.FamilyId.String != familyID {
+			return ErrNotFamilyMember
+		}
+	}
+	return nil
+}
+
+func (t *TodoService) rotationAbsences(ctx context.Context, log *slog.Logger, members []string, from time.Time) (
+	[]entity.UserAbsence, error,
+) {
+	absences, err := t.absenceRepo.GetByUserIDs(ctx, log, members, from)
+	if err != nil {
+		log.Error("Service - TodoService - rotationAbsences", "error", err)
+		return nil, fmt.Errorf("failed to get absences: %w", err)
+	}
+	return absences, nil
+}
+
+// nextRotationMember выбирает участника ротации для повторения at: первого после last, кто не отсутствует
+// в этот момент, и возвращает его индекс и пропущенных участников.
+// Если отсутствуют все, повторение получает следующий по очереди участник
+func nextRotationMember(members []string, last int, at time.Time, absences []entity.UserAbsence) (int, []string) {
+	var skipped []string
+	for i := 1; i <= len(members); i++ {
+		index := (last + i) % len(members)
+		if !isAbsent(absences, members[index], at) {
+			return index, skipped
+		}
+		skipped = append(skipped, members[index])
+	}
+	return (last + 1) % len(members), nil
+}
+
+// isAbsent проверяет, отсутствует ли пользователь в момент at
+func isAbsent(absences []entity.UserAbsence, userID string, at time.Time) bool {
+	for _, absence := range absences {
+		if absence.UserID == userID && !at.Before(absence.StartsAt) && at.Before(absence.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"family-flow-app/internal/entity"
 	"family-flow-app/internal/repo"
@@ -15,11 +16,13 @@ import (
 )
 
 type UserService struct {
-	userRepo repo.User
+	userRepo    repo.User
+	chatsRepo   repo.Chat
+	absenceRepo repo.Absence
 }
 
-func NewUserService(userRepo repo.User) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo repo.User, chatsRepo repo.Chat, absenceRepo repo.Absence) *UserService {
+	return &UserService{userRepo: userRepo, chatsRepo: chatsRepo, absenceRepo: absenceRepo}
 }
 
 func (u *UserService) Login(ctx context.Context, log *slog.Logger, input AuthInput) (string, error) {
@@ -208,6 +211,13 @@ func (u *UserService) ResetFamilyID(ctx context.Context, log *slog.Logger, id st
 		log.Error(fmt.Sprintf("Service - UserService - ResetFamilyID: %v", err))
 		return ErrCannotResetFamilyID
 	}
+
+	// Убираем пользователя из семейного чата
+	if currentUser.FamilyId.Valid {
+		if _, err = syncFamilyChat(ctx, log, u.chatsRepo, u.userRepo, currentUser.FamilyId.String); err != nil {
+			log.Error(fmt.Sprintf("Service - UserService - ResetFamilyID - syncFamilyChat: %v", err))
+		}
+	}
 	return nil
 }
 
@@ -242,3 +252,115 @@ func (u *UserService) UpdateLocation(ctx context.Context, log *slog.Logger, inpu
 	log.Info("Service - UserService - UpdateLocation - Location updated successfully")
 	return nil
 }
+
+type AbsenceCreateInput struct {
+	// UserID - кто добавляет отсутствие, MemberID - кто отсутствует
+	UserID   string
+	MemberID string
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// AddAbsence добавляет период отсутствия. Пользователь отмечает себя, родитель - любого члена своей семьи
+func (u *UserService) AddAbsence(ctx context.Context, log *slog.Logger, input AbsenceCreateInput) (
+	entity.UserAbsence, error,
+) {
+	log.Info("Service - UserService - AddAbsence", "memberID", input.MemberID)
+
+	if err := u.checkAbsenceAccess(ctx, input.UserID, input.MemberID); err != nil {
+		return entity.UserAbsence{}, err
+	}
+
+	absence, err := u.absenceRepo.Create(
+		ctx, log, entity.UserAbsence{
+			UserID:    input.MemberID,
+			StartsAt:  input.StartsAt,
+			EndsAt:    input.EndsAt,
+			CreatedBy: sql.NullString{String: input.UserID, Valid: true},
+		},
+	)
+	if err != nil {
+		log.Error("Service - UserService - AddAbsence", "error", err)
+		return entity.UserAbsence{}, fmt.Errorf("failed to add absence: %w", err)
+	}
+	return absence, nil
+}
+
+// GetAbsences возвращает текущие и будущие отсутствия членов семьи пользователя
+// (или только самого пользователя, если он не в семье)
+func (u *UserService) GetAbsences(ctx context.Context, log *slog.Logger, userID string) ([]entity.UserAbsence, error) {
+	log.Info("Service - UserService - GetAbsences", "userID", userID)
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	ids := []string{userID}
+	if user.FamilyId.Valid {
+		members, err := u.userRepo.GetByFamilyID(ctx, user.FamilyId.String)
+		if err != nil {
+			log.Error("Service - UserService - GetAbsences - GetByFamilyID", "error", err)
+			return nil, fmt.Errorf("failed to get family members: %w", err)
+		}
+		ids = ids[:0]
+		for _, member := range members {
+			ids = append(ids, member.Id)
+		}
+	}
+
+	absences, err := u.absenceRepo.GetByUserIDs(ctx, log, ids, time.Now().UTC())
+	if err != nil {
+		log.Error("Service - UserService - GetAbsences", "error", err)
+		return nil, fmt.Errorf("failed to get absences: %w", err)
+	}
+	if absences == nil {
+		absences = []entity.UserAbsence{}
+	}
+	return absences, nil
+}
+
+// DeleteAbsence удаляет период отсутствия, права те же, что у AddAbsence
+func (u *UserService) DeleteAbsence(ctx context.Context, log *slog.Logger, userID, id string) error {
+	log.Info("Service - UserService - DeleteAbsence", "id", id)
+
+	absence, err := u.absenceRepo.GetByID(ctx, log, id)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return ErrAbsenceNotFound
+		}
+		log.Error("Service - UserService - DeleteAbsence - GetByID", "error", err)
+		return fmt.Errorf("failed to get absence: %w", err)
+	}
+	if err = u.checkAbsenceAccess(ctx, userID, absence.UserID); err != nil {
+		return err
+	}
+
+	if err = u.absenceRepo.Delete(ctx, log, id); err != nil {
+		if errors.Is(err, repoerrs.ErrNotFound) {
+			return ErrAbsenceNotFound
+		}
+		log.Error("Service - UserService - DeleteAbsence", "error", err)
+		return fmt.Errorf("failed to delete absence: %w", err)
+	}
+	return nil
+}
+
+func (u *UserService) checkAbsenceAccess(ctx context.Context, userID, memberID string) error {
+	if userID == memberID {
+		return nil
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	member, err := u.userRepo.GetByID(ctx, memberID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Role != "Parent" || !user.FamilyId.Valid || user.FamilyId != member.FamilyId {
+		return ErrForbidden
+	}
+	return nil
+}
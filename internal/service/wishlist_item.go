@@ -9,12 +9,16 @@ import (
 	"family-flow-app/internal/repo"
 )
 
+// wishlistStatusCompleted - статус исполненного желания
+const wishlistStatusCompleted = "Completed"
+
 type WishlistService struct {
 	wishlistRepo repo.WishlistItem
+	achievements Achievements
 }
 
-func NewWishlistService(wishlistRepo repo.WishlistItem) *WishlistService {
-	return &WishlistService{wishlistRepo: wishlistRepo}
+func NewWishlistService(wishlistRepo repo.WishlistItem, achievements Achievements) *WishlistService {
+	return &WishlistService{wishlistRepo: wishlistRepo, achievements: achievements}
 }
 
 type WishlistCreateInput struct {
@@ -36,7 +40,7 @@ func (w *WishlistService) Create(ctx context.Context, log *slog.Logger, input Wi
 
 	id, err := w.wishlistRepo.Create(ctx, log, item)
 	if err != nil {
-		log.Error("Service - WishlistService - Create: %v", err)
+		log.Error("Service - WishlistService - Create", "error", err)
 		return "", err
 	}
 
@@ -48,7 +52,7 @@ func (w *WishlistService) Delete(ctx context.Context, log *slog.Logger, id strin
 
 	err := w.wishlistRepo.Delete(ctx, log, id)
 	if err != nil {
-		log.Error("Service - WishlistService - Delete: %v", err)
+		log.Error("Service - WishlistService - Delete", "error", err)
 		return err
 	}
 
@@ -82,10 +86,20 @@ func (w *WishlistService) Update(ctx context.Context, log *slog.Logger, input Wi
 		},
 	)
 	if err != nil {
-		log.Error("Service - WishlistService - Update: %v", err)
+		log.Error("Service - WishlistService - Update", "error", err)
 		return err
 	}
 
+	// Исполненное желание засчитывается тому, кто его забронировал
+	if input.Status == wishlistStatusCompleted {
+		item, err := w.wishlistRepo.GetByID(ctx, log, input.ID)
+		if err != nil {
+			log.Error("Service - WishlistService - Update - GetByID", "error", err)
+		} else if item.ReservedBy.Valid {
+			w.achievements.Evaluate(ctx, log, item.ReservedBy.String, entity.AchievementEventWishFulfilled)
+		}
+	}
+
 	return nil
 }
 
@@ -94,7 +108,7 @@ func (w *WishlistService) GetByIDs(ctx context.Context, log *slog.Logger, id str
 
 	items, err := w.wishlistRepo.GetByUserID(ctx, log, id)
 	if err != nil {
-		log.Error("Service - WishlistService - GetByID: %v", err)
+		log.Error("Service - WishlistService - GetByID", "error", err)
 		return nil, err
 	}
 
@@ -112,7 +126,7 @@ func (w *WishlistService) UpdateReservedBy(
 	log.Info("Service - WishlistService - UpdateReservedBy")
 	err := w.wishlistRepo.UpdateReservedBy(ctx, log, input.ID, input.ReservedBy)
 	if err != nil {
-		log.Error("Service - WishlistService - UpdateReservedBy: %v", err)
+		log.Error("Service - WishlistService - UpdateReservedBy", "error", err)
 		return err
 	}
 
@@ -129,7 +143,7 @@ func (w *WishlistService) CancelUpdateReservedBy(
 	log.Info("Service - WishlistService - UpdateReservedBy")
 	err := w.wishlistRepo.CancelUpdateReservedBy(ctx, log, input.ID)
 	if err != nil {
-		log.Error("Service - WishlistService - UpdateReservedBy: %v", err)
+		log.Error("Service - WishlistService - UpdateReservedBy", "error", err)
 		return err
 	}
 
@@ -144,7 +158,7 @@ func (w *WishlistService) GetArchivedByUserID(
 
 	items, err := w.wishlistRepo.GetArchivedByUserID(ctx, log, userID)
 	if err != nil {
-		log.Error("Service - WishlistService - GetArchivedByUserID: %v", err)
+		log.Error("Service - WishlistService - GetArchivedByUserID", "error", err)
 		return nil, err
 	}
 
@@ -157,7 +171,7 @@ func (w *WishlistService) GetByID(ctx context.Context, log *slog.Logger, id stri
 
 	item, err := w.wishlistRepo.GetByID(ctx, log, id)
 	if err != nil {
-		log.Error("Service - WishlistService - GetByID: %v", err)
+		log.Error("Service - WishlistService - GetByID", "error", err)
 		return entity.WishlistItem{}, err
 	}
 
@@ -0,0 +1,17 @@
+BEGIN;
+
+ALTER TABLE chat_participants DROP CONSTRAINT IF EXISTS chat_participants_chat_user_uniq;
+
+DROP INDEX IF EXISTS chats_direct_key_uniq;
+
+-- Объединенные при миграции семейные чаты обратно не разделяются
+DROP INDEX IF EXISTS chats_family_uniq;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS direct_key,
+    DROP COLUMN IF EXISTS family_id,
+    DROP COLUMN IF EXISTS type;
+
+DROP TYPE IF EXISTS chat_type CASCADE;
+
+COMMIT;
@@ -0,0 +1,66 @@
+BEGIN;
+
+DROP TYPE IF EXISTS chat_type CASCADE;
+
+CREATE TYPE chat_type AS ENUM ('Group', 'Family', 'Direct');
+
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS type chat_type NOT NULL DEFAULT 'Group',
+    -- Семья, к которой относится чат (для семейных и личных чатов)
+    ADD COLUMN IF NOT EXISTS family_id UUID REFERENCES families (id) ON DELETE CASCADE DEFAULT NULL,
+    -- Отсортированная пара user_id для личного чата, гарантирует единственность
+    ADD COLUMN IF NOT EXISTS direct_key VARCHAR(255) DEFAULT NULL;
+
+-- Семейные чаты, созданные раньше как обычные: чат с названием семейного, все участники которого
+-- состоят в одной семье. Самый старый такой чат семьи становится ее семейным чатом, сообщения и участники
+-- остальных переносятся в него, а сами дубликаты удаляются
+CREATE TEMP TABLE family_chat_merge ON COMMIT DROP AS
+SELECT id AS chat_id,
+    family_id,
+    first_value(id) OVER (PARTITION BY family_id ORDER BY created_at NULLS LAST, id) AS canonical_id
+FROM (
+    SELECT c.id, c.created_at, (array_agg(u.family_id))[1] AS family_id
+    FROM chats c
+    JOIN chat_participants cp ON cp.chat_id = c.id
+    JOIN users u ON u.id = cp.user_id
+    WHERE lower(trim(c.name)) IN ('family', 'семья', 'семейный чат')
+    GROUP BY c.id, c.created_at
+    HAVING bool_and(u.family_id IS NOT NULL) AND count(DISTINCT u.family_id) = 1
+) legacy;
+
+UPDATE messages m
+SET chat_id = f.canonical_id
+FROM family_chat_merge f
+WHERE m.chat_id = f.chat_id AND f.chat_id <> f.canonical_id;
+
+UPDATE chat_participants cp
+SET chat_id = f.canonical_id
+FROM family_chat_merge f
+WHERE cp.chat_id = f.chat_id AND f.chat_id <> f.canonical_id;
+
+DELETE FROM chats c
+    USING family_chat_merge f
+WHERE c.id = f.chat_id AND f.chat_id <> f.canonical_id;
+
+UPDATE chats c
+SET type = 'Family', family_id = f.family_id
+FROM family_chat_merge f
+WHERE c.id = f.chat_id AND f.chat_id = f.canonical_id;
+
+-- У семьи может быть только один семейный чат
+CREATE UNIQUE INDEX IF NOT EXISTS chats_family_uniq ON chats (family_id) WHERE type = 'Family';
+
+CREATE UNIQUE INDEX IF NOT EXISTS chats_direct_key_uniq ON chats (direct_key);
+
+-- Удаляем дубликаты участников, в том числе появившиеся при объединении семейных чатов,
+-- перед добавлением ограничения. Остается самое раннее участие
+DELETE FROM chat_participants a
+    USING chat_participants b
+WHERE a.chat_id = b.chat_id
+  AND a.user_id = b.user_id
+  AND (COALESCE(a.joined_at, 'infinity'), a.id) > (COALESCE(b.joined_at, 'infinity'), b.id);
+
+ALTER TABLE chat_participants
+    ADD CONSTRAINT chat_participants_chat_user_uniq UNIQUE (chat_id, user_id);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE chats DROP COLUMN IF EXISTS is_locked;
+
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS is_muted,
+    DROP COLUMN IF EXISTS role;
+
+DROP TYPE IF EXISTS chat_participant_role CASCADE;
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+DROP TYPE IF EXISTS chat_participant_role CASCADE;
+
+CREATE TYPE chat_participant_role AS ENUM ('Owner', 'Admin', 'Member');
+
+ALTER TABLE chat_participants
+    -- Роль участника в чате
+    ADD COLUMN IF NOT EXISTS role chat_participant_role NOT NULL DEFAULT 'Member',
+    -- Заглушенный участник не может отправлять сообщения
+    ADD COLUMN IF NOT EXISTS is_muted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- В закрытый чат могут писать только модераторы
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS is_locked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Родители получают права администратора в семейных чатах
+UPDATE chat_participants cp
+SET role = 'Admin'
+FROM chats c, users u
+WHERE cp.chat_id = c.id
+  AND cp.user_id = u.id
+  AND c.type = 'Family'
+  AND u.role = 'Parent';
+
+COMMIT;
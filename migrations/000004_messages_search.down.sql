@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS messages_chat_created_idx;
+
+DROP INDEX IF EXISTS messages_content_tsv_idx;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS content_tsv;
+
+COMMIT;
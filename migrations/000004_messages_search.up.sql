@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Поисковый вектор по тексту сообщения на русском и английском
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS content_tsv tsvector GENERATED ALWAYS AS (
+        to_tsvector('russian', coalesce(content, '')) || to_tsvector('english', coalesce(content, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS messages_content_tsv_idx ON messages USING GIN (content_tsv);
+
+CREATE INDEX IF NOT EXISTS messages_chat_created_idx ON messages (chat_id, created_at);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE chat_participants DROP COLUMN IF EXISTS notifications_muted;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Участник может отключить пуш-уведомления о новых сообщениях в чате
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS notifications_muted BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
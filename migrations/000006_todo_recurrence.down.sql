@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX IF EXISTS todo_items_series_occurrence_uniq;
+
+ALTER TABLE todo_items
+    DROP COLUMN IF EXISTS is_detached,
+    DROP COLUMN IF EXISTS occurrence_at,
+    DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS todo_series;
+
+COMMIT;
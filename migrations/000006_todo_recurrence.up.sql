@@ -0,0 +1,32 @@
+BEGIN;
+
+-- Серия повторяющихся заданий: шаблон и правило повторения (подмножество RRULE)
+CREATE TABLE IF NOT EXISTS "todo_series" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    family_id UUID REFERENCES families (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    assigned_to UUID REFERENCES users (id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users (id) ON DELETE CASCADE,
+    point INT DEFAULT 0,
+    rrule TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    -- Дата последнего созданного повторения, следующее считается от нее
+    last_occurrence_at TIMESTAMP NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS todo_series_active_idx ON todo_series (is_active);
+
+-- Повторение серии - обычное задание со ссылкой на серию.
+-- is_detached - повторение изменено отдельно и не обновляется при изменении всей серии
+ALTER TABLE todo_items
+    ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES todo_series (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS is_detached BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS todo_items_series_occurrence_uniq ON todo_items (series_id, occurrence_at);
+
+COMMIT;
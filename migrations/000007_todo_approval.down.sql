@@ -0,0 +1,26 @@
+BEGIN;
+
+ALTER TABLE todo_items
+    DROP COLUMN IF EXISTS points_awarded,
+    DROP COLUMN IF EXISTS review_comment,
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS submitted_at;
+
+ALTER TYPE todos_item_status RENAME TO todos_item_status_new;
+
+CREATE TYPE todos_item_status AS ENUM ('Active', 'Completed');
+
+ALTER TABLE todo_items ALTER COLUMN status DROP DEFAULT;
+
+ALTER TABLE todo_items
+    ALTER COLUMN status TYPE todos_item_status USING (
+        CASE status::text WHEN 'Approved' THEN 'Completed' WHEN 'Submitted' THEN 'Active'
+            WHEN 'Rejected' THEN 'Active' ELSE status::text END
+    )::todos_item_status;
+
+ALTER TABLE todo_items ALTER COLUMN status SET DEFAULT 'Active';
+
+DROP TYPE todos_item_status_new;
+
+COMMIT;
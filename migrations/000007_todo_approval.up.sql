@@ -0,0 +1,30 @@
+BEGIN;
+
+-- Статусы заданий: Active -> Submitted -> Approved / Rejected. Rejected можно отправить на проверку повторно.
+-- Ранее выполненные задания (Completed) считаются одобренными
+ALTER TYPE todos_item_status RENAME TO todos_item_status_old;
+
+CREATE TYPE todos_item_status AS ENUM ('Active', 'Submitted', 'Approved', 'Rejected');
+
+ALTER TABLE todo_items ALTER COLUMN status DROP DEFAULT;
+
+ALTER TABLE todo_items
+    ALTER COLUMN status TYPE todos_item_status USING (
+        CASE status::text WHEN 'Completed' THEN 'Approved' ELSE status::text END
+    )::todos_item_status;
+
+ALTER TABLE todo_items ALTER COLUMN status SET DEFAULT 'Active';
+
+DROP TYPE todos_item_status_old;
+
+ALTER TABLE todo_items
+    ADD COLUMN IF NOT EXISTS submitted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS review_comment TEXT NOT NULL DEFAULT '',
+    -- Баллы за задание начисляются только один раз, при одобрении
+    ADD COLUMN IF NOT EXISTS points_awarded BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE todo_items SET points_awarded = TRUE WHERE status = 'Approved';
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE todo_series DROP COLUMN IF EXISTS requires_proof;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS requires_proof;
+
+DROP TABLE IF EXISTS todo_proofs;
+
+COMMIT;
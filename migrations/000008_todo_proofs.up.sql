@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Фото, подтверждающие выполнение задания
+CREATE TABLE IF NOT EXISTS "todo_proofs" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id UUID NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    uploaded_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    path TEXT NOT NULL,
+    url TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS todo_proofs_todo_idx ON todo_proofs (todo_id);
+
+-- Родитель может потребовать фото при отправке задания на проверку
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS requires_proof BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE todo_series ADD COLUMN IF NOT EXISTS requires_proof BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
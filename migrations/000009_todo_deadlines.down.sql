@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS todo_items_deadline_idx;
+
+DROP TABLE IF EXISTS "todo_reminders";
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS is_overdue;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Задание просрочено: срок прошел, а задание не выполнено
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS is_overdue BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Отправленные напоминания о сроке. Срок входит в ключ, поэтому после переноса срока
+-- напоминания отправляются заново
+CREATE TABLE IF NOT EXISTS "todo_reminders" (
+    todo_id UUID NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    deadline TIMESTAMP NOT NULL,
+    offset_seconds INT NOT NULL,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (todo_id, deadline, offset_seconds)
+);
+
+-- Планировщик выбирает задания по сроку
+CREATE INDEX IF NOT EXISTS todo_items_deadline_idx ON todo_items (deadline);
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE todo_series
+    DROP COLUMN IF EXISTS checklist,
+    DROP COLUMN IF EXISTS points_rule;
+
+ALTER TABLE todo_items
+    DROP COLUMN IF EXISTS awarded_points,
+    DROP COLUMN IF EXISTS points_rule;
+
+DROP TYPE IF EXISTS todo_points_rule;
+
+DROP TABLE IF EXISTS "todo_checklist_items";
+
+COMMIT;
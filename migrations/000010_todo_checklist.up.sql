@@ -0,0 +1,35 @@
+BEGIN;
+
+-- Пункты чек-листа задания в порядке position
+CREATE TABLE IF NOT EXISTS "todo_checklist_items" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id UUID NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    is_done BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    done_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    done_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS todo_checklist_items_todo_idx ON todo_checklist_items (todo_id, position);
+
+DROP TYPE IF EXISTS todo_points_rule CASCADE;
+
+-- Правило начисления баллов для задания с чек-листом:
+-- All - баллы начисляются, только если отмечены все пункты; Proportional - пропорционально отмеченным пунктам
+CREATE TYPE todo_points_rule AS ENUM ('All', 'Proportional');
+
+ALTER TABLE todo_items
+    ADD COLUMN IF NOT EXISTS points_rule todo_points_rule NOT NULL DEFAULT 'All',
+    -- Фактически начисленные при одобрении баллы
+    ADD COLUMN IF NOT EXISTS awarded_points INT NOT NULL DEFAULT 0;
+
+UPDATE todo_items SET awarded_points = point WHERE points_awarded;
+
+-- Серия хранит шаблон чек-листа, он копируется в каждое повторение
+ALTER TABLE todo_series
+    ADD COLUMN IF NOT EXISTS points_rule todo_points_rule NOT NULL DEFAULT 'All',
+    ADD COLUMN IF NOT EXISTS checklist TEXT[] NOT NULL DEFAULT '{}';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS todo_items_family_created_at_idx;
+
+DROP INDEX IF EXISTS todo_items_created_by_deadline_idx;
+
+DROP INDEX IF EXISTS todo_items_assigned_deadline_idx;
+
+DROP INDEX IF EXISTS todo_items_family_deadline_idx;
+
+COMMIT;
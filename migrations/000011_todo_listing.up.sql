@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Индексы для выборки заданий с фильтрами и постраничной навигацией по (deadline, id) и (created_at, id)
+CREATE INDEX IF NOT EXISTS todo_items_family_deadline_idx ON todo_items (family_id, deadline, id);
+
+CREATE INDEX IF NOT EXISTS todo_items_assigned_deadline_idx ON todo_items (assigned_to, deadline, id);
+
+CREATE INDEX IF NOT EXISTS todo_items_created_by_deadline_idx ON todo_items (created_by, deadline, id);
+
+CREATE INDEX IF NOT EXISTS todo_items_family_created_at_idx ON todo_items (family_id, created_at, id);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE todo_series
+    DROP COLUMN IF EXISTS rotation_index,
+    DROP COLUMN IF EXISTS rotation_members;
+
+DROP TABLE IF EXISTS "user_absences";
+
+COMMIT;
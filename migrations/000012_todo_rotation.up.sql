@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Периоды отсутствия участника семьи (отпуск, лагерь). В это время ротация пропускает участника
+CREATE TABLE IF NOT EXISTS "user_absences" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS user_absences_user_idx ON user_absences (user_id, ends_at);
+
+-- Ротация серии: повторения по очереди назначаются участникам rotation_members.
+-- rotation_index - индекс участника, которому назначено последнее созданное повторение
+ALTER TABLE todo_series
+    ADD COLUMN IF NOT EXISTS rotation_members UUID[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS rotation_index INT NOT NULL DEFAULT 0;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS todo_items_deleted_at_idx;
+DROP INDEX IF EXISTS todo_items_auto_archive_idx;
+
+DELETE FROM todo_items WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE todo_items DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE todo_items DROP COLUMN IF EXISTS archived_at;
+
+COMMIT;
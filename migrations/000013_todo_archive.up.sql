@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Время архивации задания и мягкого удаления. Удаленное задание можно восстановить,
+-- пока не истекло окно отмены, после чего очистка удаляет его окончательно
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+UPDATE todo_items SET archived_at = updated_at WHERE is_archived AND archived_at IS NULL;
+
+-- Автоархивация выбирает выполненные задания по времени одобрения
+CREATE INDEX IF NOT EXISTS todo_items_auto_archive_idx ON todo_items (reviewed_at)
+    WHERE status = 'Approved' AND is_archived = FALSE AND deleted_at IS NULL;
+
+-- Очистка выбирает удаленные задания по времени удаления
+CREATE INDEX IF NOT EXISTS todo_items_deleted_at_idx ON todo_items (deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Секретный токен подписки на календарь пользователя в формате iCalendar. У пользователя один токен,
+-- перевыпуск заменяет его и отключает старую ссылку
+CREATE TABLE IF NOT EXISTS "calendar_tokens" (
+    user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "todo_activity";
+
+DROP TYPE IF EXISTS todo_activity_kind;
+
+DROP TABLE IF EXISTS "todo_comments";
+
+COMMIT;
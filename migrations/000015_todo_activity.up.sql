@@ -0,0 +1,45 @@
+BEGIN;
+
+-- Комментарии к заданию. mentions - упомянутые члены семьи, им приходит уведомление
+CREATE TABLE IF NOT EXISTS "todo_comments" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id UUID NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    mentions UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS todo_comments_todo_id_idx ON todo_comments (todo_id, created_at);
+
+DROP TYPE IF EXISTS todo_activity_kind CASCADE;
+
+CREATE TYPE todo_activity_kind AS ENUM (
+    'Created',
+    'Updated',
+    'Reassigned',
+    'DeadlineChanged',
+    'Submitted',
+    'Approved',
+    'Rejected',
+    'Commented',
+    'Archived',
+    'Unarchived',
+    'Deleted',
+    'Restored'
+);
+
+-- Лента событий задания. actor_id пуст для действий, выполненных системой.
+-- details - подробности события, например прежний и новый срок
+CREATE TABLE IF NOT EXISTS "todo_activity" (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id UUID NOT NULL REFERENCES todo_items (id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    kind todo_activity_kind NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS todo_activity_todo_id_idx ON todo_activity (todo_id, created_at);
+
+COMMIT;
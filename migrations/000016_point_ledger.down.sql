@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_point_non_negative;
+ALTER TABLE users ALTER COLUMN point DROP NOT NULL;
+
+DROP TABLE IF EXISTS point_transactions;
+DROP TYPE IF EXISTS point_transaction_kind;
+
+COMMIT;
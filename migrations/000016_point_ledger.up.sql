@@ -0,0 +1,48 @@
+BEGIN;
+
+DROP TYPE IF EXISTS point_transaction_kind CASCADE;
+
+-- Вид операции с баллами: начисление, списание, ручная корректировка и возврат
+CREATE TYPE point_transaction_kind AS ENUM ('Earn', 'Spend', 'Adjust', 'Refund');
+
+-- Журнал операций с баллами. Записи только добавляются, users.point хранит текущий баланс
+-- и меняется в той же транзакции, что и запись журнала
+CREATE TABLE IF NOT EXISTS point_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind point_transaction_kind NOT NULL,
+    -- Изменение баланса: положительное при начислении, отрицательное при списании
+    amount INT NOT NULL,
+    -- Баланс пользователя после операции
+    balance_after INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    -- Источник операции, например todo или reward_redemption, и его ID
+    source_type VARCHAR(32) NOT NULL DEFAULT '',
+    source_id UUID,
+    -- Кто выполнил операцию, NULL - система
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS point_transactions_user_idx ON point_transactions (user_id, created_at DESC, id DESC);
+
+-- Баланс не может стать отрицательным: одновременные обмены упираются в проверку,
+-- а не списывают баллы дважды. Отрицательные балансы обнуляются корректировкой в журнале,
+-- в причине которой сохраняется прежний баланс
+INSERT INTO point_transactions (user_id, kind, amount, balance_after, reason)
+SELECT id, 'Adjust', -point, 0, 'Обнуление отрицательного баланса: ' || point
+FROM users
+WHERE point < 0;
+
+UPDATE users SET point = 0 WHERE point IS NULL OR point < 0;
+ALTER TABLE users ALTER COLUMN point SET NOT NULL;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_point_non_negative;
+ALTER TABLE users ADD CONSTRAINT users_point_non_negative CHECK (point >= 0);
+
+-- Накопленные до журнала баллы переносятся одной корректировкой
+INSERT INTO point_transactions (user_id, kind, amount, balance_after, reason)
+SELECT id, 'Adjust', point, point, 'Начальный баланс'
+FROM users
+WHERE point > 0;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP INDEX IF EXISTS reward_redemptions_status_idx;
+
+-- Баллы за отклоненные обмены уже возвращены, без статуса они выглядели бы выполненными
+DELETE FROM reward_redemptions WHERE status = 'Declined';
+
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS fulfilled_at;
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS review_comment;
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS cost;
+ALTER TABLE reward_redemptions DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS reward_redemption_status;
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+DROP TYPE IF EXISTS reward_redemption_status CASCADE;
+
+-- Статус обмена: запрос ждет решения родителя, одобрен, отклонен (баллы возвращены) или выполнен
+CREATE TYPE reward_redemption_status AS ENUM ('Pending', 'Approved', 'Declined', 'Fulfilled');
+
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS status reward_redemption_status NOT NULL DEFAULT 'Pending';
+-- Списанная стоимость: при отклонении возвращается именно она, даже если цену награды изменили
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS cost INT NOT NULL DEFAULT 0;
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+-- Комментарий родителя, например причина отказа
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS review_comment TEXT NOT NULL DEFAULT '';
+ALTER TABLE reward_redemptions ADD COLUMN IF NOT EXISTS fulfilled_at TIMESTAMP;
+
+-- Обмены до появления статусов были окончательными
+UPDATE reward_redemptions rr
+SET status = 'Fulfilled',
+    fulfilled_at = rr.redeemed_at,
+    cost = COALESCE((SELECT r.cost FROM rewards r WHERE r.id = rr.reward_id), 0);
+
+-- Родитель просматривает запросы семьи по статусу
+CREATE INDEX IF NOT EXISTS reward_redemptions_status_idx ON reward_redemptions (status, redeemed_at DESC);
+
+COMMIT;
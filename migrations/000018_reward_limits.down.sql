@@ -0,0 +1,16 @@
+BEGIN;
+
+DROP INDEX IF EXISTS reward_redemptions_user_reward_idx;
+
+ALTER TABLE rewards DROP COLUMN IF EXISTS max_age;
+ALTER TABLE rewards DROP COLUMN IF EXISTS min_age;
+ALTER TABLE rewards DROP COLUMN IF EXISTS allowed_roles;
+ALTER TABLE rewards DROP COLUMN IF EXISTS available_until;
+ALTER TABLE rewards DROP COLUMN IF EXISTS available_from;
+ALTER TABLE rewards DROP COLUMN IF EXISTS limit_period;
+ALTER TABLE rewards DROP COLUMN IF EXISTS per_user_limit;
+ALTER TABLE rewards DROP COLUMN IF EXISTS stock;
+
+DROP TYPE IF EXISTS reward_limit_period;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+DROP TYPE IF EXISTS reward_limit_period CASCADE;
+
+-- Период ограничения числа обменов на одного пользователя, считается скользящим окном
+CREATE TYPE reward_limit_period AS ENUM ('Day', 'Week', 'Month', 'Ever');
+
+-- Остаток награды, NULL - без ограничения. Отклоненный обмен возвращает единицу в остаток
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS stock INT CHECK (stock IS NULL OR stock >= 0);
+-- Сколько раз один пользователь может обменять награду за период, 0 - без ограничения
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS per_user_limit INT NOT NULL DEFAULT 0 CHECK (per_user_limit >= 0);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS limit_period reward_limit_period;
+-- Период, когда награда доступна, NULL - без ограничения
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS available_from TIMESTAMP;
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS available_until TIMESTAMP;
+-- Роли, которым доступна награда, пустой массив - всем
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS allowed_roles TEXT[] NOT NULL DEFAULT '{}';
+-- Возрастные ограничения в полных годах, 0 - без ограничения
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS min_age INT NOT NULL DEFAULT 0 CHECK (min_age >= 0);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS max_age INT NOT NULL DEFAULT 0 CHECK (max_age >= 0);
+
+-- Лимит на пользователя считается по его обменам награды
+CREATE INDEX IF NOT EXISTS reward_redemptions_user_reward_idx ON reward_redemptions (user_id, reward_id, redeemed_at);
+
+COMMIT;
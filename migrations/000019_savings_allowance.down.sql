@@ -0,0 +1,35 @@
+BEGIN;
+
+DROP TABLE IF EXISTS allowance_settings;
+
+-- Отложенные баллы возвращаются на баланс операцией Release для каждой незавершенной цели, как при удалении
+-- цели, чтобы сумма журнала по-прежнему совпадала с балансом. balance_after считается нарастающим итогом
+-- по целям пользователя
+INSERT INTO point_transactions (user_id, kind, amount, balance_after, reason, source_type, source_id)
+SELECT g.user_id,
+    'Release',
+    g.saved,
+    u.point + SUM(g.saved) OVER (PARTITION BY g.user_id ORDER BY g.created_at, g.id),
+    r.title,
+    'savings_goal',
+    g.id
+FROM savings_goals g
+JOIN users u ON u.id = g.user_id
+JOIN rewards r ON r.id = g.reward_id
+WHERE g.completed_at IS NULL AND g.saved > 0;
+
+UPDATE users u
+SET point = u.point + g.saved
+FROM (
+    SELECT user_id, SUM(saved) AS saved
+    FROM savings_goals
+    WHERE completed_at IS NULL
+    GROUP BY user_id
+) g
+WHERE u.id = g.user_id;
+
+DROP TABLE IF EXISTS savings_goals;
+
+-- Значения Reserve и Release остаются в point_transaction_kind: PostgreSQL не удаляет значения перечислений
+
+COMMIT;
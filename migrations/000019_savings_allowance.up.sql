@@ -0,0 +1,36 @@
+BEGIN;
+
+-- Отложить баллы на цель и вернуть их с цели на баланс. Новые значения нельзя использовать
+-- до COMMIT, поэтому миграция только добавляет их
+ALTER TYPE point_transaction_kind ADD VALUE IF NOT EXISTS 'Reserve';
+ALTER TYPE point_transaction_kind ADD VALUE IF NOT EXISTS 'Release';
+
+-- Цели накопления: пользователь откладывает баллы на выбранную награду. Отложенные баллы
+-- списываются с баланса операцией Reserve и возвращаются операцией Release
+CREATE TABLE IF NOT EXISTS savings_goals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reward_id UUID NOT NULL REFERENCES rewards (id) ON DELETE CASCADE,
+    saved INT NOT NULL DEFAULT 0 CHECK (saved >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Время обмена накопленного на награду
+    completed_at TIMESTAMP
+);
+
+-- Одна незавершенная цель на награду у пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS savings_goals_active_idx ON savings_goals (user_id, reward_id)
+    WHERE completed_at IS NULL;
+
+-- Перевод баллов в деньги для карманных расходов, настраивается родителем семьи
+CREATE TABLE IF NOT EXISTS allowance_settings (
+    family_id UUID PRIMARY KEY REFERENCES families (id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Стоимость одного балла в минимальных единицах валюты (копейках, центах)
+    minor_per_point INT NOT NULL DEFAULT 0 CHECK (minor_per_point >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    updated_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
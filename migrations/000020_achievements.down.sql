@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS todo_items_assigned_reviewed_idx;
+DROP TABLE IF EXISTS user_achievements;
+DROP TABLE IF EXISTS achievement_rules;
+DROP TYPE IF EXISTS achievement_event;
+
+COMMIT;
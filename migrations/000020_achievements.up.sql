@@ -0,0 +1,57 @@
+BEGIN;
+
+DROP TYPE IF EXISTS achievement_event CASCADE;
+
+-- Показатель, по которому правило выдает достижение
+CREATE TYPE achievement_event AS ENUM ('TodoApproved', 'TodoStreak', 'WishFulfilled', 'RewardRedeemed');
+
+-- Правила достижений. Правила без семьи действуют по умолчанию, правило семьи с тем же code заменяет их
+CREATE TABLE IF NOT EXISTS achievement_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    family_id UUID REFERENCES families (id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    event achievement_event NOT NULL,
+    -- Значение показателя, с которого выдается достижение
+    threshold INT NOT NULL CHECK (threshold > 0),
+    -- Баллы, начисляемые вместе с достижением
+    bonus_points INT NOT NULL DEFAULT 0 CHECK (bonus_points >= 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS achievement_rules_default_code_idx ON achievement_rules (code)
+    WHERE family_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS achievement_rules_family_code_idx ON achievement_rules (family_id, code)
+    WHERE family_id IS NOT NULL;
+
+-- Полученные достижения. Название и бонус сохраняются на момент получения, чтобы правка правила
+-- не меняла уже выданное
+CREATE TABLE IF NOT EXISTS user_achievements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    bonus_points INT NOT NULL DEFAULT 0,
+    awarded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code)
+);
+
+-- Серия считается по дням одобрения заданий исполнителя
+CREATE INDEX IF NOT EXISTS todo_items_assigned_reviewed_idx ON todo_items (assigned_to, reviewed_at)
+    WHERE status = 'Approved';
+
+INSERT INTO achievement_rules (code, title, description, event, threshold, bonus_points)
+VALUES
+    ('first_todo', 'Первое задание', 'Выполнить первое задание', 'TodoApproved', 1, 5),
+    ('todos_10', 'Трудяга', 'Выполнить 10 заданий', 'TodoApproved', 10, 20),
+    ('todos_50', 'Мастер на все руки', 'Выполнить 50 заданий', 'TodoApproved', 50, 50),
+    ('streak_3', 'Три дня подряд', 'Выполнять задания 3 дня подряд', 'TodoStreak', 3, 10),
+    ('streak_7', 'Неделя без пропусков', 'Выполнять задания 7 дней подряд', 'TodoStreak', 7, 30),
+    ('first_wish', 'Исполнитель желаний', 'Исполнить желание из вишлиста члена семьи', 'WishFulfilled', 1, 10),
+    ('first_reward', 'Первая награда', 'Обменять баллы на награду', 'RewardRedeemed', 1, 0);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS reward_redemptions_user_redeemed_idx;
+DROP TABLE IF EXISTS reward_digests;
+
+COMMIT;
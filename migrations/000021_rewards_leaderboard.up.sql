@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Еженедельные сводки по баллам: отметка не дает отправить сводку семье за ту же неделю повторно
+CREATE TABLE IF NOT EXISTS reward_digests (
+    family_id UUID NOT NULL REFERENCES families (id) ON DELETE CASCADE,
+    -- Понедельник недели сводки (UTC)
+    week_start DATE NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (family_id, week_start)
+);
+
+-- Таблица лидеров суммирует обмены членов семьи за период
+CREATE INDEX IF NOT EXISTS reward_redemptions_user_redeemed_idx ON reward_redemptions (user_id, redeemed_at);
+
+COMMIT;
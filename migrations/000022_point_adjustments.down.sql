@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS point_transactions_adjustment_undo_idx;
+
+COMMIT;
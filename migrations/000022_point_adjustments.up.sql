@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Ручную корректировку баллов родителем можно отменить только один раз: отмена ссылается на нее через source_id
+CREATE UNIQUE INDEX IF NOT EXISTS point_transactions_adjustment_undo_idx
+    ON point_transactions (source_id) WHERE source_type = 'adjustment_undo';
+
+COMMIT;
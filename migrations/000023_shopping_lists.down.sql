@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS shopping_items_list_idx;
+ALTER TABLE shopping_items DROP COLUMN IF EXISTS list_id;
+DROP TABLE IF EXISTS shopping_list_shares;
+DROP TABLE IF EXISTS shopping_lists;
+
+COMMIT;
@@ -0,0 +1,60 @@
+BEGIN;
+
+-- Именованные списки покупок. Список принадлежит семье (виден всем ее членам) или пользователю
+-- (виден владельцу и тем, с кем он им поделился)
+CREATE TABLE IF NOT EXISTS shopping_lists (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    family_id UUID REFERENCES families (id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    -- Список по умолчанию, в который попадают покупки без явного списка
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+    archived_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT shopping_lists_owner_check CHECK ((family_id IS NULL) <> (user_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shopping_lists_family_default_idx ON shopping_lists (family_id)
+    WHERE is_default AND family_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS shopping_lists_user_default_idx ON shopping_lists (user_id)
+    WHERE is_default AND user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS shopping_lists_family_idx ON shopping_lists (family_id);
+CREATE INDEX IF NOT EXISTS shopping_lists_user_idx ON shopping_lists (user_id);
+
+-- Члены семьи, с которыми владелец поделился личным списком
+CREATE TABLE IF NOT EXISTS shopping_list_shares (
+    list_id UUID NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (list_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS shopping_list_shares_user_idx ON shopping_list_shares (user_id);
+
+ALTER TABLE shopping_items ADD COLUMN IF NOT EXISTS list_id UUID REFERENCES shopping_lists (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS shopping_items_list_idx ON shopping_items (list_id);
+
+-- Существующие покупки переносятся в списки по умолчанию: публичные - в список семьи,
+-- личные - в список создателя. Покупки без видимости ни в одном запросе не участвовали и остаются без списка
+INSERT INTO shopping_lists (family_id, title, is_default)
+SELECT DISTINCT family_id, 'Покупки семьи', TRUE
+FROM shopping_items
+WHERE visibility = 'Public' AND family_id IS NOT NULL;
+
+INSERT INTO shopping_lists (user_id, title, created_by, is_default)
+SELECT DISTINCT created_by, 'Мои покупки', created_by, TRUE
+FROM shopping_items
+WHERE visibility = 'Private' AND created_by IS NOT NULL;
+
+UPDATE shopping_items i
+SET list_id = l.id
+FROM shopping_lists l
+WHERE l.is_default
+  AND ((i.visibility = 'Public' AND l.family_id = i.family_id)
+    OR (i.visibility = 'Private' AND l.user_id = i.created_by));
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+DROP INDEX IF EXISTS shopping_items_list_bought_idx;
+ALTER TABLE shopping_items
+    DROP COLUMN IF EXISTS bought_at,
+    DROP COLUMN IF EXISTS store,
+    DROP COLUMN IF EXISTS actual_price_minor,
+    DROP COLUMN IF EXISTS estimated_price_minor,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS unit,
+    DROP COLUMN IF EXISTS quantity;
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- Количество, отдел магазина, цены и магазин покупки. Цены - за всю позицию в минимальных единицах валюты
+ALTER TABLE shopping_items
+    ADD COLUMN IF NOT EXISTS quantity NUMERIC(10, 3) NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    ADD COLUMN IF NOT EXISTS unit VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS category VARCHAR(32) NOT NULL DEFAULT 'other',
+    ADD COLUMN IF NOT EXISTS estimated_price_minor INT CHECK (estimated_price_minor >= 0),
+    ADD COLUMN IF NOT EXISTS actual_price_minor INT CHECK (actual_price_minor >= 0),
+    ADD COLUMN IF NOT EXISTS store VARCHAR(255) NOT NULL DEFAULT '',
+    -- Когда покупку отметили купленной, по нему считаются траты за месяц
+    ADD COLUMN IF NOT EXISTS bought_at TIMESTAMP;
+
+-- Уже купленные покупки считаются купленными в момент последнего изменения
+UPDATE shopping_items SET bought_at = updated_at WHERE status = 'Completed' AND buyer_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS shopping_items_list_bought_idx ON shopping_items (list_id, bought_at)
+    WHERE bought_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE shopping_items DROP COLUMN IF EXISTS version;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Версия покупки растет при каждом изменении. Клиенты сравнивают ее с версией из событий синхронизации
+-- и передают ожидаемую версию при изменении, чтобы не затереть чужие правки
+ALTER TABLE shopping_items ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+COMMIT;
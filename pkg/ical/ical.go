@@ -0,0 +1,120 @@
+// Package ical формирует календарь в формате iCalendar (RFC 5545): события VEVENT с постоянными UID,
+// экранированием текста и переносом длинных строк.
+package ical
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateTimeLayout = "20060102T150405Z"
+	dateLayout     = "20060102"
+	// maxLineOctets - максимальная длина строки без переноса (RFC 5545, 3.1)
+	maxLineOctets = 75
+)
+
+// Event - событие календаря
+type Event struct {
+	// UID - постоянный идентификатор: по нему клиент обновляет событие, а не создает новое
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	// End - окончание события, нулевое значение - событие без длительности
+	End time.Time
+	// AllDay - событие на весь день, учитываются только даты Start и End
+	AllDay bool
+	// RRule - правило повторения без префикса RRULE:, например FREQ=YEARLY
+	RRule string
+}
+
+// Calendar - календарь с событиями
+type Calendar struct {
+	ProdID string
+	Name   string
+	// Stamp - время формирования календаря, записывается в DTSTAMP событий
+	Stamp  time.Time
+	Events []Event
+}
+
+// String возвращает календарь в формате iCalendar со строками, разделенными CRLF
+func (c Calendar) String() string {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+c.ProdID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	stamp := c.Stamp.UTC().Format(dateTimeLayout)
+	for _, event := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+stamp)
+		if event.AllDay {
+			writeLine(&b, "DTSTART;VALUE=DATE:"+event.Start.Format(dateLayout))
+			if !event.End.IsZero() {
+				writeLine(&b, "DTEND;VALUE=DATE:"+event.End.Format(dateLayout))
+			}
+		} else {
+			writeLine(&b, "DTSTART:"+event.Start.UTC().Format(dateTimeLayout))
+			if !event.End.IsZero() {
+				writeLine(&b, "DTEND:"+event.End.UTC().Format(dateTimeLayout))
+			}
+		}
+		if event.RRule != "" {
+			writeLine(&b, "RRULE:"+event.RRule)
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if len(event.Categories) > 0 {
+			categories := make([]string, len(event.Categories))
+			for i, category := range event.Categories {
+				categories[i] = escapeText(category)
+			}
+			writeLine(&b, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11)
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// writeLine записывает строку, перенося ее по maxLineOctets байт без разрыва символов UTF-8
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Строка продолжения начинается с пробела, который входит в ее длину
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -72,8 +72,7 @@ func New(ctx context.Context, connString string, opts ...Option) (*Database, err
 		db.connAttempts--
 	}
 	if err != nil {
-		fmt.Errorf("database - New - pgxpool.NewWithConfig: %w", err)
-		return nil, err
+		return nil, fmt.Errorf("database - New - pgxpool.NewWithConfig: %w", err)
 	}
 	return db, nil
 }
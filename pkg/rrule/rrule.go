@@ -0,0 +1,272 @@
+// Package rrule реализует подмножество правил повторения RRULE из RFC 5545:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY (только для WEEKLY), BYMONTHDAY (только для MONTHLY) и UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxIterations ограничивает перебор периодов для правил, которые больше не дают повторений
+// (например, BYMONTHDAY=31 с INTERVAL=12, начиная с февраля)
+const maxIterations = 1000
+
+const (
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// ByDay - дни недели для WEEKLY. Пустой список означает день недели начала серии
+	ByDay []time.Weekday
+	// ByMonthDay - день месяца для MONTHLY, отрицательные значения считаются с конца месяца (-1 - последний день).
+	// Ноль означает день месяца начала серии. Месяцы без такого дня пропускаются
+	ByMonthDay int
+	// Until - последний допустимый момент повторения, нулевое значение - без ограничения
+	Until time.Time
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse разбирает правило вида "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE,FR". Префикс "RRULE:" допускается
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, invalid("empty rule")
+	}
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, invalid("malformed part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return Rule{}, invalid("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Rule{}, invalid("INTERVAL must be a positive number")
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			rule.ByDay = nil
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return Rule{}, invalid("unsupported BYDAY value %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(value)
+			if err != nil || day == 0 || day < -31 || day > 31 {
+				return Rule{}, invalid("BYMONTHDAY must be in range 1..31 or -31..-1")
+			}
+			rule.ByMonthDay = day
+		case "UNTIL":
+			until, err := time.Parse(untilLayout, value)
+			if err != nil {
+				until, err = time.Parse(untilDateLayout, value)
+				if err != nil {
+					return Rule{}, invalid("UNTIL must be in format YYYYMMDD or YYYYMMDDTHHMMSSZ")
+				}
+				// Дата без времени включает весь день
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			rule.Until = until
+		default:
+			return Rule{}, invalid("unsupported part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, invalid("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, invalid("BYDAY is supported only with FREQ=WEEKLY")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != Monthly {
+		return Rule{}, invalid("BYMONTHDAY is supported only with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+// String возвращает правило в каноническом виде
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, weekday := range sortedWeekdays(r.ByDay) {
+			for name, day := range weekdays {
+				if day == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next возвращает первое повторение серии, начавшейся в start, строго после after.
+// Время суток повторений совпадает с временем start. false - повторений больше нет
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	var next time.Time
+	var ok bool
+	switch r.Freq {
+	case Daily:
+		next, ok = r.nextDaily(start, after)
+	case Weekly:
+		next, ok = r.nextWeekly(start, after)
+	case Monthly:
+		next, ok = r.nextMonthly(start, after)
+	}
+	if !ok || (!r.Until.IsZero() && next.After(r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Rule) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// firstPeriod возвращает номер периода, с которого стоит начинать перебор: на один интервал раньше
+// приблизительного периода after, чтобы не пропустить повторения из-за перехода на летнее время и т.п.
+func (r Rule) firstPeriod(periods int) int {
+	interval := r.interval()
+	k := periods/interval*interval - interval
+	if k < 0 {
+		return 0
+	}
+	return k
+}
+
+func (r Rule) nextDaily(start, after time.Time) (time.Time, bool) {
+	k := r.firstPeriod(int(after.Sub(start).Hours() / 24))
+	for i := 0; i < maxIterations; i++ {
+		if t := start.AddDate(0, 0, k); t.After(after) {
+			return t, true
+		}
+		k += r.interval()
+	}
+	return time.Time{}, false
+}
+
+func (r Rule) nextWeekly(start, after time.Time) (time.Time, bool) {
+	days := r.ByDay
+	if len(days) == 0 {
+		days = []time.Weekday{start.Weekday()}
+	}
+	days = sortedWeekdays(days)
+
+	// Неделя начинается с понедельника
+	weekStart := start.AddDate(0, 0, -mondayOffset(start.Weekday()))
+	k := r.firstPeriod(int(after.Sub(weekStart).Hours() / (24 * 7)))
+	for i := 0; i < maxIterations; i++ {
+		for _, day := range days {
+			t := weekStart.AddDate(0, 0, k*7+mondayOffset(day))
+			if t.Before(start) {
+				continue
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+		k += r.interval()
+	}
+	return time.Time{}, false
+}
+
+func (r Rule) nextMonthly(start, after time.Time) (time.Time, bool) {
+	monthDay := r.ByMonthDay
+	if monthDay == 0 {
+		monthDay = start.Day()
+	}
+
+	months := (after.Year()-start.Year())*12 + int(after.Month()) - int(start.Month())
+	k := r.firstPeriod(months)
+	for i := 0; i < maxIterations; i++ {
+		first := time.Date(
+			start.Year(), start.Month()+time.Month(k), 1,
+			start.Hour(), start.Minute(), start.Second(), 0, start.Location(),
+		)
+		k += r.interval()
+
+		daysInMonth := first.AddDate(0, 1, -1).Day()
+		day := monthDay
+		if day < 0 {
+			day = daysInMonth + day + 1
+		}
+		if day < 1 || day > daysInMonth {
+			continue
+		}
+
+		t := time.Date(first.Year(), first.Month(), day, first.Hour(), first.Minute(), first.Second(), 0, first.Location())
+		if !t.Before(start) && t.After(after) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func mondayOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func sortedWeekdays(days []time.Weekday) []time.Weekday {
+	sorted := append([]time.Weekday(nil), days...)
+	sort.Slice(
+		sorted, func(i, j int) bool {
+			return mondayOffset(sorted[i]) < mondayOffset(sorted[j])
+		},
+	)
+	return sorted
+}
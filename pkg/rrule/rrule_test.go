@@ -0,0 +1,346 @@
+package rrule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+// occurrences возвращает до n первых повторений серии, начиная с самого start
+func occurrences(t *testing.T, rule Rule, start time.Time, n int) []time.Time {
+	t.Helper()
+	var got []time.Time
+	after := start.Add(-time.Second)
+	for len(got) < n {
+		next, ok := rule.Next(start, after)
+		if !ok {
+			break
+		}
+		if !next.After(after) {
+			t.Fatalf("Next(%s) = %s, not after it", after, next)
+		}
+		got = append(got, next)
+		after = next
+	}
+	return got
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		n     int
+		want  []time.Time
+	}{
+		{
+			name:  "daily with interval",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: date(2025, time.January, 30, 9, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 30, 9, 0), date(2025, time.February, 2, 9, 0),
+				date(2025, time.February, 5, 9, 0), date(2025, time.February, 8, 9, 0),
+			},
+		},
+		{
+			name:  "weekly with interval",
+			rule:  "FREQ=WEEKLY;INTERVAL=2",
+			start: date(2025, time.January, 1, 18, 30),
+			n:     3,
+			want: []time.Time{
+				date(2025, time.January, 1, 18, 30), date(2025, time.January, 15, 18, 30),
+				date(2025, time.January, 29, 18, 30),
+			},
+		},
+		{
+			name:  "monthly with interval",
+			rule:  "FREQ=MONTHLY;INTERVAL=2",
+			start: date(2025, time.November, 15, 8, 0),
+			n:     3,
+			want: []time.Time{
+				date(2025, time.November, 15, 8, 0), date(2026, time.January, 15, 8, 0),
+				date(2026, time.March, 15, 8, 0),
+			},
+		},
+		{
+			name:  "several weekdays starting mid-week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			start: date(2025, time.January, 1, 10, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 1, 10, 0), date(2025, time.January, 3, 10, 0),
+				date(2025, time.January, 6, 10, 0), date(2025, time.January, 8, 10, 0),
+			},
+		},
+		{
+			name:  "several weekdays every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: date(2025, time.January, 6, 7, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 7, 7, 0), date(2025, time.January, 9, 7, 0),
+				date(2025, time.January, 21, 7, 0), date(2025, time.January, 23, 7, 0),
+			},
+		},
+		{
+			name:  "unsorted weekdays",
+			rule:  "FREQ=WEEKLY;BYDAY=SU,FR,MO",
+			start: date(2025, time.January, 6, 7, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 6, 7, 0), date(2025, time.January, 10, 7, 0),
+				date(2025, time.January, 12, 7, 0), date(2025, time.January, 13, 7, 0),
+			},
+		},
+		{
+			name:  "month day 31 skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: date(2025, time.January, 31, 12, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 31, 12, 0), date(2025, time.March, 31, 12, 0),
+				date(2025, time.May, 31, 12, 0), date(2025, time.July, 31, 12, 0),
+			},
+		},
+		{
+			name:  "start day 31 without BYMONTHDAY skips short months",
+			rule:  "FREQ=MONTHLY",
+			start: date(2025, time.January, 31, 12, 0),
+			n:     2,
+			want:  []time.Time{date(2025, time.January, 31, 12, 0), date(2025, time.March, 31, 12, 0)},
+		},
+		{
+			name:  "last day of month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2025, time.January, 31, 12, 0),
+			n:     4,
+			want: []time.Time{
+				date(2025, time.January, 31, 12, 0), date(2025, time.February, 28, 12, 0),
+				date(2025, time.March, 31, 12, 0), date(2025, time.April, 30, 12, 0),
+			},
+		},
+		{
+			name:  "negative month day in leap february",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-3",
+			start: date(2024, time.January, 1, 12, 0),
+			n:     3,
+			want: []time.Time{
+				date(2024, time.January, 29, 12, 0), date(2024, time.February, 27, 12, 0),
+				date(2024, time.March, 29, 12, 0),
+			},
+		},
+		{
+			name:  "negative month day beyond short month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-30",
+			start: date(2025, time.January, 1, 12, 0),
+			n:     3,
+			want: []time.Time{
+				date(2025, time.January, 2, 12, 0), date(2025, time.March, 2, 12, 0),
+				date(2025, time.April, 1, 12, 0),
+			},
+		},
+		{
+			name:  "until exactly on an occurrence",
+			rule:  "FREQ=DAILY;UNTIL=20250103T090000Z",
+			start: date(2025, time.January, 1, 9, 0),
+			n:     5,
+			want: []time.Time{
+				date(2025, time.January, 1, 9, 0), date(2025, time.January, 2, 9, 0),
+				date(2025, time.January, 3, 9, 0),
+			},
+		},
+		{
+			name:  "until just before an occurrence",
+			rule:  "FREQ=DAILY;UNTIL=20250103T085959Z",
+			start: date(2025, time.January, 1, 9, 0),
+			n:     5,
+			want:  []time.Time{date(2025, time.January, 1, 9, 0), date(2025, time.January, 2, 9, 0)},
+		},
+		{
+			name:  "until date includes the whole day",
+			rule:  "FREQ=WEEKLY;BYDAY=FR;UNTIL=20250110",
+			start: date(2025, time.January, 1, 23, 0),
+			n:     5,
+			want:  []time.Time{date(2025, time.January, 3, 23, 0), date(2025, time.January, 10, 23, 0)},
+		},
+		{
+			name:  "month day never reached",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31",
+			start: date(2025, time.February, 1, 12, 0),
+			n:     1,
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rule, err := Parse(tt.rule)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", tt.rule, err)
+				}
+				got := occurrences(t, rule, tt.start, tt.n)
+				if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+					t.Errorf("occurrences = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestNextAfterLongGap(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: date(2025, time.January, 1, 9, 0),
+			after: date(2025, time.March, 1, 0, 0),
+			want:  date(2025, time.March, 2, 9, 0),
+		},
+		{
+			name:  "weekly mid-week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			start: date(2025, time.January, 6, 9, 0),
+			after: date(2025, time.March, 18, 0, 0),
+			want:  date(2025, time.March, 20, 9, 0),
+		},
+		{
+			name:  "monthly on the occurrence itself",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2025, time.January, 31, 9, 0),
+			after: date(2025, time.June, 30, 9, 0),
+			want:  date(2025, time.July, 31, 9, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rule, err := Parse(tt.rule)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", tt.rule, err)
+				}
+				got, ok := rule.Next(tt.start, tt.after)
+				if !ok || !got.Equal(tt.want) {
+					t.Errorf("Next = %s, %v, want %s", got, ok, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"RRULE:",
+		"FREQ=YEARLY",
+		"FREQ=HOURLY;INTERVAL=2",
+		"INTERVAL=2",
+		"FREQ",
+		"FREQ=",
+		"FREQ=DAILY;",
+		"FREQ=DAILY;;INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=-1",
+		"FREQ=DAILY;INTERVAL=two",
+		"FREQ=DAILY;COUNT=5",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=MO,",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=DAILY;UNTIL=2025-01-01",
+		"FREQ=DAILY;UNTIL=20250101T0900",
+	}
+	for _, s := range tests {
+		t.Run(
+			s, func(t *testing.T) {
+				if rule, err := Parse(s); !errors.Is(err, ErrInvalidRule) {
+					t.Errorf("Parse(%q) = %+v, %v, want ErrInvalidRule", s, rule, err)
+				}
+			},
+		)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Rule
+	}{
+		{input: "FREQ=DAILY", want: Rule{Freq: Daily, Interval: 1}},
+		{input: "RRULE:freq=weekly;byday=mo,fr", want: Rule{Freq: Weekly, Interval: 1, ByDay: []time.Weekday{1, 5}}},
+		{input: " FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-1 ", want: Rule{Freq: Monthly, Interval: 3, ByMonthDay: -1}},
+		{
+			input: "FREQ=DAILY;UNTIL=20250103",
+			want:  Rule{Freq: Daily, Interval: 1, Until: time.Date(2025, time.January, 3, 23, 59, 59, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.input, func(t *testing.T) {
+				got, err := Parse(tt.input)
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval ||
+					!slices.Equal(got.ByDay, tt.want.ByDay) || got.ByMonthDay != tt.want.ByMonthDay ||
+					!got.Until.Equal(tt.want.Until) {
+					t.Errorf("Parse = %+v, want %+v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{input: "FREQ=DAILY;INTERVAL=1", want: "FREQ=DAILY"},
+		{input: "RRULE:FREQ=WEEKLY;BYDAY=SU,FR,MO;INTERVAL=2", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR,SU"},
+		{input: "FREQ=MONTHLY;BYMONTHDAY=-1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{input: "freq=monthly;until=20251231", want: "FREQ=MONTHLY;UNTIL=20251231T235959Z"},
+		{
+			input: "FREQ=WEEKLY;UNTIL=20250301T120000Z;BYDAY=TU",
+			want:  "FREQ=WEEKLY;BYDAY=TU;UNTIL=20250301T120000Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.input, func(t *testing.T) {
+				rule, err := Parse(tt.input)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", tt.input, err)
+				}
+				got := rule.String()
+				if got != tt.want {
+					t.Fatalf("String() = %q, want %q", got, tt.want)
+				}
+
+				again, err := Parse(got)
+				if err != nil {
+					t.Fatalf("Parse(String()): %v", err)
+				}
+				if again.String() != got {
+					t.Errorf("second round trip = %q, want %q", again.String(), got)
+				}
+			},
+		)
+	}
+}